@@ -6,9 +6,10 @@ import (
 	"database/sql"
 	"fmt"
 	"net"
-	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"github.com/cowsql/go-cowsql/internal/protocol"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/semaphore"
+	"gopkg.in/yaml.v2"
 )
 
 // used to create a unique driver name, MUST be modified atomically
@@ -35,9 +37,16 @@ type App struct {
 	address         string
 	dir             string
 	node            *cowsql.Node
+	nodeNetwork     string
 	nodeBindAddress string
-	listener        net.Listener
+	localDSN        string
+	listeners       []net.Listener
 	tls             *tlsSetup
+	tlsStats        *tlsStats
+	background      *background // Runs background goroutines, reporting their errors
+	fds             *fdCounters // Count proxied connections
+	proxyConns      *proxyConns // Byte counters of proxied connections
+	breaker         *client.CircuitBreaker
 	dialFunc        client.DialFunc
 	store           client.NodeStore
 	driver          *driver.Driver
@@ -45,12 +54,26 @@ type App struct {
 	log             client.LogFunc
 	ctx             context.Context
 	stop            context.CancelFunc // Signal App.run() to stop.
-	proxyCh         chan struct{}      // Waits for App.proxy() to return.
+	proxyCh         chan struct{}      // Waits for all App.proxy() to return.
 	runCh           chan struct{}      // Waits for App.run() to return.
 	readyCh         chan struct{}      // Waits for startup tasks
+	readyMu         sync.Mutex
+	readyErr        error // Reason why the node is not ready, see ReadyState
+	discovery       DiscoveryFunc
 	voters          int
 	standbys        int
 	roles           RolesConfig
+	rolesDisabled   bool // Roles are managed externally.
+	clock           client.Clock
+	rolesHook       func([]Operation, error)
+	join            *joinPolicy
+	earlyReady      bool
+	labels          map[string]string // Published in the configuration registry, if not nil
+	versionSkew     versionSkew
+	events          *events    // Publishes cluster events, if a sink is set
+	probes          *probePool // Clients used to probe other nodes
+	watchdog        *watchdog  // Hands over our role when degraded, if set
+	skew            *clockSkew // Measures clock skew with the leader, if set
 }
 
 // New creates a new application node.
@@ -60,15 +83,27 @@ func New(dir string, options ...Option) (app *App, err error) {
 		option(o)
 	}
 
-	var nodeBindAddress string
-	if o.Conn != nil {
-		listener, err := net.Listen("unix", o.UnixSocket)
-		if err != nil {
-			return nil, fmt.Errorf("failed to autobind unix socket: %w", err)
-		}
+	if len(o.ListenAddresses) > 0 && o.TLS == nil {
+		return nil, fmt.Errorf("additional listen addresses require TLS")
+	}
+	if o.ClockSkewThreshold < 0 {
+		return nil, fmt.Errorf("invalid clock skew threshold %s: must not be negative", o.ClockSkewThreshold)
+	}
+	if o.WatchdogInterval > 0 && o.WatchdogFailures < 1 {
+		return nil, fmt.Errorf("invalid watchdog failures %d: must be at least 1", o.WatchdogFailures)
+	}
+	if o.StoreRefreshInterval < 0 {
+		return nil, fmt.Errorf("invalid store refresh interval %s: must not be negative", o.StoreRefreshInterval)
+	}
+	if o.VersionSkewWindow < 0 {
+		return nil, fmt.Errorf("invalid version skew window %d: must not be negative", o.VersionSkewWindow)
+	}
 
-		nodeBindAddress = listener.Addr().String()
-		listener.Close()
+	if o.Clock == nil {
+		o.Clock = client.SystemClock
+	}
+	if err := validateInternalTransport(o); err != nil {
+		return nil, err
 	}
 
 	// List of cleanup functions to run in case of errors.
@@ -83,6 +118,21 @@ func New(dir string, options ...Option) (app *App, err error) {
 		}
 	}()
 
+	// Complete or discard any interrupted write of info.yaml and
+	// cluster.yaml.
+	if err := fileRecover(dir); err != nil {
+		return nil, err
+	}
+
+	// Look for problems in the data directory before handing it to the
+	// engine.
+	if err := checkDir(dir, o.AutoRepair, o.Log); err != nil {
+		return nil, err
+	}
+
+	// Files to be written, if this is a brand new application node.
+	files := map[string][]byte{}
+
 	// Load our ID, or generate one if we are joining.
 	info := client.NodeInfo{}
 	infoFileExists, err := fileExists(dir, infoFile)
@@ -96,20 +146,31 @@ func New(dir string, options ...Option) (app *App, err error) {
 			}
 		}
 		if len(o.Cluster) == 0 {
+			if o.ID != 0 {
+				return nil, fmt.Errorf("node ID can't be set on the bootstrap node")
+			}
 			info.ID = cowsql.BootstrapID
 		} else {
-			info.ID = cowsql.GenerateID(o.Address)
+			switch o.ID {
+			case 0:
+				info.ID = cowsql.GenerateID(o.Address)
+			case 1, cowsql.BootstrapID:
+				return nil, fmt.Errorf("node ID %d is reserved for the bootstrap node", o.ID)
+			default:
+				info.ID = o.ID
+			}
 			if err := fileWrite(dir, joinFile, []byte{}); err != nil {
 				return nil, err
 			}
+			cleanups = append(cleanups, func() { fileRemove(dir, joinFile) })
 		}
 		info.Address = o.Address
 
-		if err := fileMarshal(dir, infoFile, info); err != nil {
-			return nil, err
+		data, err := yaml.Marshal(info)
+		if err != nil {
+			return nil, fmt.Errorf("marshall %s: %w", infoFile, err)
 		}
-
-		cleanups = append(cleanups, func() { fileRemove(dir, infoFile) })
+		files[infoFile] = data
 	} else {
 		if err := fileUnmarshal(dir, infoFile, &info); err != nil {
 			return nil, err
@@ -128,20 +189,31 @@ func New(dir string, options ...Option) (app *App, err error) {
 		return nil, fmt.Errorf("bootstrap node can't join a cluster")
 	}
 
-	// Open the nodes store.
+	// Make sure a brand new bootstrap node is not about to form a second
+	// cluster next to an existing one.
+	if !infoFileExists && info.ID == cowsql.BootstrapID && !o.ForceBootstrap {
+		if len(o.BootstrapPeers) > 0 || o.Discovery != nil {
+			dial := makeClientDialFunc(o)
+			if err := checkBootstrap(context.Background(), info.Address, o.BootstrapPeers, o.Discovery, dial, o.Log); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	storeFileExists, err := fileExists(dir, storeFile)
 	if err != nil {
 		return nil, err
 	}
-	store, err := client.NewYamlNodeStore(filepath.Join(dir, storeFile))
-	if err != nil {
-		return nil, fmt.Errorf("open cluster.yaml node store: %w", err)
-	}
 
 	// The info file and the store file should both exists or none of them
-	// exist.
+	// exist. Older versions wrote info.yaml first, so a crash in between
+	// might have left it alone: in that case the node never started, and
+	// cluster.yaml can be initialized as for a brand new node.
 	if infoFileExists != storeFileExists {
-		return nil, fmt.Errorf("inconsistent info.yaml and cluster.yaml")
+		if !infoFileExists {
+			return nil, fmt.Errorf("inconsistent info.yaml and cluster.yaml: cluster.yaml exists but info.yaml is missing")
+		}
+		o.Log(client.LogWarn, "info.yaml exists but cluster.yaml is missing, initializing it")
 	}
 
 	if !storeFileExists {
@@ -159,30 +231,52 @@ func New(dir string, options ...Option) (app *App, err error) {
 				nodes = append(nodes, client.NodeInfo{Address: address})
 			}
 		}
-		if err := store.Set(context.Background(), nodes); err != nil {
-			return nil, fmt.Errorf("initialize node store: %w", err)
+		data, err := client.YamlCodec.Marshal(nodes)
+		if err != nil {
+			return nil, fmt.Errorf("marshall %s: %w", storeFile, err)
 		}
-		cleanups = append(cleanups, func() { fileRemove(dir, storeFile) })
+		files[storeFile] = data
+	}
+
+	if len(files) > 0 {
+		if err := fileWriteAll(dir, files); err != nil {
+			return nil, err
+		}
+		for file := range files {
+			file := file
+			cleanups = append(cleanups, func() { fileRemove(dir, file) })
+		}
+	}
+
+	// Open the nodes store.
+	store, err := client.NewYamlNodeStore(filepath.Join(dir, storeFile))
+	if err != nil {
+		return nil, fmt.Errorf("open cluster.yaml node store: %w", err)
 	}
 
 	// Start the local cowsql engine.
 	ctx, stop := context.WithCancel(context.Background())
 	var nodeDial client.DialFunc
+	tlsStats := newTLSStats()
+	bg := &background{dir: dir, log: o.Log, handler: o.BackgroundErrorHandler}
+	fds := &fdCounters{}
+	var nodeNetwork, nodeBindAddress string
+	if o.Conn != nil || o.TLS != nil {
+		nodeNetwork, nodeBindAddress, err = internalBindAddress(o, dir, info.ID)
+		if err != nil {
+			stop()
+			return nil, err
+		}
+	}
 	if o.Conn != nil {
-		nodeDial = extDialFuncWithProxy(ctx, o.Conn.dialFunc)
+		nodeDial = extDialFuncWithProxy(ctx, o.Conn.dialFunc, bg, fds)
 	} else if o.TLS != nil {
-		nodeBindAddress = fmt.Sprintf("@cowsql-%d", info.ID)
-
-		// Within a snap we need to choose a different name for the abstract unix domain
-		// socket to get it past the AppArmor confinement.
-		// See https://github.com/snapcore/snapd/blob/master/interfaces/apparmor/template.go#L357
-		snapInstanceName := os.Getenv("SNAP_INSTANCE_NAME")
-		if len(snapInstanceName) > 0 {
-			nodeBindAddress = fmt.Sprintf("@snap.%s.cowsql-%d", snapInstanceName, info.ID)
-		}
-
-		nodeDial = makeNodeDialFunc(ctx, o.TLS.Dial)
+		nodeDial = makeNodeDialFunc(ctx, o.TLS.Dial, tlsStats, bg, fds)
 	} else {
+		nodeNetwork = "tcp"
+		if strings.HasPrefix(info.Address, "@") {
+			nodeNetwork = "unix"
+		}
 		nodeBindAddress = info.Address
 		nodeDial = client.DefaultDialFunc
 	}
@@ -205,20 +299,42 @@ func New(dir string, options ...Option) (app *App, err error) {
 	}
 	cleanups = append(cleanups, func() { node.Close() })
 
+	if runtime.GOOS != "linux" && nodeBindAddress[0] == '@' {
+		// Do not use abstract socket on other platforms and left trim "@"
+		nodeBindAddress = nodeBindAddress[1:]
+	}
+
 	// Register the local cowsql driver.
-	driverDial := client.DefaultDialFunc
-	if o.TLS != nil {
-		driverDial = client.DialFuncWithTLS(driverDial, o.TLS.Dial)
-	} else if o.Conn != nil {
-		driverDial = o.Conn.dialFunc
+	driverDial := makeClientDialFunc(o)
+	localDSN := ""
+	if o.LocalPlaintext && nodeBindAddress != info.Address {
+		localDSN = nodeBindAddress
+		driverDial = makeLocalDialFunc(driverDial, info.Address, nodeNetwork, localDSN)
 	}
 
-	driver, err := driver.New(
-		store,
+	driverOptions := []driver.Option{
 		driver.WithDialFunc(driverDial),
 		driver.WithLogFunc(o.Log),
 		driver.WithTracing(o.Tracing),
-	)
+		driver.WithBatchConcurrency(o.BatchConcurrency),
+		driver.WithMaxStatementSize(o.MaxStatementSize),
+		driver.WithClock(o.Clock),
+	}
+	if o.ContextLogger != nil {
+		driverOptions = append(driverOptions, driver.WithContextLogger(o.ContextLogger))
+	}
+	if o.LabelComments {
+		driverOptions = append(driverOptions, driver.WithLabelComments())
+	}
+	if len(o.RequiredFunctions) > 0 {
+		driverOptions = append(driverOptions, driver.WithRequiredFunctions(o.RequiredFunctions...))
+	}
+	var breaker *client.CircuitBreaker
+	if o.BreakerThreshold > 0 {
+		breaker = client.NewCircuitBreaker(o.BreakerThreshold, o.BreakerCooldown)
+		driverOptions = append(driverOptions, driver.WithCircuitBreaker(breaker))
+	}
+	driver, err := driver.New(store, driverOptions...)
 	if err != nil {
 		stop()
 		return nil, fmt.Errorf("create driver: %w", err)
@@ -226,14 +342,18 @@ func New(dir string, options ...Option) (app *App, err error) {
 	driverName := fmt.Sprintf("cowsql-%d", atomic.AddInt64(&driverIndex, 1))
 	sql.Register(driverName, driver)
 
-	if o.Voters < 3 || o.Voters%2 == 0 {
-		stop()
-		return nil, fmt.Errorf("invalid voters %d: must be an odd number greater than 1", o.Voters)
+	roles := RolesConfig{
+		Voters:               o.Voters,
+		StandBys:             o.StandBys,
+		MinStandBys:          o.MinStandBys,
+		MaxVotersPerDomain:   o.MaxVotersPerDomain,
+		MaxStandBysPerDomain: o.MaxStandBysPerDomain,
+		StrictFailureDomains: o.StrictFailureDomains,
+		VoterLabels:          o.VoterLabels,
 	}
-
-	if runtime.GOOS != "linux" && nodeBindAddress[0] == '@' {
-		// Do not use abstract socket on other platforms and left trim "@"
-		nodeBindAddress = nodeBindAddress[1:]
+	if err := roles.Validate(); err != nil {
+		stop()
+		return nil, err
 	}
 
 	app = &App{
@@ -241,41 +361,104 @@ func New(dir string, options ...Option) (app *App, err error) {
 		address:         info.Address,
 		dir:             dir,
 		node:            node,
+		nodeNetwork:     nodeNetwork,
 		nodeBindAddress: nodeBindAddress,
+		localDSN:        localDSN,
 		store:           store,
 		dialFunc:        driverDial,
 		driver:          driver,
 		driverName:      driverName,
 		log:             o.Log,
 		tls:             o.TLS,
+		tlsStats:        tlsStats,
+		background:      bg,
+		breaker:         breaker,
+		fds:             fds,
+		proxyConns:      newProxyConns(o.ProxyConnHook, o.Log),
 		ctx:             ctx,
 		stop:            stop,
 		runCh:           make(chan struct{}, 0),
 		readyCh:         make(chan struct{}, 0),
+		readyErr:        ErrNotReady,
+		discovery:       o.Discovery,
 		voters:          o.Voters,
 		standbys:        o.StandBys,
-		roles:           RolesConfig{Voters: o.Voters, StandBys: o.StandBys},
+		roles:           roles,
+		rolesHook:       o.RolesDecisionHook,
+		rolesDisabled:   o.DisableRolesManagement,
+		clock:           o.Clock,
+		join: &joinPolicy{
+			backoff:     o.JoinBackoff,
+			maxAttempts: o.JoinMaxAttempts,
+			onExhausted: o.JoinOnExhausted,
+		},
+		earlyReady: o.EarlyReady,
+		labels:     o.Labels,
+		versionSkew: versionSkew{
+			window: o.VersionSkewWindow,
+			refuse: o.VersionSkewRefuse,
+		},
+	}
+	app.probes = newProbePool(o.ProbeConnections, app.clientOptions()...)
+	if o.ClockSkewThreshold > 0 {
+		app.skew = &clockSkew{threshold: o.ClockSkewThreshold}
+	}
+	if o.EventSink != nil {
+		app.events = newEvents(o.EventSink)
+		bg.goroutine("events", func() { app.events.loop(ctx, o.Log) })
+	}
+	if o.WatchdogInterval > 0 {
+		app.watchdog = newWatchdog(o.WatchdogInterval, o.WatchdogFailures, o.HealthChecks)
+		bg.goroutine("watchdog", func() { app.watch(ctx, app.watchdog) })
 	}
 
-	// Start the proxy if a TLS configuration was provided.
+	// Start the proxy on each listen address if a TLS configuration was
+	// provided.
 	if o.TLS != nil {
-		listener, err := net.Listen("tcp", info.Address)
-		if err != nil {
-			return nil, fmt.Errorf("listen to %s: %w", info.Address, err)
+		for _, address := range append([]string{info.Address}, o.ListenAddresses...) {
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				for _, listener := range app.listeners {
+					listener.Close()
+				}
+				return nil, fmt.Errorf("listen to %s: %w", address, err)
+			}
+			app.listeners = append(app.listeners, listener)
 		}
 		proxyCh := make(chan struct{}, 0)
-
-		app.listener = listener
 		app.proxyCh = proxyCh
 
-		go app.proxy()
+		proxies := sync.WaitGroup{}
+		for _, listener := range app.listeners {
+			listener := listener
+			proxies.Add(1)
+			bg.goroutine("proxy", func() {
+				defer proxies.Done()
+				app.proxy(listener)
+			})
+		}
+		go func() {
+			proxies.Wait()
+			close(proxyCh)
+		}()
 
-		cleanups = append(cleanups, func() { listener.Close(); <-proxyCh })
+		listeners := app.listeners
+		cleanups = append(cleanups, func() {
+			for _, listener := range listeners {
+				listener.Close()
+			}
+			<-proxyCh
+		})
 
 	} else if o.Conn != nil {
-		go func() {
+		bg.goroutine("accept", func() {
 			for {
-				remote := <-o.Conn.acceptCh
+				var remote net.Conn
+				select {
+				case remote = <-o.Conn.acceptCh:
+				case <-app.ctx.Done():
+					return
+				}
 
 				// keep forward compatible
 				_, isTcp := remote.(*net.TCPConn)
@@ -287,22 +470,34 @@ func New(dir string, options ...Option) (app *App, err error) {
 					n, err := remote.Write(data)
 					if err != nil || n != len(data) {
 						remote.Close()
-						panic(fmt.Errorf("failed to write connection header: %w", err))
+						bg.report(fmt.Errorf("failed to write connection header: %w", err))
+						continue
 					}
 				}
 
-				local, err := net.Dial("unix", nodeBindAddress)
+				local, err := net.Dial(nodeNetwork, nodeBindAddress)
 				if err != nil {
 					remote.Close()
-					panic(fmt.Errorf("failed to connect to bind address %q: %w", nodeBindAddress, err))
+					bg.report(fmt.Errorf("failed to connect to bind address %q: %w", nodeBindAddress, err))
+					continue
 				}
 
-				go proxy(app.ctx, remote, local, nil)
+				done := fds.add(&fds.proxied)
+				conn := app.proxyConns.open(remote.RemoteAddr().String())
+				bg.goroutine("proxy", func() {
+					defer done()
+					defer app.proxyConns.close(conn)
+					proxy(app.ctx, remote, local, nil, nil, conn)
+				})
 			}
-		}()
+		})
 	}
 
-	go app.run(ctx, o.RolesAdjustmentFrequency, joinFileExists)
+	refresh := o.StoreRefreshInterval
+	if refresh == 0 {
+		refresh = o.RolesAdjustmentFrequency
+	}
+	bg.goroutine("run", func() { app.run(ctx, o.RolesAdjustmentFrequency, refresh, joinFileExists) })
 
 	return app, nil
 }
@@ -334,7 +529,7 @@ func (a *App) Handover(ctx context.Context) error {
 		return fmt.Errorf("cluster servers: %w", err)
 	}
 
-	changes := a.makeRolesChanges(nodes)
+	changes := a.makeRolesChanges(cli, nodes)
 
 	role, candidates := changes.Handover(a.id)
 
@@ -363,8 +558,8 @@ func (a *App) Handover(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("cluster servers: %w", err)
 		}
-		changes := a.makeRolesChanges(nodes)
-		voters := changes.list(client.Voter, true)
+		changes := a.makeRolesChanges(cli, nodes)
+		voters := changes.List(client.Voter, true)
 
 		for i, voter := range voters {
 			if voter.Address == a.address {
@@ -395,13 +590,11 @@ func (a *App) Handover(ctx context.Context) error {
 			if err == nil {
 				return nil
 			}
+			// Wait a bit before trying again
 			select {
 			case <-ctx.Done():
 				return fmt.Errorf("demote ourselves context done: %w", err)
-			default:
-				// Wait a bit before trying again
-				time.Sleep(time.Second)
-				continue
+			case <-a.clock.After(time.Second):
 			}
 		}
 	}
@@ -409,14 +602,80 @@ func (a *App) Handover(ctx context.Context) error {
 	return nil
 }
 
+// Key of the configuration registry entry telling whether roles adjustment
+// is paused.
+const rolesPausedKey = "roles/paused"
+
+// PauseRolesAdjustment stops the cluster leader from automatically promoting
+// or demoting nodes, for example during a maintenance window in which roles
+// are changed manually with client.Client.Assign().
+//
+// The pause is a cluster-wide setting stored in the configuration registry,
+// so it can be set from any node and survives leadership changes, until
+// ResumeRolesAdjustment() is called.
+//
+// Explicit operations like Handover() are not affected.
+func (a *App) PauseRolesAdjustment(ctx context.Context) error {
+	return a.setRolesAdjustmentPaused(ctx, true)
+}
+
+// ResumeRolesAdjustment resumes automatic roles adjustment after a call to
+// PauseRolesAdjustment().
+func (a *App) ResumeRolesAdjustment(ctx context.Context) error {
+	return a.setRolesAdjustmentPaused(ctx, false)
+}
+
+// RolesAdjustmentPaused returns true if automatic roles adjustment is paused
+// cluster-wide.
+func (a *App) RolesAdjustmentPaused(ctx context.Context) (bool, error) {
+	cli, err := a.Leader(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer cli.Close()
+
+	return rolesAdjustmentPaused(ctx, cli)
+}
+
+func (a *App) setRolesAdjustmentPaused(ctx context.Context, paused bool) error {
+	cli, err := a.Leader(ctx)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	return cli.SetConfig(ctx, rolesPausedKey, strconv.FormatBool(paused))
+}
+
+// Read the roles adjustment pause setting using the given leader client.
+func rolesAdjustmentPaused(ctx context.Context, cli *client.Client) (bool, error) {
+	value, err := cli.GetConfig(ctx, rolesPausedKey)
+	if err != nil {
+		if errors.Is(err, client.ErrConfigNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
 // Close the application node, releasing all resources it created.
 func (a *App) Close() error {
 	// Stop the run goroutine.
 	a.stop()
 	<-a.runCh
+	a.probes.Close()
+	if a.events != nil {
+		<-a.events.done
+	}
+	if a.watchdog != nil {
+		<-a.watchdog.done
+	}
 
-	if a.listener != nil {
-		a.listener.Close()
+	if a.listeners != nil {
+		for _, listener := range a.listeners {
+			listener.Close()
+		}
 		<-a.proxyCh
 	}
 	if err := a.node.Close(); err != nil {
@@ -435,11 +694,65 @@ func (a *App) Address() string {
 	return a.address
 }
 
+// LocalDSN returns the address of the unix socket that the local cowsql
+// engine listens to, if the WithLocalPlaintext option was given and the node
+// is behind the TLS or external connection proxy. Otherwise it returns an
+// empty string.
+//
+// Local drivers and clients can connect to the returned address with
+// client.DefaultDialFunc, skipping the proxy and its encryption.
+func (a *App) LocalDSN() string {
+	return a.localDSN
+}
+
 // Driver returns the name used to register the cowsql driver.
 func (a *App) Driver() string {
 	return a.driverName
 }
 
+// DriverStats returns statistics about the leader changes observed by the
+// registered cowsql driver, for example the time the last failover took.
+func (a *App) DriverStats() driver.Stats {
+	return a.driver.Stats()
+}
+
+// SetTracing changes the level at which statements executed through the
+// registered cowsql driver are logged, see WithTracing. It applies to the
+// connections already open as well, so statement tracing can be turned on
+// temporarily to debug an issue in production, and turned off again with
+// client.LogNone.
+func (a *App) SetTracing(level client.LogLevel) {
+	a.driver.SetTracing(level)
+}
+
+// Metrics returns usage statistics about each database accessed through the
+// registered cowsql driver, keyed by database name, for example to identify
+// the tenant generating most of the load on a cluster hosting many databases.
+//
+// Only statements issued by this node's driver are accounted.
+func (a *App) Metrics() map[string]driver.DatabaseMetrics {
+	return a.driver.Metrics()
+}
+
+// TLSStats returns statistics about the TLS handshakes performed by the node
+// proxy, for example how many of them resumed a previous session. All values
+// are zero if TLS is not enabled.
+func (a *App) TLSStats() TLSStats {
+	return a.tlsStats.get()
+}
+
+// FDStats returns the number of sockets currently held by the node for
+// cluster traffic, for example to check that connections are not leaked.
+func (a *App) FDStats() FDStats {
+	return FDStats{
+		Listeners:    len(a.listeners),
+		Proxied:      int(atomic.LoadInt64(&a.fds.proxied)),
+		NodeSockets:  int(atomic.LoadInt64(&a.fds.node)),
+		ProbeClients: a.probes.Len(),
+		Process:      processFDs(),
+	}
+}
+
 // Ready can be used to wait for a node to complete some initial tasks that are
 // initiated at startup. For example a brand new node will attempt to join the
 // cluster, a restarted node will check if it should assume some particular
@@ -473,7 +786,7 @@ func (a *App) Open(ctx context.Context, database string) (*sql.DB, error) {
 		if cause != driver.ErrNoAvailableLeader {
 			return nil, err
 		}
-		time.Sleep(time.Second)
+		<-a.clock.After(time.Second)
 	}
 	if err != nil {
 		return nil, err
@@ -484,62 +797,120 @@ func (a *App) Open(ctx context.Context, database string) (*sql.DB, error) {
 
 // Leader returns a client connected to the current cluster leader, if any.
 func (a *App) Leader(ctx context.Context) (*client.Client, error) {
-	return client.FindLeader(ctx, a.store, a.clientOptions()...)
+	options := a.clientOptions()
+	if a.breaker != nil {
+		options = append(options, client.WithCircuitBreaker(a.breaker))
+	}
+	return client.FindLeader(ctx, a.store, options...)
 }
 
 // Client returns a client connected to the local node.
 func (a *App) Client(ctx context.Context) (*client.Client, error) {
-	return client.New(ctx, a.nodeBindAddress)
+	return client.New(ctx, a.nodeBindAddress, client.WithDialFunc(a.dialNode))
+}
+
+// Dial the local node directly, through its bind address.
+func (a *App) dialNode(ctx context.Context, address string) (net.Conn, error) {
+	dialer := net.Dialer{}
+	return dialer.DialContext(ctx, a.nodeNetwork, address)
 }
 
-// Proxy incoming TLS connections.
-func (a *App) proxy() {
+// Proxy incoming TLS connections accepted by the given listener.
+func (a *App) proxy(listener net.Listener) {
 	wg := sync.WaitGroup{}
 	ctx, cancel := context.WithCancel(a.ctx)
 	for {
-		client, err := a.listener.Accept()
+		client, err := listener.Accept()
 		if err != nil {
 			cancel()
 			wg.Wait()
-			close(a.proxyCh)
 			return
 		}
 		address := client.RemoteAddr()
 		a.debug("new connection from %s", address)
-		server, err := net.Dial("unix", a.nodeBindAddress)
+		server, err := net.Dial(a.nodeNetwork, a.nodeBindAddress)
 		if err != nil {
 			a.error("dial local node: %v", err)
 			client.Close()
 			continue
 		}
 		wg.Add(1)
-		go func() {
+		done := a.fds.add(&a.fds.proxied)
+		conn := a.proxyConns.open(address.String())
+		a.background.goroutine("proxy", func() {
 			defer wg.Done()
-			if err := proxy(ctx, client, server, a.tls.Listen); err != nil {
+			defer done()
+			defer a.proxyConns.close(conn)
+			if err := proxy(ctx, client, server, a.tls.Listen, a.tlsStats, conn); err != nil {
 				a.error("proxy: %v", err)
 			}
-		}()
+		})
 	}
 }
 
 // Run background tasks. The join flag is true if the node is a brand new one
 // and should join the cluster.
-func (a *App) run(ctx context.Context, frequency time.Duration, join bool) {
+func (a *App) run(ctx context.Context, frequency, refresh time.Duration, join bool) {
 	defer close(a.runCh)
 
 	delay := time.Duration(0)
-	ready := false
+
+	// Once ready, the node store is refreshed and the roles are adjusted
+	// on independent schedules, waking up at the earliest deadline.
+	nextRefresh := time.Time{}
+	nextAdjust := time.Time{}
+	schedule := func(now time.Time) time.Duration {
+		delay := nextRefresh.Sub(now)
+		if !a.rolesDisabled && nextAdjust.Sub(now) < delay {
+			delay = nextAdjust.Sub(now)
+		}
+		return delay
+	}
+
+	ready := false     // Whether startup tasks are done
+	signaled := false  // Whether readyCh was closed
+	published := false // Whether our metadata was published
+	signalReady := func() {
+		if signaled {
+			return
+		}
+		signaled = true
+		a.setReadyState(nil)
+		close(a.readyCh)
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			// If we didn't become ready yet, close the ready
 			// channel, to unblock any call to Ready().
-			if !ready {
+			if !signaled {
+				signaled = true
 				close(a.readyCh)
 			}
 			return
-		case <-time.After(delay):
-			cli, err := a.Leader(ctx)
+		case <-a.clock.After(delay):
+			refreshDue := false
+			adjustDue := false
+			if ready {
+				now := a.clock.Now()
+				if !now.Before(nextRefresh) {
+					refreshDue = true
+					nextRefresh = now.Add(refresh)
+				}
+				if !a.rolesDisabled && !now.Before(nextAdjust) {
+					adjustDue = true
+					nextAdjust = now.Add(frequency)
+				}
+				delay = schedule(now)
+			}
+
+			var cli *client.Client
+			var err error
+			if ready {
+				cli, err = a.Leader(ctx)
+			} else {
+				cli, err = a.startupLeader(ctx)
+			}
 			if err != nil {
 				continue
 			}
@@ -547,50 +918,119 @@ func (a *App) run(ctx context.Context, frequency time.Duration, join bool) {
 			// Attempt to join the cluster if this is a brand new node.
 			if join {
 				info := client.NodeInfo{ID: a.id, Address: a.address, Role: client.Spare}
-				if err := cli.Add(ctx, info); err != nil {
-					a.warn("join cluster: %v", err)
-					delay = time.Second
+				member, err := a.checkID(ctx, cli)
+				if errors.Is(err, client.ErrIDCollision) {
+					cli.Close()
+					a.error("join cluster: %v", err)
+					a.setReadyState(fmt.Errorf("%w: %v", ErrJoinFailed, err))
+					return
+				}
+				if err == nil && !member {
+					err = cli.Add(ctx, info)
+				}
+				if err != nil {
 					cli.Close()
+					var retry bool
+					if delay, retry = a.join.failed(); !retry {
+						a.error("join cluster: %v, giving up after %d attempts", err, a.join.attempts)
+						a.setReadyState(fmt.Errorf("%w: %v", ErrJoinFailed, err))
+						if a.join.onExhausted != nil {
+							a.join.onExhausted(err)
+						}
+						return
+					}
+					a.warn("join cluster (attempt %d): %v, retrying in %s", a.join.attempts, err, delay)
 					continue
 				}
 				join = false
 				if err := fileRemove(a.dir, joinFile); err != nil {
 					a.error("remove join file: %v", err)
 				}
+			}
 
+			if a.earlyReady {
+				signalReady()
 			}
 
-			// Refresh our node store.
-			servers, err := cli.Cluster(ctx)
-			if err != nil {
-				cli.Close()
-				continue
+			// Publish our versions and labels once per run, before
+			// taking any role decision.
+			if !published {
+				if err := a.publishMetadata(ctx, cli); err != nil {
+					a.warn("publish metadata: %v", err)
+				} else {
+					published = true
+				}
 			}
-			if len(servers) == 0 {
-				a.warn("server list empty")
-				cli.Close()
-				continue
+
+			// Fetch the current cluster members, if we are starting
+			// up or it's time to refresh our node store.
+			var servers []client.NodeInfo
+			if !ready || refreshDue {
+				servers, err = cli.Cluster(ctx)
+				if err != nil {
+					cli.Close()
+					continue
+				}
+				if len(servers) == 0 {
+					a.warn("server list empty")
+					cli.Close()
+					continue
+				}
 			}
-			a.store.Set(ctx, servers)
 
 			// If we are starting up, let's see if we should
-			// promote ourselves.
+			// promote ourselves, while updating the store.
 			if !ready {
-				if err := a.maybePromoteOurselves(ctx, cli, servers); err != nil {
+				refreshed := make(chan struct{})
+				a.background.goroutine("refresh store", func() {
+					defer close(refreshed)
+					a.refreshStore(ctx, cli, servers)
+				})
+				var err error
+				if !a.rolesDisabled {
+					err = a.maybePromoteOurselves(ctx, cli, servers)
+				}
+				<-refreshed
+				if err != nil {
 					a.warn("%v", err)
 					delay = time.Second
 					cli.Close()
 					continue
 				}
 				ready = true
-				delay = frequency
-				close(a.readyCh)
+				now := a.clock.Now()
+				nextRefresh = now.Add(refresh)
+				nextAdjust = now.Add(frequency)
+				delay = schedule(now)
+				signalReady()
 				cli.Close()
 				continue
 			}
 
+			if refreshDue {
+				a.refreshStore(ctx, cli, servers)
+				if a.skew != nil {
+					a.checkClockSkew(ctx, cli)
+				}
+			}
+
 			// If we are the leader, let's see if there's any
 			// adjustment we should make to node roles.
+			if !adjustDue {
+				cli.Close()
+				continue
+			}
+			paused, err := rolesAdjustmentPaused(ctx, cli)
+			if err != nil {
+				a.warn("check roles adjustment pause: %v", err)
+				cli.Close()
+				continue
+			}
+			if paused {
+				a.debug("roles adjustment paused")
+				cli.Close()
+				continue
+			}
 			if err := a.maybeAdjustRoles(ctx, cli); err != nil {
 				a.warn("adjust roles: %v", err)
 			}
@@ -599,9 +1039,38 @@ func (a *App) run(ctx context.Context, frequency time.Duration, join bool) {
 	}
 }
 
+// Save the given cluster members in the store, and publish the changes to
+// the event sinks, if any.
+func (a *App) refreshStore(ctx context.Context, cli *client.Client, servers []client.NodeInfo) {
+	a.store.Set(ctx, servers)
+
+	if a.events != nil {
+		a.observeCluster(ctx, cli, servers)
+	}
+}
+
+// Check that our ID is not used by another member of the cluster, returning
+// true if we are already a member ourselves, for example because we crashed
+// right after joining.
+func (a *App) checkID(ctx context.Context, cli *client.Client) (bool, error) {
+	servers, err := cli.Cluster(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err := client.CheckID(servers, a.id, a.address); err != nil {
+		return false, err
+	}
+	for _, server := range servers {
+		if server.ID == a.id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Possibly change our own role at startup.
 func (a *App) maybePromoteOurselves(ctx context.Context, cli *client.Client, nodes []client.NodeInfo) error {
-	roles := a.makeRolesChanges(nodes)
+	roles := a.makeRolesChanges(cli, nodes)
 
 	role := roles.Assume(a.id)
 	if role == -1 {
@@ -617,7 +1086,7 @@ func (a *App) maybePromoteOurselves(ctx context.Context, cli *client.Client, nod
 	// node threshold. If we don't succeed in doing that, errors are
 	// ignored since the leader will eventually notice that don't have
 	// enough voters and will retry.
-	if role == client.Voter && roles.count(client.Voter, true) == 1 {
+	if role == client.Voter && roles.Count(client.Voter, true) == 1 {
 		for node := range roles.State {
 			if node.ID == a.id || node.Role == client.Voter {
 				continue
@@ -635,6 +1104,7 @@ func (a *App) maybePromoteOurselves(ctx context.Context, cli *client.Client, nod
 
 // Check if any adjustment needs to be made to existing roles.
 func (a *App) maybeAdjustRoles(ctx context.Context, cli *client.Client) error {
+	operations := []Operation{}
 again:
 	info, err := cli.Leader(ctx)
 	if err != nil {
@@ -649,10 +1119,22 @@ again:
 		return err
 	}
 
-	roles := a.makeRolesChanges(nodes)
+	roles := a.makeRolesChanges(cli, nodes)
+	if len(operations) == 0 {
+		if err := a.checkVersionSkew(roles.State); err != nil {
+			return err
+		}
+	}
 
 	role, nodes := roles.Adjust(a.id)
 	if role == -1 {
+		err := roles.Check()
+		if err != nil {
+			a.warn("roles constraints: %v", err)
+		}
+		if a.rolesHook != nil {
+			a.rolesHook(operations, err)
+		}
 		return nil
 	}
 
@@ -665,6 +1147,7 @@ again:
 			}
 			continue
 		}
+		operations = append(operations, Operation{Node: node, Role: role})
 		break
 	}
 
@@ -672,8 +1155,9 @@ again:
 }
 
 // Probe all given nodes for connectivity and metadata, then return a
-// RolesChanges object.
-func (a *App) makeRolesChanges(nodes []client.NodeInfo) RolesChanges {
+// RolesChanges object. The given leader client is used to look up the labels
+// of the online nodes.
+func (a *App) makeRolesChanges(cli *client.Client, nodes []client.NodeInfo) RolesChanges {
 	state := map[client.NodeInfo]*client.NodeMetadata{}
 	for _, node := range nodes {
 		state[node] = nil
@@ -686,6 +1170,9 @@ func (a *App) makeRolesChanges(nodes []client.NodeInfo) RolesChanges {
 		sem     = semaphore.NewWeighted(int64(nProbes)) // Limit number of parallel probes
 	)
 
+	// Drop connections to nodes that were removed.
+	a.probes.Prune(nodes)
+
 	for _, node := range nodes {
 		wg.Add(1)
 		// sem.Acquire will not block forever because the goroutines
@@ -698,29 +1185,27 @@ func (a *App) makeRolesChanges(nodes []client.NodeInfo) RolesChanges {
 		go func(node protocol.NodeInfo) {
 			defer wg.Done()
 			defer sem.Release(1)
+			defer a.background.recover("probe")
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 			defer cancel()
 
-			cli, err := client.New(ctx, node.Address, a.clientOptions()...)
+			metadata, err := a.probes.Describe(ctx, node.Address)
 			if err == nil {
-				metadata, err := cli.Describe(ctx)
-				if err == nil {
-					mtx.Lock()
-					state[node] = metadata
-					mtx.Unlock()
-				}
-				cli.Close()
+				mtx.Lock()
+				state[node] = metadata
+				mtx.Unlock()
 			}
 		}(node)
 	}
 
 	wg.Wait()
+	a.fillMetadata(cli, state)
 	return RolesChanges{Config: a.roles, State: state}
 }
 
 // Return the options to use for client.FindLeader() or client.New()
 func (a *App) clientOptions() []client.Option {
-	return []client.Option{client.WithDialFunc(a.dialFunc), client.WithLogFunc(a.log)}
+	return []client.Option{client.WithDialFunc(a.dialFunc), client.WithLogFunc(a.log), client.WithClock(a.clock)}
 }
 
 func (a *App) debug(format string, args ...interface{}) {
@@ -0,0 +1,234 @@
+// +build cgo,!nosqlite3
+
+package app_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cowsql/go-cowsql/app"
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Copy a database from an existing cluster into a new one.
+func TestSeedFrom(t *testing.T) {
+	source, cleanup := newApp(t, app.WithAddress("127.0.0.1:9001"))
+	defer cleanup()
+
+	db, err := source.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE foo(n INT, t DATETIME)")
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE INDEX foo_n ON foo(n)")
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = db.Exec("INSERT INTO foo(n, t) VALUES(?, '2021-01-01 00:00:00')", i)
+		require.NoError(t, err)
+	}
+
+	target, cleanup := newApp(t, app.WithAddress("127.0.0.1:9002"))
+	defer cleanup()
+
+	store := client.NewInmemNodeStore()
+	require.NoError(t, store.Set(context.Background(), []client.NodeInfo{{Address: "127.0.0.1:9001"}}))
+
+	require.NoError(t, target.SeedFrom(context.Background(), store, "test"))
+
+	db, err = target.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM foo").Scan(&n))
+	assert.Equal(t, 3, n)
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM sqlite_master WHERE name = 'foo_n'").Scan(&n))
+	assert.Equal(t, 1, n)
+
+	// The target database is not empty anymore.
+	assert.Error(t, target.SeedFrom(context.Background(), store, "test"))
+}
+
+// Export a database to a plain SQLite file.
+func TestExport(t *testing.T) {
+	dqApp, cleanup := newApp(t, app.WithAddress("127.0.0.1:9000"))
+	defer cleanup()
+
+	db, err := dqApp.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE foo(n INT)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO foo(n) VALUES(1)")
+	require.NoError(t, err)
+
+	dir, dirCleanup := newDir(t)
+	defer dirCleanup()
+
+	path := filepath.Join(dir, "test.db")
+	require.NoError(t, dqApp.Export(context.Background(), "test", path))
+
+	_, err = os.Stat(path + "-wal")
+	assert.True(t, os.IsNotExist(err))
+
+	exported, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer exported.Close()
+
+	var n int
+	require.NoError(t, exported.QueryRow("SELECT n FROM foo").Scan(&n))
+	assert.Equal(t, 1, n)
+}
+
+// Keep a local SQLite file in sync with a database.
+func TestMirror(t *testing.T) {
+	dqApp, cleanup := newApp(t, app.WithAddress("127.0.0.1:9000"))
+	defer cleanup()
+
+	db, err := dqApp.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE foo(n INT)")
+	require.NoError(t, err)
+
+	dir, dirCleanup := newDir(t)
+	defer dirCleanup()
+
+	path := filepath.Join(dir, "test.db")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- dqApp.Mirror(ctx, "test", path, 50*time.Millisecond)
+	}()
+	defer func() {
+		cancel()
+		assert.Equal(t, context.Canceled, <-done)
+	}()
+
+	mirror, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer mirror.Close()
+
+	_, err = db.Exec("INSERT INTO foo(n) VALUES(1)")
+	require.NoError(t, err)
+
+	var n int
+	for i := 0; i < 40; i++ {
+		err = mirror.QueryRow("SELECT count(*) FROM foo").Scan(&n)
+		if err == nil && n == 1 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+// Verify a backup and rehearse its restore on a temporary node.
+func TestVerifyBackup(t *testing.T) {
+	dqApp, cleanup := newApp(t, app.WithAddress("127.0.0.1:9000"))
+	defer cleanup()
+
+	db, err := dqApp.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE foo(n INT)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO foo(n) VALUES(1), (2)")
+	require.NoError(t, err)
+
+	cli, err := dqApp.Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	files, err := cli.Dump(context.Background(), "test")
+	require.NoError(t, err)
+
+	report, err := dqApp.VerifyBackup(context.Background(), "test", files)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+
+	report, err = app.RestoreRehearsal(context.Background(), "test", files)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, map[string]int64{"foo": 2}, report.Rows)
+}
+
+func TestBackup(t *testing.T) {
+	dqApp, cleanup := newApp(t, app.WithAddress("127.0.0.1:9000"))
+	defer cleanup()
+
+	db, err := dqApp.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE foo(n INT)")
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, dqApp.Backup(context.Background(), buf, "test"))
+
+	decompressor, err := gzip.NewReader(buf)
+	require.NoError(t, err)
+	archive := tar.NewReader(decompressor)
+	h, err := archive.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "manifest.json", h.Name)
+
+	manifest := app.BackupManifest{}
+	require.NoError(t, json.NewDecoder(archive).Decode(&manifest))
+	assert.Equal(t, dqApp.ID(), manifest.Leader.ID)
+	require.Len(t, manifest.Databases, 1)
+	assert.Equal(t, "test", manifest.Databases[0].Name)
+	assert.Equal(t, []string{"CREATE TABLE foo(n INT)"}, manifest.Databases[0].Schema)
+}
+
+func TestRestore(t *testing.T) {
+	dqApp, cleanup := newApp(t, app.WithAddress("127.0.0.1:9000"))
+	defer cleanup()
+
+	db, err := dqApp.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE foo(n INT)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO foo(n) VALUES(1)")
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, dqApp.Backup(context.Background(), buf, "test"))
+
+	dir, dirCleanup := newDir(t)
+	defer dirCleanup()
+
+	cert, pool := loadCert(t)
+	options := []app.Option{app.WithAddress("127.0.0.1:9001"), app.WithTLS(app.SimpleTLSConfig(cert, pool))}
+	require.NoError(t, app.Restore(dir, buf, options...))
+
+	restored, restoredCleanup := newAppWithDir(t, dir, app.WithAddress("127.0.0.1:9001"))
+	defer restoredCleanup()
+
+	require.NoError(t, restored.Ready(context.Background()))
+	db, err = restored.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT n FROM foo").Scan(&n))
+	assert.Equal(t, 1, n)
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/x509"
 	"database/sql"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -82,6 +83,158 @@ func TestNew_JoinerRestart(t *testing.T) {
 	require.NoError(t, app2.Ready(context.Background()))
 }
 
+// A joining node that failed to start can be started again.
+func TestNew_JoinerRetryAfterFailure(t *testing.T) {
+	addr1 := "127.0.0.1:9001"
+	addr2 := "127.0.0.1:9002"
+
+	app1, cleanup := newApp(t, app.WithAddress(addr1))
+	defer cleanup()
+
+	require.NoError(t, app1.Ready(context.Background()))
+
+	dir2, cleanup := newDir(t)
+	defer cleanup()
+
+	// Occupy the address of the joining node, so it fails to start.
+	listener, err := net.Listen("tcp", addr2)
+	require.NoError(t, err)
+
+	cert, pool := loadCert(t)
+	_, err = app.New(dir2,
+		app.WithAddress(addr2), app.WithCluster([]string{addr1}),
+		app.WithTLS(app.SimpleTLSConfig(cert, pool)))
+	require.Error(t, err)
+	require.NoError(t, listener.Close())
+
+	app2, cleanup := newAppWithDir(t, dir2, app.WithAddress(addr2), app.WithCluster([]string{addr1}))
+	defer cleanup()
+
+	require.NoError(t, app2.Ready(context.Background()))
+}
+
+// A joining node can use an ID derived from a stable name.
+func TestNew_JoinerWithID(t *testing.T) {
+	addr1 := "127.0.0.1:9001"
+	addr2 := "127.0.0.1:9002"
+
+	app1, cleanup := newApp(t, app.WithAddress(addr1))
+	defer cleanup()
+
+	id := cowsql.GenerateIDFromName("node2")
+	app2, cleanup := newApp(t, app.WithAddress(addr2), app.WithCluster([]string{addr1}), app.WithID(id))
+	defer cleanup()
+
+	require.NoError(t, app2.Ready(context.Background()))
+	assert.Equal(t, id, app2.ID())
+
+	cli, err := app1.Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	cluster, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, id, cluster[1].ID)
+}
+
+// A joining node gives up if its ID is already used by another member.
+func TestNew_JoinerIDCollision(t *testing.T) {
+	addr1 := "127.0.0.1:9001"
+	addr2 := "127.0.0.1:9002"
+	addr3 := "127.0.0.1:9003"
+
+	app1, cleanup := newApp(t, app.WithAddress(addr1))
+	defer cleanup()
+
+	id := cowsql.GenerateIDFromName("node")
+	app2, cleanup := newApp(t, app.WithAddress(addr2), app.WithCluster([]string{addr1}), app.WithID(id))
+	defer cleanup()
+	require.NoError(t, app2.Ready(context.Background()))
+
+	app3, cleanup := newApp(t, app.WithAddress(addr3), app.WithCluster([]string{addr1}), app.WithID(id))
+	defer cleanup()
+
+	var err error
+	for i := 0; i < 50; i++ {
+		err = app3.ReadyState()
+		if errors.Is(err, app.ErrJoinFailed) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.True(t, errors.Is(err, app.ErrJoinFailed))
+	assert.Contains(t, err.Error(), client.ErrIDCollision.Error())
+
+	cli, err := app1.Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	cluster, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, cluster, 2)
+}
+
+// The ID of the bootstrap node can't be set.
+func TestNew_BootstrapWithID(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	_, err := app.New(dir, app.WithAddress("127.0.0.1:9000"), app.WithID(cowsql.GenerateIDFromName("node1")))
+	assert.EqualError(t, err, "node ID can't be set on the bootstrap node")
+}
+
+// A new node does not bootstrap a second cluster next to an existing one,
+// unless forced to.
+func TestNew_BootstrapPeers(t *testing.T) {
+	addr1 := "127.0.0.1:9001"
+	addr2 := "127.0.0.1:9002"
+
+	app1, cleanup := newApp(t, app.WithAddress(addr1))
+	defer cleanup()
+	require.NoError(t, app1.Ready(context.Background()))
+
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	_, err := app.New(dir, app.WithAddress(addr2), app.WithBootstrapPeers([]string{addr1}))
+	assert.True(t, errors.Is(err, app.ErrClusterExists))
+	assert.Contains(t, err.Error(), "node 127.0.0.1:9001 is part of a cluster led by 127.0.0.1:9001")
+
+	app2, err := app.New(dir, app.WithAddress(addr2), app.WithBootstrapPeers([]string{addr1}), app.WithForceBootstrap())
+	require.NoError(t, err)
+	require.NoError(t, app2.Close())
+}
+
+// The labels of a node are published in the configuration registry.
+func TestNew_Labels(t *testing.T) {
+	labels := map[string]string{"tier": "core"}
+	app1, cleanup := newApp(t, app.WithAddress("127.0.0.1:9001"), app.WithLabels(labels))
+	defer cleanup()
+
+	require.NoError(t, app1.Ready(context.Background()))
+	assert.Equal(t, labels, app1.Labels())
+
+	cli, err := app1.Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	published, err := cli.Labels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, labels, published[app1.ID()])
+}
+
+// Each node publishes the versions it runs.
+func TestNew_Versions(t *testing.T) {
+	app1, cleanup := newApp(t, app.WithAddress("127.0.0.1:9001"))
+	defer cleanup()
+
+	require.NoError(t, app1.Ready(context.Background()))
+
+	versions, err := app1.Versions(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]client.NodeVersion{app1.ID(): app1.Version()}, versions)
+}
+
 // The second joiner promotes itself and also the first joiner.
 func TestNew_SecondJoiner(t *testing.T) {
 	addr1 := "127.0.0.1:9001"
@@ -298,6 +451,51 @@ func TestHandover_Voter(t *testing.T) {
 	assert.Equal(t, client.Voter, cluster[3].Role)
 }
 
+// The operations that Handover() would perform are returned without being
+// executed.
+func TestHandoverPlan(t *testing.T) {
+	n := 4
+	apps := make([]*app.App, n)
+
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.1:900%d", i+1)
+		options := []app.Option{app.WithAddress(addr)}
+		if i > 0 {
+			options = append(options, app.WithCluster([]string{"127.0.0.1:9001"}))
+		}
+
+		app, cleanup := newApp(t, options...)
+		defer cleanup()
+
+		require.NoError(t, app.Ready(context.Background()))
+
+		apps[i] = app
+	}
+
+	cli, err := apps[0].Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	cluster, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+
+	operations, err := apps[2].HandoverPlan(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []app.Operation{
+		{Node: cluster[3], Role: client.Voter},
+		{Node: cluster[2], Role: client.Spare},
+	}, operations)
+
+	operations, err = apps[2].AdjustmentPlan(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+
+	after, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, cluster, after)
+}
+
 // In a two-node cluster only one of them is a voter. When Handover() is called
 // on the voter, the role and leadership are transfered.
 func TestHandover_TwoNodes(t *testing.T) {
@@ -578,6 +776,183 @@ func TestRolesAdjustment_ReplaceVoter(t *testing.T) {
 	assert.Equal(t, client.Voter, cluster[3].Role)
 }
 
+// The node store is refreshed independently from roles adjustment.
+func TestStoreRefreshInterval(t *testing.T) {
+	dir, dirCleanup := newDir(t)
+	defer dirCleanup()
+
+	options := []app.Option{
+		app.WithAddress("127.0.0.1:9001"),
+		app.WithRolesAdjustmentFrequency(time.Hour),
+		app.WithStoreRefreshInterval(time.Second),
+	}
+	app1, cleanup1 := newAppWithDir(t, dir, options...)
+	defer cleanup1()
+
+	require.NoError(t, app1.Ready(context.Background()))
+
+	app2, cleanup2 := newApp(t, app.WithAddress("127.0.0.1:9002"), app.WithCluster([]string{"127.0.0.1:9001"}))
+	defer cleanup2()
+
+	require.NoError(t, app2.Ready(context.Background()))
+
+	time.Sleep(3 * time.Second)
+
+	store, err := client.NewYamlNodeStore(filepath.Join(dir, "cluster.yaml"))
+	require.NoError(t, err)
+
+	nodes, err := store.Get(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+}
+
+// Roles are adjusted at their own frequency, even if the node store is
+// refreshed less often.
+func TestStoreRefreshInterval_RolesAdjustment(t *testing.T) {
+	n := 4
+	apps := make([]*app.App, n)
+	cleanups := make([]func(), n)
+
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.1:900%d", i+1)
+		options := []app.Option{
+			app.WithAddress(addr),
+			app.WithRolesAdjustmentFrequency(2 * time.Second),
+			app.WithStoreRefreshInterval(time.Hour),
+		}
+		if i > 0 {
+			options = append(options, app.WithCluster([]string{"127.0.0.1:9001"}))
+		}
+
+		app, cleanup := newApp(t, options...)
+
+		require.NoError(t, app.Ready(context.Background()))
+
+		apps[i] = app
+		cleanups[i] = cleanup
+	}
+
+	defer cleanups[0]()
+	defer cleanups[1]()
+	defer cleanups[3]()
+
+	// A voter goes offline.
+	cleanups[2]()
+
+	time.Sleep(8 * time.Second)
+
+	cli, err := apps[0].Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	cluster, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, client.Spare, cluster[2].Role)
+	assert.Equal(t, client.Voter, cluster[3].Role)
+}
+
+// If roles management is disabled, joining nodes stay spares.
+func TestRolesManagement_Disabled(t *testing.T) {
+	n := 3
+	apps := make([]*app.App, n)
+
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.1:900%d", i+1)
+		options := []app.Option{
+			app.WithAddress(addr),
+			app.WithRolesAdjustmentFrequency(time.Second),
+			app.WithDisableRolesManagement(),
+		}
+		if i > 0 {
+			options = append(options, app.WithCluster([]string{"127.0.0.1:9001"}))
+		}
+
+		app, cleanup := newApp(t, options...)
+		defer cleanup()
+
+		require.NoError(t, app.Ready(context.Background()))
+
+		apps[i] = app
+	}
+
+	time.Sleep(3 * time.Second)
+
+	cli, err := apps[0].Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	cluster, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, client.Voter, cluster[0].Role)
+	assert.Equal(t, client.Spare, cluster[1].Role)
+	assert.Equal(t, client.Spare, cluster[2].Role)
+}
+
+// If roles adjustment is paused, an offline voter is not replaced until the
+// adjustment is resumed.
+func TestRolesAdjustment_Paused(t *testing.T) {
+	n := 4
+	apps := make([]*app.App, n)
+	cleanups := make([]func(), n)
+
+	for i := 0; i < n; i++ {
+		addr := fmt.Sprintf("127.0.0.1:900%d", i+1)
+		options := []app.Option{
+			app.WithAddress(addr),
+			app.WithRolesAdjustmentFrequency(2 * time.Second),
+		}
+		if i > 0 {
+			options = append(options, app.WithCluster([]string{"127.0.0.1:9001"}))
+		}
+
+		app, cleanup := newApp(t, options...)
+
+		require.NoError(t, app.Ready(context.Background()))
+
+		apps[i] = app
+		cleanups[i] = cleanup
+	}
+
+	defer cleanups[0]()
+	defer cleanups[1]()
+	defer cleanups[3]()
+
+	// The pause is cluster-wide, so it can be set from any node.
+	require.NoError(t, apps[1].PauseRolesAdjustment(context.Background()))
+	for _, app := range apps {
+		paused, err := app.RolesAdjustmentPaused(context.Background())
+		require.NoError(t, err)
+		assert.True(t, paused)
+	}
+
+	// A voter goes offline.
+	cleanups[2]()
+
+	time.Sleep(8 * time.Second)
+
+	cli, err := apps[0].Leader(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	cluster, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, client.Voter, cluster[2].Role)
+	assert.Equal(t, client.Spare, cluster[3].Role)
+
+	require.NoError(t, apps[3].ResumeRolesAdjustment(context.Background()))
+
+	time.Sleep(8 * time.Second)
+
+	cluster, err = cli.Cluster(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, client.Spare, cluster[2].Role)
+	assert.Equal(t, client.Voter, cluster[3].Role)
+}
+
 // If a voter goes offline, another node takes its place. If possible, pick a
 // voter from a failure domain which differs from the one of the two other
 // voters.
@@ -869,6 +1244,91 @@ func TestOpen(t *testing.T) {
 
 	_, err = db.ExecContext(context.Background(), "CREATE TABLE foo(n INT)")
 	assert.NoError(t, err)
+
+	metrics := app.Metrics()["test"]
+	assert.NotZero(t, metrics.Statements)
+}
+
+// With the WithLocalPlaintext option the local node can be reached without
+// going through the TLS proxy.
+func TestOpen_LocalPlaintext(t *testing.T) {
+	app, cleanup := newApp(t, app.WithAddress("127.0.0.1:9000"), app.WithLocalPlaintext())
+	defer cleanup()
+
+	require.NotEmpty(t, app.LocalDSN())
+
+	db, err := app.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(context.Background(), "CREATE TABLE foo(n INT)")
+	assert.NoError(t, err)
+
+	cli, err := client.New(context.Background(), app.LocalDSN())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	leader, err := cli.Leader(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:9000", leader.Address)
+
+	assert.Equal(t, uint64(0), app.TLSStats().Handshakes)
+}
+
+// The proxy can reach the local node through a TCP loopback port instead of
+// an abstract unix socket.
+func TestOpen_InternalTransportTCPLoopback(t *testing.T) {
+	options := []app.Option{
+		app.WithAddress("127.0.0.1:9000"),
+		app.WithInternalTransport(app.TransportTCPLoopback),
+	}
+	app, cleanup := newApp(t, options...)
+	defer cleanup()
+
+	db, err := app.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.ExecContext(context.Background(), "CREATE TABLE foo(n INT)")
+	assert.NoError(t, err)
+
+	cli, err := app.Client(context.Background())
+	require.NoError(t, err)
+	defer cli.Close()
+}
+
+// Vacuum a database after deleting most of its content.
+func TestVacuum(t *testing.T) {
+	dqApp, cleanup := newApp(t, app.WithAddress("127.0.0.1:9000"))
+	defer cleanup()
+
+	db, err := dqApp.Open(context.Background(), "test")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE foo(data BLOB)")
+	require.NoError(t, err)
+	for i := 0; i < 64; i++ {
+		_, err = db.Exec("INSERT INTO foo(data) VALUES(?)", make([]byte, 4096))
+		require.NoError(t, err)
+	}
+	_, err = db.Exec("DELETE FROM foo")
+	require.NoError(t, err)
+
+	progress := []app.VacuumProgress{}
+	require.NoError(t, dqApp.Vacuum(context.Background(), "test", app.WithVacuumProgress(func(p app.VacuumProgress) {
+		progress = append(progress, p)
+	})))
+
+	require.Len(t, progress, 2)
+	assert.False(t, progress[0].Done)
+	assert.True(t, progress[0].Free > 0)
+	assert.True(t, progress[1].Done)
+	assert.Equal(t, int64(0), progress[1].Free)
+
+	var free int
+	require.NoError(t, db.QueryRow("PRAGMA freelist_count").Scan(&free))
+	assert.Equal(t, 0, free)
 }
 
 // Test some setup options
@@ -1106,6 +1566,32 @@ func TestParallelNewApp(t *testing.T) {
 	}
 }
 
+// Connections are accepted on all listen addresses.
+func TestAddress_MultipleListeners(t *testing.T) {
+	cert, pool := loadCert(t)
+	dial := client.DialFuncWithTLS(client.DefaultDialFunc, app.SimpleDialTLSConfig(cert, pool))
+
+	_, cleanup := newApp(t, app.WithAddress("127.0.0.1:9000", "127.0.0.1:9001"))
+	defer cleanup()
+
+	for _, address := range []string{"127.0.0.1:9000", "127.0.0.1:9001"} {
+		cli, err := client.New(context.Background(), address, client.WithDialFunc(dial))
+		require.NoError(t, err)
+		_, err = cli.Leader(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, cli.Close())
+	}
+}
+
+// Additional listen addresses can't be used without TLS.
+func TestAddress_MultipleListenersNoTLS(t *testing.T) {
+	dir, cleanup := newDir(t)
+	defer cleanup()
+
+	_, err := app.New(dir, app.WithAddress("127.0.0.1:9000", "127.0.0.1:9001"))
+	assert.EqualError(t, err, "additional listen addresses require TLS")
+}
+
 func newAppWithDir(t *testing.T, dir string, options ...app.Option) (*app.App, func()) {
 	t.Helper()
 
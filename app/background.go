@@ -0,0 +1,85 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// BackgroundErrorHandler is invoked with the errors that happen in the
+// background goroutines of an App, which have no caller to be returned to.
+//
+// Panics recovered in those goroutines are passed as *PanicError.
+type BackgroundErrorHandler func(err error)
+
+// PanicError reports a panic recovered in a background goroutine of an App.
+type PanicError struct {
+	Task  string      // Name of the background task that panicked.
+	Value interface{} // Value passed to panic().
+	Stack []byte      // Stack trace of the goroutine.
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", e.Task, e.Value)
+}
+
+// Name of the file in the data directory where recovered panics are
+// appended, so they survive a restart of the application.
+const panicFile = "panics.log"
+
+// Run the background goroutines of an App, reporting their errors instead of
+// crashing the process.
+type background struct {
+	dir     string
+	log     client.LogFunc
+	handler BackgroundErrorHandler
+}
+
+// Log the given error and pass it to the handler, if any.
+func (b *background) report(err error) {
+	b.log(client.LogError, "%v", err)
+	if b.handler != nil {
+		b.handler(err)
+	}
+}
+
+// Recover from a panic in the background task with the given name, if any,
+// and report it. Must be deferred.
+func (b *background) recover(task string) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	err := &PanicError{Task: task, Value: value, Stack: debug.Stack()}
+	if perr := b.persist(err); perr != nil {
+		b.log(client.LogError, "record panic in %s: %v", panicFile, perr)
+	}
+	b.report(err)
+}
+
+// Run the given function in a new goroutine, recovering from panics.
+func (b *background) goroutine(task string, f func()) {
+	go func() {
+		defer b.recover(task)
+		f()
+	}()
+}
+
+// Append the given panic to the panics file in the data directory.
+func (b *background) persist(err *PanicError) error {
+	f, ferr := os.OpenFile(filepath.Join(b.dir, panicFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if ferr != nil {
+		return ferr
+	}
+	defer f.Close()
+
+	if _, ferr := fmt.Fprintf(f, "%s %v\n%s\n", time.Now().Format(time.RFC3339), err, err.Stack); ferr != nil {
+		return ferr
+	}
+
+	return f.Sync()
+}
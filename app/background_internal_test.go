@@ -0,0 +1,40 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Panics in background goroutines are recovered, persisted and reported.
+func TestBackground_Panic(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	errs := make(chan error, 1)
+	bg := &background{
+		dir:     dir,
+		log:     client.DefaultLogFunc,
+		handler: func(err error) { errs <- err },
+	}
+
+	bg.goroutine("test", func() { panic("boom") })
+
+	err := <-errs
+	assert.EqualError(t, err, "panic in test: boom")
+
+	perr, ok := err.(*PanicError)
+	require.True(t, ok)
+	assert.Equal(t, "boom", perr.Value)
+	assert.Contains(t, string(perr.Stack), "TestBackground_Panic")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, panicFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "panic in test: boom\n")
+	assert.Contains(t, string(data), "TestBackground_Panic")
+}
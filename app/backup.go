@@ -0,0 +1,255 @@
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// Version of the layout of the archives written by Backup.
+const backupFormat = 1
+
+// Name of the manifest in a backup archive, and directory holding the files of
+// the databases.
+const (
+	backupManifest  = "manifest.json"
+	backupDatabases = "databases"
+)
+
+// BackupManifest describes the content of an archive written by Backup. It's
+// stored as the first entry of the archive, named manifest.json.
+type BackupManifest struct {
+	Format    int                           // Version of the archive layout.
+	Created   time.Time                     // When the backup was started.
+	Leader    client.NodeInfo               // Node the databases were dumped from.
+	Cluster   []client.NodeInfo             // Cluster members at the time of the backup.
+	Versions  map[uint64]client.NodeVersion `json:",omitempty"` // Versions published by the nodes, if any.
+	Databases []BackupDatabase
+}
+
+// BackupDatabase describes a database in a backup archive.
+type BackupDatabase struct {
+	Name   string
+	Files  []BackupFile // Main database file and WAL.
+	Schema []string     // SQL text of the tables, indexes, views and triggers.
+}
+
+// BackupFile describes a database file in a backup archive, stored under the
+// databases/ directory.
+type BackupFile struct {
+	Name   string
+	Size   int64
+	SHA256 string // Hex-encoded checksum of the content.
+}
+
+// Backup writes a gzip-compressed tar archive holding all the databases of
+// the cluster to the given writer, for disaster recovery. If database names
+// are given, only those databases are included.
+//
+// The archive starts with a manifest, see BackupManifest, describing the
+// cluster membership, the versions of the nodes and the files and schema of
+// each database. It's followed by the main file and the WAL of each database,
+// as dumped from the current leader.
+//
+// Each database is dumped consistently, but databases are dumped one after
+// the other, so the archive is not a snapshot of the whole cluster at a single
+// point in time. The schema is read right after each dump. When no name is
+// given, the databases are the ones found in the raft data of this node, so a
+// database created very recently might be missing if the node is lagging.
+func (a *App) Backup(ctx context.Context, w io.Writer, databases ...string) error {
+	if len(databases) == 0 {
+		info, err := InspectDir(a.dir)
+		if err != nil {
+			return fmt.Errorf("list databases: %w", err)
+		}
+		databases = info.Databases
+	}
+
+	cli, err := a.Leader(ctx)
+	if err != nil {
+		return fmt.Errorf("find leader: %w", err)
+	}
+	defer cli.Close()
+
+	manifest := BackupManifest{Format: backupFormat, Created: a.clock.Now().UTC()}
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return fmt.Errorf("get leader: %w", err)
+	}
+	manifest.Leader = *leader
+	if manifest.Cluster, err = cli.Cluster(ctx); err != nil {
+		return fmt.Errorf("get cluster: %w", err)
+	}
+	if versions, err := cli.Versions(ctx); err == nil && len(versions) > 0 {
+		manifest.Versions = versions
+	}
+
+	dumps := make([][]client.File, len(databases))
+	for i, name := range databases {
+		files, err := cli.Dump(ctx, name)
+		if err != nil {
+			return fmt.Errorf("dump %s: %w", name, err)
+		}
+		schema, err := a.backupSchema(ctx, name)
+		if err != nil {
+			return fmt.Errorf("read schema of %s: %w", name, err)
+		}
+		manifest.Databases = append(manifest.Databases, newBackupDatabase(name, files, schema))
+		dumps[i] = files
+	}
+
+	return writeBackup(w, manifest, dumps)
+}
+
+// Return the SQL text of the schema objects of the database with the given
+// name.
+func (a *App) backupSchema(ctx context.Context, name string) ([]string, error) {
+	db, err := a.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, "SELECT sql FROM sqlite_master WHERE sql IS NOT NULL ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schema := []string{}
+	for rows.Next() {
+		var sql string
+		if err := rows.Scan(&sql); err != nil {
+			return nil, err
+		}
+		schema = append(schema, sql)
+	}
+
+	return schema, rows.Err()
+}
+
+func newBackupDatabase(name string, files []client.File, schema []string) BackupDatabase {
+	database := BackupDatabase{Name: name, Schema: schema}
+	for _, file := range files {
+		sum := sha256.Sum256(file.Data)
+		database.Files = append(database.Files, BackupFile{
+			Name:   filepath.Base(file.Name),
+			Size:   int64(len(file.Data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	return database
+}
+
+// Write the given manifest and the given database files as a gzip-compressed
+// tar archive.
+func writeBackup(w io.Writer, manifest BackupManifest, dumps [][]client.File) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+
+	compressor := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressor)
+	write := func(name string, data []byte) error {
+		h := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: manifest.Created}
+		if err := archive.WriteHeader(h); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+		if _, err := archive.Write(data); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+		return nil
+	}
+
+	if err := write(backupManifest, data); err != nil {
+		return err
+	}
+	for _, files := range dumps {
+		for _, file := range files {
+			if err := write(path.Join(backupDatabases, filepath.Base(file.Name)), file.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("compress archive: %w", err)
+	}
+
+	return nil
+}
+
+// Read an archive written by writeBackup, checking the files of each database
+// against the manifest. The files are returned by database name.
+func readBackup(r io.Reader) (*BackupManifest, map[string][]client.File, error) {
+	decompressor, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decompress archive: %w", err)
+	}
+	defer decompressor.Close()
+	archive := tar.NewReader(decompressor)
+
+	var manifest *BackupManifest
+	contents := map[string][]byte{}
+	for {
+		h, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read archive: %w", err)
+		}
+		data, err := ioutil.ReadAll(archive)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read %s: %w", h.Name, err)
+		}
+		if h.Name == backupManifest {
+			manifest = &BackupManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("decode manifest: %w", err)
+			}
+			continue
+		}
+		contents[h.Name] = data
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("no %s in archive", backupManifest)
+	}
+	if manifest.Format != backupFormat {
+		return nil, nil, fmt.Errorf("unsupported backup format %d", manifest.Format)
+	}
+
+	dumps := map[string][]client.File{}
+	for _, database := range manifest.Databases {
+		files := []client.File{}
+		for _, file := range database.Files {
+			data, ok := contents[path.Join(backupDatabases, file.Name)]
+			if !ok {
+				return nil, nil, fmt.Errorf("file %s of database %s is missing", file.Name, database.Name)
+			}
+			sum := sha256.Sum256(data)
+			if int64(len(data)) != file.Size || hex.EncodeToString(sum[:]) != file.SHA256 {
+				return nil, nil, fmt.Errorf("file %s of database %s is corrupted", file.Name, database.Name)
+			}
+			files = append(files, client.File{Name: file.Name, Data: data})
+		}
+		dumps[database.Name] = files
+	}
+
+	return manifest, dumps, nil
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBackup(t *testing.T) {
+	files := []client.File{
+		{Name: "test.db", Data: []byte("main")},
+		{Name: "test.db-wal", Data: []byte("wal")},
+	}
+	manifest := BackupManifest{
+		Format:    backupFormat,
+		Created:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Leader:    client.NodeInfo{ID: 1, Address: "1"},
+		Cluster:   []client.NodeInfo{{ID: 1, Address: "1", Role: client.Voter}},
+		Databases: []BackupDatabase{newBackupDatabase("test.db", files, []string{"CREATE TABLE test (n INT)"})},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeBackup(buf, manifest, [][]client.File{files}))
+
+	decompressor, err := gzip.NewReader(buf)
+	require.NoError(t, err)
+	archive := tar.NewReader(decompressor)
+	entries := map[string][]byte{}
+	names := []string{}
+	for {
+		h, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(archive)
+		require.NoError(t, err)
+		entries[h.Name] = data
+		names = append(names, h.Name)
+	}
+
+	assert.Equal(t, []string{"manifest.json", "databases/test.db", "databases/test.db-wal"}, names)
+	assert.Equal(t, []byte("main"), entries["databases/test.db"])
+	assert.Equal(t, []byte("wal"), entries["databases/test.db-wal"])
+
+	decoded := BackupManifest{}
+	require.NoError(t, json.Unmarshal(entries["manifest.json"], &decoded))
+	assert.Equal(t, manifest, decoded)
+	assert.Equal(t, []BackupFile{
+		{Name: "test.db", Size: 4, SHA256: "0d6e4079e36703ebd37c00722f5891d28b0e2811dc114b129215123adcce3605"},
+		{Name: "test.db-wal", Size: 3, SHA256: "27a75a1c9d8f31b0bc4ca4889e25fe6413f00d78d8578a36e4cce1c38c452e45"},
+	}, decoded.Databases[0].Files)
+}
+
+func TestReadBackup(t *testing.T) {
+	files := []client.File{{Name: "test.db", Data: []byte("main")}}
+	manifest := BackupManifest{
+		Format:    backupFormat,
+		Databases: []BackupDatabase{newBackupDatabase("test.db", files, nil)},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeBackup(buf, manifest, [][]client.File{files}))
+	data := buf.Bytes()
+
+	decoded, dumps, err := readBackup(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Databases, decoded.Databases)
+	assert.Equal(t, map[string][]client.File{"test.db": files}, dumps)
+
+	// A file whose content doesn't match the manifest is rejected.
+	corrupted := []client.File{{Name: "test.db", Data: []byte("MAIN")}}
+	buf.Reset()
+	require.NoError(t, writeBackup(buf, manifest, [][]client.File{corrupted}))
+	_, _, err = readBackup(buf)
+	assert.EqualError(t, err, "file test.db of database test.db is corrupted")
+
+	// So is a file missing from the archive.
+	buf.Reset()
+	require.NoError(t, writeBackup(buf, manifest, nil))
+	_, _, err = readBackup(buf)
+	assert.EqualError(t, err, "file test.db of database test.db is missing")
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// ErrClusterExists is matched by the error returned by New when a node
+// started for the first time without WithCluster would bootstrap a new
+// cluster, but some of its peers are already part of one. See
+// WithBootstrapPeers.
+var ErrClusterExists = errors.New("cluster already exists")
+
+// Probe the given peers and the ones returned by the discovery function, if
+// any, and fail if any of them is part of a cluster.
+func checkBootstrap(ctx context.Context, address string, peers []string, discovery DiscoveryFunc, dial client.DialFunc, log client.LogFunc) error {
+	if discovery != nil {
+		discovered, err := discovery(ctx)
+		if err != nil {
+			return fmt.Errorf("discover bootstrap peers: %w", err)
+		}
+		peers = append(append([]string{}, peers...), discovered...)
+	}
+
+	var (
+		mtx       sync.Mutex
+		wg        sync.WaitGroup
+		conflicts []string
+	)
+	seen := map[string]bool{address: true}
+	for _, peer := range peers {
+		if seen[peer] {
+			continue
+		}
+		seen[peer] = true
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			conflict, err := probeBootstrapPeer(ctx, peer, dial, log)
+			if err != nil {
+				log(client.LogDebug, "probe bootstrap peer %s: %v", peer, err)
+				return
+			}
+			if conflict == "" {
+				return
+			}
+			mtx.Lock()
+			conflicts = append(conflicts, conflict)
+			mtx.Unlock()
+		}(peer)
+	}
+	wg.Wait()
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+
+	return fmt.Errorf(
+		"%w: %s; use WithCluster to join it, or WithForceBootstrap to bootstrap a new cluster anyway",
+		ErrClusterExists, strings.Join(conflicts, "; "))
+}
+
+// Return a description of the cluster the given peer is part of, if any. An
+// error is returned if the peer can't be reached.
+func probeBootstrapPeer(ctx context.Context, address string, dial client.DialFunc, log client.LogFunc) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	cli, err := client.New(ctx, address, client.WithDialFunc(dial), client.WithLogFunc(log))
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return "", err
+	}
+	if leader != nil {
+		return fmt.Sprintf("node %s is part of a cluster led by %s", address, leader.Address), nil
+	}
+
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(nodes) > 0 {
+		return fmt.Sprintf("node %s is part of a cluster with %d members", address, len(nodes)), nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Peers that can't be reached are not part of any cluster.
+func TestCheckBootstrap_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	discovery := func(ctx context.Context) ([]string, error) {
+		return []string{address, "127.0.0.1:9000"}, nil
+	}
+
+	err = checkBootstrap(context.Background(), "127.0.0.1:9000", []string{address}, discovery, client.DefaultDialFunc, defaultLogFunc)
+	assert.NoError(t, err)
+}
+
+func TestCheckBootstrap_DiscoveryError(t *testing.T) {
+	discovery := func(ctx context.Context) ([]string, error) {
+		return nil, fmt.Errorf("boom")
+	}
+
+	err := checkBootstrap(context.Background(), "127.0.0.1:9000", nil, discovery, client.DefaultDialFunc, defaultLogFunc)
+	assert.EqualError(t, err, "discover bootstrap peers: boom")
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cowsql/go-cowsql"
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// Problem describes an issue found in the data directory of an application
+// node when starting it.
+type Problem struct {
+	File   string // Name of the affected file, relative to the data directory.
+	Reason string // What's wrong with the file.
+	Repair string // Description of the fix, empty if the problem can't be fixed automatically.
+	Hint   string // Suggested manual fix, for some problems that can't be fixed automatically.
+
+	fix  func() error
+	raft bool // Whether raft handles the problem by itself when starting.
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.File, p.Reason)
+}
+
+// DataDirError is returned by New when the data directory of the application
+// node contains problems that would prevent it from starting correctly.
+//
+// Problems that can be safely fixed are only reported if the app was not
+// created with WithAutoRepair, except for the ones that raft handles by itself
+// when starting, such as a partial batch at the end of an open segment after a
+// crash, which are just logged.
+type DataDirError struct {
+	Dir      string
+	Problems []Problem
+}
+
+func (e *DataDirError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = problem.String()
+	}
+	return fmt.Sprintf("data directory %s: %s", e.Dir, strings.Join(problems, "; "))
+}
+
+// Inspect the data directory and either repair or report any problem found.
+func checkDir(dir string, repair bool, log client.LogFunc) error {
+	problems, err := inspectDir(dir)
+	if err != nil {
+		return err
+	}
+
+	unfixed := []Problem{}
+	for _, problem := range problems {
+		if !repair || problem.fix == nil {
+			if problem.raft {
+				log(client.LogWarn, "%s: left to raft", problem)
+				continue
+			}
+			unfixed = append(unfixed, problem)
+			continue
+		}
+		log(client.LogWarn, "repair %s: %s", problem, problem.Repair)
+		if err := problem.fix(); err != nil {
+			return fmt.Errorf("repair %s: %w", problem.File, err)
+		}
+	}
+
+	if len(unfixed) > 0 {
+		return &DataDirError{Dir: dir, Problems: unfixed}
+	}
+
+	return nil
+}
+
+// Return the problems found in the data directory.
+func inspectDir(dir string) ([]Problem, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read data directory: %w", err)
+	}
+
+	problems := []Problem{}
+
+	names := map[string]bool{}
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	if names[joinFile] {
+		problem, err := inspectJoinFile(dir, names[infoFile])
+		if err != nil {
+			return nil, err
+		}
+		if problem != nil {
+			problems = append(problems, *problem)
+		}
+	}
+
+	raftFiles := false
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+
+		var problem *Problem
+		switch {
+		case name == "metadata1" || name == "metadata2":
+			raftFiles = true
+			if entry.Size() != raftMetadataSize {
+				problem = &Problem{
+					Reason: fmt.Sprintf("size is %d bytes instead of %d", entry.Size(), raftMetadataSize),
+					Hint:   "restore the data directory from a backup",
+				}
+			}
+		case strings.HasPrefix(name, "snapshot-"):
+			raftFiles = true
+			other := name + ".meta"
+			if strings.HasSuffix(name, ".meta") {
+				other = strings.TrimSuffix(name, ".meta")
+			}
+			if !names[other] {
+				problem = &Problem{
+					Reason: fmt.Sprintf("%s is missing", other),
+					Repair: "remove orphaned snapshot file",
+					fix:    func() error { return os.Remove(path) },
+				}
+			}
+		case strings.HasPrefix(name, "open-"):
+			raftFiles = true
+			segment, err := readSegment(path, nil)
+			if err != nil {
+				return nil, err
+			}
+			if offset := segment.Partial; offset >= 0 {
+				problem = &Problem{
+					Reason: fmt.Sprintf("partial batch at offset %d", offset),
+					Repair: fmt.Sprintf("truncate segment to %d bytes", offset),
+					fix:    func() error { return os.Truncate(path, offset) },
+					raft:   true,
+				}
+			}
+		case isClosedSegment(name):
+			raftFiles = true
+			segment, err := readSegment(path, nil)
+			if err != nil {
+				return nil, err
+			}
+			if segment.Partial >= 0 {
+				problem = &Problem{
+					Reason: fmt.Sprintf("partial batch at offset %d", segment.Partial),
+					Hint:   "restore the data directory from a backup, or remove the node and add it back",
+				}
+			}
+		}
+
+		if problem != nil {
+			problem.File = name
+			problems = append(problems, *problem)
+		}
+	}
+
+	if raftFiles && !names[infoFile] {
+		problems = append(problems, Problem{
+			File:   infoFile,
+			Reason: "missing, but raft data is present",
+			Hint:   "restore info.yaml from a backup, the node ID can't be changed",
+		})
+	}
+
+	return problems, nil
+}
+
+// Check that the join file is consistent with info.yaml.
+func inspectJoinFile(dir string, infoFileExists bool) (*Problem, error) {
+	remove := func() error { return fileRemove(dir, joinFile) }
+
+	// The join file is written before info.yaml, and will be written again
+	// if needed.
+	if !infoFileExists {
+		return &Problem{
+			File:   joinFile,
+			Reason: "info.yaml is missing",
+			Repair: "remove orphaned join file",
+			fix:    remove,
+		}, nil
+	}
+
+	info := client.NodeInfo{}
+	if err := fileUnmarshal(dir, infoFile, &info); err != nil {
+		return nil, err
+	}
+	if info.ID == cowsql.BootstrapID {
+		return &Problem{
+			File:   joinFile,
+			Reason: "bootstrap node can't join a cluster",
+			Repair: "remove orphaned join file",
+			fix:    remove,
+		}, nil
+	}
+
+	return nil, nil
+}
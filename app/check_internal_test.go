@@ -0,0 +1,130 @@
+package app
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowsql/go-cowsql"
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDir_Clean(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, fileWrite(dir, infoFile, []byte("ID: 1\nAddress: 127.0.0.1:9001\n")))
+	require.NoError(t, fileWrite(dir, "metadata1", make([]byte, raftMetadataSize)))
+	require.NoError(t, fileWrite(dir, "0000000000000001-0000000000000002", newSegment(1, 2, 0)))
+	require.NoError(t, fileWrite(dir, "open-1", append(newSegment(1, 1, 0), make([]byte, 64)...)))
+
+	assert.NoError(t, checkDir(dir, false, client.DefaultLogFunc))
+}
+
+func TestCheckDir_Report(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, fileWrite(dir, joinFile, []byte{}))
+	require.NoError(t, fileWrite(dir, "metadata1", make([]byte, 8)))
+	require.NoError(t, fileWrite(dir, "snapshot-1-8-100", []byte("data")))
+
+	err := checkDir(dir, false, client.DefaultLogFunc)
+	require.Error(t, err)
+
+	dirErr, ok := err.(*DataDirError)
+	require.True(t, ok)
+
+	files := []string{}
+	for _, problem := range dirErr.Problems {
+		files = append(files, problem.File)
+	}
+	assert.Equal(t, []string{joinFile, "metadata1", "snapshot-1-8-100", infoFile}, files)
+}
+
+func TestCheckDir_Repair(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	complete := newSegment(1, 1, 5)
+	partial := newSegment(1, 2, 9)
+	segment := append(complete, partial[8:len(partial)-4]...)
+
+	// The join file of a bootstrap node is orphaned.
+	info := fmt.Sprintf("ID: %d\nAddress: 127.0.0.1:9001\n", uint64(cowsql.BootstrapID))
+	require.NoError(t, fileWrite(dir, infoFile, []byte(info)))
+	require.NoError(t, fileWrite(dir, joinFile, []byte{}))
+	require.NoError(t, fileWrite(dir, "open-1", segment))
+
+	require.NoError(t, checkDir(dir, true, client.DefaultLogFunc))
+
+	exists, err := fileExists(dir, joinFile)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "open-1"))
+	require.NoError(t, err)
+	assert.Equal(t, complete, data)
+}
+
+// A partial batch at the end of an open segment is left to raft if repairs
+// are not enabled.
+func TestCheckDir_OpenSegment(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	complete := newSegment(1, 1, 5)
+	partial := newSegment(1, 2, 9)
+	segment := append(complete, partial[8:len(partial)-4]...)
+
+	require.NoError(t, fileWrite(dir, infoFile, []byte("ID: 1\nAddress: 127.0.0.1:9001\n")))
+	require.NoError(t, fileWrite(dir, "open-1", segment))
+
+	require.NoError(t, checkDir(dir, false, client.DefaultLogFunc))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "open-1"))
+	require.NoError(t, err)
+	assert.Equal(t, segment, data)
+}
+
+// A partial batch in a closed segment can't be repaired.
+func TestCheckDir_ClosedSegment(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	segment := newSegment(1, 1, 5)
+
+	require.NoError(t, fileWrite(dir, infoFile, []byte("ID: 1\nAddress: 127.0.0.1:9001\n")))
+	require.NoError(t, fileWrite(dir, "0000000000000001-0000000000000001", segment[:len(segment)-1]))
+
+	err := checkDir(dir, true, client.DefaultLogFunc)
+	assert.EqualError(t, err, "data directory "+dir+": 0000000000000001-0000000000000001: partial batch at offset 8")
+}
+
+// Return a raft segment with a single batch holding n entries of the given
+// term and size.
+func newSegment(term uint64, n int, size int) []byte {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, 1) // Format version
+
+	header := make([]byte, raftHeaderSize)
+	binary.LittleEndian.PutUint64(header[8:], uint64(n))
+	data = append(data, header...)
+
+	for i := 0; i < n; i++ {
+		entry := make([]byte, raftHeaderSize)
+		binary.LittleEndian.PutUint64(entry, term)
+		binary.LittleEndian.PutUint32(entry[12:], uint32(size))
+		data = append(data, entry...)
+	}
+	for i := 0; i < n; i++ {
+		data = append(data, make([]byte, (size+7)&^7)...)
+	}
+
+	return data
+}
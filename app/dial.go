@@ -7,11 +7,12 @@ import (
 	"net"
 
 	"github.com/cowsql/go-cowsql/client"
+	"github.com/cowsql/go-cowsql/internal/protocol"
 )
 
 // Like client.DialFuncWithTLS but also starts the proxy, since the raft
 // connect function only supports Unix and TCP connections.
-func makeNodeDialFunc(appCtx context.Context, config *tls.Config) client.DialFunc {
+func makeNodeDialFunc(appCtx context.Context, config *tls.Config, stats *tlsStats, bg *background, fds *fdCounters) client.DialFunc {
 	dial := func(ctx context.Context, addr string) (net.Conn, error) {
 		clonedConfig := config.Clone()
 		if len(clonedConfig.ServerName) == 0 {
@@ -22,6 +23,7 @@ func makeNodeDialFunc(appCtx context.Context, config *tls.Config) client.DialFun
 			}
 			clonedConfig.ServerName = remoteIP
 		}
+		clonedConfig.ClientSessionCache = protocol.SessionCacheForAddress(config.ClientSessionCache, addr)
 		dialer := &net.Dialer{}
 		conn, err := dialer.DialContext(ctx, "tcp", addr)
 		if err != nil {
@@ -32,7 +34,11 @@ func makeNodeDialFunc(appCtx context.Context, config *tls.Config) client.DialFun
 			return nil, fmt.Errorf("create pair of Unix sockets: %w", err)
 		}
 
-		go proxy(appCtx, conn, goUnix, clonedConfig)
+		done := fds.add(&fds.node)
+		bg.goroutine("proxy", func() {
+			defer done()
+			proxy(appCtx, conn, goUnix, clonedConfig, stats, nil)
+		})
 
 		return cUnix, nil
 	}
@@ -42,7 +48,7 @@ func makeNodeDialFunc(appCtx context.Context, config *tls.Config) client.DialFun
 
 // extDialFuncWithProxy executes given DialFunc and then copies the data back
 // and forth between the remote connection and a local unix socket.
-func extDialFuncWithProxy(appCtx context.Context, dialFunc client.DialFunc) client.DialFunc {
+func extDialFuncWithProxy(appCtx context.Context, dialFunc client.DialFunc, bg *background, fds *fdCounters) client.DialFunc {
 	return func(ctx context.Context, addr string) (net.Conn, error) {
 		goUnix, cUnix, err := socketpair()
 		if err != nil {
@@ -51,11 +57,41 @@ func extDialFuncWithProxy(appCtx context.Context, dialFunc client.DialFunc) clie
 
 		conn, err := dialFunc(ctx, addr)
 		if err != nil {
+			goUnix.Close()
+			cUnix.Close()
 			return nil, err
 		}
 
-		go proxy(appCtx, conn, goUnix, nil)
+		done := fds.add(&fds.node)
+		bg.goroutine("proxy", func() {
+			defer done()
+			proxy(appCtx, conn, goUnix, nil, nil, nil)
+		})
 
 		return cUnix, nil
 	}
 }
+
+// Return a dial function that connects to the node with the given address
+// using the given local socket, without TLS, and uses the given dial
+// function for all other nodes.
+func makeLocalDialFunc(dial client.DialFunc, address string, network string, socket string) client.DialFunc {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if addr != address {
+			return dial(ctx, addr)
+		}
+		dialer := &net.Dialer{}
+		return dialer.DialContext(ctx, network, socket)
+	}
+}
+
+// Return the dial function used by clients to connect to other nodes.
+func makeClientDialFunc(o *options) client.DialFunc {
+	dial := client.DefaultDialFunc
+	if o.TLS != nil {
+		dial = client.DialFuncWithTLS(dial, o.TLS.Dial)
+	} else if o.Conn != nil {
+		dial = o.Conn.dialFunc
+	}
+	return dial
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// EventType identifies the kind of change described by an Event.
+type EventType string
+
+// Types of the events published to the sink set with WithEventSink.
+const (
+	EventNodeJoined    EventType = "node-joined"    // A node was added to the cluster.
+	EventNodeLeft      EventType = "node-left"      // A node was removed from the cluster.
+	EventRoleChanged   EventType = "role-changed"   // The role of a node changed.
+	EventLeaderChanged EventType = "leader-changed" // A node became the leader.
+)
+
+// Event describes a change in the cluster.
+type Event struct {
+	Type         EventType `json:"type"`
+	Time         time.Time `json:"time"`
+	Reporter     uint64    `json:"reporter"`                // ID of the node publishing the event.
+	ID           uint64    `json:"id"`                      // ID of the node the event is about.
+	Address      string    `json:"address"`                 // Address of the node the event is about.
+	Role         string    `json:"role,omitempty"`          // Current role of the node, if any.
+	PreviousRole string    `json:"previous_role,omitempty"` // Role of the node before a role change.
+}
+
+// EventSink delivers cluster events to an external system, for example a
+// message bus or an alerting pipeline.
+//
+// See WebhookSink and NATSSink for the included implementations.
+type EventSink interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Maximum number of events waiting to be delivered to the sink. Further
+// events are dropped until the sink catches up.
+const eventQueueSize = 64
+
+// How long the sink has to deliver a single event.
+var eventPublishTimeout = 10 * time.Second
+
+// Track the cluster configuration and publish the changes to a sink.
+//
+// Only the leader publishes membership and role changes, so each of them is
+// published once, by the node that knows about it first. A node that becomes
+// leader publishes an EventLeaderChanged event about itself.
+type events struct {
+	sink   EventSink
+	queue  chan Event
+	done   chan struct{}     // Closed when the delivery loop returns
+	nodes  []client.NodeInfo // Last observed configuration, nil if none yet
+	leader uint64            // Last observed leader
+}
+
+func newEvents(sink EventSink) *events {
+	return &events{
+		sink:  sink,
+		queue: make(chan Event, eventQueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// Compare the given cluster configuration with the last observed one, and
+// return the events to publish.
+func (e *events) observe(self uint64, leader uint64, nodes []client.NodeInfo) []Event {
+	now := time.Now()
+
+	events := []Event{}
+	if leader == self && e.leader != self {
+		event := Event{Type: EventLeaderChanged, ID: self}
+		for _, node := range nodes {
+			if node.ID == self {
+				event.Address = node.Address
+				event.Role = node.Role.String()
+			}
+		}
+		events = append(events, event)
+	}
+
+	if leader == self && e.nodes != nil {
+		for _, change := range client.ClusterDiff(e.nodes, nodes) {
+			node := change.Node
+			switch change.Type {
+			case client.NodeAdded:
+				events = append(events, Event{Type: EventNodeJoined, ID: node.ID, Address: node.Address, Role: node.Role.String()})
+			case client.NodeRemoved:
+				events = append(events, Event{Type: EventNodeLeft, ID: node.ID, Address: node.Address})
+			case client.NodeRoleChanged:
+				events = append(events, Event{
+					Type:         EventRoleChanged,
+					ID:           node.ID,
+					Address:      node.Address,
+					Role:         node.Role.String(),
+					PreviousRole: change.Previous.Role.String(),
+				})
+			}
+		}
+	}
+
+	e.nodes = make([]client.NodeInfo, len(nodes))
+	copy(e.nodes, nodes)
+	e.leader = leader
+
+	// Leadership changes first, then by node ID, so the order is stable.
+	sort.SliceStable(events, func(i, j int) bool {
+		if (events[i].Type == EventLeaderChanged) != (events[j].Type == EventLeaderChanged) {
+			return events[i].Type == EventLeaderChanged
+		}
+		return events[i].ID < events[j].ID
+	})
+	for i := range events {
+		events[i].Time = now
+		events[i].Reporter = self
+	}
+
+	return events
+}
+
+// Queue the given event for delivery, returning false if the queue is full.
+func (e *events) publish(event Event) bool {
+	select {
+	case e.queue <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// Deliver queued events to the sink until the given context is done.
+func (e *events) loop(ctx context.Context, log client.LogFunc) {
+	defer close(e.done)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-e.queue:
+			publishCtx, cancel := context.WithTimeout(ctx, eventPublishTimeout)
+			if err := e.sink.Publish(publishCtx, event); err != nil {
+				log(client.LogWarn, "publish %s event for node %d: %v", event.Type, event.ID, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// Publish the changes in the cluster configuration since the last round.
+func (a *App) observeCluster(ctx context.Context, cli *client.Client, nodes []client.NodeInfo) {
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		a.debug("get leader for events: %v", err)
+		return
+	}
+	for _, event := range a.events.observe(a.id, leader.ID, nodes) {
+		if !a.events.publish(event) {
+			a.warn("drop %s event for node %d: queue full", event.Type, event.ID)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents_Observe(t *testing.T) {
+	e := newEvents(nil)
+
+	nodes := []client.NodeInfo{
+		{ID: 1, Address: "1", Role: client.Voter},
+		{ID: 2, Address: "2", Role: client.Voter},
+		{ID: 3, Address: "3", Role: client.Spare},
+	}
+
+	// Node 2 is the leader, nothing to publish for node 1.
+	assert.Empty(t, e.observe(1, 2, nodes))
+
+	// Node 1 becomes the leader.
+	events := e.observe(1, 1, nodes)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventLeaderChanged, events[0].Type)
+	assert.Equal(t, uint64(1), events[0].ID)
+	assert.Equal(t, uint64(1), events[0].Reporter)
+	assert.Equal(t, "voter", events[0].Role)
+
+	// No change.
+	assert.Empty(t, e.observe(1, 1, nodes))
+
+	// Node 2 leaves, node 3 is promoted and node 4 joins.
+	nodes = []client.NodeInfo{
+		{ID: 1, Address: "1", Role: client.Voter},
+		{ID: 3, Address: "3", Role: client.Voter},
+		{ID: 4, Address: "4", Role: client.Spare},
+	}
+	events = e.observe(1, 1, nodes)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, EventNodeLeft, events[0].Type)
+	assert.Equal(t, uint64(2), events[0].ID)
+
+	assert.Equal(t, EventRoleChanged, events[1].Type)
+	assert.Equal(t, uint64(3), events[1].ID)
+	assert.Equal(t, "voter", events[1].Role)
+	assert.Equal(t, "spare", events[1].PreviousRole)
+
+	assert.Equal(t, EventNodeJoined, events[2].Type)
+	assert.Equal(t, uint64(4), events[2].ID)
+	assert.Equal(t, "4", events[2].Address)
+}
+
+func TestWebhookSink(t *testing.T) {
+	events := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		event := Event{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events <- event
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("X-Token", "secret")
+	sink := &WebhookSink{URL: server.URL, Header: header}
+
+	require.NoError(t, sink.Publish(context.Background(), Event{Type: EventNodeJoined, ID: 2}))
+	event := <-events
+	assert.Equal(t, EventNodeJoined, event.Type)
+	assert.Equal(t, uint64(2), event.ID)
+
+	sink.URL += "/fail"
+	err := sink.Publish(context.Background(), Event{Type: EventNodeJoined, ID: 2})
+	assert.EqualError(t, err, "webhook returned 500 Internal Server Error")
+}
+
+func TestNATSSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Minimal NATS server, accepting a single connection.
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "CONNECT "):
+				assert.Contains(t, line, `"auth_token":"secret"`)
+			case strings.HasPrefix(line, "PUB "):
+				assert.Equal(t, "PUB cluster.events ", line[:len("PUB cluster.events ")])
+				payload, _ := reader.ReadString('\n')
+				received <- strings.TrimRight(payload, "\r\n")
+			case line == "PING\r\n":
+				conn.Write([]byte("PONG\r\n"))
+			}
+		}
+	}()
+
+	sink := &NATSSink{Address: listener.Addr().String(), Subject: "cluster.events", Token: "secret"}
+	require.NoError(t, sink.Publish(context.Background(), Event{Type: EventLeaderChanged, ID: 1}))
+
+	event := Event{}
+	require.NoError(t, json.Unmarshal([]byte(<-received), &event))
+	assert.Equal(t, EventLeaderChanged, event.Type)
+	assert.Equal(t, uint64(1), event.ID)
+}
+
+func TestNATSSink_Error(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {}\r\n"))
+		conn.Write([]byte("-ERR 'Authorization Violation'\r\n"))
+		ioutil.ReadAll(conn)
+	}()
+
+	sink := &NATSSink{Address: listener.Addr().String(), Subject: "cluster.events"}
+	err = sink.Publish(context.Background(), Event{Type: EventLeaderChanged, ID: 1})
+	assert.EqualError(t, err, "NATS server error: 'Authorization Violation'")
+}
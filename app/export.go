@@ -0,0 +1,180 @@
+// +build cgo,!nosqlite3
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Export writes a consistent snapshot of the database with the given name to
+// a plain SQLite file at the given path, replacing it if it exists.
+//
+// The snapshot is dumped from the current leader and its WAL is checkpointed
+// into the main file, so the result is a self-contained database that can be
+// queried with any SQLite tool, without loading the cluster. Changes made to
+// the database after the export are not reflected in the file.
+func (a *App) Export(ctx context.Context, dbname string, path string) error {
+	cli, err := a.Leader(ctx)
+	if err != nil {
+		return fmt.Errorf("find leader: %w", err)
+	}
+	defer cli.Close()
+
+	// Work in the destination directory, so the final rename is atomic.
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".cowsql-export-")
+	if err != nil {
+		return fmt.Errorf("create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename, err := dumpDatabase(ctx, cli, dir, dbname)
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		return fmt.Errorf("open dump: %w", err)
+	}
+	defer db.Close()
+
+	// Switching away from WAL mode checkpoints the WAL into the main file.
+	if _, err := db.ExecContext(ctx, "PRAGMA journal_mode=DELETE"); err != nil {
+		return fmt.Errorf("checkpoint WAL: %w", err)
+	}
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("close dump: %w", err)
+	}
+
+	if err := os.Rename(filename, path); err != nil {
+		return fmt.Errorf("rename dump: %w", err)
+	}
+
+	return nil
+}
+
+// Mirror keeps the plain SQLite file at the given path in sync with the
+// database with the given name, giving the application a local read-only copy
+// that can be queried without any network round trip.
+//
+// Every interval the database is dumped from the current leader and, if its
+// content changed since the last round, copied into the file using the SQLite
+// backup API. The file is updated in place, so connections already opened on
+// it see the new content, and the copy is atomic for them. Errors are logged
+// and the next round is attempted.
+//
+// Mirror blocks until the given context is done or the App is closed. The
+// file must not be modified by the application.
+func (a *App) Mirror(ctx context.Context, dbname string, path string, interval time.Duration) error {
+	var digest []byte
+	for {
+		if err := a.mirror(ctx, dbname, path, &digest); err != nil {
+			a.warn("mirror database %s: %v", dbname, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Perform a single mirror round, skipping the copy if the digest of the dump
+// matches the given one, which is updated otherwise.
+func (a *App) mirror(ctx context.Context, dbname string, path string, digest *[]byte) error {
+	cli, err := a.Leader(ctx)
+	if err != nil {
+		return fmt.Errorf("find leader: %w", err)
+	}
+	defer cli.Close()
+
+	dir, err := ioutil.TempDir("", "cowsql-mirror-")
+	if err != nil {
+		return fmt.Errorf("create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := cli.Dump(ctx, dbname)
+	if err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+	hash := sha256.New()
+	for _, file := range files {
+		hash.Write(file.Data)
+	}
+	sum := hash.Sum(nil)
+	if bytes.Equal(sum, *digest) {
+		return nil
+	}
+
+	filename, err := writeDump(dir, dbname, files)
+	if err != nil {
+		return err
+	}
+
+	driver := &sqlite3.SQLiteDriver{}
+	src, err := driver.Open(filename)
+	if err != nil {
+		return fmt.Errorf("open dump: %w", err)
+	}
+	defer src.Close()
+	dst, err := driver.Open(path)
+	if err != nil {
+		return fmt.Errorf("open mirror: %w", err)
+	}
+	defer dst.Close()
+
+	backup, err := dst.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+	if err != nil {
+		return fmt.Errorf("start backup: %w", err)
+	}
+	if _, err := backup.Step(-1); err != nil {
+		backup.Finish()
+		return fmt.Errorf("copy database: %w", err)
+	}
+	if err := backup.Finish(); err != nil {
+		return fmt.Errorf("finish backup: %w", err)
+	}
+
+	*digest = sum
+
+	return nil
+}
+
+// Dump the database with the given name into the given directory, returning
+// the path of the main database file.
+func dumpDatabase(ctx context.Context, cli *client.Client, dir string, dbname string) (string, error) {
+	files, err := cli.Dump(ctx, dbname)
+	if err != nil {
+		return "", fmt.Errorf("dump: %w", err)
+	}
+
+	return writeDump(dir, dbname, files)
+}
+
+// Write the given dump files into the given directory, returning the path of
+// the main database file.
+func writeDump(dir string, dbname string, files []client.File) (string, error) {
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.Base(file.Name))
+		if err := ioutil.WriteFile(path, file.Data, 0600); err != nil {
+			return "", fmt.Errorf("write %s: %w", file.Name, err)
+		}
+	}
+
+	return filepath.Join(dir, filepath.Base(dbname)), nil
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// FDStats reports the sockets held by an App for cluster traffic, as returned
+// by App.FDStats.
+//
+// Each proxied connection holds two sockets on the Go side, the remote
+// connection and one end of a Unix socket pair. All of them are created with
+// the close-on-exec flag set, so they are not leaked to child processes.
+type FDStats struct {
+	Listeners    int // Sockets accepting connections from other nodes and clients.
+	Proxied      int // Incoming connections being proxied to the local node.
+	NodeSockets  int // Outgoing connections of the local node being proxied to other nodes.
+	ProbeClients int // Idle clients kept open to probe other nodes.
+	Process      int // File descriptors open in the whole process, or -1 if unknown.
+}
+
+// Count the connections being proxied by an App.
+type fdCounters struct {
+	proxied int64 // Set atomically
+	node    int64 // Set atomically
+}
+
+// Increment the given counter, returning a function that decrements it.
+func (c *fdCounters) add(counter *int64) func() {
+	atomic.AddInt64(counter, 1)
+	return func() { atomic.AddInt64(counter, -1) }
+}
+
+// Return the number of file descriptors open in the process, or -1 if it
+// can't be determined.
+func processFDs() int {
+	f, err := os.Open("/dev/fd")
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+
+	// Don't count the descriptor used to read the directory.
+	return len(names) - 1
+}
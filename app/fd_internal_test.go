@@ -0,0 +1,49 @@
+package app
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestSocketpair_Cloexec(t *testing.T) {
+	fds, err := socketpairCloexec()
+	require.NoError(t, err)
+	defer syscall.Close(fds[0])
+	defer syscall.Close(fds[1])
+
+	for _, fd := range fds {
+		flags, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0)
+		require.NoError(t, err)
+		assert.NotZero(t, flags&unix.FD_CLOEXEC)
+	}
+}
+
+func TestSocketpair_NoLeak(t *testing.T) {
+	before := processFDs()
+	require.True(t, before > 0)
+
+	c1, c2, err := socketpair()
+	require.NoError(t, err)
+	assert.Equal(t, before+2, processFDs())
+
+	c1.Close()
+	c2.Close()
+	assert.Equal(t, before, processFDs())
+}
+
+func TestFDCounters(t *testing.T) {
+	fds := &fdCounters{}
+	done1 := fds.add(&fds.proxied)
+	done2 := fds.add(&fds.proxied)
+	fds.add(&fds.node)
+	assert.Equal(t, int64(2), fds.proxied)
+	assert.Equal(t, int64(1), fds.node)
+
+	done1()
+	done2()
+	assert.Equal(t, int64(0), fds.proxied)
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 	"github.com/google/renameio"
@@ -21,6 +22,11 @@ const (
 	// the cluster. In case the node doesn't successfully make it to join
 	// the cluster first time it's started, it will re-try the next time.
 	joinFile = "join"
+
+	// Directory where files written together are staged, and flag file
+	// marking the staged files as complete.
+	stagingDir      = ".staging"
+	stagingComplete = ".complete"
 )
 
 // Return true if the given file exists in the given directory.
@@ -48,18 +54,6 @@ func fileWrite(dir, file string, data []byte) error {
 	return nil
 }
 
-// Marshal the given object as YAML into the given file.
-func fileMarshal(dir, file string, object interface{}) error {
-	data, err := yaml.Marshal(object)
-	if err != nil {
-		return fmt.Errorf("marshall %s: %w", file, err)
-	}
-	if err := fileWrite(dir, file, data); err != nil {
-		return err
-	}
-	return nil
-}
-
 // Unmarshal the given YAML file into the given object.
 func fileUnmarshal(dir, file string, object interface{}) error {
 	path := filepath.Join(dir, file)
@@ -75,6 +69,95 @@ func fileUnmarshal(dir, file string, object interface{}) error {
 	return nil
 }
 
+// Write the given files in the given directory, so that either all or none
+// of them are written, even in case of a crash.
+//
+// The files are first written to a staging directory, which is then marked as
+// complete before moving the files to their final location. If a crash
+// happens in the middle, fileRecover completes or discards the write.
+func fileWriteAll(dir string, files map[string][]byte) error {
+	staging := filepath.Join(dir, stagingDir)
+
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("remove %s: %w", stagingDir, err)
+	}
+	if err := os.Mkdir(staging, 0700); err != nil {
+		return fmt.Errorf("create %s: %w", stagingDir, err)
+	}
+
+	for file, data := range files {
+		if err := fileWrite(staging, file, data); err != nil {
+			return err
+		}
+	}
+
+	// This is the commit point.
+	if err := fileWrite(staging, stagingComplete, []byte{}); err != nil {
+		return err
+	}
+	if err := dirSync(staging); err != nil {
+		return err
+	}
+
+	return fileRecover(dir)
+}
+
+// Complete a write started by fileWriteAll if the staged files are complete,
+// or discard it otherwise.
+func fileRecover(dir string) error {
+	staging := filepath.Join(dir, stagingDir)
+
+	exists, err := fileExists(dir, stagingDir)
+	if err != nil || !exists {
+		return err
+	}
+
+	complete, err := fileExists(staging, stagingComplete)
+	if err != nil {
+		return err
+	}
+
+	if complete {
+		entries, err := ioutil.ReadDir(staging)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", stagingDir, err)
+		}
+		for _, entry := range entries {
+			// Skip the flag file and any leftover temporary file.
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if err := os.Rename(filepath.Join(staging, entry.Name()), filepath.Join(dir, entry.Name())); err != nil {
+				return fmt.Errorf("move %s: %w", entry.Name(), err)
+			}
+		}
+		if err := dirSync(dir); err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(staging); err != nil {
+		return fmt.Errorf("remove %s: %w", stagingDir, err)
+	}
+
+	return nil
+}
+
+// Flush the entries of the given directory to disk.
+func dirSync(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", dir, err)
+	}
+	defer f.Close()
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", dir, err)
+	}
+
+	return nil
+}
+
 // Remove a file in the given directory.
 func fileRemove(dir, file string) error {
 	return os.Remove(filepath.Join(dir, file))
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWriteAll(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	files := map[string][]byte{infoFile: []byte("info"), storeFile: []byte("store")}
+	require.NoError(t, fileWriteAll(dir, files))
+
+	assertFile(t, dir, infoFile, "info")
+	assertFile(t, dir, storeFile, "store")
+
+	exists, err := fileExists(dir, stagingDir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+// A crash after the staged files were marked as complete is recovered by
+// moving them in place.
+func TestFileRecover_Complete(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	staging := filepath.Join(dir, stagingDir)
+	require.NoError(t, os.Mkdir(staging, 0700))
+	require.NoError(t, fileWrite(staging, storeFile, []byte("store")))
+	require.NoError(t, fileWrite(staging, stagingComplete, []byte{}))
+
+	// The info file was already moved.
+	require.NoError(t, fileWrite(dir, infoFile, []byte("info")))
+
+	require.NoError(t, fileRecover(dir))
+
+	assertFile(t, dir, infoFile, "info")
+	assertFile(t, dir, storeFile, "store")
+
+	exists, err := fileExists(dir, stagingDir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+// A crash before the staged files were marked as complete is recovered by
+// discarding them.
+func TestFileRecover_Incomplete(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	staging := filepath.Join(dir, stagingDir)
+	require.NoError(t, os.Mkdir(staging, 0700))
+	require.NoError(t, fileWrite(staging, infoFile, []byte("info")))
+
+	require.NoError(t, fileRecover(dir))
+
+	for _, file := range []string{infoFile, storeFile, stagingDir} {
+		exists, err := fileExists(dir, file)
+		require.NoError(t, err)
+		assert.False(t, exists, file)
+	}
+}
+
+func newDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "cowsql-app-test-")
+	require.NoError(t, err)
+	return dir
+}
+
+func assertFile(t *testing.T, dir, file, content string) {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join(dir, file))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
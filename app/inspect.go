@@ -0,0 +1,249 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// DirInfo describes the content of the data directory of an application
+// node, as returned by InspectDir.
+type DirInfo struct {
+	Node      client.NodeInfo      // ID and address of the node, from info.yaml.
+	Joining   bool                 // Whether the node still has to join the cluster.
+	Cluster   []client.NodeInfo    // Cluster members as last known by the node, from cluster.yaml.
+	Store     client.StoreMetadata // When and by whom cluster.yaml was last updated.
+	Snapshots []SnapshotInfo       // Raft snapshots, oldest first.
+	Segments  []SegmentInfo        // Raft log segments, closed ones first.
+	LastEntry LastEntryInfo        // Last entry persisted in the raft log.
+	Databases []string             // Names of the databases found in the raft data.
+	Problems  []Problem            // Problems found in the directory, as checked by New.
+}
+
+// SnapshotInfo describes a raft snapshot in the data directory.
+type SnapshotInfo struct {
+	Name      string
+	Term      uint64
+	Index     uint64
+	Timestamp time.Time
+	Size      int64
+}
+
+// SegmentInfo describes a raft log segment in the data directory.
+type SegmentInfo struct {
+	Name    string
+	Open    bool   // Whether the segment is still being written.
+	First   uint64 // Index of the first entry, for closed segments.
+	Last    uint64 // Index of the last entry, for closed segments.
+	Entries uint64 // Number of entries in complete batches.
+	Term    uint64 // Term of the last entry in complete batches.
+	Size    int64
+	Partial int64 // Offset of a partial batch, or -1.
+}
+
+// Raft entry type holding a cowsql command, and types of the commands
+// naming a database.
+const (
+	raftCommand = 1
+
+	commandOpen       = 1
+	commandFrames     = 2
+	commandUndo       = 3
+	commandCheckpoint = 4
+)
+
+// InspectDir reads the data directory of a stopped application node without
+// starting it, for example for post-mortem analysis.
+//
+// Database names are collected from the most recent snapshot and from the
+// commands in the raft log, on a best-effort basis: data that can't be
+// decoded is skipped.
+func InspectDir(dir string) (*DirInfo, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read data directory: %w", err)
+	}
+
+	info := &DirInfo{}
+	names := map[string]bool{}
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	if names[infoFile] {
+		if err := fileUnmarshal(dir, infoFile, &info.Node); err != nil {
+			return nil, err
+		}
+	}
+	info.Joining = names[joinFile]
+
+	if names[storeFile] {
+		store, err := client.NewYamlNodeStore(filepath.Join(dir, storeFile))
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", storeFile, err)
+		}
+		info.Store = store.Metadata()
+		if info.Cluster, err = store.Get(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	databases := map[string]bool{}
+	visit := func(kind uint8, data []byte) {
+		if name := commandDatabase(kind, data); name != "" {
+			databases[name] = true
+		}
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		switch {
+		case strings.HasPrefix(name, "snapshot-") && !strings.HasSuffix(name, ".meta"):
+			snapshot := SnapshotInfo{Name: name, Size: entry.Size()}
+			var timestamp uint64
+			if _, err := fmt.Sscanf(name, "snapshot-%d-%d-%d", &snapshot.Term, &snapshot.Index, &timestamp); err != nil {
+				continue
+			}
+			snapshot.Timestamp = time.Unix(0, int64(timestamp)*int64(time.Millisecond))
+			info.Snapshots = append(info.Snapshots, snapshot)
+		case strings.HasPrefix(name, "open-") || isClosedSegment(name):
+			segment, err := readSegment(path, visit)
+			if err != nil {
+				return nil, err
+			}
+			info.Segments = append(info.Segments, SegmentInfo{
+				Name:    name,
+				Open:    strings.HasPrefix(name, "open-"),
+				Entries: segment.Entries,
+				Term:    segment.Term,
+				Size:    entry.Size(),
+				Partial: segment.Partial,
+			})
+			if !strings.HasPrefix(name, "open-") {
+				s := &info.Segments[len(info.Segments)-1]
+				fmt.Sscanf(name, "%d-%d", &s.First, &s.Last)
+			}
+		}
+	}
+
+	sort.Slice(info.Snapshots, func(i, j int) bool {
+		return info.Snapshots[i].Index < info.Snapshots[j].Index
+	})
+	sort.SliceStable(info.Segments, func(i, j int) bool {
+		a, b := info.Segments[i], info.Segments[j]
+		if a.Open != b.Open {
+			return !a.Open
+		}
+		if !a.Open {
+			return a.First < b.First
+		}
+		var x, y uint64
+		fmt.Sscanf(a.Name, "open-%d", &x)
+		fmt.Sscanf(b.Name, "open-%d", &y)
+		return x < y
+	})
+
+	if n := len(info.Snapshots); n > 0 {
+		names, err := snapshotDatabases(filepath.Join(dir, info.Snapshots[n-1].Name))
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			databases[name] = true
+		}
+	}
+	for name := range databases {
+		info.Databases = append(info.Databases, name)
+	}
+	sort.Strings(info.Databases)
+
+	if info.LastEntry, err = lastEntryInfo(dir); err != nil {
+		return nil, err
+	}
+	if info.Problems, err = inspectDir(dir); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Return the name of the database that the given raft entry refers to, if
+// it's a cowsql command.
+//
+// Commands start with an 8-byte header holding the format version and the
+// command type, followed by the database name for all the commands handled
+// here.
+func commandDatabase(kind uint8, data []byte) string {
+	if kind != raftCommand || len(data) <= 8 || data[0] != 1 {
+		return ""
+	}
+	switch data[1] {
+	case commandOpen, commandFrames, commandUndo, commandCheckpoint:
+	default:
+		return ""
+	}
+	end := bytes.IndexByte(data[8:], 0)
+	if end <= 0 {
+		return ""
+	}
+	return string(data[8 : 8+end])
+}
+
+// Return the names of the databases in the given snapshot.
+//
+// A snapshot starts with a header holding the format version and the number
+// of databases, then each database has a header holding its name and the
+// sizes of its main and WAL files, followed by their content.
+func snapshotDatabases(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, nil
+	}
+	if binary.LittleEndian.Uint64(header) != 1 {
+		return nil, nil
+	}
+	n := binary.LittleEndian.Uint64(header[8:])
+
+	names := []string{}
+	offset := int64(16)
+	buf := make([]byte, 4096)
+	for i := uint64(0); i < n; i++ {
+		read, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("read snapshot: %w", err)
+		}
+		end := bytes.IndexByte(buf[:read], 0)
+		if end <= 0 {
+			break
+		}
+		names = append(names, string(buf[:end]))
+
+		// The name is padded to 8 bytes, and followed by the sizes.
+		sizes := (end + 1 + 7) &^ 7
+		if sizes+16 > read {
+			break
+		}
+		main := binary.LittleEndian.Uint64(buf[sizes:])
+		wal := binary.LittleEndian.Uint64(buf[sizes+8:])
+		offset += int64(sizes+16) + int64(main) + int64(wal)
+	}
+
+	return names, nil
+}
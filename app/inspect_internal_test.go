@@ -0,0 +1,86 @@
+package app
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectDir(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, fileWrite(dir, infoFile, []byte("ID: 1\nAddress: 127.0.0.1:9001\n")))
+	require.NoError(t, fileWrite(dir, storeFile, []byte("- ID: 1\n  Address: 127.0.0.1:9001\n  Role: 0\n")))
+	require.NoError(t, fileWrite(dir, "snapshot-1-2-1000", newSnapshot("test.db", "other.db")))
+	require.NoError(t, fileWrite(dir, "snapshot-1-2-1000.meta", []byte("meta")))
+	require.NoError(t, fileWrite(dir, "0000000000000001-0000000000000002", newSegment(1, 2, 8)))
+	require.NoError(t, fileWrite(dir, "open-1", newCommandSegment(2, "new.db")))
+
+	info, err := InspectDir(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, client.NodeInfo{ID: 1, Address: "127.0.0.1:9001"}, info.Node)
+	assert.False(t, info.Joining)
+	assert.Equal(t, []client.NodeInfo{{ID: 1, Address: "127.0.0.1:9001", Role: client.Voter}}, info.Cluster)
+
+	require.Len(t, info.Snapshots, 1)
+	assert.Equal(t, uint64(2), info.Snapshots[0].Index)
+	assert.Equal(t, int64(1), info.Snapshots[0].Timestamp.Unix())
+
+	require.Len(t, info.Segments, 2)
+	assert.Equal(t, "0000000000000001-0000000000000002", info.Segments[0].Name)
+	assert.Equal(t, uint64(2), info.Segments[0].Last)
+	assert.Equal(t, int64(-1), info.Segments[0].Partial)
+	assert.True(t, info.Segments[1].Open)
+	assert.Equal(t, uint64(1), info.Segments[1].Entries)
+
+	assert.Equal(t, LastEntryInfo{Term: 2, Index: 3}, info.LastEntry)
+	assert.Equal(t, []string{"new.db", "other.db", "test.db"}, info.Databases)
+	assert.Empty(t, info.Problems)
+}
+
+// Return a snapshot holding empty databases with the given names.
+func newSnapshot(names ...string) []byte {
+	data := make([]byte, 16)
+	binary.LittleEndian.PutUint64(data, 1) // Format version
+	binary.LittleEndian.PutUint64(data[8:], uint64(len(names)))
+	for _, name := range names {
+		data = append(data, newText(name)...)
+		data = append(data, make([]byte, 16)...) // Main and WAL sizes
+	}
+	return data
+}
+
+// Return a raft segment with a single batch holding an open command for the
+// given database.
+func newCommandSegment(term uint64, name string) []byte {
+	command := make([]byte, 8)
+	command[0] = 1 // Format version
+	command[1] = commandOpen
+	command = append(command, newText(name)...)
+
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, 1) // Format version
+
+	header := make([]byte, raftHeaderSize)
+	binary.LittleEndian.PutUint64(header[8:], 1)
+	data = append(data, header...)
+
+	entry := make([]byte, raftHeaderSize)
+	binary.LittleEndian.PutUint64(entry, term)
+	entry[8] = raftCommand
+	binary.LittleEndian.PutUint32(entry[12:], uint32(len(command)))
+	data = append(data, entry...)
+
+	return append(data, command...)
+}
+
+// Return the given string nul-terminated and padded to 8 bytes.
+func newText(s string) []byte {
+	return append([]byte(s), make([]byte, 8-len(s)%8)...)
+}
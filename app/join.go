@@ -0,0 +1,55 @@
+package app
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrJoinFailed is matched by the error returned by ReadyState when the node
+// gave up joining the cluster, after exhausting the attempts allowed by the
+// policy set with WithJoinRetryPolicy.
+var ErrJoinFailed = errors.New("join failed")
+
+// BackoffFunc returns how long to wait before the given retry attempt,
+// starting from 1.
+type BackoffFunc func(attempt int) time.Duration
+
+// ConstantBackoff returns a BackoffFunc that always waits the given delay.
+func ConstantBackoff(delay time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff returns a BackoffFunc that waits the given base delay
+// before the first retry, doubling it at each attempt up to the given cap.
+func ExponentialBackoff(base, cap time.Duration) BackoffFunc {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < cap; i++ {
+			delay *= 2
+		}
+		if delay > cap {
+			delay = cap
+		}
+		return delay
+	}
+}
+
+// Decide whether and when to retry joining the cluster.
+type joinPolicy struct {
+	backoff     BackoffFunc
+	maxAttempts int             // 0 for unlimited
+	onExhausted func(err error) // Invoked when giving up, if set
+	attempts    int             // Failed attempts so far
+}
+
+// Record a failed join attempt, returning how long to wait before the next
+// one, or false if no more attempts are allowed.
+func (p *joinPolicy) failed() (time.Duration, bool) {
+	p.attempts++
+	if p.maxAttempts > 0 && p.attempts >= p.maxAttempts {
+		return 0, false
+	}
+	return p.backoff(p.attempts), true
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+	assert.Equal(t, 100*time.Millisecond, backoff(1))
+	assert.Equal(t, 200*time.Millisecond, backoff(2))
+	assert.Equal(t, 800*time.Millisecond, backoff(4))
+	assert.Equal(t, time.Second, backoff(5))
+	assert.Equal(t, time.Second, backoff(100))
+}
+
+func TestJoinPolicy_Failed(t *testing.T) {
+	policy := &joinPolicy{backoff: ConstantBackoff(time.Second), maxAttempts: 3}
+
+	delay, retry := policy.failed()
+	assert.True(t, retry)
+	assert.Equal(t, time.Second, delay)
+
+	_, retry = policy.failed()
+	assert.True(t, retry)
+
+	_, retry = policy.failed()
+	assert.False(t, retry)
+	assert.Equal(t, 3, policy.attempts)
+}
+
+func TestJoinPolicy_Unlimited(t *testing.T) {
+	policy := &joinPolicy{backoff: ConstantBackoff(time.Second)}
+	for i := 0; i < 100; i++ {
+		_, retry := policy.failed()
+		assert.True(t, retry)
+	}
+}
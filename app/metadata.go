@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cowsql/go-cowsql"
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// Version skew policy set with WithVersionSkew.
+type versionSkew struct {
+	window int
+	refuse bool
+}
+
+// Labels returns the labels attached to this node with WithLabels.
+func (a *App) Labels() map[string]string {
+	labels := make(map[string]string, len(a.labels))
+	for key, value := range a.labels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// Version returns the versions of go-cowsql and libcowsql run by this node.
+func (a *App) Version() client.NodeVersion {
+	return client.NodeVersion{Client: client.Version, Engine: cowsql.EngineVersion()}
+}
+
+// Versions returns the versions run by the nodes of the cluster, keyed by
+// node ID. Nodes that never published their versions, for example because
+// they run an older go-cowsql, are not included.
+func (a *App) Versions(ctx context.Context) (map[uint64]client.NodeVersion, error) {
+	cli, err := a.Leader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("find leader: %w", err)
+	}
+	defer cli.Close()
+
+	return cli.Versions(ctx)
+}
+
+// Publish our versions and labels in the configuration registry, using the
+// given leader client.
+func (a *App) publishMetadata(ctx context.Context, cli *client.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// Avoid a write at every startup if nothing changed.
+	versions, err := cli.Versions(ctx)
+	if err != nil {
+		return err
+	}
+	if version, ok := versions[a.id]; !ok || version != a.Version() {
+		if err := cli.SetVersion(ctx, a.id, a.Version()); err != nil {
+			return err
+		}
+	}
+
+	if a.labels != nil {
+		return cli.SetLabels(ctx, a.id, a.labels)
+	}
+
+	return nil
+}
+
+// Fill the labels and versions of the online nodes in the given state with
+// the ones published in the configuration registry, using the given leader
+// client.
+//
+// Failing to look them up is not fatal: role decisions are then taken as if
+// the nodes had no labels.
+func (a *App) fillMetadata(cli *client.Client, state map[client.NodeInfo]*client.NodeMetadata) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	labels, err := cli.Labels(ctx)
+	if err != nil {
+		a.debug("get node labels: %v", err)
+		return
+	}
+	versions, err := cli.Versions(ctx)
+	if err != nil {
+		a.debug("get node versions: %v", err)
+		return
+	}
+
+	for node, metadata := range state {
+		if metadata == nil {
+			continue
+		}
+		metadata.Labels = labels[node.ID]
+		if version, ok := versions[node.ID]; ok {
+			metadata.Version = &version
+		}
+	}
+}
+
+// Check the versions of the online nodes in the given state against the skew
+// window set with WithVersionSkew, logging a warning if it's exceeded. An
+// error is returned only if role changes should be refused.
+func (a *App) checkVersionSkew(state map[client.NodeInfo]*client.NodeMetadata) error {
+	versions := map[uint64]client.NodeVersion{}
+	for node, metadata := range state {
+		if metadata != nil && metadata.Version != nil {
+			versions[node.ID] = *metadata.Version
+		}
+	}
+
+	err := client.CheckVersionSkew(versions, a.versionSkew.window)
+	if err == nil {
+		return nil
+	}
+	if a.versionSkew.refuse {
+		return fmt.Errorf("refuse role changes: %w", err)
+	}
+	a.warn("%v", err)
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -28,9 +29,16 @@ type Option func(*options)
 // interfaces will be used, with port 9000.
 //
 // The address must be stable across application restarts.
-func WithAddress(address string) Option {
+//
+// Additional addresses to listen to can be given after the first one, for
+// example to make the node reachable on both an internal and an external
+// interface. Only the first address is advertised to other nodes. Listening
+// on additional addresses requires WithTLS, since connections are accepted
+// by the TLS proxy, which runs on each address.
+func WithAddress(address string, listen ...string) Option {
 	return func(options *options) {
 		options.Address = address
+		options.ListenAddresses = listen
 	}
 }
 
@@ -45,6 +53,48 @@ func WithCluster(cluster []string) Option {
 	}
 }
 
+// WithID sets the ID of a newly added application node, instead of generating
+// a random one with cowsql.GenerateID. For example cowsql.GenerateIDFromName
+// can be used to derive it from a stable name.
+//
+// It can only be used together with WithCluster, since the bootstrap node
+// always uses cowsql.BootstrapID, and it's ignored if the node was already
+// started once. Joining fails with ErrJoinFailed if another member of the
+// cluster already uses the ID.
+func WithID(id uint64) Option {
+	return func(options *options) {
+		options.ID = id
+	}
+}
+
+// WithBootstrapPeers sets the addresses of nodes that might already form a
+// cluster, which are probed before bootstrapping a new one.
+//
+// A node started for the first time without WithCluster bootstraps a new
+// cluster. If this happens by mistake on a node that was meant to join an
+// existing cluster, for example because of a deployment error, two separate
+// clusters are formed. To prevent that, New probes the given addresses, as
+// well as the ones returned by the discovery function set with
+// WithDiscovery, and fails with an error matching ErrClusterExists if any of
+// them is already part of a cluster, unless WithForceBootstrap is used.
+//
+// The check can't detect nodes that are bootstrapping concurrently, so it
+// only guards against nodes that are already running.
+func WithBootstrapPeers(addresses []string) Option {
+	return func(options *options) {
+		options.BootstrapPeers = addresses
+	}
+}
+
+// WithForceBootstrap makes a node started for the first time without
+// WithCluster bootstrap a new cluster even if some of the peers set with
+// WithBootstrapPeers or WithDiscovery are already part of a cluster.
+func WithForceBootstrap() Option {
+	return func(options *options) {
+		options.ForceBootstrap = true
+	}
+}
+
 // WithExternalConn enables passing an external dial function that will be used
 // whenever cowsql needs to make an outside connection.
 //
@@ -74,6 +124,32 @@ func WithTLS(listen *tls.Config, dial *tls.Config) Option {
 	}
 }
 
+// WithLocalPlaintext makes the App's own driver and clients connect to the
+// local node through its unix socket, skipping the proxy and its TLS
+// encryption. Connections to other nodes are not affected.
+//
+// The address of the unix socket is returned by App.LocalDSN(), so other
+// drivers running on the same host can take the same shortcut.
+//
+// This option has no effect unless WithTLS or WithExternalConn is used.
+func WithLocalPlaintext() Option {
+	return func(options *options) {
+		options.LocalPlaintext = true
+	}
+}
+
+// WithDiscovery sets a function used to find other nodes of the cluster when
+// none of the nodes in the store can be reached at startup, for example
+// because they were all removed while this node was down.
+//
+// The addresses returned by the function are added to the store and tried on
+// the next attempt. See also App.ReadyState.
+func WithDiscovery(discovery DiscoveryFunc) Option {
+	return func(options *options) {
+		options.Discovery = discovery
+	}
+}
+
 // WithUnixSocket allows setting a specific socket path for communication between go-cowsql and cowsql.
 //
 // The default is an empty string which means a random abstract unix socket.
@@ -83,6 +159,24 @@ func WithUnixSocket(path string) Option {
 	}
 }
 
+// WithInternalTransport sets the transport used for the hop between the
+// proxy and the local node, when WithTLS or WithExternalConn are used:
+//
+//   - TransportAbstract, the default, binds the node to an abstract unix
+//     socket, or to the socket set with WithUnixSocket;
+//   - TransportUnix binds the node to the socket file set with
+//     WithUnixSocket, or to a cowsql.sock file in the data directory;
+//   - TransportTCPLoopback binds the node to a random port of 127.0.0.1.
+//
+// Abstract sockets are not available in some sandboxes, which can use one of
+// the other transports instead. Note that with TransportTCPLoopback any local
+// process can connect to the node without TLS.
+func WithInternalTransport(transport string) Option {
+	return func(options *options) {
+		options.InternalTransport = transport
+	}
+}
+
 // WithVoters sets the number of nodes in the cluster that should have the
 // Voter role.
 //
@@ -127,6 +221,224 @@ func WithStandBys(n int) Option {
 	}
 }
 
+// WithMinStandBys sets the minimum number of nodes in the cluster that should
+// have the StandBy role, whether they are online or not.
+//
+// Offline stand-by nodes are normally demoted to spare, in order to replace
+// them with online ones. If n is greater than zero, offline stand-bys are not
+// demoted when that would leave less than n stand-bys in the cluster.
+//
+// The given value must not be greater than the one passed to WithStandBys.
+//
+// The default value is 0.
+func WithMinStandBys(n int) Option {
+	return func(options *options) {
+		options.MinStandBys = n
+	}
+}
+
+// WithMaxPerFailureDomain sets the maximum number of online voters and
+// stand-bys that can belong to the same failure domain.
+//
+// A node is not promoted to a role if its failure domain already contains the
+// given number of online nodes with that role. Nodes that already have a role
+// are not demoted because of these limits.
+//
+// All App instances in a cluster must be created with the same
+// WithMaxPerFailureDomain setting.
+//
+// The default value is 0 for both, meaning no limit.
+func WithMaxPerFailureDomain(voters, standbys int) Option {
+	return func(options *options) {
+		options.MaxVotersPerDomain = voters
+		options.MaxStandBysPerDomain = standbys
+	}
+}
+
+// WithStrictFailureDomains prevents two voters from belonging to the same
+// failure domain, as long as there are at least as many failure domains with
+// online nodes as voters set with WithVoters.
+//
+// By default failure domains are only used to choose the preferred node to
+// promote, and two voters might end up in the same failure domain even if
+// another one is available.
+//
+// When the constraint can't be satisfied, for example because there are not
+// enough failure domains, the leader logs a warning and reports the problem to
+// the hook set with WithRolesDecisionHook, if any.
+//
+// All App instances in a cluster must be created with the same
+// WithStrictFailureDomains setting.
+func WithStrictFailureDomains() Option {
+	return func(options *options) {
+		options.StrictFailureDomains = true
+	}
+}
+
+// WithLabels attaches the given key/value labels to this node, for example
+// its machine type, version or deployment group.
+//
+// The labels are published in the cluster-wide configuration registry when
+// the node starts, and are then available in the metadata of the node, for
+// example in the State of RolesChanges, and to WithVoterLabels. Passing an
+// empty map removes labels published by a previous run.
+func WithLabels(labels map[string]string) Option {
+	return func(options *options) {
+		options.Labels = labels
+	}
+}
+
+// WithVoterLabels restricts promotion to voter to nodes whose labels, set
+// with WithLabels, include all the given key/value pairs.
+//
+// All App instances in a cluster must be created with the same
+// WithVoterLabels setting.
+func WithVoterLabels(labels map[string]string) Option {
+	return func(options *options) {
+		options.VoterLabels = labels
+	}
+}
+
+// WithVersionSkew sets how many minor versions apart the go-cowsql and
+// libcowsql versions run by the nodes of the cluster can be, 1 by default.
+// Nodes with different major versions are always considered skewed.
+//
+// Each node publishes its versions when it starts, see App.Versions, and the
+// leader checks them before adjusting roles. If the skew exceeds the window,
+// the leader logs a warning, or, if refuse is true, it does not change any
+// role until the skew is resolved, for example by completing a rolling
+// upgrade.
+func WithVersionSkew(window int, refuse bool) Option {
+	return func(options *options) {
+		options.VersionSkewWindow = window
+		options.VersionSkewRefuse = refuse
+	}
+}
+
+// WithRolesDecisionHook sets a function that the cluster leader calls after
+// each roles adjustment round.
+//
+// The hook receives the role changes that were performed, if any, and an error
+// describing the failure domain constraints that could not be satisfied, or
+// nil.
+func WithRolesDecisionHook(hook func(operations []Operation, err error)) Option {
+	return func(options *options) {
+		options.RolesDecisionHook = hook
+	}
+}
+
+// WithEventSink makes the node publish cluster events, such as nodes joining
+// or leaving, role changes and leadership changes, to the given sink.
+//
+// Events are detected by comparing the cluster configuration across roles
+// adjustment rounds, and membership and role changes are published only by
+// the leader, so each of them is published once if all nodes use the same
+// sink. Events are delivered in the background: if the sink can't keep up,
+// events are dropped and a warning is logged.
+func WithEventSink(sink EventSink) Option {
+	return func(options *options) {
+		options.EventSink = sink
+	}
+}
+
+// WithBackgroundErrorHandler sets a function that is invoked with the errors
+// that happen in the background goroutines of the node, for example when an
+// external connection can't be forwarded to the local node.
+//
+// Those goroutines also recover from panics, which would otherwise crash the
+// whole process: the panic is logged, appended along with its stack trace to
+// the panics.log file in the data directory, and passed to the handler as a
+// *PanicError.
+func WithBackgroundErrorHandler(handler BackgroundErrorHandler) Option {
+	return func(options *options) {
+		options.BackgroundErrorHandler = handler
+	}
+}
+
+// WithProxyConnHook sets a function called with the final byte counters and
+// duration of each connection proxied to the local node when it's closed,
+// for example to export them as metrics. See also App.ProxyConns.
+//
+// The hook is called from the goroutine that was proxying the connection, so
+// it should not block.
+func WithProxyConnHook(hook func(ProxyConnStats)) Option {
+	return func(options *options) {
+		options.ProxyConnHook = hook
+	}
+}
+
+// WithProbeConnections sets the maximum number of connections to other nodes
+// that are kept open between roles adjustment rounds.
+//
+// Every round the cluster leader probes all nodes to check whether they are
+// online. Keeping connections open avoids establishing them (and possibly
+// performing a TLS handshake) every time, at the cost of one file descriptor
+// per connection. Nodes beyond the limit are probed with a new connection
+// every time.
+//
+// The default value is 16. A value of zero disables reusing connections.
+func WithProbeConnections(n int) Option {
+	return func(options *options) {
+		options.ProbeConnections = n
+	}
+}
+
+// WithJoinRetryPolicy sets how a brand new node retries joining the cluster
+// when the leader fails to add it.
+//
+// The backoff function determines how long to wait before each retry. If
+// maxAttempts is greater than 0, the node gives up after that many failed
+// attempts: onExhausted, if not nil, is invoked with the last error, the
+// background tasks of the node stop, and ReadyState returns an error
+// matching ErrJoinFailed. The node must then be closed, and can be created
+// again to retry.
+//
+// If not used, the node retries every second, forever.
+func WithJoinRetryPolicy(backoff BackoffFunc, maxAttempts int, onExhausted func(err error)) Option {
+	return func(options *options) {
+		options.JoinBackoff = backoff
+		options.JoinMaxAttempts = maxAttempts
+		options.JoinOnExhausted = onExhausted
+	}
+}
+
+// WithEarlyReady makes Ready return as soon as the node has joined the
+// cluster, if it's a brand new node, and has connected to the leader.
+//
+// Refreshing the node store and checking whether the node should promote
+// itself continue in the background, so with large clusters the application
+// can start serving earlier, at the cost of the node possibly not having its
+// final role yet when Ready returns.
+func WithEarlyReady() Option {
+	return func(options *options) {
+		options.EarlyReady = true
+	}
+}
+
+// WithCircuitBreaker makes the node skip other nodes for the given cooldown
+// after the given number of consecutive failed attempts to connect to them,
+// when looking for the cluster leader. It improves the latency of new
+// connections while a node is down for an extended period.
+//
+// The circuit breaker is shared by the driver and by the clients returned by
+// App.Leader.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(options *options) {
+		options.BreakerThreshold = threshold
+		options.BreakerCooldown = cooldown
+	}
+}
+
+// WithRequiredFunctions makes the connections opened by the driver of the node
+// check that the cluster leader provides the SQL functions with the given
+// names, so that App.Open fails early if it doesn't. See
+// driver.WithRequiredFunctions.
+func WithRequiredFunctions(names ...string) Option {
+	return func(options *options) {
+		options.RequiredFunctions = names
+	}
+}
+
 // WithRolesAdjustmentFrequency sets the frequency at which the current cluster
 // leader will check if the roles of the various nodes in the cluster matches
 // the desired setup and perform promotions/demotions to adjust the situation
@@ -139,6 +451,68 @@ func WithRolesAdjustmentFrequency(frequency time.Duration) Option {
 	}
 }
 
+// WithStoreRefreshInterval sets the frequency at which this node refreshes
+// its node store with the current cluster members, so the clients and drivers
+// of the App learn about topology changes.
+//
+// Refreshes and roles adjustment are scheduled independently, so this
+// interval doesn't affect how often roles are adjusted.
+//
+// The default is the roles adjustment frequency, see
+// WithRolesAdjustmentFrequency.
+func WithStoreRefreshInterval(interval time.Duration) Option {
+	return func(options *options) {
+		options.StoreRefreshInterval = interval
+	}
+}
+
+// WithHandoverWatchdog makes the node check its own health every interval,
+// and hand over its role with Handover() once the checks have failed the given
+// number of consecutive times, so a degraded voter removes itself from the
+// quorum path before it slows down the whole cluster.
+//
+// If no checks are given, CheckDisk and CheckLocalNode are used. Each round
+// of checks must complete within the interval.
+//
+// Note that the leader might promote the node again later if the cluster
+// lacks healthy nodes for the desired number of voters and stand-bys.
+func WithHandoverWatchdog(interval time.Duration, failures int, checks ...HealthCheck) Option {
+	return func(options *options) {
+		options.WatchdogInterval = interval
+		options.WatchdogFailures = failures
+		options.HealthChecks = checks
+	}
+}
+
+// WithClockSkewThreshold makes the node measure the skew between its clock
+// and the clock of the cluster leader each time it refreshes its node store,
+// and log a warning when the skew exceeds the given threshold. Large skew
+// makes logs of different nodes hard to correlate and can interact badly with
+// deadlines.
+//
+// The last measurement is available with App.ClockSkew(). See
+// client.Client.ClockSkew for how the skew is measured.
+func WithClockSkewThreshold(threshold time.Duration) Option {
+	return func(options *options) {
+		options.ClockSkewThreshold = threshold
+	}
+}
+
+// WithDisableRolesManagement prevents this node from ever changing roles
+// automatically: it won't promote itself at startup, nor promote or demote
+// other nodes when it's the leader. This is meant for deployments where roles
+// are managed externally, for example by an orchestrator using
+// client.Client.Assign().
+//
+// The node store is still refreshed periodically, and Ready() still returns
+// once the node has joined the cluster. Explicit operations like Handover()
+// are not affected.
+func WithDisableRolesManagement() Option {
+	return func(options *options) {
+		options.DisableRolesManagement = true
+	}
+}
+
 // WithLogFunc sets a custom log function.
 func WithLogFunc(log client.LogFunc) Option {
 	return func(options *options) {
@@ -147,13 +521,67 @@ func WithLogFunc(log client.LogFunc) Option {
 }
 
 // WithTracing will emit a log message at the given level every time a
-// statement gets executed.
+// statement gets executed. The level can be changed later with
+// App.SetTracing.
 func WithTracing(level client.LogLevel) Option {
 	return func(options *options) {
 		options.Tracing = level
 	}
 }
 
+// WithClock sets the clock used by the node to schedule its periodic tasks
+// and to wait between retries, including the ones of the registered driver
+// and of the clients returned by Leader().
+//
+// It's meant for tests and simulations, which can pass a client.ManualClock
+// in order not to wait on real time. The default is client.SystemClock.
+func WithClock(clock client.Clock) Option {
+	return func(options *options) {
+		options.Clock = clock
+	}
+}
+
+// WithContextLogger sets a function extracting a logging function from the
+// context of the calls made through the registered driver, for example a
+// per-request logger including a request ID. It's used for the trace and
+// error messages of the call instead of the function set with WithLogFunc,
+// unless it returns nil.
+func WithContextLogger(extract func(ctx context.Context) client.LogFunc) Option {
+	return func(options *options) {
+		options.ContextLogger = extract
+	}
+}
+
+// WithLabelComments makes the registered driver send the labels attached to
+// the context of a statement with driver.WithLabel to the server, as SQL
+// comments.
+func WithLabelComments() Option {
+	return func(options *options) {
+		options.LabelComments = true
+	}
+}
+
+// WithBatchConcurrency limits the number of statements tagged with
+// driver.PriorityBatch that the registered driver executes at the same time,
+// and delays them while interactive statements are in flight.
+//
+// See driver.WithBatchConcurrency for details.
+func WithBatchConcurrency(n int) Option {
+	return func(options *options) {
+		options.BatchConcurrency = n
+	}
+}
+
+// WithMaxStatementSize makes the registered driver reject statements whose
+// SQL text is larger than the given number of bytes.
+//
+// See driver.WithMaxStatementSize for details.
+func WithMaxStatementSize(size int) Option {
+	return func(options *options) {
+		options.MaxStatementSize = size
+	}
+}
+
 // WithFailureDomain sets the node's failure domain.
 //
 // Failure domains are taken into account when deciding which nodes to promote
@@ -192,6 +620,22 @@ func WithAutoRecovery(recovery bool) Option {
 	}
 }
 
+// WithAutoRepair enables or disables automatic repair of the data directory
+// at startup.
+//
+// Before starting the node, New inspects the data directory and returns a
+// *DataDirError describing any problem found. When auto-repair is enabled,
+// problems that can be safely fixed, like an orphaned join file or a partial
+// batch at the end of an open raft segment, are fixed instead, and only the
+// remaining ones are reported.
+//
+// Auto-repair is disabled by default.
+func WithAutoRepair(repair bool) Option {
+	return func(options *options) {
+		options.AutoRepair = repair
+	}
+}
+
 type tlsSetup struct {
 	Listen *tls.Config
 	Dial   *tls.Config
@@ -203,20 +647,59 @@ type connSetup struct {
 }
 
 type options struct {
+	ID                       uint64
 	Address                  string
+	ListenAddresses          []string
 	Cluster                  []string
+	BootstrapPeers           []string
+	ForceBootstrap           bool
 	Log                      client.LogFunc
 	Tracing                  client.LogLevel
+	ContextLogger            func(context.Context) client.LogFunc
+	Clock                    client.Clock
+	BatchConcurrency         int
+	LabelComments            bool
+	MaxStatementSize         int
 	TLS                      *tlsSetup
 	Conn                     *connSetup
 	Voters                   int
 	StandBys                 int
+	MinStandBys              int
+	MaxVotersPerDomain       int
+	MaxStandBysPerDomain     int
+	StrictFailureDomains     bool
+	Labels                   map[string]string
+	VoterLabels              map[string]string
+	VersionSkewWindow        int
+	VersionSkewRefuse        bool
+	RolesDecisionHook        func([]Operation, error)
+	EventSink                EventSink
+	BackgroundErrorHandler   BackgroundErrorHandler
+	JoinBackoff              BackoffFunc
+	EarlyReady               bool
+	BreakerThreshold         int
+	BreakerCooldown          time.Duration
+	RequiredFunctions        []string
+	JoinMaxAttempts          int
+	JoinOnExhausted          func(error)
+	ProbeConnections         int
+	ProxyConnHook            func(ProxyConnStats)
 	RolesAdjustmentFrequency time.Duration
+	DisableRolesManagement   bool
+	StoreRefreshInterval     time.Duration
+	WatchdogInterval         time.Duration
+	WatchdogFailures         int
+	HealthChecks             []HealthCheck
+	ClockSkewThreshold       time.Duration
 	FailureDomain            uint64
 	NetworkLatency           time.Duration
 	UnixSocket               string
+	InternalTransport        string
+	LocalPlaintext           bool
+	Discovery                DiscoveryFunc
 	SnapshotParams           cowsql.SnapshotParams
 	AutoRecovery             bool
+	AutoRepair               bool
 }
 
 // Create a options object with sane defaults.
@@ -227,7 +710,12 @@ func defaultOptions() *options {
 		Voters:                   3,
 		StandBys:                 3,
 		RolesAdjustmentFrequency: 30 * time.Second,
+		JoinBackoff:              ConstantBackoff(time.Second),
+		ProbeConnections:         16,
+		InternalTransport:        TransportAbstract,
+		Clock:                    client.SystemClock,
 		AutoRecovery:             true,
+		VersionSkewWindow:        1,
 	}
 }
 
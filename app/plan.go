@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cowsql/go-cowsql/app/roles"
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// Operation describes a single change to the cluster, as performed by
+// client.Client.Assign() or client.Client.Transfer().
+type Operation struct {
+	Transfer bool            // True for a leadership transfer, false for a role change.
+	Node     client.NodeInfo // Target node, with its current role.
+	Role     client.NodeRole // New role of the node, for role changes.
+}
+
+func (o Operation) String() string {
+	if o.Transfer {
+		return fmt.Sprintf("transfer leadership to %s", o.Node.Address)
+	}
+	return fmt.Sprintf("change %s from %s to %s", o.Node.Address, o.Node.Role, o.Role)
+}
+
+// HandoverPlan returns the operations that Handover() would execute, without
+// performing them.
+//
+// Handover() tries other candidates if the preferred one fails to accept a
+// role, the plan only contains the preferred ones.
+func (a *App) HandoverPlan(ctx context.Context) ([]Operation, error) {
+	cli, err := a.Leader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("find leader: %w", err)
+	}
+	defer cli.Close()
+
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cluster servers: %w", err)
+	}
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("leader address: %w", err)
+	}
+
+	changes := a.makeRolesChanges(cli, nodes)
+	operations := []Operation{}
+
+	// Possibly transfer our role.
+	role, candidates := changes.Handover(a.id)
+	if role != -1 {
+		candidate := candidates[0]
+		operations = append(operations, Operation{Node: candidate, Role: role})
+		changes = assign(changes, candidate, role)
+	}
+
+	// Transfer leadership if we are the current leader.
+	if leader != nil && leader.Address == a.address {
+		for _, voter := range changes.List(client.Voter, true) {
+			if voter.Address == a.address {
+				continue
+			}
+			operations = append(operations, Operation{Transfer: true, Node: voter})
+			break
+		}
+	}
+
+	// Demote ourselves if we have promoted someone else.
+	if role != -1 {
+		for node := range changes.State {
+			if node.ID == a.id {
+				operations = append(operations, Operation{Node: node, Role: client.Spare})
+				break
+			}
+		}
+	}
+
+	return operations, nil
+}
+
+// AdjustmentPlan returns the operations that the leader would execute to
+// adjust the roles of the nodes in the cluster, without performing them.
+//
+// The plan is computed with the roles configuration of this node, and is
+// returned even if the roles adjustment is paused.
+func (a *App) AdjustmentPlan(ctx context.Context) ([]Operation, error) {
+	cli, err := a.Leader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("find leader: %w", err)
+	}
+	defer cli.Close()
+
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("leader address: %w", err)
+	}
+	if leader == nil {
+		return nil, fmt.Errorf("no leader available")
+	}
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cluster servers: %w", err)
+	}
+
+	changes := a.makeRolesChanges(cli, nodes)
+
+	operations := []Operation{}
+	for _, step := range changes.Simulate(leader.ID) {
+		operations = append(operations, Operation{Node: step.Node, Role: step.Role})
+	}
+
+	return operations, nil
+}
+
+// Return a copy of the given changes with the role of the given node
+// changed.
+func assign(changes roles.Changes, node client.NodeInfo, role client.NodeRole) roles.Changes {
+	state := make(map[client.NodeInfo]*client.NodeMetadata, len(changes.State))
+	for other, metadata := range changes.State {
+		if other == node {
+			other.Role = role
+		}
+		state[other] = metadata
+	}
+	return roles.Changes{Config: changes.Config, State: state}
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// Pool of clients used to probe other nodes, so connections can be reused
+// across roles adjustment rounds instead of being established every time.
+type probePool struct {
+	mu      sync.Mutex
+	clients map[string]*client.Client // Idle clients by node address
+	size    int                       // Maximum number of idle clients
+	closed  bool                      // Whether the pool was closed
+	options []client.Option
+}
+
+func newProbePool(size int, options ...client.Option) *probePool {
+	return &probePool{
+		clients: map[string]*client.Client{},
+		size:    size,
+		options: options,
+	}
+}
+
+// Describe the node with the given address, using an idle client if one is
+// available.
+func (p *probePool) Describe(ctx context.Context, address string) (*client.NodeMetadata, error) {
+	if cli := p.get(address); cli != nil {
+		metadata, err := cli.Describe(ctx)
+		if err == nil {
+			p.put(address, cli)
+			return metadata, nil
+		}
+		// The connection might have been dropped while idle, retry
+		// with a new one.
+		cli.Close()
+	}
+
+	cli, err := client.New(ctx, address, p.options...)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := cli.Describe(ctx)
+	if err != nil {
+		cli.Close()
+		return nil, err
+	}
+	p.put(address, cli)
+
+	return metadata, nil
+}
+
+// Close the idle clients of nodes whose address is not in the given list.
+func (p *probePool) Prune(nodes []client.NodeInfo) {
+	addresses := map[string]bool{}
+	for _, node := range nodes {
+		addresses[node.Address] = true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for address, cli := range p.clients {
+		if !addresses[address] {
+			cli.Close()
+			delete(p.clients, address)
+		}
+	}
+}
+
+// Close all idle clients, and stop keeping new ones.
+func (p *probePool) Close() {
+	p.Prune(nil)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+}
+
+// Return the number of idle clients.
+func (p *probePool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.clients)
+}
+
+// Take the idle client for the given address, if any.
+func (p *probePool) get(address string) *client.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cli := p.clients[address]
+	delete(p.clients, address)
+
+	return cli
+}
+
+// Keep the given client for later use, or close it if the pool is full.
+func (p *probePool) put(address string, cli *client.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.clients[address]; ok || p.closed || len(p.clients) >= p.size {
+		cli.Close()
+		return
+	}
+
+	p.clients[address] = cli
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbePool(t *testing.T) {
+	pool := newProbePool(2)
+
+	cli1 := newPipeClient(t)
+	cli2 := newPipeClient(t)
+	cli3 := newPipeClient(t)
+
+	pool.put("1", cli1)
+	pool.put("1", cli2) // Already one idle client for this address.
+	pool.put("2", newPipeClient(t))
+	pool.put("3", cli3) // Pool is full.
+
+	assert.Len(t, pool.clients, 2)
+	assert.Equal(t, 2, pool.Len())
+	assert.Equal(t, cli1, pool.get("1"))
+	assert.Nil(t, pool.get("1"))
+	assert.Nil(t, pool.get("3"))
+
+	pool.put("1", cli1)
+	pool.Prune([]client.NodeInfo{{Address: "1"}})
+	assert.Len(t, pool.clients, 1)
+
+	pool.Close()
+	assert.Len(t, pool.clients, 0)
+
+	pool.put("1", newPipeClient(t))
+	assert.Len(t, pool.clients, 0)
+}
+
+// Return a client connected to an in-memory server that ignores all
+// requests.
+func newPipeClient(t *testing.T) *client.Client {
+	t.Helper()
+
+	dial := func(ctx context.Context, address string) (net.Conn, error) {
+		conn, server := net.Pipe()
+		go io.Copy(ioutil.Discard, server)
+		return conn, nil
+	}
+
+	cli, err := client.New(context.Background(), "", client.WithDialFunc(dial))
+	require.NoError(t, err)
+
+	return cli
+}
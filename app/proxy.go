@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"io"
 	"net"
 	"os"
 	"reflect"
@@ -26,7 +25,11 @@ import (
 // - an error occurs when writing or reading data
 //
 // In case of errors, details are returned.
-func proxy(ctx context.Context, remote net.Conn, local net.Conn, config *tls.Config) error {
+//
+// If a TLS config is given, the outcome of the handshake is recorded in the
+// given stats, if not nil. The bytes copied in each direction are counted in
+// the given connection, if not nil.
+func proxy(ctx context.Context, remote net.Conn, local net.Conn, config *tls.Config, stats *tlsStats, conn *proxyConn) error {
 	tcp, err := tryExtractTCPConn(remote)
 	if err == nil {
 		if err := setKeepalive(tcp); err != nil {
@@ -34,12 +37,28 @@ func proxy(ctx context.Context, remote net.Conn, local net.Conn, config *tls.Con
 		}
 	}
 
+	var handshake func() error
 	if config != nil {
+		var tlsConn *tls.Conn
 		if config.ClientCAs != nil {
-			remote = tls.Server(remote, config)
+			tlsConn = tls.Server(remote, config)
 		} else {
-			remote = tls.Client(remote, config)
+			tlsConn = tls.Client(remote, config)
 		}
+		handshake = func() error {
+			start := time.Now()
+			err := tlsConn.Handshake()
+			if stats != nil {
+				stats.handshake(tlsConn.ConnectionState(), time.Since(start), err)
+			}
+			return err
+		}
+		remote = tlsConn
+	}
+
+	var in, out *uint64
+	if conn != nil {
+		in, out = &conn.in, &conn.out
 	}
 
 	remoteToLocal := make(chan error, 0)
@@ -48,12 +67,20 @@ func proxy(ctx context.Context, remote net.Conn, local net.Conn, config *tls.Con
 	// Start copying data back and forth until either the client or the
 	// server get closed or hit an error.
 	go func() {
-		_, err := io.Copy(local, remote)
+		// Perform the handshake explicitly, so it can be measured. It's
+		// done here, so cancelling the context still interrupts it.
+		if handshake != nil {
+			if err := handshake(); err != nil {
+				remoteToLocal <- err
+				return
+			}
+		}
+		_, err := proxyCopy(local, remote, in)
 		remoteToLocal <- err
 	}()
 
 	go func() {
-		_, err := io.Copy(remote, local)
+		_, err := proxyCopy(remote, local, out)
 		localToRemote <- err
 	}()
 
@@ -179,7 +206,7 @@ func setKeepalive(conn *net.TCPConn) error {
 
 // Returns a pair of connected unix sockets.
 func socketpair() (net.Conn, net.Conn, error) {
-	fds, err := syscall.Socketpair(syscall.AF_LOCAL, syscall.SOCK_STREAM, 0)
+	fds, err := socketpairCloexec()
 	if err != nil {
 		return nil, nil, err
 	}
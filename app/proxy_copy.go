@@ -0,0 +1,65 @@
+package app
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Size of the buffers used to copy data between proxied connections.
+const proxyBufferSize = 64 * 1024
+
+// Buffers used to copy data between proxied connections, shared across
+// connections to avoid allocating a new one for each of them.
+var proxyBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, proxyBufferSize)
+		return &buf
+	},
+}
+
+// Copy data from src to dst until either EOF is reached on src or an error
+// occurs. The number of bytes written so far is atomically added to the given
+// counter as the copy progresses, if not nil.
+//
+// When both connections are plain sockets, data is moved within the kernel
+// where the platform supports it, see spliceCopy. Otherwise a pooled buffer is
+// used.
+func proxyCopy(dst net.Conn, src net.Conn, counter *uint64) (int64, error) {
+	if written, handled, err := spliceCopy(dst, src, counter); handled {
+		return written, err
+	}
+
+	buf := proxyBuffers.Get().(*[]byte)
+	defer proxyBuffers.Put(buf)
+
+	var written int64
+	for {
+		n, err := src.Read(*buf)
+		if n > 0 {
+			m, werr := dst.Write((*buf)[:n])
+			written += int64(m)
+			countBytes(counter, m)
+			if werr != nil {
+				return written, werr
+			}
+			if m != n {
+				return written, io.ErrShortWrite
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// Add the given number of bytes to the given counter, if not nil.
+func countBytes(counter *uint64, n int) {
+	if counter != nil && n > 0 {
+		atomic.AddUint64(counter, uint64(n))
+	}
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Data is proxied unchanged in both directions between plain sockets.
+func TestProxy_Plain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	closed := make(chan ProxyConnStats, 1)
+	conns := newProxyConns(func(stats ProxyConnStats) { closed <- stats }, defaultLogFunc)
+
+	go func() {
+		remote, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		goUnix, cUnix, err := socketpair()
+		if err != nil {
+			return
+		}
+		go func() {
+			io.Copy(cUnix, cUnix)
+			cUnix.Close()
+		}()
+		conn := conns.open(remote.RemoteAddr().String())
+		defer conns.close(conn)
+		proxy(ctx, remote, goUnix, nil, nil, conn)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+
+	data := make([]byte, 1024*1024)
+	_, err = rand.Read(data)
+	require.NoError(t, err)
+
+	go conn.Write(data)
+
+	echoed := make([]byte, len(data))
+	_, err = io.ReadFull(conn, echoed)
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(data, echoed))
+
+	active := conns.get()
+	require.Len(t, active, 1)
+	assert.Equal(t, conn.LocalAddr().String(), active[0].Remote)
+
+	conn.Close()
+	stats := <-closed
+	assert.Equal(t, uint64(len(data)), stats.BytesIn)
+	assert.Equal(t, uint64(len(data)), stats.BytesOut)
+	assert.Empty(t, conns.get())
+}
+
+// Connections that are not plain sockets are copied through a buffer.
+func TestProxyCopy_Buffered(t *testing.T) {
+	src, writer := net.Pipe()
+	reader, dst := net.Pipe()
+
+	go func() {
+		writer.Write([]byte("hello"))
+		writer.Close()
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := proxyCopy(dst, src, nil)
+		dst.Close()
+		done <- err
+	}()
+
+	data, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.NoError(t, <-done)
+}
@@ -2,8 +2,37 @@
 
 package app
 
+import (
+	"net"
+	"syscall"
+)
+
 // from netinet/tcp.h (OS X 10.9.4)
 const (
 	_TCP_KEEPINTVL = 0x101 /* interval between keepalives */
 	_TCP_KEEPCNT   = 0x102 /* number of keepalives before close */
 )
+
+// Create a pair of connected Unix sockets with the close-on-exec flag set.
+//
+// Darwin has no SOCK_CLOEXEC, so hold the fork lock like the standard library
+// does, to prevent a concurrent fork from inheriting the sockets.
+func socketpairCloexec() ([2]int, error) {
+	syscall.ForkLock.RLock()
+	defer syscall.ForkLock.RUnlock()
+
+	fds, err := syscall.Socketpair(syscall.AF_LOCAL, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return fds, err
+	}
+	syscall.CloseOnExec(fds[0])
+	syscall.CloseOnExec(fds[1])
+
+	return fds, nil
+}
+
+// Splicing is not available, data is always copied through a userspace
+// buffer.
+func spliceCopy(dst net.Conn, src net.Conn, counter *uint64) (int64, bool, error) {
+	return 0, false, nil
+}
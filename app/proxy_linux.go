@@ -3,10 +3,115 @@
 package app
 
 import (
+	"net"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 const (
 	_TCP_KEEPINTVL = syscall.TCP_KEEPINTVL /* interval between keepalives */
 	_TCP_KEEPCNT   = syscall.TCP_KEEPCNT   /* number of keepalives before close */
 )
+
+// Create a pair of connected Unix sockets, atomically setting the
+// close-on-exec flag.
+func socketpairCloexec() ([2]int, error) {
+	return syscall.Socketpair(syscall.AF_LOCAL, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+}
+
+// Maximum number of bytes moved by a single splice call, matching the default
+// capacity of a pipe.
+const spliceChunk = 64 * 1024
+
+// Copy data from src to dst within the kernel, using splice(2) through an
+// intermediate pipe, without copying it to userspace.
+//
+// The returned boolean is false if the connections are not plain sockets or
+// splicing is not supported for them, in which case nothing was copied and
+// the caller should fall back to a regular copy. See proxyCopy for the
+// counter.
+func spliceCopy(dst net.Conn, src net.Conn, counter *uint64) (int64, bool, error) {
+	dstConn, ok := dst.(syscall.Conn)
+	if !ok {
+		return 0, false, nil
+	}
+	srcConn, ok := src.(syscall.Conn)
+	if !ok {
+		return 0, false, nil
+	}
+	rawDst, err := dstConn.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+	rawSrc, err := srcConn.SyscallConn()
+	if err != nil {
+		return 0, false, nil
+	}
+
+	var pipe [2]int
+	if err := unix.Pipe2(pipe[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		return 0, false, nil
+	}
+	defer unix.Close(pipe[0])
+	defer unix.Close(pipe[1])
+
+	var written int64
+	for {
+		// Move the available data from the source socket into the pipe,
+		// waiting for it to become readable if needed.
+		n, err := spliceWait(rawSrc.Read, func(fd int) (int, error) {
+			return splice(fd, pipe[1], spliceChunk)
+		})
+		if err == unix.EINVAL && written == 0 {
+			return 0, false, nil
+		}
+		if err != nil {
+			return written, true, err
+		}
+		if n == 0 {
+			return written, true, nil // EOF
+		}
+
+		// Drain the pipe into the destination socket, waiting for it to
+		// become writable if needed.
+		for n > 0 {
+			m, err := spliceWait(rawDst.Write, func(fd int) (int, error) {
+				return splice(pipe[0], fd, n)
+			})
+			if err != nil {
+				return written, true, err
+			}
+			n -= m
+			written += int64(m)
+			countBytes(counter, m)
+		}
+	}
+}
+
+// Call the given non-blocking splice function from within the given RawConn
+// Read or Write method, so it's retried once the socket is ready whenever it
+// would block.
+func spliceWait(wait func(func(uintptr) bool) error, f func(fd int) (int, error)) (int, error) {
+	var n int
+	var err error
+	werr := wait(func(fd uintptr) bool {
+		n, err = f(int(fd))
+		return err != unix.EAGAIN
+	})
+	if werr != nil {
+		return 0, werr
+	}
+	return n, err
+}
+
+// Move up to n bytes from one file descriptor to another, retrying on EINTR.
+func splice(from int, to int, n int) (int, error) {
+	for {
+		m, err := unix.Splice(from, nil, to, nil, n, unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+		if err == unix.EINTR {
+			continue
+		}
+		return int(m), err
+	}
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// ProxyConnStats describes a connection from another node or a client that is
+// proxied to the local node, as returned by App.ProxyConns or passed to the
+// hook set with WithProxyConnHook.
+type ProxyConnStats struct {
+	Remote   string        // Address of the other end of the connection.
+	BytesIn  uint64        // Bytes received from the other end.
+	BytesOut uint64        // Bytes sent to the other end.
+	Start    time.Time     // When the connection was accepted.
+	Duration time.Duration // How long the connection has been open.
+}
+
+// A connection being proxied to the local node.
+type proxyConn struct {
+	remote string
+	start  time.Time
+	in     uint64 // Set atomically
+	out    uint64 // Set atomically
+}
+
+func (c *proxyConn) stats() ProxyConnStats {
+	return ProxyConnStats{
+		Remote:   c.remote,
+		BytesIn:  atomic.LoadUint64(&c.in),
+		BytesOut: atomic.LoadUint64(&c.out),
+		Start:    c.start,
+		Duration: time.Since(c.start),
+	}
+}
+
+// Track the connections being proxied to the local node.
+type proxyConns struct {
+	mu    sync.Mutex
+	conns map[*proxyConn]struct{}
+	hook  func(ProxyConnStats)
+	log   client.LogFunc
+}
+
+func newProxyConns(hook func(ProxyConnStats), log client.LogFunc) *proxyConns {
+	return &proxyConns{conns: map[*proxyConn]struct{}{}, hook: hook, log: log}
+}
+
+// Start tracking a new connection from the given address.
+func (p *proxyConns) open(remote string) *proxyConn {
+	conn := &proxyConn{remote: remote, start: time.Now()}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conns[conn] = struct{}{}
+	return conn
+}
+
+// Stop tracking the given connection, reporting its final counters.
+func (p *proxyConns) close(conn *proxyConn) {
+	p.mu.Lock()
+	delete(p.conns, conn)
+	p.mu.Unlock()
+
+	stats := conn.stats()
+	p.log(client.LogDebug, "connection from %s closed after %s: %d bytes in, %d bytes out",
+		stats.Remote, stats.Duration, stats.BytesIn, stats.BytesOut)
+	if p.hook != nil {
+		p.hook(stats)
+	}
+}
+
+func (p *proxyConns) get() []ProxyConnStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := make([]ProxyConnStats, 0, len(p.conns))
+	for conn := range p.conns {
+		conns = append(conns, conn.stats())
+	}
+	return conns
+}
+
+// ProxyConns returns the byte counters of the connections currently proxied
+// to the local node, from other nodes and from clients, sorted by total
+// number of bytes transferred, largest first. It can be used to identify
+// bandwidth-heavy clients.
+//
+// Only connections accepted by the node are proxied, which is the case when
+// WithTLS or WithExternalConn are used. See also WithProxyConnHook.
+func (a *App) ProxyConns() []ProxyConnStats {
+	conns := a.proxyConns.get()
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].BytesIn+conns[i].BytesOut > conns[j].BytesIn+conns[j].BytesOut
+	})
+	return conns
+}
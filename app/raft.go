@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Size of a raft metadata file and of the header of a raft segment batch or
+// entry.
+const (
+	raftMetadataSize = 32
+	raftHeaderSize   = 16
+)
+
+// LastEntryInfo holds the term and index of the last entry in the raft log
+// of an application node.
+type LastEntryInfo struct {
+	Term  uint64
+	Index uint64
+}
+
+// LastEntryInfo returns the term and index of the last entry persisted in the
+// local raft log of this application node.
+//
+// Entries are applied right after being persisted, so this is a point that
+// can be used to tag a dump of the node's databases. Comparing the index with
+// the one of the leader tells how far behind a follower is, for example before
+// taking a dump from it.
+//
+// The information is read from the data directory, and is zero if the log is
+// empty.
+func (a *App) LastEntryInfo(ctx context.Context) (LastEntryInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return LastEntryInfo{}, err
+	}
+	return lastEntryInfo(a.dir)
+}
+
+// Read the term and index of the last entry from the raft files in the given
+// directory.
+func lastEntryInfo(dir string) (LastEntryInfo, error) {
+	last := LastEntryInfo{}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return last, fmt.Errorf("read data directory: %w", err)
+	}
+
+	names := map[string]bool{}
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	closed := ""
+	var closedLast uint64
+	open := map[uint64]string{} // Open segment names by counter
+	counters := []uint64{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasPrefix(name, "snapshot-") && !strings.HasSuffix(name, ".meta"):
+			var term, index, timestamp uint64
+			if _, err := fmt.Sscanf(name, "snapshot-%d-%d-%d", &term, &index, &timestamp); err != nil {
+				continue
+			}
+			if names[name+".meta"] && index > last.Index {
+				last = LastEntryInfo{Term: term, Index: index}
+			}
+		case strings.HasPrefix(name, "open-"):
+			var counter uint64
+			if _, err := fmt.Sscanf(name, "open-%d", &counter); err != nil {
+				continue
+			}
+			open[counter] = name
+			counters = append(counters, counter)
+		case isClosedSegment(name):
+			var first, end uint64
+			fmt.Sscanf(name, "%d-%d", &first, &end)
+			if end > closedLast {
+				closed = name
+				closedLast = end
+			}
+		}
+	}
+
+	if closed != "" && closedLast >= last.Index {
+		segment, err := readSegment(filepath.Join(dir, closed), nil)
+		if err != nil {
+			return last, err
+		}
+		if segment.Entries > 0 {
+			last = LastEntryInfo{Term: segment.Term, Index: closedLast}
+		}
+	}
+
+	// Open segments hold the entries following the last closed segment,
+	// in the order of their counter.
+	sort.Slice(counters, func(i, j int) bool { return counters[i] < counters[j] })
+	for _, counter := range counters {
+		segment, err := readSegment(filepath.Join(dir, open[counter]), nil)
+		if err != nil {
+			return last, err
+		}
+		if segment.Entries > 0 {
+			last = LastEntryInfo{Term: segment.Term, Index: last.Index + segment.Entries}
+		}
+	}
+
+	return last, nil
+}
+
+// Return true if the given name is the one of a closed raft segment, in the
+// form <first index>-<last index>.
+func isClosedSegment(name string) bool {
+	var first, last uint64
+	n, err := fmt.Sscanf(name, "%d-%d", &first, &last)
+	return err == nil && n == 2 && fmt.Sprintf("%016d-%016d", first, last) == name
+}
+
+// Summary of the content of a raft segment.
+type segmentInfo struct {
+	Entries uint64 // Number of entries in complete batches.
+	Term    uint64 // Term of the last entry in complete batches.
+	Partial int64  // Offset of the first partial batch, or -1.
+}
+
+// Walk the batches of the given raft segment, passing the type and data of
+// each entry in complete batches to the given function, if not nil.
+//
+// Only the batch and entry headers are read, along with the entries data if
+// visit is not nil, so the segment is never loaded in memory as a whole.
+func readSegment(path string, visit func(kind uint8, data []byte)) (segmentInfo, error) {
+	info := segmentInfo{Partial: -1}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return info, fmt.Errorf("read segment: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return info, fmt.Errorf("read segment: %w", err)
+	}
+	size := uint64(stat.Size())
+
+	read := func(offset, n uint64) ([]byte, error) {
+		buf := make([]byte, n)
+		if _, err := file.ReadAt(buf, int64(offset)); err != nil {
+			return nil, fmt.Errorf("read segment: %w", err)
+		}
+		return buf, nil
+	}
+
+	// Empty or unused segments are discarded by raft itself.
+	if size < 8 {
+		return info, nil
+	}
+	format, err := read(0, 8)
+	if err != nil {
+		return info, err
+	}
+	if binary.LittleEndian.Uint64(format) == 0 {
+		return info, nil
+	}
+
+	offset := uint64(8)
+	for offset+raftHeaderSize <= size {
+		header, err := read(offset, raftHeaderSize)
+		if err != nil {
+			return info, err
+		}
+		n := binary.LittleEndian.Uint64(header[8:])
+
+		// Open segments are preallocated and zero-filled.
+		if n == 0 {
+			return info, nil
+		}
+
+		if n > (size-offset)/raftHeaderSize {
+			info.Partial = int64(offset)
+			return info, nil
+		}
+		headers := offset + raftHeaderSize
+		start := headers + n*raftHeaderSize
+		if start > size {
+			info.Partial = int64(offset)
+			return info, nil
+		}
+		entries, err := read(headers, n*raftHeaderSize)
+		if err != nil {
+			return info, err
+		}
+		end := start
+		for i := uint64(0); i < n; i++ {
+			length := uint64(binary.LittleEndian.Uint32(entries[i*raftHeaderSize+12:]))
+			end += (length + 7) &^ 7 // Entries data is padded to 8 bytes
+		}
+		if end > size {
+			info.Partial = int64(offset)
+			return info, nil
+		}
+		if visit != nil {
+			data, err := read(start, end-start)
+			if err != nil {
+				return info, err
+			}
+			position := uint64(0)
+			for i := uint64(0); i < n; i++ {
+				entry := entries[i*raftHeaderSize:]
+				length := uint64(binary.LittleEndian.Uint32(entry[12:]))
+				visit(entry[8], data[position:position+length])
+				position += (length + 7) &^ 7
+			}
+		}
+
+		info.Entries += n
+		info.Term = binary.LittleEndian.Uint64(entries[(n-1)*raftHeaderSize:])
+		offset = end
+	}
+
+	// What's left is too short to hold a batch header.
+	tail, err := read(offset, size-offset)
+	if err != nil {
+		return info, err
+	}
+	for _, b := range tail {
+		if b != 0 {
+			info.Partial = int64(offset)
+			return info, nil
+		}
+	}
+
+	return info, nil
+}
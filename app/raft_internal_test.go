@@ -0,0 +1,41 @@
+package app
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastEntryInfo(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	last, err := lastEntryInfo(dir)
+	require.NoError(t, err)
+	assert.Equal(t, LastEntryInfo{}, last)
+
+	require.NoError(t, fileWrite(dir, "snapshot-2-10-123", []byte("data")))
+	require.NoError(t, fileWrite(dir, "snapshot-2-10-123.meta", []byte("meta")))
+
+	last, err = lastEntryInfo(dir)
+	require.NoError(t, err)
+	assert.Equal(t, LastEntryInfo{Term: 2, Index: 10}, last)
+
+	require.NoError(t, fileWrite(dir, "0000000000000009-0000000000000012", newSegment(3, 4, 8)))
+
+	last, err = lastEntryInfo(dir)
+	require.NoError(t, err)
+	assert.Equal(t, LastEntryInfo{Term: 3, Index: 12}, last)
+
+	// Open segments are preallocated, and the last one might still be
+	// unused.
+	require.NoError(t, fileWrite(dir, "open-2", append(newSegment(4, 3, 8), make([]byte, 64)...)))
+	require.NoError(t, fileWrite(dir, "open-10", append(newSegment(5, 1, 8), make([]byte, 64)...)))
+	require.NoError(t, fileWrite(dir, "open-11", make([]byte, 64)))
+
+	last, err = lastEntryInfo(dir)
+	require.NoError(t, err)
+	assert.Equal(t, LastEntryInfo{Term: 5, Index: 16}, last)
+}
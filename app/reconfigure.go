@@ -0,0 +1,118 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/cowsql/go-cowsql"
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// ErrConfirmationMismatch is returned by ForceReconfigure when the given
+// confirmation token does not match the one returned by
+// ForceReconfigureToken.
+var ErrConfirmationMismatch = errors.New("confirmation token mismatch")
+
+// ForceReconfigureToken returns the confirmation token that must be passed to
+// ForceReconfigure in order to force the given configuration onto the given
+// data directory.
+//
+// The configuration is validated first, see ForceReconfigure. The token
+// depends on the directory and on the configuration, so it can't be reused by
+// mistake for a different node or a different configuration.
+func ForceReconfigureToken(dir string, nodes []client.NodeInfo) (string, error) {
+	if err := validateReconfigure(dir, nodes); err != nil {
+		return "", err
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n", abs)
+	for _, node := range nodes {
+		fmt.Fprintf(hash, "%d %s %d\n", node.ID, node.Address, node.Role)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:12], nil
+}
+
+// ForceReconfigure forces a new cluster configuration onto the raft log in
+// the given data directory, and replaces the nodes in its cluster.yaml file
+// accordingly.
+//
+// WARNING: this is an unsafe, last-resort recovery procedure, meant for
+// clusters that lost the majority of their voters and can't elect a leader
+// anymore. It bypasses the raft consensus, so entries that were not
+// replicated to the chosen node are lost, and using it while the cluster is
+// still able to make progress leads to divergent logs. Before using it:
+//
+//   - back up the data directories of all nodes;
+//   - stop all nodes;
+//   - pick the node with the most recent raft log, see InspectDir;
+//   - force the new configuration onto that node only, then copy its raft
+//     files and cluster.yaml to the other nodes of the new configuration.
+//
+// The node must be stopped. The given configuration must include the node
+// owning the directory, with non-zero IDs matching the ones in the info.yaml
+// files of the nodes, and at least one voter. To prevent accidental use, the
+// token returned by ForceReconfigureToken for the same directory and
+// configuration must be given, otherwise ErrConfirmationMismatch is returned.
+func ForceReconfigure(dir string, nodes []client.NodeInfo, token string) error {
+	expected, err := ForceReconfigureToken(dir, nodes)
+	if err != nil {
+		return err
+	}
+	if token != expected {
+		return ErrConfirmationMismatch
+	}
+
+	if err := cowsql.ReconfigureMembershipExt(dir, nodes); err != nil {
+		return fmt.Errorf("reconfigure membership: %w", err)
+	}
+
+	return UpdateStoreFile(dir, nodes)
+}
+
+// Check that the given configuration can be forced onto the node owning the
+// given data directory.
+func validateReconfigure(dir string, nodes []client.NodeInfo) error {
+	if err := validateStore(nodes); err != nil {
+		return err
+	}
+
+	info := client.NodeInfo{}
+	if err := fileUnmarshal(dir, infoFile, &info); err != nil {
+		return err
+	}
+
+	voters := 0
+	found := false
+	for _, node := range nodes {
+		if node.ID == 0 {
+			return fmt.Errorf("node %s has no ID", node.Address)
+		}
+		if node.Role == client.Voter {
+			voters++
+		}
+		if node.ID == info.ID {
+			if node.Address != info.Address {
+				return fmt.Errorf("node %d has address %s in %s, not %s", info.ID, info.Address, infoFile, node.Address)
+			}
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("configuration does not include node %d owning %s", info.ID, dir)
+	}
+	if voters == 0 {
+		return fmt.Errorf("configuration has no voters")
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForceReconfigureToken(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	info := []byte("ID: 1\nAddress: 1.2.3.4:666\n")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, infoFile), info, 0600))
+
+	nodes := []client.NodeInfo{
+		{ID: 1, Address: "1.2.3.4:666", Role: client.Voter},
+		{ID: 2, Address: "5.6.7.8:666", Role: client.Spare},
+	}
+
+	token, err := ForceReconfigureToken(dir, nodes)
+	require.NoError(t, err)
+	assert.Len(t, token, 12)
+
+	// The token is stable.
+	again, err := ForceReconfigureToken(dir, nodes)
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+
+	// The token depends on the configuration.
+	nodes[1].Role = client.Voter
+	other, err := ForceReconfigureToken(dir, nodes)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	assert.Equal(t, ErrConfirmationMismatch, ForceReconfigure(dir, nodes, token))
+}
+
+func TestForceReconfigure_Invalid(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	info := []byte("ID: 1\nAddress: 1.2.3.4:666\n")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, infoFile), info, 0600))
+
+	cases := []struct {
+		nodes []client.NodeInfo
+		err   string
+	}{
+		{nil, "no nodes given"},
+		{[]client.NodeInfo{{Address: "1.2.3.4:666", Role: client.Voter}}, "node 1.2.3.4:666 has no ID"},
+		{[]client.NodeInfo{{ID: 2, Address: "5.6.7.8:666", Role: client.Voter}}, "configuration does not include node 1 owning " + dir},
+		{[]client.NodeInfo{{ID: 1, Address: "5.6.7.8:666", Role: client.Voter}}, "node 1 has address 1.2.3.4:666 in info.yaml, not 5.6.7.8:666"},
+		{[]client.NodeInfo{{ID: 1, Address: "1.2.3.4:666", Role: client.StandBy}}, "configuration has no voters"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.err, func(t *testing.T) {
+			assert.EqualError(t, ForceReconfigure(dir, c.nodes, "token"), c.err)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// +build cgo,!nosqlite3
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// Restore populates the given data directory with the databases of a backup
+// archive written by App.Backup, so that a node started on it with New forms
+// a new single-node cluster holding the restored data. Other nodes can then
+// join it as usual.
+//
+// The directory must not exist or be empty. The given options are used to
+// start the node while loading the databases, and should match the ones the
+// node will be started with afterwards, in particular WithAddress and any TLS
+// option. The info.yaml and cluster.yaml files are written as for any brand
+// new node.
+//
+// The databases are loaded through the node, so the restored node has its own
+// raft history. The configuration database, holding node labels and
+// versions, is not restored, since it refers to the nodes of the old cluster.
+func Restore(dir string, r io.Reader, options ...Option) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read data directory: %w", err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("data directory %s is not empty", dir)
+	}
+
+	manifest, dumps, err := readBackup(r)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create data directory: %w", err)
+	}
+
+	app, err := New(dir, options...)
+	if err != nil {
+		return fmt.Errorf("start node: %w", err)
+	}
+	if err := app.restore(context.Background(), manifest, dumps); err != nil {
+		app.Close()
+		return err
+	}
+
+	return app.Close()
+}
+
+// Wait for the node to be ready and load the databases of the given backup.
+func (a *App) restore(ctx context.Context, manifest *BackupManifest, dumps map[string][]client.File) error {
+	if err := a.Ready(ctx); err != nil {
+		return fmt.Errorf("node not ready: %w", err)
+	}
+
+	for _, database := range manifest.Databases {
+		if database.Name == client.ConfigDatabase {
+			continue
+		}
+		if err := a.restoreDatabase(ctx, database.Name, dumps[database.Name]); err != nil {
+			return fmt.Errorf("restore database %s: %w", database.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Load the given dump files into the database with the given name.
+func (a *App) restoreDatabase(ctx context.Context, name string, files []client.File) error {
+	src, cleanup, err := openDump(name, files)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	db, err := a.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return loadDump(ctx, db, src)
+}
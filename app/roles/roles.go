@@ -0,0 +1,503 @@
+// Package roles implements the algorithm used by the high-level App object to
+// decide which node should have which role in a cluster.
+//
+// The algorithm is exposed so it can be used without an App, or to simulate
+// what the App would do for a given cluster state.
+package roles
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+const minVoters = 3
+
+// Config can be used to tweak the algorithm implemented by Changes.
+type Config struct {
+	Voters   int // Target number of voters, 3 by default.
+	StandBys int // Target number of stand-bys, 3 by default.
+
+	// Minimum number of nodes with the stand-by role, online or not.
+	// Offline stand-bys are not demoted if that would leave less
+	// stand-bys than this.
+	MinStandBys int
+
+	// Maximum number of online voters and stand-bys in a single failure
+	// domain. Nodes are not promoted to a role if their failure domain
+	// already has that many online nodes with that role. Zero means no
+	// limit.
+	MaxVotersPerDomain   int
+	MaxStandBysPerDomain int
+
+	// If true, nodes are not promoted to voter if their failure domain
+	// already has an online voter, as long as there are at least as many
+	// failure domains with online nodes as target voters. Otherwise
+	// failure domains are only used to sort candidates.
+	StrictFailureDomains bool
+
+	// If not empty, only nodes whose labels include all the given
+	// key/value pairs are promoted to voter, see client.SetLabels. Voters
+	// that don't match are not demoted.
+	VoterLabels map[string]string
+}
+
+// Validate checks that the configuration is consistent.
+func (c Config) Validate() error {
+	if c.Voters < 3 || c.Voters%2 == 0 {
+		return fmt.Errorf("invalid voters %d: must be an odd number greater than 1", c.Voters)
+	}
+	if c.StandBys < 0 {
+		return fmt.Errorf("invalid stand-bys %d: must not be negative", c.StandBys)
+	}
+	if c.MinStandBys < 0 || c.MinStandBys > c.StandBys {
+		return fmt.Errorf("invalid minimum stand-bys %d: must be between 0 and %d", c.MinStandBys, c.StandBys)
+	}
+	if c.MaxVotersPerDomain < 0 {
+		return fmt.Errorf("invalid maximum voters per failure domain %d: must not be negative", c.MaxVotersPerDomain)
+	}
+	if c.MaxStandBysPerDomain < 0 {
+		return fmt.Errorf("invalid maximum stand-bys per failure domain %d: must not be negative", c.MaxStandBysPerDomain)
+	}
+	return nil
+}
+
+// Changes implements an algorithm to take decisions about which node should
+// have which role in a cluster.
+//
+// You normally don't need to use this data structure since it's already
+// transparently wired into the high-level App object. However this is exposed
+// for users who don't want to use the high-level App object but still want to
+// implement the same roles management algorithm.
+//
+// Changes only takes decisions based on its State, and never changes it.
+type Changes struct {
+	// Algorithm configuration.
+	Config Config
+
+	// Current state of the cluster. Each node in the cluster must be
+	// present as a key in the map, and its value should be its associated
+	// failure domain and weight metadata or nil if the node is currently
+	// offline.
+	State map[client.NodeInfo]*client.NodeMetadata
+}
+
+// Assume decides if a node should assume a different role than the one it
+// currently has. It should normally be run at node startup, where the
+// algorithm might decide that the node should assume the Voter or Stand-By
+// role in case there's a shortage of them.
+//
+// Return -1 in case no role change is needed.
+func (c *Changes) Assume(id uint64) client.NodeRole {
+	// If the cluster is still too small, do nothing.
+	if c.size() < minVoters {
+		return -1
+	}
+
+	node := c.get(id)
+
+	// If we are not in the cluster, it means we were removed, just do nothing.
+	if node == nil {
+		return -1
+	}
+
+	// If we already have the Voter or StandBy role, there's nothing to do.
+	if node.Role == client.Voter || node.Role == client.StandBy {
+		return -1
+	}
+
+	onlineVoters := c.List(client.Voter, true)
+	onlineStandbys := c.List(client.StandBy, true)
+
+	// If we have already the desired number of online voters and
+	// stand-bys, there's nothing to do.
+	if len(onlineVoters) >= c.Config.Voters && len(onlineStandbys) >= c.Config.StandBys {
+		return -1
+	}
+
+	// Figure if we need to become stand-by or voter.
+	if len(onlineVoters) < c.Config.Voters && c.fits(*node, client.Voter, node.ID) {
+		return client.Voter
+	}
+	if len(onlineStandbys) < c.Config.StandBys && c.fits(*node, client.StandBy, node.ID) {
+		return client.StandBy
+	}
+
+	return -1
+}
+
+// Handover decides if a node should transfer its current role to another
+// node. This is typically run when the node is shutting down and is hence going to be offline soon.
+//
+// Return the role that should be handed over and list of candidates that
+// should receive it, in order of preference.
+func (c *Changes) Handover(id uint64) (client.NodeRole, []client.NodeInfo) {
+	node := c.get(id)
+
+	// If we are not in the cluster, it means we were removed, just do nothing.
+	if node == nil {
+		return -1, nil
+	}
+
+	// If we aren't a voter or a stand-by, there's nothing to do.
+	if node.Role != client.Voter && node.Role != client.StandBy {
+		return -1, nil
+	}
+
+	// Make a list of all online nodes with the same role and get their
+	// failure domains.
+	peers := c.List(node.Role, true)
+	for i := range peers {
+		if peers[i].ID == node.ID {
+			peers = append(peers[:i], peers[i+1:]...)
+			break
+		}
+	}
+	domains := c.failureDomains(peers)
+
+	// Online spare nodes are always candidates.
+	candidates := c.List(client.Spare, true)
+
+	// Stand-by nodes are candidates if we need to transfer voting
+	// rights, and they are preferred over spares.
+	if node.Role == client.Voter {
+		candidates = append(c.List(client.StandBy, true), candidates...)
+	}
+	candidates = c.filter(candidates, node.Role, node.ID)
+
+	if len(candidates) == 0 {
+		// No online node available to be promoted.
+		return -1, nil
+	}
+
+	c.sortCandidates(candidates, domains)
+
+	return node.Role, candidates
+}
+
+// Adjust decides if there should be changes in the current roles.
+//
+// Return the role that should be assigned and a list of candidates that should
+// assume it, in order of preference.
+func (c *Changes) Adjust(leader uint64) (client.NodeRole, []client.NodeInfo) {
+	if c.size() == 1 {
+		return -1, nil
+	}
+
+	// If the cluster is too small, make sure we have just one voter (us).
+	if c.size() < minVoters {
+		for _, node := range c.nodes() {
+			if node.ID == leader || node.Role != client.Voter {
+				continue
+			}
+			return client.Spare, []client.NodeInfo{node}
+		}
+		return -1, nil
+	}
+
+	onlineVoters := c.List(client.Voter, true)
+	onlineStandbys := c.List(client.StandBy, true)
+	offlineVoters := c.List(client.Voter, false)
+	offlineStandbys := c.List(client.StandBy, false)
+
+	// If we have exactly the desired number of voters and stand-bys, and they are all
+	// online, we're good.
+	if len(offlineVoters) == 0 && len(onlineVoters) == c.Config.Voters && len(offlineStandbys) == 0 && len(onlineStandbys) == c.Config.StandBys {
+		return -1, nil
+	}
+
+	// If we have less online voters than desired, let's try to promote
+	// some other node.
+	if n := len(onlineVoters); n < c.Config.Voters {
+		candidates := c.List(client.StandBy, true)
+		candidates = append(candidates, c.List(client.Spare, true)...)
+		candidates = c.filter(candidates, client.Voter, 0)
+
+		if len(candidates) == 0 {
+			return -1, nil
+		}
+
+		domains := c.failureDomains(onlineVoters)
+		c.sortCandidates(candidates, domains)
+
+		return client.Voter, candidates
+	}
+
+	// If we have more online voters than desired, let's demote one of
+	// them.
+	if n := len(onlineVoters); n > c.Config.Voters {
+		nodes := []client.NodeInfo{}
+		for _, node := range onlineVoters {
+			// Don't demote the leader.
+			if node.ID == leader {
+				continue
+			}
+			nodes = append(nodes, node)
+		}
+
+		return client.Spare, nodes
+	}
+
+	// If we have offline voters, let's demote one of them.
+	if n := len(offlineVoters); n > 0 {
+		return client.Spare, offlineVoters
+	}
+
+	// If we have less online stand-bys than desired, let's try to promote
+	// some other node.
+	if n := len(onlineStandbys); n < c.Config.StandBys {
+		candidates := c.filter(c.List(client.Spare, true), client.StandBy, 0)
+
+		if len(candidates) == 0 {
+			return -1, nil
+		}
+
+		domains := c.failureDomains(onlineStandbys)
+		c.sortCandidates(candidates, domains)
+
+		return client.StandBy, candidates
+	}
+
+	// If we have more online stand-bys than desired, let's demote one of
+	// them.
+	if n := len(onlineStandbys); n > c.Config.StandBys {
+		nodes := []client.NodeInfo{}
+		for _, node := range onlineStandbys {
+			// Don't demote the leader.
+			if node.ID == leader {
+				continue
+			}
+			nodes = append(nodes, node)
+		}
+
+		return client.Spare, nodes
+	}
+
+	// If we have offline stand-bys, let's demote one of them, unless we
+	// would go below the minimum.
+	if n := len(offlineStandbys); n > 0 && len(onlineStandbys)+n > c.Config.MinStandBys {
+		return client.Spare, offlineStandbys
+	}
+
+	return -1, nil
+}
+
+// Step is a single role change decided by the algorithm.
+type Step struct {
+	Node client.NodeInfo // Node whose role changes, with its current role.
+	Role client.NodeRole // New role of the node.
+}
+
+// Simulate returns the role changes that the leader with the given ID would
+// make in order to adjust the cluster, without performing them. This is what
+// the high-level App object does periodically.
+//
+// Each change is applied to a copy of the State before deciding the next one,
+// assuming that the first candidate returned by Adjust always accepts its new
+// role. The changes are returned in the order they would be made.
+func (c *Changes) Simulate(leader uint64) []Step {
+	state := make(map[client.NodeInfo]*client.NodeMetadata, len(c.State))
+	for node, metadata := range c.State {
+		state[node] = metadata
+	}
+	simulation := &Changes{Config: c.Config, State: state}
+
+	steps := []Step{}
+
+	// Each node should change its role at most a couple of times, stop
+	// if the algorithm doesn't converge.
+	for i := 0; i < 3*len(state); i++ {
+		role, candidates := simulation.Adjust(leader)
+		if role == -1 || len(candidates) == 0 {
+			break
+		}
+		node := candidates[0]
+		steps = append(steps, Step{Node: node, Role: role})
+
+		metadata := state[node]
+		delete(state, node)
+		node.Role = role
+		state[node] = metadata
+	}
+
+	return steps
+}
+
+// Return the number of nodes il the cluster.
+func (c *Changes) size() int {
+	return len(c.State)
+}
+
+// Return all nodes in the cluster, ordered by ID.
+func (c *Changes) nodes() []client.NodeInfo {
+	nodes := make([]client.NodeInfo, 0, len(c.State))
+	for node := range c.State {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// Return information about the node with the given ID, or nil if no node
+// matches.
+func (c *Changes) get(id uint64) *client.NodeInfo {
+	for node := range c.State {
+		if node.ID == id {
+			return &node
+		}
+	}
+	return nil
+}
+
+// List returns the online or offline nodes with the given role, ordered by
+// ID.
+func (c *Changes) List(role client.NodeRole, online bool) []client.NodeInfo {
+	nodes := []client.NodeInfo{}
+	for _, node := range c.nodes() {
+		if node.Role == role && c.State[node] != nil == online {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// Count returns the number of online or offline nodes with the given role.
+func (c *Changes) Count(role client.NodeRole, online bool) int {
+	return len(c.List(role, online))
+}
+
+// Check returns an error if the current state doesn't satisfy the failure
+// domain constraints of the configuration, for example because there are not
+// enough failure domains to spread voters when StrictFailureDomains is set.
+func (c *Changes) Check() error {
+	if !c.Config.StrictFailureDomains {
+		return nil
+	}
+
+	if n := c.domains(); n < c.Config.Voters {
+		return fmt.Errorf("only %d failure domains available for %d voters", n, c.Config.Voters)
+	}
+
+	voters := map[uint64]client.NodeInfo{}
+	for _, node := range c.List(client.Voter, true) {
+		domain := c.State[node].FailureDomain
+		if other, ok := voters[domain]; ok {
+			return fmt.Errorf("voters %s and %s share failure domain %d", other.Address, node.Address, domain)
+		}
+		voters[domain] = node
+	}
+
+	return nil
+}
+
+// Return the number of distinct failure domains of online nodes.
+func (c *Changes) domains() int {
+	domains := map[uint64]bool{}
+	for _, metadata := range c.State {
+		if metadata != nil {
+			domains[metadata.FailureDomain] = true
+		}
+	}
+	return len(domains)
+}
+
+// Return true if the given node can be promoted to the given role without
+// exceeding the maximum number of nodes with that role in its failure domain,
+// not counting the node being replaced, if any.
+func (c *Changes) fits(node client.NodeInfo, role client.NodeRole, replaced uint64) bool {
+	max := c.Config.MaxVotersPerDomain
+	if role == client.StandBy {
+		max = c.Config.MaxStandBysPerDomain
+	}
+	if role == client.Voter && c.Config.StrictFailureDomains && c.domains() >= c.Config.Voters {
+		if max == 0 || max > 1 {
+			max = 1
+		}
+	}
+	metadata := c.State[node]
+	if role == client.Voter && len(c.Config.VoterLabels) > 0 {
+		if metadata == nil || !matchLabels(metadata.Labels, c.Config.VoterLabels) {
+			return false
+		}
+	}
+	if max == 0 || metadata == nil {
+		return true
+	}
+	n := 0
+	for _, other := range c.List(role, true) {
+		if other.ID == node.ID || other.ID == replaced {
+			continue
+		}
+		if c.State[other].FailureDomain == metadata.FailureDomain {
+			n++
+		}
+	}
+	return n < max
+}
+
+// Return true if the given labels include all the wanted ones.
+func matchLabels(labels, want map[string]string) bool {
+	for key, value := range want {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the candidates that can be promoted to the given role.
+func (c *Changes) filter(candidates []client.NodeInfo, role client.NodeRole, replaced uint64) []client.NodeInfo {
+	filtered := []client.NodeInfo{}
+	for _, candidate := range candidates {
+		if c.fits(candidate, role, replaced) {
+			filtered = append(filtered, candidate)
+		}
+	}
+	return filtered
+}
+
+// Return a map of the failure domains associated with the
+// given nodes.
+func (c *Changes) failureDomains(nodes []client.NodeInfo) map[uint64]bool {
+	domains := map[uint64]bool{}
+	for _, node := range nodes {
+		metadata := c.State[node]
+		if metadata == nil {
+			continue
+		}
+		domains[metadata.FailureDomain] = true
+	}
+	return domains
+}
+
+// Sort the given candidates according to their failure domain and
+// weight. Candidates belonging to a failure domain different from the given
+// domains take precedence, and ties keep the given order.
+func (c *Changes) sortCandidates(candidates []client.NodeInfo, domains map[uint64]bool) {
+	less := func(i, j int) bool {
+		metadata1 := c.metadata(candidates[i])
+		metadata2 := c.metadata(candidates[j])
+
+		// If i's failure domain is not in the given list, but j's is,
+		// then i takes precedence.
+		if !domains[metadata1.FailureDomain] && domains[metadata2.FailureDomain] {
+			return true
+		}
+
+		// If j's failure domain is not in the given list, but i's is,
+		// then j takes precedence.
+		if !domains[metadata2.FailureDomain] && domains[metadata1.FailureDomain] {
+			return false
+		}
+
+		return metadata1.Weight < metadata2.Weight
+	}
+
+	sort.SliceStable(candidates, less)
+}
+
+// Return the metadata of the given node, if any.
+func (c *Changes) metadata(node client.NodeInfo) *client.NodeMetadata {
+	return c.State[node]
+}
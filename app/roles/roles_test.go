@@ -0,0 +1,415 @@
+package roles_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/app/roles"
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	voter   = client.Voter
+	standby = client.StandBy
+	spare   = client.Spare
+	none    = client.NodeRole(-1)
+)
+
+// Description of a node in a test cluster.
+type node struct {
+	id     uint64
+	role   client.NodeRole
+	online bool
+	domain uint64
+	weight uint64
+	labels map[string]string
+}
+
+func newChanges(voters, standbys int, nodes ...node) *roles.Changes {
+	return newChangesWithConfig(roles.Config{Voters: voters, StandBys: standbys}, nodes...)
+}
+
+func newChangesWithConfig(config roles.Config, nodes ...node) *roles.Changes {
+	state := map[client.NodeInfo]*client.NodeMetadata{}
+	for _, n := range nodes {
+		var metadata *client.NodeMetadata
+		if n.online {
+			metadata = &client.NodeMetadata{FailureDomain: n.domain, Weight: n.weight, Labels: n.labels}
+		}
+		state[info(n.id, n.role)] = metadata
+	}
+	return &roles.Changes{Config: config, State: state}
+}
+
+func info(id uint64, role client.NodeRole) client.NodeInfo {
+	return client.NodeInfo{ID: id, Address: fmt.Sprintf("127.0.0.1:900%d", id), Role: role}
+}
+
+func TestChanges_Assume(t *testing.T) {
+	cases := []struct {
+		title   string
+		changes *roles.Changes
+		id      uint64
+		role    client.NodeRole
+	}{{
+		"cluster too small",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: spare, online: true}),
+		2,
+		none,
+	}, {
+		"node not in cluster",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: spare, online: true}, node{id: 3, role: spare, online: true}),
+		4,
+		none,
+	}, {
+		"node already voter",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: spare, online: true}),
+		2,
+		none,
+	}, {
+		"voters shortage",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: spare, online: true}),
+		3,
+		voter,
+	}, {
+		"offline voters don't count",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter}, node{id: 4, role: spare, online: true}),
+		4,
+		voter,
+	}, {
+		"stand-bys shortage",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}, node{id: 4, role: spare, online: true}),
+		4,
+		standby,
+	}, {
+		"enough voters and stand-bys",
+		newChanges(3, 1, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}, node{id: 4, role: standby, online: true}, node{id: 5, role: spare, online: true}),
+		5,
+		none,
+	}, {
+		"failure domain full of voters",
+		newChangesWithConfig(roles.Config{Voters: 3, StandBys: 3, MaxVotersPerDomain: 1},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: spare, online: true, domain: 2}),
+		3,
+		standby,
+	}, {
+		"failure domain full of voters and stand-bys",
+		newChangesWithConfig(roles.Config{Voters: 3, StandBys: 3, MaxVotersPerDomain: 1, MaxStandBysPerDomain: 1},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: standby, online: true, domain: 2},
+			node{id: 4, role: spare, online: true, domain: 2}),
+		4,
+		none,
+	}}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			assert.Equal(t, c.role, c.changes.Assume(c.id))
+		})
+	}
+}
+
+func TestChanges_Handover(t *testing.T) {
+	cases := []struct {
+		title      string
+		changes    *roles.Changes
+		id         uint64
+		role       client.NodeRole
+		candidates []client.NodeInfo
+	}{{
+		"node not in cluster",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}),
+		2,
+		none,
+		nil,
+	}, {
+		"spare node",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: spare, online: true}),
+		2,
+		none,
+		nil,
+	}, {
+		"no online candidate",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: spare}),
+		1,
+		none,
+		nil,
+	}, {
+		"stand-bys preferred to spares",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: spare, online: true}, node{id: 3, role: standby, online: true}),
+		1,
+		voter,
+		[]client.NodeInfo{info(3, standby), info(2, spare)},
+	}, {
+		"different failure domain preferred",
+		newChanges(3, 3,
+			node{id: 1, role: standby, online: true, domain: 1},
+			node{id: 2, role: standby, online: true, domain: 1},
+			node{id: 3, role: spare, online: true, domain: 1},
+			node{id: 4, role: spare, online: true, domain: 2, weight: 5}),
+		1,
+		standby,
+		[]client.NodeInfo{info(4, spare), info(3, spare)},
+	}, {
+		"lower weight preferred",
+		newChanges(3, 3,
+			node{id: 1, role: standby, online: true},
+			node{id: 2, role: spare, online: true, weight: 2},
+			node{id: 3, role: spare, online: true, weight: 1}),
+		1,
+		standby,
+		[]client.NodeInfo{info(3, spare), info(2, spare)},
+	}, {
+		"failure domain full of voters",
+		newChangesWithConfig(roles.Config{Voters: 3, StandBys: 3, MaxVotersPerDomain: 1},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: spare, online: true, domain: 1},
+			node{id: 4, role: spare, online: true, domain: 2}),
+		1,
+		voter,
+		[]client.NodeInfo{info(3, spare)},
+	}}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			role, candidates := c.changes.Handover(c.id)
+			assert.Equal(t, c.role, role)
+			assert.Equal(t, c.candidates, candidates)
+		})
+	}
+}
+
+func TestChanges_Adjust(t *testing.T) {
+	cases := []struct {
+		title      string
+		changes    *roles.Changes
+		role       client.NodeRole
+		candidates []client.NodeInfo
+	}{{
+		"single node",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}),
+		none,
+		nil,
+	}, {
+		"cluster too small",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}),
+		spare,
+		[]client.NodeInfo{info(2, voter)},
+	}, {
+		"cluster too small with one voter",
+		newChanges(3, 3, node{id: 1, role: voter, online: true}, node{id: 2, role: spare, online: true}),
+		none,
+		nil,
+	}, {
+		"desired roles",
+		newChanges(3, 0, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}),
+		none,
+		nil,
+	}, {
+		"voters shortage",
+		newChanges(3, 0, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: spare, online: true}, node{id: 4, role: spare}),
+		voter,
+		[]client.NodeInfo{info(3, spare)},
+	}, {
+		"voters shortage with failure domains",
+		newChanges(3, 0,
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 1},
+			node{id: 3, role: voter},
+			node{id: 4, role: spare, online: true, domain: 1},
+			node{id: 5, role: spare, online: true, domain: 2}),
+		voter,
+		[]client.NodeInfo{info(5, spare), info(4, spare)},
+	}, {
+		"too many voters",
+		newChanges(3, 0, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}, node{id: 4, role: voter, online: true}),
+		spare,
+		[]client.NodeInfo{info(2, voter), info(3, voter), info(4, voter)},
+	}, {
+		"offline voter",
+		newChanges(3, 0, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}, node{id: 4, role: voter}),
+		spare,
+		[]client.NodeInfo{info(4, voter)},
+	}, {
+		"stand-bys shortage",
+		newChanges(3, 1, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}, node{id: 4, role: spare, online: true}),
+		standby,
+		[]client.NodeInfo{info(4, spare)},
+	}, {
+		"too many stand-bys",
+		newChanges(3, 1, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}, node{id: 4, role: standby, online: true}, node{id: 5, role: standby, online: true}),
+		spare,
+		[]client.NodeInfo{info(4, standby), info(5, standby)},
+	}, {
+		"offline stand-by",
+		newChanges(3, 1, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}, node{id: 4, role: standby, online: true}, node{id: 5, role: standby}),
+		spare,
+		[]client.NodeInfo{info(5, standby)},
+	}, {
+		"offline stand-by with minimum",
+		newChangesWithConfig(roles.Config{Voters: 3, StandBys: 2, MinStandBys: 2},
+			node{id: 1, role: voter, online: true},
+			node{id: 2, role: voter, online: true},
+			node{id: 3, role: voter, online: true},
+			node{id: 4, role: standby, online: true},
+			node{id: 5, role: standby}),
+		none,
+		nil,
+	}, {
+		"voters shortage with full failure domains",
+		newChangesWithConfig(roles.Config{Voters: 3, MaxVotersPerDomain: 1},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: spare, online: true, domain: 1},
+			node{id: 4, role: spare, online: true, domain: 2}),
+		none,
+		nil,
+	}, {
+		"strict failure domains",
+		newChangesWithConfig(roles.Config{Voters: 3, StrictFailureDomains: true},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: spare, online: true, domain: 1},
+			node{id: 4, role: spare, online: true, domain: 3}),
+		voter,
+		[]client.NodeInfo{info(4, spare)},
+	}, {
+		"strict failure domains without enough domains",
+		newChangesWithConfig(roles.Config{Voters: 3, StrictFailureDomains: true},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: spare, online: true, domain: 1}),
+		voter,
+		[]client.NodeInfo{info(3, spare)},
+	}, {
+		"stand-bys shortage with full failure domains",
+		newChangesWithConfig(roles.Config{Voters: 3, StandBys: 2, MaxStandBysPerDomain: 1},
+			node{id: 1, role: voter, online: true},
+			node{id: 2, role: voter, online: true},
+			node{id: 3, role: voter, online: true},
+			node{id: 4, role: standby, online: true, domain: 1},
+			node{id: 5, role: spare, online: true, domain: 1},
+			node{id: 6, role: spare, online: true, domain: 2}),
+		standby,
+		[]client.NodeInfo{info(6, spare)},
+	}, {
+		"voter labels",
+		newChangesWithConfig(roles.Config{Voters: 3, VoterLabels: map[string]string{"tier": "core"}},
+			node{id: 1, role: voter, online: true},
+			node{id: 2, role: voter, online: true},
+			node{id: 3, role: spare, online: true, labels: map[string]string{"tier": "edge"}},
+			node{id: 4, role: spare, online: true, labels: map[string]string{"tier": "core", "arch": "arm64"}},
+			node{id: 5, role: spare, online: true}),
+		voter,
+		[]client.NodeInfo{info(4, spare)},
+	}}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			role, candidates := c.changes.Adjust(1)
+			assert.Equal(t, c.role, role)
+			assert.Equal(t, c.candidates, candidates)
+		})
+	}
+}
+
+func TestChanges_Simulate(t *testing.T) {
+	cases := []struct {
+		title   string
+		changes *roles.Changes
+		steps   []roles.Step
+	}{{
+		"desired roles",
+		newChanges(3, 0, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter, online: true}),
+		[]roles.Step{},
+	}, {
+		"replace offline voter",
+		newChanges(3, 0, node{id: 1, role: voter, online: true}, node{id: 2, role: voter, online: true}, node{id: 3, role: voter}, node{id: 4, role: spare, online: true}),
+		[]roles.Step{{Node: info(4, spare), Role: voter}, {Node: info(3, voter), Role: spare}},
+	}, {
+		"bootstrap roles",
+		newChanges(3, 1, node{id: 1, role: voter, online: true}, node{id: 2, role: spare, online: true}, node{id: 3, role: spare, online: true}, node{id: 4, role: spare, online: true}),
+		[]roles.Step{{Node: info(2, spare), Role: voter}, {Node: info(3, spare), Role: voter}, {Node: info(4, spare), Role: standby}},
+	}}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			state := map[client.NodeInfo]*client.NodeMetadata{}
+			for node, metadata := range c.changes.State {
+				state[node] = metadata
+			}
+			assert.Equal(t, c.steps, c.changes.Simulate(1))
+			assert.Equal(t, state, c.changes.State)
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cases := []struct {
+		config roles.Config
+		err    string
+	}{
+		{roles.Config{Voters: 3, StandBys: 3, MinStandBys: 3, MaxVotersPerDomain: 1}, ""},
+		{roles.Config{Voters: 2}, "invalid voters 2: must be an odd number greater than 1"},
+		{roles.Config{Voters: 3, StandBys: -1}, "invalid stand-bys -1: must not be negative"},
+		{roles.Config{Voters: 3, StandBys: 1, MinStandBys: 2}, "invalid minimum stand-bys 2: must be between 0 and 1"},
+		{roles.Config{Voters: 3, MaxVotersPerDomain: -1}, "invalid maximum voters per failure domain -1: must not be negative"},
+		{roles.Config{Voters: 3, MaxStandBysPerDomain: -1}, "invalid maximum stand-bys per failure domain -1: must not be negative"},
+	}
+	for _, c := range cases {
+		err := c.config.Validate()
+		if c.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, c.err)
+		}
+	}
+}
+
+func TestChanges_Check(t *testing.T) {
+	cases := []struct {
+		title   string
+		changes *roles.Changes
+		err     string
+	}{{
+		"not strict",
+		newChanges(3, 0,
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 1},
+			node{id: 3, role: voter, online: true, domain: 1}),
+		"",
+	}, {
+		"voters in distinct domains",
+		newChangesWithConfig(roles.Config{Voters: 3, StrictFailureDomains: true},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: voter, online: true, domain: 3}),
+		"",
+	}, {
+		"not enough domains",
+		newChangesWithConfig(roles.Config{Voters: 3, StrictFailureDomains: true},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: voter, online: true, domain: 2}),
+		"only 2 failure domains available for 3 voters",
+	}, {
+		"voters sharing a domain",
+		newChangesWithConfig(roles.Config{Voters: 3, StrictFailureDomains: true},
+			node{id: 1, role: voter, online: true, domain: 1},
+			node{id: 2, role: voter, online: true, domain: 2},
+			node{id: 3, role: voter, online: true, domain: 2},
+			node{id: 4, role: spare, online: true, domain: 3}),
+		"voters 127.0.0.1:9002 and 127.0.0.1:9003 share failure domain 2",
+	}}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			err := c.changes.Check()
+			if c.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// +build cgo,!nosqlite3
+
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/cowsql/go-cowsql/client"
+	_ "github.com/mattn/go-sqlite3" // Go SQLite bindings
+)
+
+// Maximum number of rows copied in a single transaction by SeedFrom.
+const seedBatchSize = 1000
+
+// SeedFrom copies the given databases from another cluster, whose nodes are
+// listed in the given store, into this cluster.
+//
+// It's meant to initialize a brand new cluster with the data of an existing
+// one, for example for blue/green migrations or to refresh a staging
+// environment. Each database is dumped from the leader of the source cluster
+// and its schema and rows are then inserted into the database with the same
+// name in this cluster, which must not exist yet or be empty. Only the content
+// of the databases is copied: IDs, addresses and roles of the nodes of this
+// cluster are not affected.
+//
+// The source cluster is contacted using the same dial function used for this
+// cluster, so if TLS is enabled the two clusters must trust each other's
+// certificates.
+func (a *App) SeedFrom(ctx context.Context, source client.NodeStore, dbnames ...string) error {
+	cli, err := client.FindLeader(ctx, source, a.clientOptions()...)
+	if err != nil {
+		return fmt.Errorf("find source leader: %w", err)
+	}
+	defer cli.Close()
+
+	dir, err := ioutil.TempDir("", "cowsql-seed-")
+	if err != nil {
+		return fmt.Errorf("create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range dbnames {
+		if err := a.seedDatabase(ctx, cli, dir, name); err != nil {
+			return fmt.Errorf("seed database %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *App) seedDatabase(ctx context.Context, cli *client.Client, dir string, name string) error {
+	filename, err := dumpDatabase(ctx, cli, dir, name)
+	if err != nil {
+		return err
+	}
+
+	source, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		return fmt.Errorf("open dump: %w", err)
+	}
+	defer source.Close()
+
+	target, err := a.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	var n int
+	if err := target.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master").Scan(&n); err != nil {
+		return fmt.Errorf("check schema: %w", err)
+	}
+	if n > 0 {
+		return fmt.Errorf("database is not empty")
+	}
+
+	objects, err := seedSchema(ctx, source)
+	if err != nil {
+		return fmt.Errorf("read schema: %w", err)
+	}
+
+	// Create and fill the tables first, then create indexes, triggers and
+	// views, so triggers don't fire while copying.
+	for _, object := range objects {
+		if object.Type != "table" {
+			continue
+		}
+		if _, err := target.ExecContext(ctx, object.SQL); err != nil {
+			return fmt.Errorf("create table %s: %w", object.Name, err)
+		}
+		if err := seedTable(ctx, source, target, object.Name); err != nil {
+			return fmt.Errorf("copy table %s: %w", object.Name, err)
+		}
+	}
+	for _, object := range objects {
+		if object.Type == "table" {
+			continue
+		}
+		if _, err := target.ExecContext(ctx, object.SQL); err != nil {
+			return fmt.Errorf("create %s %s: %w", object.Type, object.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Schema object of a database, as stored in sqlite_master.
+type schemaObject struct {
+	Type string
+	Name string
+	SQL  string
+}
+
+// Return the user-defined schema objects of the given database, in creation
+// order.
+func seedSchema(ctx context.Context, db *sql.DB) ([]schemaObject, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT type, name, sql FROM sqlite_master
+  WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+  ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := []schemaObject{}
+	for rows.Next() {
+		object := schemaObject{}
+		if err := rows.Scan(&object.Type, &object.Name, &object.SQL); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, rows.Err()
+}
+
+// Copy all rows of the given table, in batches of seedBatchSize rows.
+func seedTable(ctx context.Context, source *sql.DB, target *sql.DB, table string) error {
+	rows, err := source.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 0", quoteName(table)))
+	if err != nil {
+		return err
+	}
+	columns, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	// Use "+column" expressions, which have no declared type, so values
+	// are returned as stored and not converted (e.g. to time.Time).
+	exprs := make([]string, len(columns))
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = quoteName(column)
+		exprs[i] = "+" + names[i]
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), quoteName(table))
+	insert := fmt.Sprintf(
+		"INSERT INTO %s(%s) VALUES(%s)", quoteName(table), strings.Join(names, ", "),
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+
+	rows, err = source.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var tx *sql.Tx
+	var stmt *sql.Stmt
+	n := 0
+	for rows.Next() {
+		if tx == nil {
+			if tx, err = target.BeginTx(ctx, nil); err != nil {
+				return err
+			}
+			if stmt, err = tx.PrepareContext(ctx, insert); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			tx.Rollback()
+			return err
+		}
+		n++
+		if n%seedBatchSize == 0 {
+			if err := tx.Commit(); err != nil {
+				return err
+			}
+			tx = nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if tx != nil {
+			tx.Rollback()
+		}
+		return err
+	}
+	if tx != nil {
+		return tx.Commit()
+	}
+
+	return nil
+}
+
+// Quote the given identifier for use in a SQL statement.
+func quoteName(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,158 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// WebhookSink is an EventSink that sends each event as a JSON object in the
+// body of an HTTP POST request.
+type WebhookSink struct {
+	URL    string       // Endpoint receiving the events.
+	Header http.Header  // Additional request headers, for example for authentication.
+	Client *http.Client // Client used to send requests, http.DefaultClient if nil.
+}
+
+// Publish implements EventSink.
+func (s *WebhookSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range s.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := s.Client
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+// NATSSink is an EventSink that publishes each event as a JSON message on a
+// NATS subject.
+//
+// A new connection is made for each event, which is fine given how rare
+// cluster events are, and the event is considered delivered only once the
+// server has acknowledged it.
+type NATSSink struct {
+	Address string          // Address of the NATS server, in host:port form.
+	Subject string          // Subject to publish the events on.
+	Token   string          // Authentication token, if the server requires one.
+	Dial    client.DialFunc // Dial function, plain TCP if nil. Can be used to enable TLS.
+}
+
+// Publish implements EventSink.
+func (s *NATSSink) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	dial := s.Dial
+	if dial == nil {
+		dial = func(ctx context.Context, address string) (net.Conn, error) {
+			dialer := net.Dialer{}
+			return dialer.DialContext(ctx, "tcp", address)
+		}
+	}
+	conn, err := dial(ctx, s.Address)
+	if err != nil {
+		return fmt.Errorf("connect to NATS server: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The server greets clients with an INFO line.
+	reader := bufio.NewReader(conn)
+	line, err := natsReadLine(reader)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected NATS greeting: %q", line)
+	}
+
+	connect, err := json.Marshal(natsConnect{Name: "cowsql", AuthToken: s.Token})
+	if err != nil {
+		return err
+	}
+
+	// Send a PING after publishing, the matching PONG confirms that the
+	// server processed the previous commands.
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "CONNECT %s\r\n", connect)
+	fmt.Fprintf(&buf, "PUB %s %d\r\n", s.Subject, len(payload))
+	buf.Write(payload)
+	buf.WriteString("\r\nPING\r\n")
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("send to NATS server: %w", err)
+	}
+
+	for {
+		line, err := natsReadLine(reader)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// Options sent by NATSSink in the CONNECT command.
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// Read a line sent by a NATS server, without the trailing CRLF.
+func natsReadLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("receive from NATS server: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Make sure the sinks implement the interface.
+var (
+	_ EventSink = &WebhookSink{}
+	_ EventSink = &NATSSink{}
+)
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// Track the skew between the clock of this node and the one of the leader.
+type clockSkew struct {
+	value     int64 // Last measured skew in nanoseconds, accessed atomically
+	threshold time.Duration
+	exceeded  bool // Whether the last measurement exceeded the threshold
+}
+
+// ClockSkew returns the last measured offset of the clock of the cluster
+// leader relative to the clock of this node, if the WithClockSkewThreshold
+// option was given. A positive value means that the leader's clock is ahead.
+//
+// It returns zero if the option was not given or no measurement was taken
+// yet.
+func (a *App) ClockSkew() time.Duration {
+	if a.skew == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&a.skew.value))
+}
+
+// Measure the clock skew against the leader the given client is connected
+// to, warning when it exceeds the configured threshold.
+func (a *App) checkClockSkew(ctx context.Context, cli *client.Client) {
+	skew, err := cli.ClockSkew(ctx)
+	if err != nil {
+		a.debug("measure clock skew: %v", err)
+		return
+	}
+	atomic.StoreInt64(&a.skew.value, int64(skew))
+
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs > a.skew.threshold:
+		if !a.skew.exceeded {
+			a.warn("clock skew with leader is %s, above threshold of %s", skew, a.skew.threshold)
+		}
+		a.skew.exceeded = true
+	case a.skew.exceeded:
+		a.info("clock skew with leader is back to %s", skew)
+		a.skew.exceeded = false
+	}
+}
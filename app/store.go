@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// UpdateStore replaces the nodes in the store of this node, which is
+// persisted in the cluster.yaml file of its data directory.
+//
+// It's the supported way to fix stale addresses, for example when all the
+// nodes in the store were removed or changed address while this node was
+// down and it can't find the leader anymore. Once the leader is found, the
+// store is periodically refreshed with the actual cluster configuration.
+func (a *App) UpdateStore(ctx context.Context, nodes []client.NodeInfo) error {
+	if err := validateStore(nodes); err != nil {
+		return err
+	}
+	if err := a.store.Set(ctx, nodes); err != nil {
+		return fmt.Errorf("update store: %w", err)
+	}
+	return nil
+}
+
+// UpdateStoreFile replaces the nodes in the cluster.yaml file of the given
+// data directory, like App.UpdateStore does.
+//
+// It can be used while the node is stopped. If the node is running, it picks
+// up the change the next time it fails to find the leader, but App.UpdateStore
+// should be preferred.
+func UpdateStoreFile(dir string, nodes []client.NodeInfo) error {
+	if err := validateStore(nodes); err != nil {
+		return err
+	}
+
+	exists, err := fileExists(dir, infoFile)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s is not the data directory of a node: no %s", dir, infoFile)
+	}
+
+	store, err := client.NewYamlNodeStore(filepath.Join(dir, storeFile))
+	if err != nil {
+		return fmt.Errorf("open %s: %w", storeFile, err)
+	}
+	if err := store.Set(context.Background(), nodes); err != nil {
+		return fmt.Errorf("update %s: %w", storeFile, err)
+	}
+
+	return nil
+}
+
+// Check that the given nodes can be used to find the leader.
+func validateStore(nodes []client.NodeInfo) error {
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes given")
+	}
+
+	addresses := map[string]bool{}
+	ids := map[uint64]bool{}
+	for _, node := range nodes {
+		if node.Address == "" {
+			return fmt.Errorf("node %d has no address", node.ID)
+		}
+		if addresses[node.Address] {
+			return fmt.Errorf("duplicate address %s", node.Address)
+		}
+		addresses[node.Address] = true
+		if node.ID == 0 {
+			continue
+		}
+		if ids[node.ID] {
+			return fmt.Errorf("duplicate ID %d", node.ID)
+		}
+		ids[node.ID] = true
+	}
+
+	return nil
+}
+
+// Load the nodes in the cluster.yaml file into the store, if the file was
+// changed by UpdateStoreFile since it was last loaded or written.
+func (a *App) reloadStore(ctx context.Context) error {
+	file, err := client.NewYamlNodeStore(filepath.Join(a.dir, storeFile))
+	if err != nil {
+		return err
+	}
+	nodes, err := file.Get(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := a.store.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 || reflect.DeepEqual(nodes, current) {
+		return nil
+	}
+
+	a.info("reload %s", storeFile)
+
+	return a.store.Set(ctx, nodes)
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateStoreFile(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	nodes := []client.NodeInfo{{ID: 1, Address: "1.2.3.4:666"}}
+
+	// Not a data directory.
+	assert.Error(t, UpdateStoreFile(dir, nodes))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, infoFile), []byte("ID: 1\n"), 0600))
+	require.NoError(t, UpdateStoreFile(dir, nodes))
+
+	store, err := client.NewYamlNodeStore(filepath.Join(dir, storeFile))
+	require.NoError(t, err)
+	stored, err := store.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, nodes, stored)
+}
+
+func TestValidateStore(t *testing.T) {
+	cases := []struct {
+		nodes []client.NodeInfo
+		err   string
+	}{
+		{nil, "no nodes given"},
+		{[]client.NodeInfo{{ID: 1}}, "node 1 has no address"},
+		{[]client.NodeInfo{{ID: 1, Address: "a"}, {ID: 2, Address: "a"}}, "duplicate address a"},
+		{[]client.NodeInfo{{ID: 1, Address: "a"}, {ID: 1, Address: "b"}}, "duplicate ID 1"},
+	}
+	for _, c := range cases {
+		assert.EqualError(t, validateStore(c.nodes), c.err)
+	}
+
+	assert.NoError(t, validateStore([]client.NodeInfo{{Address: "a"}, {Address: "b"}}))
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"sync"
+	"time"
 )
 
 // SimpleTLSConfig returns a pair of TLS configuration objects with sane
@@ -89,16 +91,18 @@ func SimpleListenTLSConfig(cert tls.Certificate, pool *x509.CertPool) *tls.Confi
 // The returned config can be used as "client" parameter for the WithTLS App
 // option, or as "config" parameter for the client.DialFuncWithTLS() helper.
 //
-// TLS connections using the same `Config` will share a ClientSessionCache.
-// You can override this behaviour by setting your own ClientSessionCache or
-// nil.
+// TLS connections using the same `Config` will share a ClientSessionCache,
+// so connections to nodes that were already contacted can resume the previous
+// session instead of performing a full handshake. You can override this
+// behaviour by setting your own ClientSessionCache or nil.
 //
 // A user can modify the returned config to suit their specifig needs.
 func SimpleDialTLSConfig(cert tls.Certificate, pool *x509.CertPool) *tls.Config {
 	config := &tls.Config{
-		MinVersion:   tls.VersionTLS12,
-		RootCAs:      pool,
-		Certificates: []tls.Certificate{cert},
+		MinVersion:         tls.VersionTLS12,
+		RootCAs:            pool,
+		Certificates:       []tls.Certificate{cert},
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
 	}
 
 	x509cert, err := x509.ParseCertificate(cert.Certificate[0])
@@ -112,3 +116,54 @@ func SimpleDialTLSConfig(cert tls.Certificate, pool *x509.CertPool) *tls.Config
 
 	return config
 }
+
+// TLSStats holds statistics about the TLS handshakes performed by the proxy
+// of an App, both for incoming and outgoing node connections.
+type TLSStats struct {
+	Handshakes    uint64            // Number of successful handshakes.
+	Resumed       uint64            // Number of handshakes that resumed a previous session.
+	Failed        uint64            // Number of failed handshakes.
+	HandshakeTime time.Duration     // Total time spent in successful handshakes.
+	CipherSuites  map[uint16]uint64 // Number of handshakes by negotiated cipher suite.
+}
+
+// Track the TLS handshakes performed by the proxy.
+type tlsStats struct {
+	mu    sync.Mutex
+	stats TLSStats
+}
+
+func newTLSStats() *tlsStats {
+	return &tlsStats{stats: TLSStats{CipherSuites: map[uint16]uint64{}}}
+}
+
+// Record the outcome of a handshake.
+func (s *tlsStats) handshake(state tls.ConnectionState, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.stats.Failed++
+		return
+	}
+
+	s.stats.Handshakes++
+	if state.DidResume {
+		s.stats.Resumed++
+	}
+	s.stats.HandshakeTime += duration
+	s.stats.CipherSuites[state.CipherSuite]++
+}
+
+func (s *tlsStats) get() TLSStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.CipherSuites = make(map[uint16]uint64, len(s.stats.CipherSuites))
+	for suite, n := range s.stats.CipherSuites {
+		stats.CipherSuites[suite] = n
+	}
+
+	return stats
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Connections to the same node resume the TLS session of the previous ones.
+func TestProxy_TLSSessionResumption(t *testing.T) {
+	cert, pool := loadTestCert(t)
+	listen, dial := SimpleTLSConfig(cert, pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	serverStats := newTLSStats()
+	go func() {
+		for {
+			remote, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			goUnix, cUnix, err := socketpair()
+			if err != nil {
+				return
+			}
+			go io.Copy(cUnix, cUnix)
+			go proxy(ctx, remote, goUnix, listen, serverStats, nil)
+		}
+	}()
+
+	clientStats := newTLSStats()
+	nodeDial := makeNodeDialFunc(ctx, dial, clientStats, &background{log: client.DefaultLogFunc}, &fdCounters{})
+
+	for i := 0; i < 2; i++ {
+		conn, err := nodeDial(ctx, listener.Addr().String())
+		require.NoError(t, err)
+
+		_, err = conn.Write([]byte("hello"))
+		require.NoError(t, err)
+		buf := make([]byte, 5)
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(buf))
+
+		conn.Close()
+	}
+
+	for _, stats := range []TLSStats{clientStats.get(), serverStats.get()} {
+		assert.Equal(t, uint64(2), stats.Handshakes)
+		assert.Equal(t, uint64(1), stats.Resumed)
+		assert.Equal(t, uint64(0), stats.Failed)
+		assert.NotZero(t, stats.HandshakeTime)
+		assert.Len(t, stats.CipherSuites, 1)
+	}
+}
+
+// Loads the test TLS certificates.
+func loadTestCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	crt := filepath.Join("testdata", "cluster.crt")
+	key := filepath.Join("testdata", "cluster.key")
+
+	keypair, err := tls.LoadX509KeyPair(crt, key)
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile(crt)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(data))
+
+	return keypair, pool
+}
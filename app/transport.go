@@ -0,0 +1,93 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Transports that can be passed to WithInternalTransport.
+const (
+	// TransportAbstract binds the local node to an abstract unix socket.
+	TransportAbstract = "abstract"
+
+	// TransportUnix binds the local node to a unix socket file.
+	TransportUnix = "unix"
+
+	// TransportTCPLoopback binds the local node to a TCP port of the
+	// loopback interface.
+	TransportTCPLoopback = "tcp-loopback"
+)
+
+// Name of the socket file used by TransportUnix, within the data directory.
+const socketFile = "cowsql.sock"
+
+// Maximum length of a unix socket path, including the terminating null
+// byte.
+const maxSocketPath = 108
+
+func validateInternalTransport(o *options) error {
+	switch o.InternalTransport {
+	case TransportAbstract:
+		return nil
+	case TransportUnix, TransportTCPLoopback:
+		if o.TLS == nil && o.Conn == nil {
+			return fmt.Errorf("internal transport %q requires TLS or an external connection", o.InternalTransport)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid internal transport %q", o.InternalTransport)
+	}
+}
+
+// Return the network and address the local node should bind to, when its
+// connections are proxied.
+func internalBindAddress(o *options, dir string, id uint64) (string, string, error) {
+	switch o.InternalTransport {
+	case TransportTCPLoopback:
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return "", "", fmt.Errorf("failed to pick loopback port: %w", err)
+		}
+		address := listener.Addr().String()
+		listener.Close()
+		return "tcp", address, nil
+
+	case TransportUnix:
+		path := o.UnixSocket
+		if path == "" {
+			path = filepath.Join(dir, socketFile)
+		}
+		if len(path) >= maxSocketPath {
+			return "", "", fmt.Errorf("unix socket path %s is too long, use WithUnixSocket", path)
+		}
+		// Remove any socket left over by a previous run.
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", "", fmt.Errorf("remove stale unix socket: %w", err)
+		}
+		return "unix", path, nil
+	}
+
+	if o.Conn != nil {
+		listener, err := net.Listen("unix", o.UnixSocket)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to autobind unix socket: %w", err)
+		}
+		address := listener.Addr().String()
+		listener.Close()
+		return "unix", address, nil
+	}
+
+	address := fmt.Sprintf("@cowsql-%d", id)
+
+	// Within a snap we need to choose a different name for the abstract unix domain
+	// socket to get it past the AppArmor confinement.
+	// See https://github.com/snapcore/snapd/blob/master/interfaces/apparmor/template.go#L357
+	snapInstanceName := os.Getenv("SNAP_INSTANCE_NAME")
+	if len(snapInstanceName) > 0 {
+		address = fmt.Sprintf("@snap.%s.cowsql-%d", snapInstanceName, id)
+	}
+
+	return "unix", address, nil
+}
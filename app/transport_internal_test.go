@@ -0,0 +1,74 @@
+package app
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateInternalTransport(t *testing.T) {
+	o := defaultOptions()
+	assert.NoError(t, validateInternalTransport(o))
+
+	o.InternalTransport = TransportTCPLoopback
+	assert.EqualError(t, validateInternalTransport(o), `internal transport "tcp-loopback" requires TLS or an external connection`)
+
+	o.TLS = &tlsSetup{Listen: &tls.Config{}, Dial: &tls.Config{}}
+	assert.NoError(t, validateInternalTransport(o))
+
+	o.InternalTransport = "foo"
+	assert.EqualError(t, validateInternalTransport(o), `invalid internal transport "foo"`)
+}
+
+func TestInternalBindAddress_Abstract(t *testing.T) {
+	o := defaultOptions()
+
+	network, address, err := internalBindAddress(o, "", 123)
+	require.NoError(t, err)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "@cowsql-123", address)
+}
+
+func TestInternalBindAddress_Unix(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, socketFile)
+	require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+
+	o := defaultOptions()
+	o.InternalTransport = TransportUnix
+
+	network, address, err := internalBindAddress(o, dir, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, path, address)
+
+	// The stale socket file was removed.
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	o.UnixSocket = "/" + strings.Repeat("x", maxSocketPath)
+	_, _, err = internalBindAddress(o, dir, 1)
+	assert.Error(t, err)
+}
+
+func TestInternalBindAddress_TCPLoopback(t *testing.T) {
+	o := defaultOptions()
+	o.InternalTransport = TransportTCPLoopback
+
+	network, address, err := internalBindAddress(o, "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", network)
+
+	host, _, err := net.SplitHostPort(address)
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+}
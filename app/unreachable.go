@@ -0,0 +1,194 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// ErrNotReady is returned by ReadyState while the node is still performing
+// its startup tasks.
+var ErrNotReady = errors.New("node not ready")
+
+// ErrClusterUnreachable is matched by the error returned by ReadyState when
+// no node in the store could be reached as leader at startup. The error is
+// a *ClusterUnreachableError holding the details.
+var ErrClusterUnreachable = errors.New("cluster unreachable")
+
+// ClusterUnreachableError holds the reason why each of the nodes in the store
+// could not be used to find the leader.
+type ClusterUnreachableError struct {
+	Errors map[string]error // Errors by node address.
+}
+
+func (e *ClusterUnreachableError) Error() string {
+	addresses := make([]string, 0, len(e.Errors))
+	for address := range e.Errors {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	details := make([]string, len(addresses))
+	for i, address := range addresses {
+		details[i] = fmt.Sprintf("%s: %v", address, e.Errors[address])
+	}
+
+	return fmt.Sprintf("%v: %s", ErrClusterUnreachable, strings.Join(details, "; "))
+}
+
+// Is makes errors.Is() match ErrClusterUnreachable.
+func (e *ClusterUnreachableError) Is(target error) bool {
+	return target == ErrClusterUnreachable
+}
+
+// DiscoveryFunc returns the addresses of cluster nodes, for example by
+// querying DNS or a service registry.
+type DiscoveryFunc func(ctx context.Context) ([]string, error)
+
+// How long to search for the leader at startup before diagnosing why it
+// can't be found.
+var startupLeaderTimeout = 10 * time.Second
+
+// How long to wait for each node when diagnosing why the leader can't be
+// found.
+var probeTimeout = 5 * time.Second
+
+// ReadyState returns nil if the node has completed its startup tasks (see
+// Ready). Otherwise it returns ErrNotReady, or a *ClusterUnreachableError if
+// none of the nodes in the store could be reached as leader during the last
+// attempt, for example because they were all removed or are down.
+func (a *App) ReadyState() error {
+	a.readyMu.Lock()
+	defer a.readyMu.Unlock()
+	return a.readyErr
+}
+
+func (a *App) setReadyState(err error) {
+	a.readyMu.Lock()
+	defer a.readyMu.Unlock()
+	a.readyErr = err
+}
+
+// Find the leader during startup. If it can't be found within a reasonable
+// time, record the reason why in the ready state and fall back to the
+// discovery function, if any, to get new nodes to try.
+func (a *App) startupLeader(ctx context.Context) (*client.Client, error) {
+	leaderCtx, cancel := context.WithTimeout(ctx, startupLeaderTimeout)
+	cli, err := a.Leader(leaderCtx)
+	cancel()
+	if err == nil || ctx.Err() != nil {
+		return cli, err
+	}
+
+	// The store might have been fixed with UpdateStoreFile.
+	if err := a.reloadStore(ctx); err != nil {
+		a.warn("reload store: %v", err)
+	}
+
+	unreachable, err := a.diagnoseLeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.setReadyState(unreachable)
+	a.warn("%v", unreachable)
+
+	if a.discovery != nil {
+		if err := a.discover(ctx); err != nil {
+			a.warn("discover nodes: %v", err)
+		}
+	}
+
+	return nil, unreachable
+}
+
+// Try each node in the store once, collecting the reason why it can't be
+// used to reach the leader.
+func (a *App) diagnoseLeader(ctx context.Context) (*ClusterUnreachableError, error) {
+	nodes, err := a.store.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get nodes from store: %w", err)
+	}
+
+	// Probe all nodes concurrently, so a large store with many unreachable
+	// nodes doesn't take probeTimeout per node.
+	var (
+		mtx sync.Mutex
+		wg  sync.WaitGroup
+	)
+	unreachable := &ClusterUnreachableError{Errors: map[string]error{}}
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(address string) {
+			defer wg.Done()
+			defer a.background.recover("probe leader")
+			err := a.probeLeader(ctx, address)
+			mtx.Lock()
+			unreachable.Errors[address] = err
+			mtx.Unlock()
+		}(node.Address)
+	}
+	wg.Wait()
+
+	return unreachable, nil
+}
+
+func (a *App) probeLeader(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	cli, err := client.New(ctx, address, a.clientOptions()...)
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	leader, err := cli.Leader(ctx)
+	if err != nil {
+		return err
+	}
+	if leader == nil {
+		return fmt.Errorf("no known leader")
+	}
+
+	return fmt.Errorf("leader %s not reachable", leader.Address)
+}
+
+// Add the nodes returned by the discovery function to the store.
+func (a *App) discover(ctx context.Context) error {
+	addresses, err := a.discovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := a.store.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("get nodes from store: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, node := range nodes {
+		known[node.Address] = true
+	}
+	added := 0
+	for _, address := range addresses {
+		if known[address] || address == a.address {
+			continue
+		}
+		known[address] = true
+		nodes = append(nodes, client.NodeInfo{Address: address, Role: client.Voter})
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+
+	a.info("discovered %d new nodes", added)
+
+	return a.store.Set(ctx, nodes)
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterUnreachableError(t *testing.T) {
+	var err error = &ClusterUnreachableError{Errors: map[string]error{
+		"2.2.2.2:666": fmt.Errorf("no known leader"),
+		"1.1.1.1:666": fmt.Errorf("connection refused"),
+	}}
+
+	assert.True(t, errors.Is(err, ErrClusterUnreachable))
+	assert.EqualError(t, err,
+		"cluster unreachable: 1.1.1.1:666: connection refused; 2.2.2.2:666: no known leader")
+}
+
+func TestDiscover(t *testing.T) {
+	store := client.NewInmemNodeStore()
+	ctx := context.Background()
+	require.NoError(t, store.Set(ctx, []client.NodeInfo{{ID: 1, Address: "1.1.1.1:666"}}))
+
+	a := &App{
+		address: "3.3.3.3:666",
+		store:   store,
+		log:     defaultLogFunc,
+		discovery: func(ctx context.Context) ([]string, error) {
+			return []string{"1.1.1.1:666", "2.2.2.2:666", "3.3.3.3:666"}, nil
+		},
+	}
+	require.NoError(t, a.discover(ctx))
+
+	nodes, err := store.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []client.NodeInfo{
+		{ID: 1, Address: "1.1.1.1:666"},
+		{Address: "2.2.2.2:666", Role: client.Voter},
+	}, nodes)
+}
+
+func TestDiagnoseLeader_Concurrent(t *testing.T) {
+	defer func(timeout time.Duration) { probeTimeout = timeout }(probeTimeout)
+	probeTimeout = 100 * time.Millisecond
+
+	store := client.NewInmemNodeStore()
+	ctx := context.Background()
+	nodes := []client.NodeInfo{}
+	for i := 1; i <= 10; i++ {
+		nodes = append(nodes, client.NodeInfo{ID: uint64(i), Address: fmt.Sprintf("1.1.1.%d:666", i)})
+	}
+	require.NoError(t, store.Set(ctx, nodes))
+
+	a := &App{
+		store:      store,
+		log:        defaultLogFunc,
+		background: &background{log: defaultLogFunc},
+		// Never connect, so each probe takes the full timeout.
+		dialFunc: func(ctx context.Context, address string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	start := time.Now()
+	unreachable, err := a.diagnoseLeader(ctx)
+	require.NoError(t, err)
+	assert.Len(t, unreachable.Errors, 10)
+	assert.True(t, time.Since(start) < 5*probeTimeout)
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cowsql/go-cowsql"
+	"github.com/cowsql/go-cowsql/client"
+	"gopkg.in/yaml.v2"
+)
+
+// ValidateDir checks the data directory of an application node without
+// starting it, and returns the problems that would make New fail or that
+// New would repair, if created with WithAutoRepair.
+//
+// The given options should be the ones the node is going to be started with,
+// since some checks depend on them, for example WithAddress and WithCluster.
+// Problems that can't be repaired automatically come with a Hint suggesting
+// how to fix them by hand.
+func ValidateDir(dir string, options ...Option) ([]Problem, error) {
+	o := defaultOptions()
+	for _, option := range options {
+		option(o)
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		if os.IsNotExist(err) {
+			return []Problem{{
+				File:   ".",
+				Reason: "data directory does not exist",
+				Hint:   "create it, or check the path",
+			}}, nil
+		}
+		return nil, err
+	}
+
+	problems, err := inspectDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	infoFileExists, err := fileExists(dir, infoFile)
+	if err != nil {
+		return nil, err
+	}
+	storeFileExists, err := fileExists(dir, storeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	info := client.NodeInfo{}
+	if infoFileExists {
+		problems = append(problems, validateInfoFile(dir, o.Address, &info)...)
+	} else if storeFileExists {
+		problems = append(problems, Problem{
+			File:   infoFile,
+			Reason: "missing, but cluster.yaml exists",
+			Hint:   "restore info.yaml from a backup, or remove cluster.yaml if the node never started",
+		})
+	}
+
+	if storeFileExists {
+		problems = append(problems, validateStoreFile(dir, info)...)
+	} else if len(o.Cluster) == 0 && infoFileExists && info.ID != cowsql.BootstrapID {
+		problems = append(problems, Problem{
+			File:   storeFile,
+			Reason: "missing, and no cluster addresses given",
+			Hint:   "pass the addresses of existing nodes with WithCluster, or restore cluster.yaml from a backup",
+		})
+	}
+
+	return problems, nil
+}
+
+// Check the content of info.yaml, filling the given node info with it.
+func validateInfoFile(dir string, address string, info *client.NodeInfo) []Problem {
+	problem := func(reason, hint string) []Problem {
+		return []Problem{{File: infoFile, Reason: reason, Hint: hint}}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, infoFile))
+	if err != nil {
+		return problem(fmt.Sprintf("can't be read: %v", err), "check the permissions of the file")
+	}
+	if err := yaml.Unmarshal(data, info); err != nil {
+		return problem(fmt.Sprintf("can't be parsed: %v", err), "fix the YAML syntax, or restore the file from a backup")
+	}
+
+	switch {
+	case info.ID == 0:
+		return problem("node ID is missing", "restore the file from a backup, the ID can't be changed")
+	case info.Address == "":
+		return problem("node address is missing", "set the Address field to the address the node listens on")
+	case address != "" && address != info.Address:
+		return problem(
+			fmt.Sprintf("address %q does not match %q", info.Address, address),
+			"start the node with the address in info.yaml, or update info.yaml and cluster.yaml on all nodes if the address changed")
+	}
+
+	return nil
+}
+
+// Check the content of cluster.yaml, and its consistency with the given node
+// info.
+func validateStoreFile(dir string, info client.NodeInfo) []Problem {
+	problem := func(reason string) []Problem {
+		return []Problem{{
+			File:   storeFile,
+			Reason: reason,
+			Hint:   "fix the list of nodes with 'cowsql-app store set'",
+		}}
+	}
+
+	store, err := client.NewYamlNodeStore(filepath.Join(dir, storeFile))
+	if err != nil {
+		return problem(fmt.Sprintf("can't be parsed: %v", err))
+	}
+	nodes, err := store.Get(context.Background())
+	if err != nil {
+		return problem(fmt.Sprintf("can't be read: %v", err))
+	}
+	if err := validateStore(nodes); err != nil {
+		return problem(err.Error())
+	}
+
+	for _, node := range nodes {
+		if info.ID != 0 && node.ID == info.ID && node.Address != info.Address {
+			return problem(fmt.Sprintf("node %d has address %q, but info.yaml has %q", node.ID, node.Address, info.Address))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowsql/go-cowsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDir(t *testing.T) {
+	cases := []struct {
+		title   string
+		files   map[string]string
+		options []Option
+		reasons []string
+	}{{
+		"brand new node",
+		map[string]string{},
+		nil,
+		[]string{},
+	}, {
+		"valid node",
+		map[string]string{
+			infoFile:  "ID: 2\nAddress: 1.2.3.4:9000\n",
+			storeFile: "- ID: 2\n  Address: 1.2.3.4:9000\n  Role: 0\n",
+		},
+		[]Option{WithAddress("1.2.3.4:9000")},
+		[]string{},
+	}, {
+		"address mismatch",
+		map[string]string{
+			infoFile:  "ID: 2\nAddress: 1.2.3.4:9000\n",
+			storeFile: "- ID: 2\n  Address: 1.2.3.5:9000\n  Role: 0\n",
+		},
+		[]Option{WithAddress("1.2.3.4:9001")},
+		[]string{
+			`info.yaml: address "1.2.3.4:9000" does not match "1.2.3.4:9001"`,
+			`cluster.yaml: node 2 has address "1.2.3.5:9000", but info.yaml has "1.2.3.4:9000"`,
+		},
+	}, {
+		"missing info file",
+		map[string]string{
+			storeFile: "- ID: 2\n  Address: 1.2.3.4:9000\n  Role: 0\n",
+		},
+		nil,
+		[]string{"info.yaml: missing, but cluster.yaml exists"},
+	}, {
+		"missing store file",
+		map[string]string{
+			infoFile: "ID: 2\nAddress: 1.2.3.4:9000\n",
+		},
+		nil,
+		[]string{"cluster.yaml: missing, and no cluster addresses given"},
+	}, {
+		"bootstrap node joining",
+		map[string]string{
+			infoFile:  fmt.Sprintf("ID: %d\nAddress: 1.2.3.4:9000\n", uint64(cowsql.BootstrapID)),
+			storeFile: "- ID: 2\n  Address: 1.2.3.4:9000\n  Role: 0\n- ID: 3\n  Address: 1.2.3.4:9000\n  Role: 0\n",
+			joinFile:  "",
+		},
+		nil,
+		[]string{
+			"join: bootstrap node can't join a cluster",
+			"cluster.yaml: duplicate address 1.2.3.4:9000",
+		},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			dir := newDir(t)
+			defer os.RemoveAll(dir)
+
+			for name, content := range c.files {
+				require.NoError(t, fileWrite(dir, name, []byte(content)))
+			}
+
+			problems, err := ValidateDir(dir, c.options...)
+			require.NoError(t, err)
+
+			reasons := []string{}
+			for _, problem := range problems {
+				reasons = append(reasons, problem.String())
+				assert.True(t, problem.Repair != "" || problem.Hint != "", problem.String())
+			}
+			assert.Equal(t, c.reasons, reasons)
+		})
+	}
+
+	problems, err := ValidateDir(filepath.Join(os.TempDir(), "does-not-exist"))
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "data directory does not exist", problems[0].Reason)
+}
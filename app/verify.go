@@ -0,0 +1,421 @@
+// +build cgo,!nosqlite3
+
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// DumpReport describes the outcome of checking a database dump.
+type DumpReport struct {
+	// Problems reported by SQLite's integrity_check, empty if none.
+	Integrity []string
+
+	// Number of rows in each table of the checked database.
+	Rows map[string]int64
+
+	// Tables whose number of rows does not match the reference database,
+	// or that exist in only one of the two.
+	Mismatches []string
+}
+
+// OK returns true if no integrity problem and no mismatch was found.
+func (r *DumpReport) OK() bool {
+	return len(r.Integrity) == 0 && len(r.Mismatches) == 0
+}
+
+// VerifyDump checks that the given dump files of the database with the given
+// name form a sound SQLite database.
+//
+// The dump is written to a temporary directory and opened with an embedded
+// SQLite, which runs integrity_check on it and counts the rows of each
+// table. If source is not nil, the counts are compared against the ones of
+// the tables in source, typically the live database the dump was taken
+// from. Writes committed to source after the dump was taken show up as
+// mismatches.
+//
+// An error is returned only if the check itself could not be performed:
+// problems found in the dump are reported in the returned DumpReport.
+func VerifyDump(ctx context.Context, dbname string, files []client.File, source *sql.DB) (*DumpReport, error) {
+	db, cleanup, err := openDump(dbname, files)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	report := &DumpReport{}
+
+	report.Integrity, err = integrityCheck(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Rows, err = countRows(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("count dump rows: %w", err)
+	}
+
+	if source != nil {
+		rows, err := countRows(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("count source rows: %w", err)
+		}
+		report.Mismatches = compareRows(report.Rows, rows, "dump", "source")
+	}
+
+	return report, nil
+}
+
+// VerifyBackup is like VerifyDump, but compares the given dump against the
+// current content of the database with the given name in this cluster.
+func (a *App) VerifyBackup(ctx context.Context, dbname string, files []client.File) (*DumpReport, error) {
+	db, err := a.Open(ctx, dbname)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return VerifyDump(ctx, dbname, files, db)
+}
+
+// Counter used to give each rehearsal node a unique address.
+var rehearsals uint64
+
+// RestoreRehearsal restores the given dump files of the database with the
+// given name into a temporary single-node cluster, as a way to gain
+// confidence that a backup is actually restorable.
+//
+// The node is bootstrapped in a temporary directory, listening on an
+// abstract unix socket, and the schema and rows of the dump are loaded into
+// it using regular SQL statements. The restored database is then checked with
+// integrity_check and its row counts are compared against the ones of the
+// dump. The node and its directory are removed before returning.
+//
+// The given options are used to create the temporary node, for example to
+// set a log function.
+func RestoreRehearsal(ctx context.Context, dbname string, files []client.File, options ...Option) (*DumpReport, error) {
+	src, cleanup, err := openDump(dbname, files)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "cowsql-rehearsal-")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	address := fmt.Sprintf("@cowsql-rehearsal-%d-%d", os.Getpid(), atomic.AddUint64(&rehearsals, 1))
+	options = append([]Option{WithAddress(address)}, options...)
+
+	app, err := New(dir, options...)
+	if err != nil {
+		return nil, fmt.Errorf("start rehearsal node: %w", err)
+	}
+	defer app.Close()
+
+	if err := app.Ready(ctx); err != nil {
+		return nil, fmt.Errorf("rehearsal node not ready: %w", err)
+	}
+
+	db, err := app.Open(ctx, dbname)
+	if err != nil {
+		return nil, fmt.Errorf("open rehearsal database: %w", err)
+	}
+	defer db.Close()
+
+	if err := loadDump(ctx, db, src); err != nil {
+		return nil, fmt.Errorf("restore dump: %w", err)
+	}
+
+	report := &DumpReport{}
+
+	report.Integrity, err = integrityCheck(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Rows, err = countRows(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("count restored rows: %w", err)
+	}
+
+	rows, err := countRows(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("count dump rows: %w", err)
+	}
+	report.Mismatches = compareRows(report.Rows, rows, "restored database", "dump")
+
+	return report, nil
+}
+
+// Write the given dump files into a temporary directory and open the main
+// database file with SQLite. The returned function closes the database and
+// removes the directory.
+func openDump(dbname string, files []client.File) (*sql.DB, func(), error) {
+	dir, err := ioutil.TempDir("", "cowsql-verify-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("create temporary directory: %w", err)
+	}
+
+	filename, err := writeDump(dir, dbname, files)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+
+	db, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("open dump: %w", err)
+	}
+
+	cleanup := func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+
+	return db, cleanup, nil
+}
+
+// Run integrity_check against the given database, returning the problems it
+// reports, if any.
+func integrityCheck(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, fmt.Errorf("integrity check: %w", err)
+		}
+		if line != "ok" {
+			problems = append(problems, line)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("integrity check: %w", err)
+	}
+
+	return problems, nil
+}
+
+// Return the names of the user tables in the given database.
+func tableNames(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// Count the rows of each user table in the given database.
+func countRows(ctx context.Context, db *sql.DB) (map[string]int64, error) {
+	names, err := tableNames(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(names))
+	for _, name := range names {
+		var n int64
+		query := fmt.Sprintf("SELECT count(*) FROM %s", quoteIdentifier(name))
+		if err := db.QueryRowContext(ctx, query).Scan(&n); err != nil {
+			return nil, fmt.Errorf("count %s: %w", name, err)
+		}
+		counts[name] = n
+	}
+
+	return counts, nil
+}
+
+// Compare two sets of row counts, returning a sorted description of the
+// tables that differ.
+func compareRows(got, want map[string]int64, gotName, wantName string) []string {
+	var mismatches []string
+	for name, n := range got {
+		m, ok := want[name]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("table %s missing from %s", name, wantName))
+			continue
+		}
+		if n != m {
+			mismatches = append(mismatches, fmt.Sprintf("table %s has %d rows in %s and %d in %s", name, n, gotName, m, wantName))
+		}
+	}
+	for name := range want {
+		if _, ok := got[name]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("table %s missing from %s", name, gotName))
+		}
+	}
+	sort.Strings(mismatches)
+
+	return mismatches
+}
+
+// Number of rows inserted in a single transaction when loading a dump.
+const loadBatchSize = 1000
+
+// Load the schema and rows of the src database into dst.
+//
+// Tables are created first and filled, then indexes, triggers and views are
+// created, so they don't slow down or interfere with the inserts.
+func loadDump(ctx context.Context, dst *sql.DB, src *sql.DB) error {
+	rows, err := src.QueryContext(ctx, `
+SELECT type, name, sql FROM sqlite_master
+ WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+ ORDER BY CASE type WHEN 'table' THEN 0 ELSE 1 END, rowid`)
+	if err != nil {
+		return fmt.Errorf("read schema: %w", err)
+	}
+	type object struct{ kind, name, sql string }
+	var objects []object
+	for rows.Next() {
+		var o object
+		if err := rows.Scan(&o.kind, &o.name, &o.sql); err != nil {
+			rows.Close()
+			return fmt.Errorf("read schema: %w", err)
+		}
+		objects = append(objects, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read schema: %w", err)
+	}
+
+	for _, o := range objects {
+		if o.kind == "table" {
+			if _, err := dst.ExecContext(ctx, o.sql); err != nil {
+				return fmt.Errorf("create table %s: %w", o.name, err)
+			}
+		}
+	}
+
+	for _, o := range objects {
+		if o.kind == "table" && !strings.HasPrefix(strings.ToUpper(o.sql), "CREATE VIRTUAL") {
+			if err := copyRows(ctx, dst, src, o.name); err != nil {
+				return fmt.Errorf("copy table %s: %w", o.name, err)
+			}
+		}
+	}
+
+	// Preserve AUTOINCREMENT counters, which may be ahead of the rows.
+	var n int
+	err = src.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE name = 'sqlite_sequence'").Scan(&n)
+	if err != nil {
+		return fmt.Errorf("read schema: %w", err)
+	}
+	if n > 0 {
+		if _, err := dst.ExecContext(ctx, "DELETE FROM sqlite_sequence"); err != nil {
+			return fmt.Errorf("reset sqlite_sequence: %w", err)
+		}
+		if err := copyRows(ctx, dst, src, "sqlite_sequence"); err != nil {
+			return fmt.Errorf("copy sqlite_sequence: %w", err)
+		}
+	}
+
+	for _, o := range objects {
+		if o.kind != "table" {
+			if _, err := dst.ExecContext(ctx, o.sql); err != nil {
+				return fmt.Errorf("create %s %s: %w", o.kind, o.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Copy all rows of the given table from src to dst, in batches of
+// loadBatchSize rows per transaction.
+func copyRows(ctx context.Context, dst *sql.DB, src *sql.DB, table string) error {
+	rows, err := src.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s", quoteIdentifier(table)))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	insert := fmt.Sprintf("INSERT INTO %s VALUES (%s)", quoteIdentifier(table), placeholders)
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	var tx *sql.Tx
+	var pending int
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		if tx == nil {
+			if tx, err = dst.BeginTx(ctx, nil); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, insert, values...); err != nil {
+			return err
+		}
+		pending++
+		if pending == loadBatchSize {
+			err := tx.Commit()
+			tx, pending = nil, 0
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if tx != nil {
+		err := tx.Commit()
+		tx = nil
+		return err
+	}
+
+	return nil
+}
+
+// Quote the given SQL identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,139 @@
+// +build cgo,!nosqlite3
+
+package app
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyDump(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	source := newSQLite(t, filepath.Join(dir, "test.db"),
+		"CREATE TABLE foo(n INT)",
+		"INSERT INTO foo(n) VALUES(1), (2)",
+		"CREATE TABLE bar(s TEXT)",
+	)
+	defer source.Close()
+
+	files := sqliteDump(t, source, filepath.Join(dir, "test.db"))
+
+	report, err := VerifyDump(context.Background(), "test.db", files, source)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, map[string]int64{"foo": 2, "bar": 0}, report.Rows)
+
+	_, err = source.Exec("INSERT INTO bar(s) VALUES('x')")
+	require.NoError(t, err)
+	_, err = source.Exec("CREATE TABLE egg(n INT)")
+	require.NoError(t, err)
+
+	report, err = VerifyDump(context.Background(), "test.db", files, source)
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Empty(t, report.Integrity)
+	assert.Equal(t, []string{
+		"table bar has 0 rows in dump and 1 in source",
+		"table egg missing from dump",
+	}, report.Mismatches)
+}
+
+// Without a source only the integrity of the dump is checked.
+func TestVerifyDump_NoSource(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	source := newSQLite(t, filepath.Join(dir, "test.db"), "CREATE TABLE foo(n INT)")
+	defer source.Close()
+
+	files := sqliteDump(t, source, filepath.Join(dir, "test.db"))
+
+	report, err := VerifyDump(context.Background(), "test.db", files, nil)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, map[string]int64{"foo": 0}, report.Rows)
+}
+
+// A dump that is not a database can't be verified.
+func TestVerifyDump_Garbage(t *testing.T) {
+	files := []client.File{{Name: "test.db", Data: []byte("garbage garbage garbage")}}
+
+	_, err := VerifyDump(context.Background(), "test.db", files, nil)
+	assert.Error(t, err)
+}
+
+func TestLoadDump(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	src := newSQLite(t, filepath.Join(dir, "src.db"),
+		"CREATE TABLE foo(id INTEGER PRIMARY KEY AUTOINCREMENT, s TEXT, b BLOB)",
+		"INSERT INTO foo(s, b) VALUES('a', x'00ff'), (NULL, NULL)",
+		"UPDATE sqlite_sequence SET seq = 10 WHERE name = 'foo'",
+		"CREATE INDEX foo_s ON foo(s)",
+		"CREATE VIEW foo_view AS SELECT s FROM foo",
+		`CREATE TABLE "odd ""name"" "(n INT)`,
+		`INSERT INTO "odd ""name"" "(n) VALUES(1)`,
+	)
+	defer src.Close()
+
+	dst := newSQLite(t, filepath.Join(dir, "dst.db"))
+	defer dst.Close()
+
+	require.NoError(t, loadDump(context.Background(), dst, src))
+
+	rows, err := countRows(context.Background(), dst)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"foo": 2, `odd "name" `: 1}, rows)
+
+	var b []byte
+	require.NoError(t, dst.QueryRow("SELECT b FROM foo WHERE s = 'a'").Scan(&b))
+	assert.Equal(t, []byte{0x00, 0xff}, b)
+
+	var seq int
+	require.NoError(t, dst.QueryRow("SELECT seq FROM sqlite_sequence WHERE name = 'foo'").Scan(&seq))
+	assert.Equal(t, 10, seq)
+
+	var n int
+	require.NoError(t, dst.QueryRow("SELECT count(*) FROM sqlite_master WHERE name IN ('foo_s', 'foo_view')").Scan(&n))
+	assert.Equal(t, 2, n)
+}
+
+// Create a plain SQLite database at the given path and run the given
+// statements against it.
+func newSQLite(t *testing.T, path string, stmts ...string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	for _, stmt := range stmts {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err, stmt)
+	}
+
+	return db
+}
+
+// Return the content of the given SQLite database in the same shape as a
+// cluster dump.
+func sqliteDump(t *testing.T, db *sql.DB, path string) []client.File {
+	t.Helper()
+
+	_, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+
+	return []client.File{{Name: filepath.Base(path), Data: data}}
+}
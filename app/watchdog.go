@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// HealthCheck checks the health of the local node, returning an error if it's
+// degraded. See WithHandoverWatchdog.
+type HealthCheck func(ctx context.Context, app *App) error
+
+// CheckDisk is a HealthCheck that fails if a small file can't be written and
+// synced in the data directory of the node.
+func CheckDisk(ctx context.Context, app *App) error {
+	path := filepath.Join(app.dir, ".health")
+	if err := ioutil.WriteFile(path, []byte("ok"), 0600); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	defer os.Remove(path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// CheckLocalNode is a HealthCheck that fails if the local node doesn't answer
+// requests.
+func CheckLocalNode(ctx context.Context, app *App) error {
+	cli, err := app.Client(ctx)
+	if err != nil {
+		return fmt.Errorf("connect to local node: %w", err)
+	}
+	defer cli.Close()
+
+	if _, err := cli.Leader(ctx); err != nil {
+		return fmt.Errorf("query local node: %w", err)
+	}
+
+	return nil
+}
+
+// Run health checks periodically, handing over the role of the node when
+// they fail persistently.
+type watchdog struct {
+	interval time.Duration
+	failures int
+	checks   []HealthCheck
+	done     chan struct{} // Closed when the watch loop returns
+}
+
+func newWatchdog(interval time.Duration, failures int, checks []HealthCheck) *watchdog {
+	if len(checks) == 0 {
+		checks = []HealthCheck{CheckDisk, CheckLocalNode}
+	}
+	return &watchdog{
+		interval: interval,
+		failures: failures,
+		checks:   checks,
+		done:     make(chan struct{}),
+	}
+}
+
+// Run all checks, returning the first error.
+func (w *watchdog) check(ctx context.Context, app *App) error {
+	ctx, cancel := context.WithTimeout(ctx, w.interval)
+	defer cancel()
+
+	for _, check := range w.checks {
+		if err := check(ctx, app); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check the health of the node every interval until the given context is
+// done.
+func (a *App) watch(ctx context.Context, w *watchdog) {
+	defer close(w.done)
+
+	failed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.clock.After(w.interval):
+		}
+
+		err := w.check(ctx, a)
+		if err == nil {
+			failed = 0
+			continue
+		}
+		failed++
+		a.warn("health check failed (%d/%d): %v", failed, w.failures, err)
+		if failed < w.failures {
+			continue
+		}
+		failed = 0
+
+		a.error("node degraded, handing over: %v", err)
+		if err := a.Handover(ctx); err != nil {
+			a.error("handover: %v", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDisk(t *testing.T) {
+	dir := newDir(t)
+	defer os.RemoveAll(dir)
+
+	app := &App{dir: dir}
+	assert.NoError(t, CheckDisk(context.Background(), app))
+
+	// The probe file is removed.
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	app.dir = "/non/existing"
+	assert.Error(t, CheckDisk(context.Background(), app))
+}
+
+func TestWatchdog_Check(t *testing.T) {
+	calls := []string{}
+	check := func(name string, err error) HealthCheck {
+		return func(ctx context.Context, app *App) error {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	w := newWatchdog(time.Second, 3, []HealthCheck{
+		check("ok", nil),
+		check("failing", fmt.Errorf("boom")),
+		check("skipped", nil),
+	})
+
+	assert.EqualError(t, w.check(context.Background(), &App{}), "boom")
+	assert.Equal(t, []string{"ok", "failing"}, calls)
+
+	// The default checks are used if none is given.
+	assert.Len(t, newWatchdog(time.Second, 3, nil).checks, 2)
+}
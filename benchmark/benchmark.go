@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/cowsql/go-cowsql/app"
@@ -18,14 +19,18 @@ const (
 )
 
 type Benchmark struct {
-	app     *app.App
-	db      *sql.DB
-	dir     string
-	options *options
-	workers []*worker
+	app      *app.App
+	db       *sql.DB
+	dir      string
+	options  *options
+	workers  []*worker
+	memory   *memoryTracker
+	failover *failoverTracker
+	trace    []traceEntry
+	traceCh  chan traceEntry
 }
 
-func createWorkers(o *options) []*worker {
+func createWorkers(o *options, traceCh chan traceEntry) []*worker {
 	workers := make([]*worker, o.nWorkers)
 	for i := 0; i < o.nWorkers; i++ {
 		switch o.workload {
@@ -33,6 +38,8 @@ func createWorkers(o *options) []*worker {
 			workers[i] = newWorker(kvWriter, o)
 		case kvReadWrite:
 			workers[i] = newWorker(kvReaderWriter, o)
+		case replay:
+			workers[i] = newTraceWorker(traceCh, o)
 		}
 	}
 	return workers
@@ -49,15 +56,99 @@ func New(app *app.App, db *sql.DB, dir string, options ...Option) (bm *Benchmark
 		db:      db,
 		dir:     dir,
 		options: o,
-		workers: createWorkers(o),
+	}
+
+	if o.workload == replay {
+		if o.trace == "" {
+			return nil, fmt.Errorf("no trace file given for the replay workload")
+		}
+		bm.trace, err = loadTrace(o.trace)
+		if err != nil {
+			return nil, err
+		}
+		bm.traceCh = make(chan traceEntry)
+	}
+
+	bm.workers = createWorkers(o, bm.traceCh)
+
+	if o.memoryInterval > 0 {
+		bm.memory = newMemoryTracker()
+	}
+
+	if o.failoverAt > 0 {
+		trigger := o.failover
+		if trigger == nil {
+			trigger = transferLeadership(app.Leader)
+		}
+		bm.failover = &failoverTracker{trigger: trigger}
 	}
 
 	return bm, nil
 }
 
-func (bm *Benchmark) runWorkload(ctx context.Context) {
-	for _, worker := range bm.workers {
-		go worker.run(ctx, bm.db)
+// Start the workers, returning a channel that gets closed once they are all
+// done.
+func (bm *Benchmark) runWorkload(ctx context.Context) <-chan struct{} {
+	if bm.memory != nil {
+		go bm.memory.run(ctx, bm.options.memoryInterval)
+	}
+	if bm.traceCh != nil {
+		go replayTrace(ctx, bm.trace, bm.traceCh, bm.options.maxSpeed)
+	}
+
+	start := time.Now()
+	for _, w := range bm.workers {
+		w.tracker.exclude(start.Add(bm.options.warmup))
+	}
+	if bm.options.progress > 0 {
+		go bm.reportProgress(ctx, start)
+	}
+	if bm.failover != nil {
+		go bm.failover.run(ctx, bm.options.failoverAt)
+	}
+
+	wg := sync.WaitGroup{}
+	for _, w := range bm.workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			w.run(ctx, bm.db)
+		}(w)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	return done
+}
+
+// Print the number of queries measured so far every progress interval, until
+// the given context is done.
+func (bm *Benchmark) reportProgress(ctx context.Context, start time.Time) {
+	ticker := time.NewTicker(bm.options.progress)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(start)
+			if elapsed < bm.options.warmup {
+				fmt.Printf("[%4ds] warming up\n", int(elapsed.Seconds()))
+				continue
+			}
+			n, nErr := 0, 0
+			for _, w := range bm.workers {
+				wn, wErr := w.tracker.count()
+				n += wn
+				nErr += wErr
+			}
+			steady := (elapsed - bm.options.warmup).Seconds()
+			fmt.Printf("[%4ds] %d queries (%d errors), %.1f queries/s\n", int(elapsed.Seconds()), n, nErr, float64(n)/steady)
+		}
 	}
 }
 
@@ -68,7 +159,9 @@ func (bm *Benchmark) kvSetup() error {
 
 func (bm *Benchmark) setup() error {
 	switch bm.options.workload {
-
+	case replay:
+		// The trace is expected to create the schema it needs.
+		return nil
 	default:
 		return bm.kvSetup()
 	}
@@ -81,13 +174,23 @@ func reportName(id int, work work) string {
 // Returns a map of filename to filecontent
 func (bm *Benchmark) reportFiles() map[string]string {
 	allReports := make(map[string]string)
+	n := 0
 	for i, worker := range bm.workers {
 		reports := worker.report()
 		for w, report := range reports {
 			file := reportName(i, w)
 			allReports[file] = fmt.Sprintf("%s", report)
+			n += report.n
 		}
 	}
+	if bm.memory != nil {
+		file := fmt.Sprintf("memory-%d", time.Now().Unix())
+		allReports[file] = fmt.Sprintf("%s", bm.memory.report(n))
+	}
+	if results := bm.Results(); results.Failover != nil {
+		file := fmt.Sprintf("failover-%d", time.Now().Unix())
+		allReports[file] = fmt.Sprintf("%s", results.Failover)
+	}
 	return allReports
 }
 
@@ -116,7 +219,7 @@ func (bm *Benchmark) reportResults() error {
 func (bm *Benchmark) nodeOnline(node *client.NodeInfo) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
-	cli, err := client.New(ctx, node.Address)
+	cli, err := client.New(ctx, node.Address, client.WithDialFunc(bm.options.dialFunc))
 	if err != nil {
 		return false
 	}
@@ -182,14 +285,16 @@ func (bm *Benchmark) Run(ch <-chan os.Signal) error {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), bm.options.duration)
+	ctx, cancel := context.WithTimeout(context.Background(), bm.options.warmup+bm.options.duration)
 	defer cancel()
 
-	bm.runWorkload(ctx)
+	done := bm.runWorkload(ctx)
 
 	select {
 	case <-ctx.Done():
 		break
+	case <-done:
+		break
 	case <-ch:
 		cancel()
 		break
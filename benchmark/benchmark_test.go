@@ -5,11 +5,13 @@ import (
 	"database/sql"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/cowsql/go-cowsql/app"
 	"github.com/cowsql/go-cowsql/benchmark"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -65,6 +67,52 @@ func TestNew_Default(t *testing.T) {
 	require.NoError(t, err)
 
 	bmRun(t, bm, app, db)
+
+	exec := bm.Results().Query("exec")
+	assert.NotZero(t, exec.Count)
+	assert.True(t, exec.P99 >= exec.P50)
+}
+
+// Create a Benchmark with a warm-up period and progress output.
+func TestNew_Warmup(t *testing.T) {
+	dir, app, db, cleanup := bmSetup(t, addr1, nil)
+	defer cleanup()
+
+	bm, err := benchmark.New(
+		app,
+		db,
+		dir,
+		benchmark.WithCluster([]string{addr1}),
+		benchmark.WithWarmup(1),
+		benchmark.WithProgressInterval(1),
+		benchmark.WithDuration(1))
+	require.NoError(t, err)
+
+	bmRun(t, bm, app, db)
+
+	assert.NotZero(t, bm.Results().Query("exec").Count)
+}
+
+// Trigger a failover during the benchmark. With a single node there's no
+// other voter to transfer leadership to.
+func TestNew_Failover(t *testing.T) {
+	dir, app, db, cleanup := bmSetup(t, addr1, nil)
+	defer cleanup()
+
+	bm, err := benchmark.New(
+		app,
+		db,
+		dir,
+		benchmark.WithCluster([]string{addr1}),
+		benchmark.WithFailoverAt(1),
+		benchmark.WithDuration(2))
+	require.NoError(t, err)
+
+	bmRun(t, bm, app, db)
+
+	failover := bm.Results().Failover
+	require.NotNil(t, failover)
+	assert.EqualError(t, failover.Err, "no other voter to transfer leadership to")
 }
 
 // Create a Benchmark with a kvReadWriteWorkload.
@@ -84,6 +132,74 @@ func TestNew_KvReadWrite(t *testing.T) {
 	bmRun(t, bm, app, db)
 }
 
+// Create a Benchmark that samples memory usage.
+func TestNew_MemoryInterval(t *testing.T) {
+	dir, app, db, cleanup := bmSetup(t, addr1, nil)
+	defer cleanup()
+
+	bm, err := benchmark.New(
+		app,
+		db,
+		dir,
+		benchmark.WithCluster([]string{addr1}),
+		benchmark.WithDuration(1),
+		benchmark.WithMemoryInterval(100))
+	require.NoError(t, err)
+
+	bmRun(t, bm, app, db)
+
+	files, err := filepath.Glob(filepath.Join(dir, "results", "memory-*"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := ioutil.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "max rss [B]")
+}
+
+// Create a Benchmark replaying a SQL trace.
+func TestNew_Replay(t *testing.T) {
+	dir, app, db, cleanup := bmSetup(t, addr1, nil)
+	defer cleanup()
+
+	trace := filepath.Join(dir, "trace.jsonl")
+	lines := `{"timestamp": 0, "statement": "CREATE TABLE test (n INT, s TEXT)"}
+{"timestamp": 1000000, "statement": "INSERT INTO test(n, s) VALUES(?, ?)", "params": [1, "one"]}
+{"timestamp": 2000000, "statement": "INSERT INTO test(n, s) VALUES(?, ?)", "params": [2, null]}
+{"timestamp": 3000000, "statement": "SELECT n, s FROM test WHERE n > ?", "params": [0]}
+`
+	require.NoError(t, ioutil.WriteFile(trace, []byte(lines), 0644))
+
+	bm, err := benchmark.New(
+		app,
+		db,
+		dir,
+		benchmark.WithCluster([]string{addr1}),
+		benchmark.WithDuration(5),
+		benchmark.WithWorkload("replay"),
+		benchmark.WithTrace(trace))
+	require.NoError(t, err)
+
+	defer db.Close()
+	defer app.Close()
+	require.NoError(t, bm.Run(make(chan os.Signal)))
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM test").Scan(&n))
+	assert.Equal(t, 2, n)
+}
+
+// Create a replay Benchmark without a trace file.
+func TestNew_ReplayNoTrace(t *testing.T) {
+	dir, app, db, cleanup := bmSetup(t, addr1, nil)
+	defer cleanup()
+	defer db.Close()
+	defer app.Close()
+
+	_, err := benchmark.New(app, db, dir, benchmark.WithWorkload("replay"))
+	assert.EqualError(t, err, "no trace file given for the replay workload")
+}
+
 // Create a clustered Benchmark.
 func TestNew_ClusteredKvReadWrite(t *testing.T) {
 	dir, app, db, cleanup := bmSetup(t, addr1, nil)
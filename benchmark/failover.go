@@ -0,0 +1,140 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// FailoverFunc triggers a failover of the cluster under benchmark, for
+// example by killing the process of the leader node. See WithFailoverFunc.
+type FailoverFunc func(ctx context.Context) error
+
+// FailoverResults describes the impact of the failover triggered during a
+// benchmark, see WithFailoverAt.
+type FailoverResults struct {
+	At     time.Time     // When the failover was triggered.
+	Err    error         // Error returned by the failover function, if any.
+	Gap    time.Duration // Time until a query started after At succeeded, or -1 if none did.
+	Errors int           // Number of queries that failed during the gap.
+}
+
+func (r FailoverResults) String() string {
+	err := "none"
+	if r.Err != nil {
+		err = r.Err.Error()
+	}
+	return fmt.Sprintf("at [timestamp in ns] %d\n"+
+		"error %s\n"+
+		"gap [ms] %s\n"+
+		"errors %d\n",
+		r.At.UnixNano(), err, durToMs(r.Gap), r.Errors)
+}
+
+// Trigger a failover and record when it happened.
+type failoverTracker struct {
+	lock    sync.Mutex
+	trigger FailoverFunc
+	at      time.Time
+	err     error
+}
+
+// Trigger the failover once the given delay has elapsed, unless the given
+// context is done first.
+func (f *failoverTracker) run(ctx context.Context, delay time.Duration) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(delay):
+	}
+
+	at := time.Now()
+	err := f.trigger(ctx)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.at = at
+	f.err = err
+}
+
+// Compute the availability gap out of the measurements of the given
+// trackers. Return false if the failover was not triggered.
+func (f *failoverTracker) results(trackers []*tracker) (FailoverResults, bool) {
+	f.lock.Lock()
+	at, err := f.at, f.err
+	f.lock.Unlock()
+
+	if at.IsZero() {
+		return FailoverResults{}, false
+	}
+
+	results := FailoverResults{At: at, Err: err, Gap: -1}
+	var recovered time.Time
+	for _, t := range trackers {
+		t.lock.RLock()
+		for _, measurements := range t.measurements {
+			for _, m := range measurements {
+				if m.start.Before(at) {
+					continue
+				}
+				end := m.start.Add(m.duration)
+				if recovered.IsZero() || end.Before(recovered) {
+					recovered = end
+				}
+			}
+		}
+		t.lock.RUnlock()
+	}
+	if !recovered.IsZero() {
+		results.Gap = recovered.Sub(at)
+	}
+
+	for _, t := range trackers {
+		t.lock.RLock()
+		for _, errors := range t.errors {
+			for _, e := range errors {
+				if e.start.Before(at) || (!recovered.IsZero() && e.start.After(recovered)) {
+					continue
+				}
+				results.Errors++
+			}
+		}
+		t.lock.RUnlock()
+	}
+
+	return results, true
+}
+
+// Return a failover function transferring leadership from the current leader
+// to another voter.
+func transferLeadership(leader func(context.Context) (*client.Client, error)) FailoverFunc {
+	return func(ctx context.Context) error {
+		cli, err := leader(ctx)
+		if err != nil {
+			return fmt.Errorf("find leader: %w", err)
+		}
+		defer cli.Close()
+
+		info, err := cli.Leader(ctx)
+		if err != nil {
+			return fmt.Errorf("get leader: %w", err)
+		}
+		nodes, err := cli.Cluster(ctx)
+		if err != nil {
+			return fmt.Errorf("get cluster: %w", err)
+		}
+		for _, node := range nodes {
+			if node.ID == info.ID || node.Role != client.Voter {
+				continue
+			}
+			if err := cli.Transfer(ctx, node.ID); err != nil {
+				return fmt.Errorf("transfer leadership to %s: %w", node.Address, err)
+			}
+			return nil
+		}
+		return fmt.Errorf("no other voter to transfer leadership to")
+	}
+}
@@ -0,0 +1,61 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverTracker_Results(t *testing.T) {
+	triggered := make(chan struct{})
+	failover := &failoverTracker{trigger: func(ctx context.Context) error {
+		close(triggered)
+		return nil
+	}}
+
+	_, ok := failover.results(nil)
+	assert.False(t, ok)
+
+	failover.run(context.Background(), 0)
+	<-triggered
+
+	at := failover.at
+	boom := errors.New("boom")
+	tr := newTracker()
+	tr.measurements[exec] = []measurement{
+		{at.Add(-time.Second), 2 * time.Second},                // Started before the failover
+		{at.Add(300 * time.Millisecond), time.Second},          // First to succeed after it
+		{at.Add(500 * time.Millisecond), 5 * time.Millisecond}, // First to complete after it
+	}
+	tr.errors[exec] = []measurementErr{
+		{at.Add(-time.Millisecond), boom},
+		{at.Add(100 * time.Millisecond), boom},
+		{at.Add(200 * time.Millisecond), boom},
+		{at.Add(time.Second), boom},
+	}
+
+	results, ok := failover.results([]*tracker{tr})
+	require.True(t, ok)
+	assert.NoError(t, results.Err)
+	assert.Equal(t, 505*time.Millisecond, results.Gap)
+	assert.Equal(t, 2, results.Errors)
+}
+
+// The failover is not triggered if the benchmark ends first.
+func TestFailoverTracker_Canceled(t *testing.T) {
+	failover := &failoverTracker{trigger: func(ctx context.Context) error {
+		t.Fatal("failover triggered")
+		return nil
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	failover.run(ctx, time.Hour)
+
+	_, ok := failover.results(nil)
+	assert.False(t, ok)
+}
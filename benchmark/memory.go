@@ -0,0 +1,172 @@
+package benchmark
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A point-in-time snapshot of the memory used by the process. Since the
+// cowsql engine runs inside the benchmark process, the RSS accounts for both
+// the Go side and the C side of the node.
+type memorySample struct {
+	time       time.Time
+	heapAlloc  uint64 // Bytes of allocated heap objects
+	heapInuse  uint64 // Bytes in in-use heap spans
+	totalAlloc uint64 // Cumulative bytes allocated for heap objects
+	mallocs    uint64 // Cumulative count of heap objects allocated
+	numGC      uint32 // Number of completed GC cycles
+	rss        uint64 // Resident set size in bytes, 0 if not available
+}
+
+func (s memorySample) String() string {
+	return fmt.Sprintf("%v %d %d %d %d %d %d",
+		s.time.UnixNano(), s.heapAlloc, s.heapInuse, s.totalAlloc, s.mallocs, s.numGC, s.rss)
+}
+
+func sampleMemory() memorySample {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return memorySample{
+		time:       time.Now(),
+		heapAlloc:  stats.HeapAlloc,
+		heapInuse:  stats.HeapInuse,
+		totalAlloc: stats.TotalAlloc,
+		mallocs:    stats.Mallocs,
+		numGC:      stats.NumGC,
+		rss:        readRSS(),
+	}
+}
+
+// Return the resident set size of the process as reported by /proc, or 0 if
+// it can't be determined.
+func readRSS() uint64 {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[0] != "VmRSS:" || fields[2] != "kB" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+
+	return 0
+}
+
+// Periodically samples the memory usage of the process while a benchmark
+// runs.
+type memoryTracker struct {
+	lock    sync.Mutex
+	samples []memorySample
+}
+
+type memoryReport struct {
+	n          int // Number of operations performed during the run
+	allocBytes uint64
+	allocs     uint64
+	numGC      uint32
+	maxHeap    uint64
+	maxRSS     uint64
+	samples    []memorySample
+}
+
+func (r memoryReport) String() string {
+	var ssb strings.Builder
+	for _, s := range r.samples {
+		fmt.Fprintf(&ssb, "%s\n", s)
+	}
+
+	var bytesPerOp, allocsPerOp uint64
+	if r.n > 0 {
+		bytesPerOp = r.allocBytes / uint64(r.n)
+		allocsPerOp = r.allocs / uint64(r.n)
+	}
+
+	return fmt.Sprintf("n %d\n"+
+		"alloc [B] %d\n"+
+		"allocs %d\n"+
+		"alloc/op [B] %d\n"+
+		"allocs/op %d\n"+
+		"gc %d\n"+
+		"max heap [B] %d\n"+
+		"max rss [B] %d\n"+
+		"samples [timestamp in ns] [heap alloc B] [heap inuse B] [total alloc B] [mallocs] [gc] [rss B]\n%s\n",
+		r.n, r.allocBytes, r.allocs, bytesPerOp, allocsPerOp, r.numGC,
+		r.maxHeap, r.maxRSS, ssb.String())
+}
+
+func (t *memoryTracker) sample() {
+	s := sampleMemory()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.samples = append(t.samples, s)
+}
+
+// Take a sample every interval until the context is done.
+func (t *memoryTracker) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	t.sample()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.sample()
+		}
+	}
+}
+
+// Build a report out of the samples taken so far, plus a final one. The given
+// number of operations is used to compute per-operation figures.
+func (t *memoryTracker) report(n int) memoryReport {
+	t.sample()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	first := t.samples[0]
+	last := t.samples[len(t.samples)-1]
+	report := memoryReport{
+		n:          n,
+		allocBytes: last.totalAlloc - first.totalAlloc,
+		allocs:     last.mallocs - first.mallocs,
+		numGC:      last.numGC - first.numGC,
+		samples:    t.samples,
+	}
+
+	for _, s := range t.samples {
+		if s.heapAlloc > report.maxHeap {
+			report.maxHeap = s.heapAlloc
+		}
+		if s.rss > report.maxRSS {
+			report.maxRSS = s.rss
+		}
+	}
+
+	return report
+}
+
+func newMemoryTracker() *memoryTracker {
+	return &memoryTracker{
+		lock:    sync.Mutex{},
+		samples: []memorySample{},
+	}
+}
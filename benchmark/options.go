@@ -3,6 +3,8 @@ package benchmark
 import (
 	"strings"
 	"time"
+
+	"github.com/cowsql/go-cowsql/client"
 )
 
 type workload int32
@@ -10,6 +12,7 @@ type workload int32
 const (
 	kvWrite     workload = iota
 	kvReadWrite workload = iota
+	replay      workload = iota
 )
 
 type Option func(*options)
@@ -21,6 +24,14 @@ type options struct {
 	nWorkers       int
 	kvKeySizeB     int
 	kvValueSizeB   int
+	memoryInterval time.Duration
+	dialFunc       client.DialFunc
+	trace          string
+	maxSpeed       bool
+	warmup         time.Duration
+	progress       time.Duration
+	failoverAt     time.Duration
+	failover       FailoverFunc
 }
 
 func parseWorkload(workload string) workload {
@@ -29,6 +40,8 @@ func parseWorkload(workload string) workload {
 		return kvWrite
 	case "kvreadwrite":
 		return kvReadWrite
+	case "replay":
+		return replay
 	default:
 		return kvWrite
 	}
@@ -41,6 +54,23 @@ func WithWorkload(workload string) Option {
 	}
 }
 
+// WithTrace sets the path of the SQL trace file to run with the "replay"
+// workload. The replay stops when the trace is over or when the benchmark
+// duration has elapsed, whichever comes first.
+func WithTrace(path string) Option {
+	return func(options *options) {
+		options.trace = path
+	}
+}
+
+// WithMaxSpeed makes the "replay" workload execute the statements of the trace
+// as fast as possible, instead of preserving their relative timing.
+func WithMaxSpeed(maxSpeed bool) Option {
+	return func(options *options) {
+		options.maxSpeed = maxSpeed
+	}
+}
+
 // WithDuration sets the duration of the benchmark.
 func WithDuration(seconds int) Option {
 	return func(options *options) {
@@ -48,6 +78,43 @@ func WithDuration(seconds int) Option {
 	}
 }
 
+// WithWarmup sets how long the workload runs before the benchmark duration
+// starts. Queries started during the warm-up are not included in the
+// results, so they reflect the steady state instead of the cluster formation
+// and cache warm-up.
+func WithWarmup(seconds int) Option {
+	return func(options *options) {
+		options.warmup = time.Duration(seconds) * time.Second
+	}
+}
+
+// WithProgressInterval sets how often the progress of the benchmark is
+// printed while it runs. A value of 0 disables progress output.
+func WithProgressInterval(seconds int) Option {
+	return func(options *options) {
+		options.progress = time.Duration(seconds) * time.Second
+	}
+}
+
+// WithFailoverAt makes the benchmark trigger a failover once the given number
+// of seconds have elapsed since the workload started, and record how long the
+// cluster was unavailable. By default leadership is transferred to another
+// voter, see WithFailoverFunc. A value of 0 disables the failover.
+func WithFailoverAt(seconds int) Option {
+	return func(options *options) {
+		options.failoverAt = time.Duration(seconds) * time.Second
+	}
+}
+
+// WithFailoverFunc sets the function triggering the failover requested with
+// WithFailoverAt, for example to kill the process of the leader node instead
+// of transferring leadership.
+func WithFailoverFunc(failover FailoverFunc) Option {
+	return func(options *options) {
+		options.failover = failover
+	}
+}
+
 // WithWorkers sets the number of workers of the benchmark.
 func WithWorkers(n int) Option {
 	return func(options *options) {
@@ -85,13 +152,32 @@ func WithClusterTimeout(cTo int) Option {
 	}
 }
 
+// WithMemoryInterval sets how often the memory usage of the process is sampled
+// while the benchmark runs. A value of 0 disables memory tracking.
+func WithMemoryInterval(ms int) Option {
+	return func(options *options) {
+		options.memoryInterval = time.Duration(ms) * time.Millisecond
+	}
+}
+
+// WithDialFunc sets the dial function used to check whether the nodes of the
+// cluster are online. It must be set when targeting a cluster using TLS or
+// external connections.
+func WithDialFunc(dial client.DialFunc) Option {
+	return func(options *options) {
+		options.dialFunc = dial
+	}
+}
+
 func defaultOptions() *options {
 	return &options{
 		cluster:        nil,
 		clusterTimeout: time.Minute,
+		dialFunc:       client.DefaultDialFunc,
 		duration:       time.Minute,
 		kvKeySizeB:     32,
 		kvValueSizeB:   1024,
+		memoryInterval: time.Second,
 		nWorkers:       1,
 		workload:       kvWrite,
 	}
@@ -0,0 +1,121 @@
+package benchmark
+
+import (
+	"sort"
+	"time"
+)
+
+// Results holds the measurements taken by the workers of a benchmark, as
+// returned by Benchmark.Results.
+type Results struct {
+	Workers  []WorkerResults
+	Failover *FailoverResults // Impact of the failover, if one was triggered.
+}
+
+// WorkerResults holds the measurements taken by a single worker, by query
+// type ("exec" for writes, "query" for reads).
+type WorkerResults struct {
+	Worker  int
+	Queries map[string]QueryResults
+}
+
+// QueryResults summarizes the measurements of a type of query.
+type QueryResults struct {
+	Count     int             // Number of successful queries.
+	Errors    int             // Number of failed queries.
+	Total     time.Duration   // Total latency of the successful queries.
+	Avg       time.Duration   // Average latency.
+	Min       time.Duration   // Minimum latency.
+	Max       time.Duration   // Maximum latency.
+	P50       time.Duration   // Median latency.
+	P95       time.Duration   // 95th percentile of the latency.
+	P99       time.Duration   // 99th percentile of the latency.
+	Latencies []time.Duration // Latency of each successful query, in order.
+}
+
+// Query returns the results of the given type of query, aggregated over all
+// workers.
+func (r Results) Query(kind string) QueryResults {
+	latencies := []time.Duration{}
+	errors := 0
+	for _, worker := range r.Workers {
+		results := worker.Queries[kind]
+		latencies = append(latencies, results.Latencies...)
+		errors += results.Errors
+	}
+	return newQueryResults(latencies, errors)
+}
+
+// Results returns the measurements taken so far by the workers, typically
+// after Run returns. It's meant for test suites and performance gates, which
+// can check latencies without parsing the result files.
+func (bm *Benchmark) Results() Results {
+	results := Results{Workers: make([]WorkerResults, len(bm.workers))}
+	trackers := make([]*tracker, len(bm.workers))
+	for i, worker := range bm.workers {
+		results.Workers[i] = worker.tracker.results(i)
+		trackers[i] = worker.tracker
+	}
+	if bm.failover != nil {
+		if failover, ok := bm.failover.results(trackers); ok {
+			results.Failover = &failover
+		}
+	}
+	return results
+}
+
+func (t *tracker) results(id int) WorkerResults {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	results := WorkerResults{Worker: id, Queries: map[string]QueryResults{}}
+	works := map[work]bool{}
+	for w := range t.measurements {
+		works[w] = true
+	}
+	for w := range t.errors {
+		works[w] = true
+	}
+	for w := range works {
+		latencies := make([]time.Duration, len(t.measurements[w]))
+		for i, m := range t.measurements[w] {
+			latencies[i] = m.duration
+		}
+		results.Queries[w.String()] = newQueryResults(latencies, len(t.errors[w]))
+	}
+
+	return results
+}
+
+func newQueryResults(latencies []time.Duration, errors int) QueryResults {
+	results := QueryResults{Count: len(latencies), Errors: errors, Latencies: latencies}
+	if len(latencies) == 0 {
+		return results
+	}
+
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, latency := range sorted {
+		results.Total += latency
+	}
+	results.Avg = results.Total / time.Duration(len(sorted))
+	results.Min = sorted[0]
+	results.Max = sorted[len(sorted)-1]
+	results.P50 = percentile(sorted, 50)
+	results.P95 = percentile(sorted, 95)
+	results.P99 = percentile(sorted, 99)
+
+	return results
+}
+
+// Return the given percentile of the given sorted latencies, using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,44 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker_Results(t *testing.T) {
+	tracker := newTracker()
+	start := time.Now()
+	for i := 1; i <= 100; i++ {
+		tracker.measurements[exec] = append(tracker.measurements[exec], measurement{start, time.Duration(i) * time.Millisecond})
+	}
+	tracker.errors[query] = append(tracker.errors[query], measurementErr{start, errors.New("boom")})
+
+	w := &worker{tracker: tracker}
+	bm := &Benchmark{workers: []*worker{w, w}}
+	results := bm.Results()
+
+	assert.Len(t, results.Workers, 2)
+	assert.Equal(t, 1, results.Workers[1].Worker)
+
+	writes := results.Workers[0].Queries["exec"]
+	assert.Equal(t, 100, writes.Count)
+	assert.Equal(t, 0, writes.Errors)
+	assert.Equal(t, time.Millisecond, writes.Min)
+	assert.Equal(t, 100*time.Millisecond, writes.Max)
+	assert.Equal(t, 50500*time.Microsecond, writes.Avg)
+	assert.Equal(t, 50*time.Millisecond, writes.P50)
+	assert.Equal(t, 95*time.Millisecond, writes.P95)
+	assert.Equal(t, 99*time.Millisecond, writes.P99)
+
+	reads := results.Workers[0].Queries["query"]
+	assert.Equal(t, 0, reads.Count)
+	assert.Equal(t, 1, reads.Errors)
+
+	total := results.Query("exec")
+	assert.Equal(t, 200, total.Count)
+	assert.Equal(t, 50*time.Millisecond, total.P50)
+	assert.Equal(t, 2, results.Query("query").Errors)
+}
@@ -0,0 +1,137 @@
+package benchmark
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// A single statement of a captured SQL trace.
+//
+// Trace files hold one JSON object per line, for example:
+//
+//	{"timestamp": 1690000000000000000, "statement": "INSERT INTO t(n) VALUES(?)", "params": [1]}
+//
+// The timestamp is in nanoseconds and only the difference between timestamps
+// matters, since it's used to reproduce the pacing of the original workload.
+type traceEntry struct {
+	Timestamp int64         `json:"timestamp"`
+	Statement string        `json:"statement"`
+	Params    []interface{} `json:"params"`
+}
+
+// Load the entries of the trace file at the given path.
+func loadTrace(path string) ([]traceEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace %v: %v", path, err)
+	}
+	defer f.Close()
+
+	entries := []traceEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseTraceEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trace %v at line %d: %v", path, n, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace %v: %v", path, err)
+	}
+
+	return entries, nil
+}
+
+func parseTraceEntry(line string) (traceEntry, error) {
+	var entry traceEntry
+
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&entry); err != nil {
+		return entry, err
+	}
+	if strings.TrimSpace(entry.Statement) == "" {
+		return entry, fmt.Errorf("empty statement")
+	}
+
+	// Integers would otherwise be decoded as float64.
+	for i, param := range entry.Params {
+		number, ok := param.(json.Number)
+		if !ok {
+			continue
+		}
+		if n, err := number.Int64(); err == nil {
+			entry.Params[i] = n
+		} else if f, err := number.Float64(); err == nil {
+			entry.Params[i] = f
+		} else {
+			return entry, fmt.Errorf("invalid number parameter %q", number)
+		}
+	}
+
+	return entry, nil
+}
+
+// Return the type of work needed to execute the given statement.
+func traceWork(statement string) work {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return none
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "WITH", "PRAGMA", "EXPLAIN", "VALUES":
+		return query
+	default:
+		return exec
+	}
+}
+
+// Feed the trace entries to the replaying workers, closing the channel when
+// the trace is over or the context is done.
+//
+// Unless maxSpeed is true, the relative timing of the entries is preserved.
+// Entries are handed out as soon as a worker is available, so if the workers
+// can't keep up the replay falls behind the original pacing.
+func replayTrace(ctx context.Context, entries []traceEntry, ch chan<- traceEntry, maxSpeed bool) {
+	defer close(ch)
+
+	if len(entries) == 0 {
+		return
+	}
+
+	start := time.Now()
+	first := entries[0].Timestamp
+
+	for _, entry := range entries {
+		if !maxSpeed {
+			offset := time.Duration(entry.Timestamp - first)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case ch <- entry:
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package benchmark
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceEntry(t *testing.T) {
+	line := `{"timestamp": 1000, "statement": "INSERT INTO t(n, f, s) VALUES(?, ?, ?)", "params": [1, 1.5, "one"]}`
+	entry, err := parseTraceEntry(line)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1000), entry.Timestamp)
+	assert.Equal(t, "INSERT INTO t(n, f, s) VALUES(?, ?, ?)", entry.Statement)
+	assert.Equal(t, []interface{}{int64(1), 1.5, "one"}, entry.Params)
+}
+
+func TestParseTraceEntry_Error(t *testing.T) {
+	cases := []struct {
+		title string
+		line  string
+		err   string
+	}{
+		{
+			"invalid JSON",
+			`{"timestamp": 0, "statement": `,
+			"unexpected EOF",
+		},
+		{
+			"missing statement",
+			`{"timestamp": 0}`,
+			"empty statement",
+		},
+		{
+			"empty statement",
+			`{"timestamp": 0, "statement": ""}`,
+			"empty statement",
+		},
+		{
+			"whitespace statement",
+			`{"timestamp": 0, "statement": " \t\n"}`,
+			"empty statement",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			_, err := parseTraceEntry(c.line)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
@@ -36,6 +36,7 @@ type tracker struct {
 	lock         sync.RWMutex
 	measurements map[work][]measurement
 	errors       map[work][]measurementErr
+	since        time.Time // Queries started before are not measured
 }
 
 type report struct {
@@ -75,6 +76,9 @@ func (r report) String() string {
 func (t *tracker) measure(start time.Time, work work, err *error) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
+	if start.Before(t.since) {
+		return
+	}
 	duration := time.Since(start)
 	if *err == nil {
 		m := measurement{start, duration}
@@ -120,6 +124,27 @@ func (t *tracker) report() map[work]report {
 	return reports
 }
 
+// Discard the queries started before the given time.
+func (t *tracker) exclude(until time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.since = until
+}
+
+// Return the number of queries measured so far, and how many of them failed.
+func (t *tracker) count() (int, int) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	n, nErr := 0, 0
+	for _, measurements := range t.measurements {
+		n += len(measurements)
+	}
+	for _, errors := range t.errors {
+		nErr += len(errors)
+	}
+	return n + nErr, nErr
+}
+
 func newTracker() *tracker {
 	return &tracker{
 		lock:         sync.RWMutex{},
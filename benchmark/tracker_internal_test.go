@@ -0,0 +1,28 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Queries started during the warm-up are not measured.
+func TestTracker_Exclude(t *testing.T) {
+	tracker := newTracker()
+	now := time.Now()
+	tracker.exclude(now)
+
+	var err error
+	tracker.measure(now.Add(-time.Second), exec, &err)
+	tracker.measure(now, exec, &err)
+
+	err = errors.New("boom")
+	tracker.measure(now.Add(-time.Second), query, &err)
+	tracker.measure(now.Add(time.Millisecond), query, &err)
+
+	n, nErr := tracker.count()
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 1, nErr)
+}
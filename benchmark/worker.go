@@ -35,6 +35,7 @@ const (
 	kvWriter       workerType = iota
 	kvReader       workerType = iota
 	kvReaderWriter workerType = iota
+	traceReplayer  workerType = iota
 
 	kvReadSql  = "SELECT value FROM model WHERE key = ?"
 	kvWriteSql = "INSERT OR REPLACE INTO model(key, value) VALUES(?, ?)"
@@ -44,7 +45,8 @@ const (
 // in order to do that. `lastWork` and `lastArgs` refer to the previously
 // executed operation and can be used to determine the next work the worker
 // should perform. `kvKeys` tells the worker which keys it has inserted in the
-// database.
+// database. `trace` is where a trace replayer receives the statements to
+// execute.
 type worker struct {
 	workerType   workerType
 	lastWork     work
@@ -53,6 +55,7 @@ type worker struct {
 	kvKeySizeB   int
 	kvValueSizeB int
 	kvKeys       []string
+	trace        <-chan traceEntry
 }
 
 // Thanks to https://stackoverflow.com/a/22892986
@@ -97,6 +100,12 @@ func (w *worker) getWork() (work, string, []interface{}) {
 		}
 		k, v := w.randNewKey(), w.randValue()
 		return exec, kvWriteSql, []interface{}{k, v}
+	case traceReplayer:
+		entry, ok := <-w.trace
+		if !ok {
+			return none, "", []interface{}{}
+		}
+		return traceWork(entry.Statement), entry.Statement, entry.Params
 	default:
 		return none, "", []interface{}{}
 	}
@@ -111,6 +120,11 @@ func (w *worker) doWork(ctx context.Context, db *sql.DB) {
 	w.lastWork = work
 	w.lastArgs = args
 
+	if w.workerType == traceReplayer {
+		w.doTraceWork(ctx, db, work, q, args)
+		return
+	}
+
 	switch work {
 	case exec:
 		w.kvKeys = append(w.kvKeys, fmt.Sprintf("%v", (args[0])))
@@ -127,6 +141,29 @@ func (w *worker) doWork(ctx context.Context, db *sql.DB) {
 	}
 }
 
+// Execute a statement of a trace, draining all rows it returns.
+func (w *worker) doTraceWork(ctx context.Context, db *sql.DB, work work, q string, args []interface{}) {
+	var err error
+
+	switch work {
+	case exec:
+		defer w.tracker.measure(time.Now(), work, &err)
+		_, err = db.ExecContext(ctx, q, args...)
+	case query:
+		defer w.tracker.measure(time.Now(), work, &err)
+		var rows *sql.Rows
+		rows, err = db.QueryContext(ctx, q, args...)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+		}
+		err = rows.Err()
+	}
+}
+
+// Perform work until the context is done or there's no more work to do.
 func (w *worker) run(ctx context.Context, db *sql.DB) {
 	for {
 		if ctx.Err() != nil {
@@ -134,6 +171,9 @@ func (w *worker) run(ctx context.Context, db *sql.DB) {
 		}
 
 		w.doWork(ctx, db)
+		if w.lastWork == none {
+			return
+		}
 	}
 }
 
@@ -141,6 +181,12 @@ func (w *worker) report() map[work]report {
 	return w.tracker.report()
 }
 
+func newTraceWorker(trace <-chan traceEntry, o *options) *worker {
+	w := newWorker(traceReplayer, o)
+	w.trace = trace
+	return w
+}
+
 func newWorker(workerType workerType, o *options) *worker {
 	return &worker{
 		workerType:   workerType,
@@ -0,0 +1,24 @@
+package client
+
+import (
+	"time"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+)
+
+// CircuitBreaker tracks which nodes are failing, so that FindLeader and the
+// driver can skip them for a while instead of waiting for a connection
+// timeout at every attempt. See NewCircuitBreaker.
+type CircuitBreaker = protocol.Breaker
+
+// NewCircuitBreaker returns a circuit breaker that skips a node after the
+// given number of consecutive failed connection attempts, and lets a single
+// attempt through once the given cooldown has passed, to check whether the
+// node is back.
+//
+// The same circuit breaker should be shared by everything using the same
+// NodeStore, for example by passing it both to FindLeader with
+// WithCircuitBreaker and to the driver.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return protocol.NewBreaker(threshold, cooldown)
+}
@@ -2,6 +2,8 @@ package client
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/cowsql/go-cowsql/internal/protocol"
 	"github.com/pkg/errors"
@@ -13,6 +15,17 @@ type DialFunc = protocol.DialFunc
 // Client speaks the cowsql wire protocol.
 type Client struct {
 	protocol *protocol.Protocol
+	log      LogFunc
+	dial     DialFunc // Used to connect to other nodes, e.g. by RemovalImpact
+	order    ClusterOrder
+
+	// Connect to the current leader, if the client is resilient.
+	reconnect func(context.Context) (*protocol.Protocol, error)
+	clock     Clock
+
+	configMu   sync.Mutex // Serializes opening the config database
+	configOpen bool       // Whether the config database is open
+	configID   uint32     // ID of the config database
 }
 
 // Option that can be used to tweak client parameters.
@@ -21,6 +34,13 @@ type Option func(*options)
 type options struct {
 	DialFunc DialFunc
 	LogFunc  LogFunc
+	Strict   bool
+	Breaker  *CircuitBreaker
+	Clock    Clock
+
+	ClusterOrder      ClusterOrder
+	MaxTotalRetryTime time.Duration
+	ResilientAdmin    bool
 }
 
 // WithDialFunc sets a custom dial function for creating the client network
@@ -39,6 +59,35 @@ func WithLogFunc(log LogFunc) Option {
 	}
 }
 
+// WithStrictProtocol makes the client check that the type of each response
+// received from the server matches the type of the request it answers, and
+// fail with a descriptive error otherwise. It helps detecting mismatches
+// between the wire protocol spoken by the client and by the server.
+func WithStrictProtocol() Option {
+	return func(options *options) {
+		options.Strict = true
+	}
+}
+
+// WithCircuitBreaker makes FindLeader use the given circuit breaker to skip
+// nodes that failed repeatedly.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(options *options) {
+		options.Breaker = breaker
+	}
+}
+
+// WithMaxTotalRetryTime caps the total time that FindLeader spends looking
+// for the leader, including retries and the backoff between them, after which
+// it fails as if the context was done, even if it is not done yet.
+//
+// If not used, FindLeader retries until the context is done.
+func WithMaxTotalRetryTime(max time.Duration) Option {
+	return func(options *options) {
+		options.MaxTotalRetryTime = max
+	}
+}
+
 // New creates a new client connected to the cowsql node with the given
 // address.
 func New(ctx context.Context, address string, options ...Option) (*Client, error) {
@@ -58,8 +107,9 @@ func New(ctx context.Context, address string, options ...Option) (*Client, error
 		conn.Close()
 		return nil, err
 	}
+	protocol.SetStrict(o.Strict)
 
-	client := &Client{protocol: protocol}
+	client := &Client{protocol: protocol, log: o.LogFunc, dial: o.DialFunc, order: o.ClusterOrder}
 
 	return client, nil
 }
@@ -68,12 +118,14 @@ func New(ctx context.Context, address string, options ...Option) (*Client, error
 func (c *Client) Leader(ctx context.Context) (*NodeInfo, error) {
 	request := protocol.Message{}
 	request.Init(16)
+	defer request.Release()
 	response := protocol.Message{}
 	response.Init(512)
+	defer response.Release()
 
 	protocol.EncodeLeader(&request)
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return nil, errors.Wrap(err, "failed to send Leader request")
 	}
 
@@ -88,15 +140,19 @@ func (c *Client) Leader(ctx context.Context) (*NodeInfo, error) {
 }
 
 // Cluster returns information about all nodes in the cluster.
+//
+// The nodes are sorted in the order set with WithClusterOrder, if any.
 func (c *Client) Cluster(ctx context.Context) ([]NodeInfo, error) {
 	request := protocol.Message{}
 	request.Init(16)
+	defer request.Release()
 	response := protocol.Message{}
 	response.Init(512)
+	defer response.Release()
 
 	protocol.EncodeCluster(&request, protocol.ClusterFormatV1)
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return nil, errors.Wrap(err, "failed to send Cluster request")
 	}
 
@@ -104,6 +160,7 @@ func (c *Client) Cluster(ctx context.Context) ([]NodeInfo, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse Node response")
 	}
+	SortNodes(servers, c.order)
 
 	return servers, nil
 }
@@ -121,12 +178,14 @@ type File struct {
 func (c *Client) Dump(ctx context.Context, dbname string) ([]File, error) {
 	request := protocol.Message{}
 	request.Init(16)
+	defer request.Release()
 	response := protocol.Message{}
 	response.Init(512)
+	defer response.Release()
 
 	protocol.EncodeDump(&request, dbname)
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return nil, errors.Wrap(err, "failed to send dump request")
 	}
 
@@ -155,29 +214,36 @@ func (c *Client) Dump(ctx context.Context, dbname string) ([]File, error) {
 // desired role is Voter, the node being added must be online, since it will be
 // granted voting rights only once it catches up with the leader's log.
 func (c *Client) Add(ctx context.Context, node NodeInfo) error {
+	add := func() error { return c.add(ctx, node) }
+	if err := c.replay(ctx, "add", add, c.hasNode(ctx, node.ID, nil)); err != nil {
+		return err
+	}
+
+	// If the desired role is spare, there's nothing to do, since all newly
+	// added nodes have the spare role.
+	if node.Role == Spare {
+		return nil
+	}
+
+	return c.Assign(ctx, node.ID, node.Role)
+}
+
+func (c *Client) add(ctx context.Context, node NodeInfo) error {
 	request := protocol.Message{}
 	response := protocol.Message{}
 
 	request.Init(4096)
+	defer request.Release()
 	response.Init(4096)
+	defer response.Release()
 
 	protocol.EncodeAdd(&request, node.ID, node.Address)
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return err
 	}
 
-	if err := protocol.DecodeEmpty(&response); err != nil {
-		return err
-	}
-
-	// If the desired role is spare, there's nothing to do, since all newly
-	// added nodes have the spare role.
-	if node.Role == Spare {
-		return nil
-	}
-
-	return c.Assign(ctx, node.ID, node.Role)
+	return protocol.DecodeEmpty(&response)
 }
 
 // Assign a role to a node.
@@ -191,15 +257,23 @@ func (c *Client) Add(ctx context.Context, node NodeInfo) error {
 // If the target node does not exist or has already the desired role, an error
 // is returned.
 func (c *Client) Assign(ctx context.Context, id uint64, role NodeRole) error {
+	assign := func() error { return c.assign(ctx, id, role) }
+	assigned := c.hasNode(ctx, id, func(node NodeInfo) bool { return node.Role == role })
+	return c.replay(ctx, "assign", assign, assigned)
+}
+
+func (c *Client) assign(ctx context.Context, id uint64, role NodeRole) error {
 	request := protocol.Message{}
 	response := protocol.Message{}
 
 	request.Init(4096)
+	defer request.Release()
 	response.Init(4096)
+	defer response.Release()
 
 	protocol.EncodeAssign(&request, id, uint64(role))
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return err
 	}
 
@@ -214,15 +288,29 @@ func (c *Client) Assign(ctx context.Context, id uint64, role NodeRole) error {
 //
 // This must be invoked one client connected to the current leader.
 func (c *Client) Transfer(ctx context.Context, id uint64) error {
+	transfer := func() error { return c.transfer(ctx, id) }
+	transferred := func() (bool, error) {
+		leader, err := c.Leader(ctx)
+		if err != nil {
+			return false, err
+		}
+		return leader.ID == id, nil
+	}
+	return c.replay(ctx, "transfer", transfer, transferred)
+}
+
+func (c *Client) transfer(ctx context.Context, id uint64) error {
 	request := protocol.Message{}
 	response := protocol.Message{}
 
 	request.Init(4096)
+	defer request.Release()
 	response.Init(4096)
+	defer response.Release()
 
 	protocol.EncodeTransfer(&request, id)
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return err
 	}
 
@@ -235,14 +323,26 @@ func (c *Client) Transfer(ctx context.Context, id uint64) error {
 
 // Remove a node from the cluster.
 func (c *Client) Remove(ctx context.Context, id uint64) error {
+	remove := func() error { return c.remove(ctx, id) }
+	present := c.hasNode(ctx, id, nil)
+	removed := func() (bool, error) {
+		ok, err := present()
+		return !ok, err
+	}
+	return c.replay(ctx, "remove", remove, removed)
+}
+
+func (c *Client) remove(ctx context.Context, id uint64) error {
 	request := protocol.Message{}
 	request.Init(4096)
+	defer request.Release()
 	response := protocol.Message{}
 	response.Init(4096)
+	defer response.Release()
 
 	protocol.EncodeRemove(&request, id)
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return err
 	}
 
@@ -257,18 +357,28 @@ func (c *Client) Remove(ctx context.Context, id uint64) error {
 type NodeMetadata struct {
 	FailureDomain uint64
 	Weight        uint64
+
+	// Labels published by the node with SetLabels. Describe does not fill
+	// them, since they are not known to the engine.
+	Labels map[string]string `json:",omitempty"`
+
+	// Versions published by the node with SetVersion. Describe does not
+	// fill them either.
+	Version *NodeVersion `json:",omitempty"`
 }
 
 // Describe returns metadata about the node we're connected with.
 func (c *Client) Describe(ctx context.Context) (*NodeMetadata, error) {
 	request := protocol.Message{}
 	request.Init(4096)
+	defer request.Release()
 	response := protocol.Message{}
 	response.Init(4096)
+	defer response.Release()
 
 	protocol.EncodeDescribe(&request, protocol.RequestDescribeFormatV0)
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return nil, err
 	}
 
@@ -289,12 +399,14 @@ func (c *Client) Describe(ctx context.Context) (*NodeMetadata, error) {
 func (c *Client) Weight(ctx context.Context, weight uint64) error {
 	request := protocol.Message{}
 	request.Init(4096)
+	defer request.Release()
 	response := protocol.Message{}
 	response.Init(4096)
+	defer response.Release()
 
 	protocol.EncodeWeight(&request, weight)
 
-	if err := c.protocol.Call(ctx, &request, &response); err != nil {
+	if err := c.call(ctx, &request, &response); err != nil {
 		return err
 	}
 
@@ -305,6 +417,15 @@ func (c *Client) Weight(ctx context.Context, weight uint64) error {
 	return nil
 }
 
+// Perform a call, logging failures along with the ID of the call.
+func (c *Client) call(ctx context.Context, request, response *protocol.Message) error {
+	err := c.protocol.Call(ctx, request, response)
+	if err != nil {
+		c.log(LogDebug, "call %d failed: %v", protocol.CallID(err), err)
+	}
+	return err
+}
+
 // Close the client.
 func (c *Client) Close() error {
 	return c.protocol.Close()
@@ -315,5 +436,6 @@ func defaultOptions() *options {
 	return &options{
 		DialFunc: DefaultDialFunc,
 		LogFunc:  DefaultLogFunc,
+		Clock:    SystemClock,
 	}
 }
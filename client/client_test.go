@@ -76,6 +76,96 @@ func TestClient_Dump(t *testing.T) {
 	assert.Equal(t, 8272, len(files[1].Data))
 }
 
+func TestClient_Config(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, err = cli.GetConfig(ctx, "voters")
+	assert.Equal(t, client.ErrConfigNotFound, err)
+
+	require.NoError(t, cli.SetConfig(ctx, "voters", "3"))
+	require.NoError(t, cli.SetConfig(ctx, "voters", "5"))
+
+	value, err := cli.GetConfig(ctx, "voters")
+	require.NoError(t, err)
+	assert.Equal(t, "5", value)
+
+	files, err := cli.Dump(ctx, client.ConfigDatabase)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestClient_ClockSkew(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	// The node shares our clock.
+	skew, err := cli.ClockSkew(ctx)
+	require.NoError(t, err)
+	assert.True(t, skew > -100*time.Millisecond && skew < 100*time.Millisecond, "skew %s", skew)
+}
+
+func TestClient_Labels(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	labels, err := cli.Labels(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+
+	require.NoError(t, cli.SetLabels(ctx, 1, map[string]string{"tier": "core"}))
+	require.NoError(t, cli.SetLabels(ctx, 2, map[string]string{"tier": "edge", "arch": "arm64"}))
+	require.NoError(t, cli.SetLabels(ctx, 3, map[string]string{"tier": "edge"}))
+	require.NoError(t, cli.SetLabels(ctx, 3, nil))
+
+	labels, err = cli.Labels(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]map[string]string{
+		1: {"tier": "core"},
+		2: {"tier": "edge", "arch": "arm64"},
+	}, labels)
+}
+
+func TestClient_Versions(t *testing.T) {
+	node, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	version := client.NodeVersion{Client: client.Version, Engine: "1.14.0"}
+	require.NoError(t, cli.SetVersion(ctx, 1, version))
+
+	versions, err := cli.Versions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]client.NodeVersion{1: version}, versions)
+}
+
 func TestClient_Cluster(t *testing.T) {
 	node, cleanup := newNode(t)
 	defer cleanup()
@@ -130,6 +220,82 @@ func TestClient_Transfer(t *testing.T) {
 
 }
 
+func TestClient_Promote(t *testing.T) {
+	node1, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node1.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, cleanup = addNode(t, cli, 2)
+	defer cleanup()
+
+	lags := []uint64{3, 1}
+	lag := func(ctx context.Context, node client.NodeInfo) (uint64, error) {
+		assert.Equal(t, client.StandBy, node.Role)
+		n := lags[0]
+		lags = lags[1:]
+		return n, nil
+	}
+	progress := []uint64{}
+	err = cli.Promote(ctx, 2,
+		client.WaitCatchUp(true),
+		client.WithLagFunc(lag),
+		client.WithMaxLag(1),
+		client.WithCatchUpProgress(func(p client.CatchUpProgress) {
+			progress = append(progress, p.Lag)
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 1}, progress)
+
+	nodes, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, client.Voter, nodes[1].Role)
+}
+
+func TestClient_RemoveGraceful(t *testing.T) {
+	node1, cleanup := newNode(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	cli, err := client.New(ctx, node1.BindAddress())
+	require.NoError(t, err)
+	defer cli.Close()
+
+	_, cleanup = addNode(t, cli, 2)
+	defer cleanup()
+
+	// Removing the only voter would lose quorum.
+	impact, err := cli.RemovalImpact(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, impact.Voters)
+	assert.True(t, impact.QuorumAtRisk)
+
+	err = cli.RemoveGraceful(ctx, 1)
+	_, ok := err.(*client.RemovalUnsafeError)
+	assert.True(t, ok)
+
+	// Removing the spare is fine.
+	impact, err = cli.RemovalImpact(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, impact.Voters)
+	assert.Equal(t, 1, impact.ReachableVoters)
+	assert.Equal(t, 0, impact.FaultTolerance)
+
+	require.NoError(t, cli.RemoveGraceful(ctx, 2))
+
+	nodes, err := cli.Cluster(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, nodes, 1)
+}
+
 func TestClient_Describe(t *testing.T) {
 	node, cleanup := newNode(t)
 	defer cleanup()
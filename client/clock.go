@@ -0,0 +1,90 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+)
+
+// Clock tells the current time and waits for durations to elapse, see
+// WithClock.
+type Clock = protocol.Clock
+
+// SystemClock is the Clock based on real time.
+var SystemClock = protocol.SystemClock
+
+// WithClock sets the clock used by FindLeader to wait between attempts to
+// find the leader. The default is SystemClock.
+func WithClock(clock Clock) Option {
+	return func(options *options) {
+		options.Clock = clock
+	}
+}
+
+// ManualClock is a Clock whose time only moves forward when Advance is
+// called, so tests and simulations involving retries and periodic tasks can
+// run instantly.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []manualWaiter
+}
+
+type manualWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewManualClock returns a ManualClock set at the given time.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel receiving the time of the clock once it has been
+// advanced by at least the given duration.
+func (c *ManualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, manualWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by the given duration, firing the channels
+// returned by After whose duration has elapsed.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.deadline.After(c.now) {
+			waiters = append(waiters, waiter)
+			continue
+		}
+		waiter.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// Waiters returns the number of channels returned by After that didn't fire
+// yet. Tests can poll it to know when the code under test is waiting.
+func (c *ManualClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
@@ -0,0 +1,36 @@
+package client_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := client.NewManualClock(start)
+
+	short := clock.After(time.Second)
+	long := clock.After(time.Minute)
+	assert.Equal(t, 2, clock.Waiters())
+
+	// Non-positive durations fire immediately.
+	assert.Equal(t, start, <-clock.After(0))
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(30*time.Second), clock.Now())
+	assert.Equal(t, start.Add(30*time.Second), <-short)
+	assert.Equal(t, 1, clock.Waiters())
+
+	select {
+	case <-long:
+		t.Fatal("timer fired too early")
+	default:
+	}
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-long)
+	assert.Equal(t, 0, clock.Waiters())
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ClusterOrder is a canonical ordering of the nodes returned by
+// Client.Cluster, see WithClusterOrder and SortNodes.
+type ClusterOrder int
+
+// Available orderings of the cluster nodes.
+const (
+	OrderNone      ClusterOrder = iota // As returned by the server.
+	OrderByID                          // By ascending node ID.
+	OrderByAddress                     // By address, then by ID.
+	OrderByRole                        // Voters, stand-bys then spares, by ID within each role.
+)
+
+// WithClusterOrder makes Client.Cluster return the nodes in the given order,
+// instead of the order of the server's internal configuration, which can
+// change over time.
+func WithClusterOrder(order ClusterOrder) Option {
+	return func(options *options) {
+		options.ClusterOrder = order
+	}
+}
+
+// SortNodes sorts the given nodes in place, in the given order.
+func SortNodes(nodes []NodeInfo, order ClusterOrder) {
+	var less func(a, b NodeInfo) bool
+	switch order {
+	case OrderByID:
+		less = func(a, b NodeInfo) bool {
+			return a.ID < b.ID
+		}
+	case OrderByAddress:
+		less = func(a, b NodeInfo) bool {
+			if a.Address != b.Address {
+				return a.Address < b.Address
+			}
+			return a.ID < b.ID
+		}
+	case OrderByRole:
+		less = func(a, b NodeInfo) bool {
+			if a.Role != b.Role {
+				return a.Role < b.Role
+			}
+			return a.ID < b.ID
+		}
+	default:
+		return
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return less(nodes[i], nodes[j]) })
+}
+
+// ClusterChangeType identifies the kind of a ClusterChange.
+type ClusterChangeType int
+
+// Kinds of changes between two snapshots of the cluster.
+const (
+	NodeAdded          ClusterChangeType = iota // The node is new.
+	NodeRemoved                                 // The node is gone.
+	NodeRoleChanged                             // The role of the node changed.
+	NodeAddressChanged                          // The address of the node changed.
+)
+
+func (t ClusterChangeType) String() string {
+	switch t {
+	case NodeAdded:
+		return "added"
+	case NodeRemoved:
+		return "removed"
+	case NodeRoleChanged:
+		return "role changed"
+	case NodeAddressChanged:
+		return "address changed"
+	default:
+		return "unknown"
+	}
+}
+
+// ClusterChange describes a change to a node between two snapshots of the
+// cluster, as returned by ClusterDiff.
+type ClusterChange struct {
+	Type     ClusterChangeType
+	Node     NodeInfo // The node after the change, or before it was removed.
+	Previous NodeInfo // The node before a role or address change.
+}
+
+func (c ClusterChange) String() string {
+	switch c.Type {
+	case NodeAdded:
+		return fmt.Sprintf("node %d added at %s as %s", c.Node.ID, c.Node.Address, c.Node.Role)
+	case NodeRemoved:
+		return fmt.Sprintf("node %d at %s removed", c.Node.ID, c.Node.Address)
+	case NodeRoleChanged:
+		return fmt.Sprintf("node %d at %s changed role from %s to %s", c.Node.ID, c.Node.Address, c.Previous.Role, c.Node.Role)
+	case NodeAddressChanged:
+		return fmt.Sprintf("node %d changed address from %s to %s", c.Node.ID, c.Previous.Address, c.Node.Address)
+	default:
+		return fmt.Sprintf("node %d: unknown change", c.Node.ID)
+	}
+}
+
+// ClusterDiff compares two snapshots of the cluster, as returned by
+// Client.Cluster, and returns the changes from the first to the second.
+//
+// Nodes are matched by ID, so the order of the snapshots doesn't matter. The
+// changes are sorted by node ID, and a node whose address and role both
+// changed yields two changes, address first.
+func ClusterDiff(before, after []NodeInfo) []ClusterChange {
+	previous := make(map[uint64]NodeInfo, len(before))
+	for _, node := range before {
+		previous[node.ID] = node
+	}
+	current := make(map[uint64]NodeInfo, len(after))
+	for _, node := range after {
+		current[node.ID] = node
+	}
+
+	changes := []ClusterChange{}
+	for id, node := range current {
+		old, ok := previous[id]
+		if !ok {
+			changes = append(changes, ClusterChange{Type: NodeAdded, Node: node})
+			continue
+		}
+		if old.Address != node.Address {
+			changes = append(changes, ClusterChange{Type: NodeAddressChanged, Node: node, Previous: old})
+		}
+		if old.Role != node.Role {
+			changes = append(changes, ClusterChange{Type: NodeRoleChanged, Node: node, Previous: old})
+		}
+	}
+	for id, node := range previous {
+		if _, ok := current[id]; !ok {
+			changes = append(changes, ClusterChange{Type: NodeRemoved, Node: node})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Node.ID < changes[j].Node.ID
+	})
+
+	return changes
+}
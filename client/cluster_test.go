@@ -0,0 +1,66 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortNodes(t *testing.T) {
+	nodes := []client.NodeInfo{
+		{ID: 3, Address: "127.0.0.1:9001", Role: client.Spare},
+		{ID: 1, Address: "127.0.0.1:9003", Role: client.Voter},
+		{ID: 2, Address: "127.0.0.1:9002", Role: client.Voter},
+	}
+
+	cases := []struct {
+		order client.ClusterOrder
+		ids   []uint64
+	}{
+		{client.OrderNone, []uint64{3, 1, 2}},
+		{client.OrderByID, []uint64{1, 2, 3}},
+		{client.OrderByAddress, []uint64{3, 2, 1}},
+		{client.OrderByRole, []uint64{1, 2, 3}},
+	}
+
+	for _, c := range cases {
+		sorted := make([]client.NodeInfo, len(nodes))
+		copy(sorted, nodes)
+		client.SortNodes(sorted, c.order)
+
+		ids := []uint64{}
+		for _, node := range sorted {
+			ids = append(ids, node.ID)
+		}
+		assert.Equal(t, c.ids, ids)
+	}
+}
+
+func TestClusterDiff(t *testing.T) {
+	before := []client.NodeInfo{
+		{ID: 1, Address: "127.0.0.1:9001", Role: client.Voter},
+		{ID: 2, Address: "127.0.0.1:9002", Role: client.Voter},
+		{ID: 3, Address: "127.0.0.1:9003", Role: client.StandBy},
+	}
+	after := []client.NodeInfo{
+		{ID: 4, Address: "127.0.0.1:9004", Role: client.Spare},
+		{ID: 3, Address: "127.0.0.1:9013", Role: client.Voter},
+		{ID: 1, Address: "127.0.0.1:9001", Role: client.Voter},
+	}
+
+	changes := client.ClusterDiff(before, after)
+
+	descriptions := []string{}
+	for _, change := range changes {
+		descriptions = append(descriptions, change.String())
+	}
+	assert.Equal(t, []string{
+		"node 2 at 127.0.0.1:9002 removed",
+		"node 3 changed address from 127.0.0.1:9003 to 127.0.0.1:9013",
+		"node 3 at 127.0.0.1:9013 changed role from stand-by to voter",
+		"node 4 added at 127.0.0.1:9004 as spare",
+	}, descriptions)
+
+	assert.Empty(t, client.ClusterDiff(after, after))
+}
@@ -0,0 +1,223 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NodeStoreCodec serializes the list of nodes persisted by a FileNodeStore.
+type NodeStoreCodec interface {
+	Marshal(nodes []NodeInfo) ([]byte, error)
+	Unmarshal(data []byte) ([]NodeInfo, error)
+}
+
+// Codecs supported by FileNodeStore.
+var (
+	YamlCodec NodeStoreCodec = yamlCodec{}
+	JSONCodec NodeStoreCodec = jsonCodec{}
+	TOMLCodec NodeStoreCodec = tomlCodec{}
+)
+
+// CodecForPath returns the codec matching the extension of the given file
+// name: ".yaml" or ".yml" for YAML, ".json" for JSON and ".toml" for TOML.
+func CodecForPath(path string) (NodeStoreCodec, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YamlCodec, nil
+	case ".json":
+		return JSONCodec, nil
+	case ".toml":
+		return TOMLCodec, nil
+	}
+	return nil, fmt.Errorf("no codec for file %s", path)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(nodes []NodeInfo) ([]byte, error) {
+	return yaml.Marshal(nodes)
+}
+
+func (yamlCodec) Unmarshal(data []byte) ([]NodeInfo, error) {
+	nodes := []NodeInfo{}
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(nodes []NodeInfo) ([]byte, error) {
+	return json.MarshalIndent(nodes, "", "  ")
+}
+
+func (jsonCodec) Unmarshal(data []byte) ([]NodeInfo, error) {
+	nodes := []NodeInfo{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nodes, nil
+	}
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// The TOML codec supports the subset of the format needed to hold the list of
+// nodes, as an array of tables:
+//
+//	[[Nodes]]
+//	ID = 1
+//	Address = "127.0.0.1:9001"
+//	Role = 0
+//
+// Since TOML integers are signed, IDs that don't fit in 63 bits are written
+// as quoted decimal strings.
+type tomlCodec struct{}
+
+func (tomlCodec) Marshal(nodes []NodeInfo) ([]byte, error) {
+	buf := bytes.Buffer{}
+	for i, node := range nodes {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("[[Nodes]]\n")
+		if node.ID > math.MaxInt64 {
+			fmt.Fprintf(&buf, "ID = \"%d\"\n", node.ID)
+		} else {
+			fmt.Fprintf(&buf, "ID = %d\n", node.ID)
+		}
+		fmt.Fprintf(&buf, "Address = %s\n", tomlQuote(node.Address))
+		fmt.Fprintf(&buf, "Role = %d\n", node.Role)
+	}
+	return buf.Bytes(), nil
+}
+
+func (tomlCodec) Unmarshal(data []byte) ([]NodeInfo, error) {
+	nodes := []NodeInfo{}
+	var node *NodeInfo
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if tomlStripComment(line) != "[[Nodes]]" {
+				return nil, fmt.Errorf("line %d: unexpected table %s", n, line)
+			}
+			nodes = append(nodes, NodeInfo{})
+			node = &nodes[len(nodes)-1]
+			continue
+		}
+
+		i := strings.IndexByte(line, '=')
+		if i == -1 {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		if node == nil {
+			return nil, fmt.Errorf("line %d: key outside of [[Nodes]] table", n)
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+
+		var err error
+		switch key {
+		case "ID":
+			node.ID, err = tomlUint(value)
+		case "Address":
+			node.Address, err = tomlString(value)
+		case "Role":
+			var role uint64
+			role, err = tomlUint(value)
+			node.Role = NodeRole(role)
+		default:
+			// Ignore unknown keys, like the other codecs do.
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %v", n, key, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// Parse an integer, possibly given as a quoted string.
+func tomlUint(value string) (uint64, error) {
+	value = tomlStripComment(value)
+	if strings.HasPrefix(value, "\"") {
+		s, err := tomlString(value)
+		if err != nil {
+			return 0, err
+		}
+		value = s
+	}
+	return strconv.ParseUint(strings.Replace(value, "_", "", -1), 10, 64)
+}
+
+// Parse a basic string.
+func tomlString(value string) (string, error) {
+	if !strings.HasPrefix(value, "\"") {
+		return "", fmt.Errorf("expected a string")
+	}
+	// Find the closing quote, skipping escaped characters.
+	end := -1
+	for i := 1; i < len(value); i++ {
+		if value[i] == '\\' {
+			i++
+			continue
+		}
+		if value[i] == '"' {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		return "", fmt.Errorf("unterminated string")
+	}
+	if rest := tomlStripComment(value[end+1:]); rest != "" {
+		return "", fmt.Errorf("unexpected %q after string", rest)
+	}
+	return strconv.Unquote(value[:end+1])
+}
+
+// Remove a trailing comment from a value that contains no strings.
+func tomlStripComment(value string) string {
+	if i := strings.IndexByte(value, '#'); i != -1 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// Quote a string as a TOML basic string.
+func tomlQuote(s string) string {
+	buf := strings.Builder{}
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r < 0x20 || r == 0x7f || r == utf8.RuneError:
+			fmt.Fprintf(&buf, "\\u%04x", r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
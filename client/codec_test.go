@@ -0,0 +1,134 @@
+package client_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Nodes are persisted and loaded back with all supported codecs.
+func TestFileNodeStore(t *testing.T) {
+	nodes := []client.NodeInfo{
+		{ID: 1, Address: "127.0.0.1:9001", Role: client.Voter},
+		{ID: 1<<64 - 1, Address: "[::1]:9002", Role: client.StandBy},
+		{ID: 3, Address: "@\"weird\\name\"", Role: client.Spare},
+	}
+
+	for _, ext := range []string{".yaml", ".yml", ".json", ".toml"} {
+		t.Run(ext, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cowsql-store-test-")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "cluster"+ext)
+
+			store, err := client.NewFileNodeStore(path, nil)
+			require.NoError(t, err)
+
+			servers, err := store.Get(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, []client.NodeInfo{}, servers)
+
+			require.NoError(t, store.Set(context.Background(), nodes))
+
+			store, err = client.NewFileNodeStore(path, nil)
+			require.NoError(t, err)
+
+			servers, err = store.Get(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, nodes, servers)
+		})
+	}
+}
+
+// Leading comments are preserved and provenance metadata is recorded.
+func TestFileNodeStore_Metadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cowsql-store-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cluster.yaml")
+	data := []byte("# Managed by ops.\n- ID: 1\n  Address: 127.0.0.1:9001\n  Role: 0\n")
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+
+	store, err := client.NewYamlNodeStore(path)
+	require.NoError(t, err)
+	assert.Equal(t, client.StoreMetadata{}, store.Metadata())
+
+	nodes := []client.NodeInfo{{ID: 1, Address: "127.0.0.1:9002"}}
+	store.SetUpdatedBy("test")
+	require.NoError(t, store.Set(context.Background(), nodes))
+
+	// Setting the same nodes doesn't rewrite the file.
+	require.NoError(t, store.Set(context.Background(), nodes))
+
+	store, err = client.NewYamlNodeStore(path)
+	require.NoError(t, err)
+
+	metadata := store.Metadata()
+	assert.Equal(t, uint64(1), metadata.Generation)
+	assert.Equal(t, "test", metadata.UpdatedBy)
+	assert.WithinDuration(t, time.Now(), metadata.UpdatedAt, time.Minute)
+
+	servers, err := store.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, nodes, servers)
+
+	data, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "# Managed by ops.\n# cowsql-generation: 1\n"))
+}
+
+func TestFileNodeStore_UnknownExtension(t *testing.T) {
+	_, err := client.NewFileNodeStore("cluster.ini", nil)
+	assert.EqualError(t, err, "no codec for file cluster.ini")
+}
+
+func TestTOMLCodec_Unmarshal(t *testing.T) {
+	data := []byte(`
+# Cluster nodes.
+[[Nodes]]
+ID = 1_000 # Comment
+Address = "127.0.0.1:9001"
+Role = 1
+Weight = 3
+
+[[Nodes]]
+ID = "18446744073709551615"
+Address = "127.0.0.1:9002" # Comment
+`)
+
+	nodes, err := client.TOMLCodec.Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, []client.NodeInfo{
+		{ID: 1000, Address: "127.0.0.1:9001", Role: client.StandBy},
+		{ID: 1<<64 - 1, Address: "127.0.0.1:9002", Role: client.Voter},
+	}, nodes)
+}
+
+func TestTOMLCodec_UnmarshalError(t *testing.T) {
+	cases := []struct {
+		data string
+		err  string
+	}{
+		{"ID = 1", "line 1: key outside of [[Nodes]] table"},
+		{"[Nodes]", "line 1: unexpected table [Nodes]"},
+		{"[[Nodes]]\nID", "line 2: expected key = value"},
+		{"[[Nodes]]\nID = x", `line 2: ID: strconv.ParseUint: parsing "x": invalid syntax`},
+		{"[[Nodes]]\nAddress = \"foo", "line 2: Address: unterminated string"},
+	}
+	for _, c := range cases {
+		t.Run(c.err, func(t *testing.T) {
+			_, err := client.TOMLCodec.Unmarshal([]byte(c.data))
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
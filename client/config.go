@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// ConfigDatabase is the name of the database reserved for the cluster-wide
+// configuration registry accessed with SetConfig and GetConfig.
+const ConfigDatabase = "cowsql-config"
+
+// ErrConfigNotFound is returned by GetConfig when the given key is not set.
+var ErrConfigNotFound = errors.New("config key not found")
+
+// SetConfig sets the value of the given key in the cluster-wide configuration
+// registry.
+//
+// The registry is stored in a regular replicated database, so the client must
+// be connected to the leader, see FindLeader.
+func (c *Client) SetConfig(ctx context.Context, key, value string) error {
+	db, err := c.configDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	sql := "INSERT OR REPLACE INTO config(key, value) VALUES(?, ?)"
+	if err := c.configExec(ctx, db, sql, key, value); err != nil {
+		return errors.Wrapf(err, "failed to set config key %s", key)
+	}
+
+	return nil
+}
+
+// GetConfig returns the value of the given key in the cluster-wide
+// configuration registry, or ErrConfigNotFound if the key is not set.
+//
+// As with SetConfig, the client must be connected to the leader.
+func (c *Client) GetConfig(ctx context.Context, key string) (string, error) {
+	db, err := c.configDB(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	request := protocol.Message{}
+	request.Init(4096)
+	defer request.Release()
+	response := protocol.Message{}
+	response.Init(4096)
+	defer response.Release()
+
+	args := []driver.NamedValue{{Ordinal: 1, Value: key}}
+	protocol.EncodeQuerySQLV0(&request, uint64(db), "SELECT value FROM config WHERE key = ?", args)
+
+	if err := c.call(ctx, &request, &response); err != nil {
+		return "", errors.Wrapf(err, "failed to get config key %s", key)
+	}
+
+	rows, err := protocol.DecodeRows(&response)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse rows response")
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		if err == io.EOF {
+			return "", ErrConfigNotFound
+		}
+		return "", errors.Wrap(err, "failed to parse row")
+	}
+
+	value, ok := dest[0].(string)
+	if !ok {
+		return "", errors.Errorf("unexpected value type %T for config key %s", dest[0], key)
+	}
+
+	return value, nil
+}
+
+// Return the ID of the configuration database, opening it and creating the
+// config table the first time.
+//
+// Only one database can be opened on a connection, so the ID is cached.
+func (c *Client) configDB(ctx context.Context) (uint32, error) {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+
+	if c.configOpen {
+		return c.configID, nil
+	}
+
+	request := protocol.Message{}
+	request.Init(64)
+	defer request.Release()
+	response := protocol.Message{}
+	response.Init(64)
+	defer response.Release()
+
+	protocol.EncodeOpen(&request, ConfigDatabase, 0, "volatile")
+
+	if err := c.call(ctx, &request, &response); err != nil {
+		return 0, errors.Wrap(err, "failed to open config database")
+	}
+
+	db, err := protocol.DecodeDb(&response)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse db response")
+	}
+
+	sql := "CREATE TABLE IF NOT EXISTS config (key TEXT PRIMARY KEY NOT NULL, value TEXT NOT NULL)"
+	if err := c.configExec(ctx, db, sql); err != nil {
+		return 0, errors.Wrap(err, "failed to create config table")
+	}
+
+	c.configID = db
+	c.configOpen = true
+
+	return db, nil
+}
+
+// Execute a statement against the configuration database.
+func (c *Client) configExec(ctx context.Context, db uint32, sql string, values ...string) error {
+	request := protocol.Message{}
+	request.Init(4096)
+	defer request.Release()
+	response := protocol.Message{}
+	response.Init(64)
+	defer response.Release()
+
+	args := make([]driver.NamedValue, len(values))
+	for i, value := range values {
+		args[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+	}
+	protocol.EncodeExecSQLV0(&request, uint64(db), sql, args)
+
+	if err := c.call(ctx, &request, &response); err != nil {
+		return err
+	}
+
+	if _, err := protocol.DecodeResult(&response); err != nil {
+		return errors.Wrap(err, "failed to parse result response")
+	}
+
+	return nil
+}
+
+// Set the value of the registry key holding the given per-node setting of
+// the node with the given ID, which is the given prefix followed by the ID in
+// hexadecimal. An empty value removes the key.
+func (c *Client) setNodeConfig(ctx context.Context, prefix string, id uint64, value string) error {
+	db, err := c.configDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%x", prefix, id)
+	if value == "" {
+		return c.configExec(ctx, db, "DELETE FROM config WHERE key = ?", key)
+	}
+
+	return c.configExec(ctx, db, "INSERT OR REPLACE INTO config(key, value) VALUES(?, ?)", key, value)
+}
+
+// Return the values of the given per-node setting, keyed by node ID.
+func (c *Client) nodeConfig(ctx context.Context, prefix string) (map[uint64]string, error) {
+	db, err := c.configDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request := protocol.Message{}
+	request.Init(4096)
+	defer request.Release()
+	response := protocol.Message{}
+	response.Init(4096)
+	defer response.Release()
+
+	args := []driver.NamedValue{{Ordinal: 1, Value: prefix + "%"}}
+	protocol.EncodeQuerySQLV0(&request, uint64(db), "SELECT key, value FROM config WHERE key LIKE ?", args)
+
+	if err := c.call(ctx, &request, &response); err != nil {
+		return nil, err
+	}
+
+	rows, err := protocol.DecodeRows(&response)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse rows response")
+	}
+	defer func() { rows.Close() }()
+
+	values := map[uint64]string{}
+	dest := make([]driver.Value, 2)
+	for {
+		err := rows.Next(dest)
+		if err == protocol.ErrRowsPart {
+			rows.Close()
+			if err := c.protocol.More(ctx, &response); err != nil {
+				return nil, err
+			}
+			if rows, err = protocol.DecodeRows(&response); err != nil {
+				return nil, errors.Wrap(err, "failed to parse rows response")
+			}
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse row")
+		}
+		key, _ := dest[0].(string)
+		value, _ := dest[1].(string)
+		id, err := strconv.ParseUint(strings.TrimPrefix(key, prefix), 16, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid key %q", key)
+		}
+		values[id] = value
+	}
+
+	return values, nil
+}
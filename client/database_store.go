@@ -20,6 +20,10 @@ type nodeStoreOptions struct {
 }
 
 // DatabaseNodeStore persists a list addresses of cowsql nodes in a SQL table.
+//
+// If the table also has "id" and "role" integer columns, they are used to
+// persist the IDs and roles of the nodes. Otherwise only the addresses are
+// persisted, and all nodes are returned with ID 1 and the voter role.
 type DatabaseNodeStore struct {
 	db     *sql.DB // Database handle to use.
 	schema string  // Name of the schema holding the servers table.
@@ -30,14 +34,16 @@ type DatabaseNodeStore struct {
 
 // DefaultNodeStore creates a new NodeStore using the given filename.
 //
-// If the filename ends with ".yaml" then the YamlNodeStore implementation will
-// be used. Otherwise the SQLite-based one will be picked, with default names
-// for the schema, table and column parameters.
+// If the filename ends with ".yaml", ".yml", ".json" or ".toml" then the
+// FileNodeStore implementation will be used, with the matching codec.
+// Otherwise the SQLite-based one will be picked, with default names for the
+// schema, table and column parameters.
 //
-// It also creates the table if it doesn't exist yet.
+// It also creates the table if it doesn't exist yet, or upgrades it to hold
+// node IDs and roles if it was created by an older version.
 func DefaultNodeStore(filename string) (NodeStore, error) {
-	if strings.HasSuffix(filename, ".yaml") {
-		return NewYamlNodeStore(filename)
+	if codec, err := CodecForPath(filename); err == nil {
+		return NewFileNodeStore(filename, codec)
 	}
 
 	// Open the database.
@@ -56,6 +62,10 @@ func DefaultNodeStore(filename string) (NodeStore, error) {
 		return nil, errors.Wrap(err, "failed to create servers table")
 	}
 
+	if err := upgradeNodeStoreTable(db, "main", "servers"); err != nil {
+		return nil, err
+	}
+
 	store := NewNodeStore(db, "main", "servers", "address")
 
 	return store, nil
@@ -77,6 +87,70 @@ func NewNodeStore(db *sql.DB, schema, table, column string, options ...NodeStore
 	}
 }
 
+// Add the id and role columns to the given servers table, if missing. Rows
+// created before the upgrade get ID 1 and the voter role, matching what was
+// previously returned for them.
+func upgradeNodeStoreTable(db *sql.DB, schema, table string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer tx.Rollback()
+
+	hasID, hasRole, err := nodeStoreColumns(context.Background(), tx, schema, table)
+	if err != nil {
+		return err
+	}
+
+	if !hasID {
+		query := fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN id INTEGER NOT NULL DEFAULT 1", schema, table)
+		if _, err := tx.Exec(query); err != nil {
+			return errors.Wrap(err, "failed to add id column to servers table")
+		}
+	}
+	if !hasRole {
+		query := fmt.Sprintf("ALTER TABLE %s.%s ADD COLUMN role INTEGER NOT NULL DEFAULT %d", schema, table, Voter)
+		if _, err := tx.Exec(query); err != nil {
+			return errors.Wrap(err, "failed to add role column to servers table")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return nil
+}
+
+// Check whether the given servers table has the id and role columns.
+func nodeStoreColumns(ctx context.Context, tx *sql.Tx, schema, table string) (bool, bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?, ?)", table, schema)
+	if err != nil {
+		return false, false, errors.Wrap(err, "failed to query servers table columns")
+	}
+	defer rows.Close()
+
+	hasID := false
+	hasRole := false
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, false, errors.Wrap(err, "failed to fetch servers table column")
+		}
+		switch strings.ToLower(name) {
+		case "id":
+			hasID = true
+		case "role":
+			hasRole = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, false, errors.Wrap(err, "result set failure")
+	}
+
+	return hasID, hasRole, nil
+}
+
 // WithNodeStoreWhereClause configures the node store to append the given
 // hard-coded where clause to the SELECT query used to fetch nodes. Only the
 // clause itself must be given, without the "WHERE" prefix.
@@ -94,7 +168,18 @@ func (d *DatabaseNodeStore) Get(ctx context.Context) ([]NodeInfo, error) {
 	}
 	defer tx.Rollback()
 
-	query := fmt.Sprintf("SELECT %s FROM %s.%s", d.column, d.schema, d.table)
+	hasID, hasRole, err := nodeStoreColumns(ctx, tx, d.schema, d.table)
+	if err != nil {
+		return nil, err
+	}
+	full := hasID && hasRole
+
+	columns := d.column
+	if full {
+		columns += ", id, role"
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s", columns, d.schema, d.table)
 	if d.where != "" {
 		query += " WHERE " + d.where
 	}
@@ -107,11 +192,16 @@ func (d *DatabaseNodeStore) Get(ctx context.Context) ([]NodeInfo, error) {
 	servers := make([]NodeInfo, 0)
 	for rows.Next() {
 		var address string
-		err := rows.Scan(&address)
-		if err != nil {
+		id := int64(1)
+		role := int64(Voter)
+		dest := []interface{}{&address}
+		if full {
+			dest = append(dest, &id, &role)
+		}
+		if err := rows.Scan(dest...); err != nil {
 			return nil, errors.Wrap(err, "failed to fetch server address")
 		}
-		servers = append(servers, NodeInfo{ID: 1, Address: address})
+		servers = append(servers, NodeInfo{ID: uint64(id), Address: address, Role: NodeRole(role)})
 	}
 	if err := rows.Err(); err != nil {
 		return nil, errors.Wrap(err, "result set failure")
@@ -127,13 +217,24 @@ func (d *DatabaseNodeStore) Set(ctx context.Context, servers []NodeInfo) error {
 		return errors.Wrap(err, "failed to begin transaction")
 	}
 
+	hasID, hasRole, err := nodeStoreColumns(ctx, tx, d.schema, d.table)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	full := hasID && hasRole
+
 	query := fmt.Sprintf("DELETE FROM %s.%s", d.schema, d.table)
 	if _, err := tx.ExecContext(ctx, query); err != nil {
 		tx.Rollback()
 		return errors.Wrap(err, "failed to delete existing servers rows")
 	}
 
-	query = fmt.Sprintf("INSERT INTO %s.%s(%s) VALUES (?)", d.schema, d.table, d.column)
+	if full {
+		query = fmt.Sprintf("INSERT INTO %s.%s(%s, id, role) VALUES (?, ?, ?)", d.schema, d.table, d.column)
+	} else {
+		query = fmt.Sprintf("INSERT INTO %s.%s(%s) VALUES (?)", d.schema, d.table, d.column)
+	}
 	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
 		tx.Rollback()
@@ -142,7 +243,11 @@ func (d *DatabaseNodeStore) Set(ctx context.Context, servers []NodeInfo) error {
 	defer stmt.Close()
 
 	for _, server := range servers {
-		if _, err := stmt.ExecContext(ctx, server.Address); err != nil {
+		args := []interface{}{server.Address}
+		if full {
+			args = append(args, int64(server.ID), int64(server.Role))
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
 			tx.Rollback()
 			return errors.Wrapf(err, "failed to insert server %s", server.Address)
 		}
@@ -18,6 +18,10 @@ func DefaultDialFunc(ctx context.Context, address string) (net.Conn, error) {
 //
 // The given dial function will be used to establish the network connection,
 // and the given TLS config will be used for encryption.
+//
+// If the config has a ClientSessionCache, sessions are cached separately for
+// each address, so they can be resumed even if all nodes share the same
+// server name.
 func DialFuncWithTLS(dial DialFunc, config *tls.Config) DialFunc {
 	return func(ctx context.Context, addr string) (net.Conn, error) {
 		clonedConfig := config.Clone()
@@ -28,6 +32,7 @@ func DialFuncWithTLS(dial DialFunc, config *tls.Config) DialFunc {
 			}
 			clonedConfig.ServerName = remoteIP
 		}
+		clonedConfig.ClientSessionCache = protocol.SessionCacheForAddress(config.ClientSessionCache, addr)
 		conn, err := dial(ctx, addr)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,400 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// DumpOption can be used to tweak how dumps are written by DumpTo and
+// WriteDump, and read by ReadDump.
+type DumpOption func(*dumpOptions)
+
+// KeyFunc returns the key used to encrypt or decrypt a dump, which must be
+// 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+type KeyFunc func() ([]byte, error)
+
+// Compression is an algorithm used to compress dumps.
+//
+// Only GzipCompression is built in, other algorithms such as zstd can be
+// used by providing their implementation.
+type Compression struct {
+	Name      string // Identifies the algorithm in the dump header.
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCompression compresses dumps with gzip.
+var GzipCompression = Compression{
+	Name: "gzip",
+	NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+	NewReader: func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+}
+
+// WithDumpCompression compresses the dump with the given algorithm.
+//
+// When reading a dump, it makes the given algorithm available in addition to
+// the built-in ones.
+func WithDumpCompression(compression Compression) DumpOption {
+	return func(options *dumpOptions) {
+		options.compression = &compression
+	}
+}
+
+// WithDumpEncryption encrypts the dump with AES-GCM, using the key returned
+// by the given function.
+//
+// When reading a dump, the function is used to get the key to decrypt it,
+// and is required if the dump is encrypted.
+func WithDumpEncryption(key KeyFunc) DumpOption {
+	return func(options *dumpOptions) {
+		options.key = key
+	}
+}
+
+type dumpOptions struct {
+	compression *Compression
+	key         KeyFunc
+}
+
+// Format of dumps written by WriteDump:
+//
+//   - magic (8 bytes)
+//   - flags (1 byte), bit 0 set if encrypted
+//   - length of the compression name (1 byte), 0 if not compressed
+//   - compression name
+//   - nonce prefix (8 bytes), if encrypted
+//   - payload: a tar archive holding the dump files, possibly compressed and
+//     then encrypted
+//
+// The encrypted payload is a sequence of chunks, each prefixed by its
+// length, whose high bit marks the final chunk. Each chunk is sealed with a
+// nonce made of the prefix and of the chunk index, using the header and the
+// length as additional data, so chunks can't be reordered, truncated or
+// attached to another header.
+const (
+	dumpMagic     = "COWSQLD1"
+	dumpEncrypted = 1 << 0
+	dumpChunkSize = 64 * 1024
+	dumpFinal     = 1 << 31
+)
+
+// DumpTo dumps the content of the database with the given name, like Dump,
+// and writes it to the given writer in the format of WriteDump.
+func (c *Client) DumpTo(ctx context.Context, dbname string, w io.Writer, options ...DumpOption) error {
+	files, err := c.Dump(ctx, dbname)
+	if err != nil {
+		return err
+	}
+	return WriteDump(w, files, options...)
+}
+
+// WriteDump writes the given dump files to the given writer, as a tar
+// archive, possibly compressed and encrypted according to the given options.
+//
+// Use ReadDump to get the files back.
+func WriteDump(w io.Writer, files []File, options ...DumpOption) error {
+	o := &dumpOptions{}
+	for _, option := range options {
+		option(o)
+	}
+
+	header := []byte(dumpMagic)
+	var flags byte
+	if o.key != nil {
+		flags |= dumpEncrypted
+	}
+	header = append(header, flags)
+	if o.compression != nil {
+		if len(o.compression.Name) == 0 || len(o.compression.Name) > 255 {
+			return errors.Errorf("invalid compression name %q", o.compression.Name)
+		}
+		header = append(header, byte(len(o.compression.Name)))
+		header = append(header, o.compression.Name...)
+	} else {
+		header = append(header, 0)
+	}
+
+	var aead cipher.AEAD
+	if o.key != nil {
+		var err error
+		if aead, err = dumpCipher(o.key); err != nil {
+			return err
+		}
+		prefix := make([]byte, 8)
+		if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+			return errors.Wrap(err, "generate nonce")
+		}
+		header = append(header, prefix...)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return errors.Wrap(err, "write header")
+	}
+
+	// Build the pipeline from the innermost writer.
+	closers := []io.Closer{}
+	var payload io.Writer = w
+	if aead != nil {
+		e := &dumpEncrypter{w: w, aead: aead, header: header}
+		closers = append(closers, e)
+		payload = e
+	}
+	if o.compression != nil {
+		compressor, err := o.compression.NewWriter(payload)
+		if err != nil {
+			return errors.Wrap(err, "create compressor")
+		}
+		closers = append(closers, compressor)
+		payload = compressor
+	}
+
+	archive := tar.NewWriter(payload)
+	for _, file := range files {
+		h := &tar.Header{Name: file.Name, Mode: 0600, Size: int64(len(file.Data))}
+		if err := archive.WriteHeader(h); err != nil {
+			return errors.Wrapf(err, "write %s", file.Name)
+		}
+		if _, err := archive.Write(file.Data); err != nil {
+			return errors.Wrapf(err, "write %s", file.Name)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return errors.Wrap(err, "write archive")
+	}
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadDump reads the dump files written by WriteDump or DumpTo from the
+// given reader.
+//
+// Encrypted dumps require WithDumpEncryption with the same key they were
+// written with, and dumps compressed with an algorithm that is not built in
+// require WithDumpCompression with its implementation.
+func ReadDump(r io.Reader, options ...DumpOption) ([]File, error) {
+	o := &dumpOptions{}
+	for _, option := range options {
+		option(o)
+	}
+
+	header := make([]byte, len(dumpMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.Wrap(err, "read header")
+	}
+	if string(header[:len(dumpMagic)]) != dumpMagic {
+		return nil, errors.New("not a cowsql dump")
+	}
+	flags := header[len(dumpMagic)]
+	name := make([]byte, header[len(dumpMagic)+1])
+	if _, err := io.ReadFull(r, name); err != nil {
+		return nil, errors.Wrap(err, "read header")
+	}
+	header = append(header, name...)
+
+	var payload io.Reader = r
+	if flags&dumpEncrypted != 0 {
+		if o.key == nil {
+			return nil, errors.New("dump is encrypted but no key was given")
+		}
+		aead, err := dumpCipher(o.key)
+		if err != nil {
+			return nil, err
+		}
+		prefix := make([]byte, 8)
+		if _, err := io.ReadFull(r, prefix); err != nil {
+			return nil, errors.Wrap(err, "read header")
+		}
+		header = append(header, prefix...)
+		payload = &dumpDecrypter{r: r, aead: aead, header: header}
+	}
+
+	if len(name) > 0 {
+		compression := GzipCompression
+		if o.compression != nil && o.compression.Name == string(name) {
+			compression = *o.compression
+		} else if string(name) != GzipCompression.Name {
+			return nil, errors.Errorf("unsupported compression %q", name)
+		}
+		decompressor, err := compression.NewReader(payload)
+		if err != nil {
+			return nil, errors.Wrap(err, "create decompressor")
+		}
+		defer decompressor.Close()
+		payload = decompressor
+	}
+
+	files := []File{}
+	archive := tar.NewReader(payload)
+	for {
+		h, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "read archive")
+		}
+		data, err := ioutil.ReadAll(archive)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %s", h.Name)
+		}
+		files = append(files, File{Name: h.Name, Data: data})
+	}
+
+	// Consume the rest of the payload, so that a truncated or tampered
+	// encrypted dump is detected.
+	if _, err := io.Copy(ioutil.Discard, payload); err != nil {
+		return nil, errors.Wrap(err, "read archive")
+	}
+
+	return files, nil
+}
+
+// Return the AES-GCM cipher for the key returned by the given function.
+func dumpCipher(key KeyFunc) (cipher.AEAD, error) {
+	k, err := key()
+	if err != nil {
+		return nil, errors.Wrap(err, "get encryption key")
+	}
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, errors.Wrap(err, "create cipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+// Return the nonce and additional data of the chunk with the given index and
+// length field.
+func dumpChunkParams(header []byte, index uint32, length uint32) ([]byte, []byte) {
+	nonce := make([]byte, 12)
+	copy(nonce, header[len(header)-8:])
+	binary.BigEndian.PutUint32(nonce[8:], index)
+
+	data := make([]byte, len(header)+4)
+	copy(data, header)
+	binary.BigEndian.PutUint32(data[len(header):], length)
+
+	return nonce, data
+}
+
+// Encrypt a payload in chunks.
+type dumpEncrypter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	index  uint32
+}
+
+func (e *dumpEncrypter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		m := dumpChunkSize - len(e.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		e.buf = append(e.buf, p[:m]...)
+		p = p[m:]
+		if len(e.buf) == dumpChunkSize {
+			if err := e.flush(false); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Write the final chunk.
+func (e *dumpEncrypter) Close() error {
+	return e.flush(true)
+}
+
+func (e *dumpEncrypter) flush(final bool) error {
+	if e.index == ^uint32(0) {
+		return errors.New("dump too large")
+	}
+	length := uint32(len(e.buf) + e.aead.Overhead())
+	if final {
+		length |= dumpFinal
+	}
+	nonce, data := dumpChunkParams(e.header, e.index, length)
+	chunk := make([]byte, 4, 4+len(e.buf)+e.aead.Overhead())
+	binary.BigEndian.PutUint32(chunk, length)
+	chunk = e.aead.Seal(chunk, nonce, e.buf, data)
+	if _, err := e.w.Write(chunk); err != nil {
+		return errors.Wrap(err, "write chunk")
+	}
+	e.buf = e.buf[:0]
+	e.index++
+	return nil
+}
+
+// Decrypt a payload written by dumpEncrypter.
+type dumpDecrypter struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	buf    *bytes.Reader
+	index  uint32
+	final  bool
+}
+
+func (d *dumpDecrypter) Read(p []byte) (int, error) {
+	for d.buf == nil || d.buf.Len() == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	return d.buf.Read(p)
+}
+
+// Read and decrypt the next chunk.
+func (d *dumpDecrypter) next() error {
+	prefix := make([]byte, 4)
+	if _, err := io.ReadFull(d.r, prefix); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return errors.Wrap(err, "read chunk")
+	}
+	length := binary.BigEndian.Uint32(prefix)
+	size := length &^ dumpFinal
+	if size < uint32(d.aead.Overhead()) || size > dumpChunkSize+uint32(d.aead.Overhead()) {
+		return errors.New("invalid chunk length")
+	}
+	chunk := make([]byte, size)
+	if _, err := io.ReadFull(d.r, chunk); err != nil {
+		return errors.Wrap(err, "read chunk")
+	}
+	nonce, data := dumpChunkParams(d.header, d.index, length)
+	plain, err := d.aead.Open(chunk[:0], nonce, chunk, data)
+	if err != nil {
+		return errors.Wrap(err, "decrypt chunk")
+	}
+	d.buf = bytes.NewReader(plain)
+	d.index++
+	d.final = length&dumpFinal != 0
+	return nil
+}
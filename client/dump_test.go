@@ -0,0 +1,101 @@
+package client_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDump(t *testing.T) {
+	files := []client.File{
+		{Name: "test.db", Data: bytes.Repeat([]byte("db"), 100000)},
+		{Name: "test.db-wal", Data: []byte("wal")},
+	}
+	key := func() ([]byte, error) { return bytes.Repeat([]byte{1}, 32), nil }
+
+	cases := map[string][]client.DumpOption{
+		"plain":      nil,
+		"compressed": {client.WithDumpCompression(client.GzipCompression)},
+		"encrypted":  {client.WithDumpEncryption(key)},
+		"both":       {client.WithDumpCompression(client.GzipCompression), client.WithDumpEncryption(key)},
+	}
+	for name, options := range cases {
+		t.Run(name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			require.NoError(t, client.WriteDump(&buf, files, options...))
+
+			dump, err := client.ReadDump(bytes.NewReader(buf.Bytes()), options...)
+			require.NoError(t, err)
+			assert.Equal(t, files, dump)
+		})
+	}
+}
+
+func TestReadDump_Encrypted(t *testing.T) {
+	files := []client.File{{Name: "test.db", Data: bytes.Repeat([]byte("db"), 100000)}}
+	key := func() ([]byte, error) { return bytes.Repeat([]byte{1}, 16), nil }
+	wrong := func() ([]byte, error) { return bytes.Repeat([]byte{2}, 16), nil }
+
+	buf := bytes.Buffer{}
+	require.NoError(t, client.WriteDump(&buf, files, client.WithDumpEncryption(key)))
+	data := buf.Bytes()
+
+	// The data is not stored in clear.
+	assert.False(t, bytes.Contains(data, []byte("dbdbdbdb")))
+
+	_, err := client.ReadDump(bytes.NewReader(data))
+	assert.EqualError(t, err, "dump is encrypted but no key was given")
+
+	_, err = client.ReadDump(bytes.NewReader(data), client.WithDumpEncryption(wrong))
+	assert.EqualError(t, err, "read archive: decrypt chunk: cipher: message authentication failed")
+
+	// Truncated after the first chunk.
+	_, err = client.ReadDump(bytes.NewReader(data[:8+2+8+4+64*1024+16]), client.WithDumpEncryption(key))
+	assert.Error(t, err)
+
+	// Tampered header.
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = client.ReadDump(bytes.NewReader(tampered), client.WithDumpEncryption(key))
+	assert.Error(t, err)
+
+	failing := func() ([]byte, error) { return nil, fmt.Errorf("vault unavailable") }
+	err = client.WriteDump(&buf, files, client.WithDumpEncryption(failing))
+	assert.EqualError(t, err, "get encryption key: vault unavailable")
+}
+
+func TestReadDump_CustomCompression(t *testing.T) {
+	files := []client.File{{Name: "test.db", Data: []byte("db")}}
+	identity := client.Compression{
+		Name:      "identity",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) { return nopWriteCloser{w}, nil },
+		NewReader: func(r io.Reader) (io.ReadCloser, error) { return ioutil.NopCloser(r), nil },
+	}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, client.WriteDump(&buf, files, client.WithDumpCompression(identity)))
+
+	_, err := client.ReadDump(bytes.NewReader(buf.Bytes()))
+	assert.EqualError(t, err, `unsupported compression "identity"`)
+
+	dump, err := client.ReadDump(bytes.NewReader(buf.Bytes()), client.WithDumpCompression(identity))
+	require.NoError(t, err)
+	assert.Equal(t, files, dump)
+}
+
+func TestReadDump_NotADump(t *testing.T) {
+	_, err := client.ReadDump(bytes.NewReader([]byte("SQLite format 3\x00")))
+	assert.EqualError(t, err, "not a cowsql dump")
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,24 @@
+package client
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrIDCollision is returned by CheckID when a node ID is already used by
+// another member of the cluster.
+var ErrIDCollision = errors.New("node ID already in use")
+
+// CheckID checks that a node with the given ID and address can join a cluster
+// with the given members.
+//
+// If a member with a different address already uses the ID, ErrIDCollision is
+// returned, wrapped with the address of that member. A member with the same ID
+// and address is not a collision: it's the node itself, which already joined.
+func CheckID(nodes []NodeInfo, id uint64, address string) error {
+	for _, node := range nodes {
+		if node.ID == id && node.Address != address {
+			return errors.Wrapf(ErrIDCollision, "ID %d used by %s", id, node.Address)
+		}
+	}
+	return nil
+}
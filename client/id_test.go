@@ -0,0 +1,23 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckID(t *testing.T) {
+	nodes := []client.NodeInfo{
+		{ID: 1, Address: "1.2.3.4:666"},
+		{ID: 2, Address: "5.6.7.8:666"},
+	}
+
+	assert.NoError(t, client.CheckID(nodes, 3, "9.9.9.9:666"))
+	assert.NoError(t, client.CheckID(nodes, 2, "5.6.7.8:666"))
+
+	err := client.CheckID(nodes, 2, "9.9.9.9:666")
+	assert.True(t, errors.Is(err, client.ErrIDCollision))
+	assert.EqualError(t, err, "ID 2 used by 5.6.7.8:666: node ID already in use")
+}
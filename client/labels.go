@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Prefix of the configuration registry keys holding node labels.
+const labelsKeyPrefix = "labels/"
+
+// SetLabels publishes the labels of the node with the given ID in the
+// cluster-wide configuration registry, replacing any previous ones. Passing
+// no labels removes them.
+//
+// The Describe response of the engine has a fixed set of fields, so labels
+// are kept in the registry instead, where any client can read them with
+// Labels. As with SetConfig, the client must be connected to the leader.
+func (c *Client) SetLabels(ctx context.Context, id uint64, labels map[string]string) error {
+	value := ""
+	if len(labels) > 0 {
+		data, err := json.Marshal(labels)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode labels")
+		}
+		value = string(data)
+	}
+
+	if err := c.setNodeConfig(ctx, labelsKeyPrefix, id, value); err != nil {
+		return errors.Wrapf(err, "failed to set labels of node %x", id)
+	}
+
+	return nil
+}
+
+// Labels returns the labels published by the nodes of the cluster with
+// SetLabels, keyed by node ID. Nodes without labels are not included.
+//
+// As with GetConfig, the client must be connected to the leader.
+func (c *Client) Labels(ctx context.Context) (map[uint64]map[string]string, error) {
+	values, err := c.nodeConfig(ctx, labelsKeyPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get labels")
+	}
+
+	all := make(map[uint64]map[string]string, len(values))
+	for id, value := range values {
+		labels := map[string]string{}
+		if err := json.Unmarshal([]byte(value), &labels); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode labels of node %x", id)
+		}
+		all[id] = labels
+	}
+
+	return all, nil
+}
@@ -20,7 +20,11 @@ func FindLeader(ctx context.Context, store NodeStore, options ...Option) (*Clien
 	}
 
 	config := protocol.Config{
-		Dial: o.DialFunc,
+		Dial:         o.DialFunc,
+		Strict:       o.Strict,
+		Breaker:      o.Breaker,
+		Clock:        o.Clock,
+		MaxRetryTime: o.MaxTotalRetryTime,
 	}
 	connector := protocol.NewConnector(0, store, config, o.LogFunc)
 	protocol, err := connector.Connect(ctx)
@@ -28,7 +32,10 @@ func FindLeader(ctx context.Context, store NodeStore, options ...Option) (*Clien
 		return nil, err
 	}
 
-	client := &Client{protocol: protocol}
+	client := &Client{protocol: protocol, log: o.LogFunc, dial: o.DialFunc, order: o.ClusterOrder, clock: o.Clock}
+	if o.ResilientAdmin {
+		client.reconnect = connector.Connect
+	}
 
 	return client, nil
 }
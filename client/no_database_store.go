@@ -3,17 +3,15 @@
 package client
 
 import (
-	"strings"
-
 	"github.com/pkg/errors"
 )
 
 // DefaultNodeStore creates a new NodeStore using the given filename.
 //
-// The filename must end with ".yaml".
+// The filename must end with ".yaml", ".yml", ".json" or ".toml".
 func DefaultNodeStore(filename string) (NodeStore, error) {
-	if strings.HasSuffix(filename, ".yaml") {
-		return NewYamlNodeStore(filename)
+	if codec, err := CodecForPath(filename); err == nil {
+		return NewFileNodeStore(filename, codec)
 	}
 
 	return nil, errors.New("built without support for DatabaseNodeStore")
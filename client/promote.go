@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PromoteOption can be used to tweak the behavior of Client.Promote.
+type PromoteOption func(*promoteOptions)
+
+// LagFunc returns how many log entries the given node is behind the leader.
+//
+// The wire protocol does not expose the applied index of a node, so it's up
+// to the caller to provide it, for example by asking the application running
+// on that node.
+type LagFunc func(ctx context.Context, node NodeInfo) (uint64, error)
+
+// CatchUpProgress is passed to the progress callback of Client.Promote each
+// time the lag of the node being promoted is checked.
+type CatchUpProgress struct {
+	Node NodeInfo // Node being promoted.
+	Lag  uint64   // Number of entries the node is behind the leader.
+}
+
+type promoteOptions struct {
+	WaitCatchUp bool
+	MaxLag      uint64
+	Lag         LagFunc
+	Progress    func(CatchUpProgress)
+}
+
+// WaitCatchUp makes Client.Promote wait for the node to catch up with the
+// leader before assigning it the voter role.
+func WaitCatchUp(wait bool) PromoteOption {
+	return func(options *promoteOptions) {
+		options.WaitCatchUp = wait
+	}
+}
+
+// WithMaxLag sets the maximum number of entries the node can be behind the
+// leader for Client.Promote to consider it caught up. The default is 0.
+func WithMaxLag(entries uint64) PromoteOption {
+	return func(options *promoteOptions) {
+		options.MaxLag = entries
+	}
+}
+
+// WithLagFunc sets the function used by Client.Promote to monitor the lag of
+// the node.
+func WithLagFunc(lag LagFunc) PromoteOption {
+	return func(options *promoteOptions) {
+		options.Lag = lag
+	}
+}
+
+// WithCatchUpProgress sets a function that Client.Promote invokes each time
+// it checks the lag of the node.
+func WithCatchUpProgress(progress func(CatchUpProgress)) PromoteOption {
+	return func(options *promoteOptions) {
+		options.Progress = progress
+	}
+}
+
+// Interval between two checks of the lag of a node being promoted.
+var catchUpInterval = 250 * time.Millisecond
+
+// Promote assigns the voter role to the node with the given ID.
+//
+// Without options, this is the same as Assign(ctx, id, Voter). With
+// WaitCatchUp(true), a spare node is first made a stand-by, so it starts
+// replicating the log without affecting the quorum. Then, if a LagFunc was
+// given, Promote waits until the node is at most WithMaxLag entries behind
+// the leader, and only then assigns it the voter role.
+//
+// If the context is done before the node catches up, an error is returned and
+// the node is left as a stand-by.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) Promote(ctx context.Context, id uint64, options ...PromoteOption) error {
+	o := &promoteOptions{}
+	for _, option := range options {
+		option(o)
+	}
+
+	if !o.WaitCatchUp {
+		return c.Assign(ctx, id, Voter)
+	}
+
+	nodes, err := c.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	var node *NodeInfo
+	for i := range nodes {
+		if nodes[i].ID == id {
+			node = &nodes[i]
+			break
+		}
+	}
+	if node == nil {
+		return errors.Errorf("node %d not found", id)
+	}
+
+	if node.Role == Spare {
+		if err := c.Assign(ctx, id, StandBy); err != nil {
+			return errors.Wrap(err, "failed to assign stand-by role")
+		}
+		node.Role = StandBy
+	}
+
+	if o.Lag != nil {
+		if err := waitCatchUp(ctx, *node, o); err != nil {
+			return err
+		}
+	}
+
+	return c.Assign(ctx, id, Voter)
+}
+
+// Poll the lag of the given node until it's within the configured limit.
+func waitCatchUp(ctx context.Context, node NodeInfo, o *promoteOptions) error {
+	for {
+		lag, err := o.Lag(ctx, node)
+		if err != nil {
+			return errors.Wrap(err, "failed to get node lag")
+		}
+		if o.Progress != nil {
+			o.Progress(CatchUpProgress{Node: node, Lag: lag})
+		}
+		if lag <= o.MaxLag {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "node still %d entries behind", lag)
+		case <-time.After(catchUpInterval):
+		}
+	}
+}
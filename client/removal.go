@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RemovalImpact describes the consequences of removing a node from the
+// cluster, as reported by Client.RemovalImpact.
+type RemovalImpact struct {
+	Node            NodeInfo       // Node that would be removed.
+	Voters          int            // Number of voters left after the removal.
+	ReachableVoters int            // Number of voters left that could be reached.
+	Quorum          int            // Number of voters needed for a quorum after the removal.
+	FaultTolerance  int            // Number of reachable voters that could still fail, negative if quorum would be lost.
+	FailureDomains  map[uint64]int // Number of reachable voters left in each failure domain.
+	LostDomain      bool           // Whether the failure domain of the node would be left without voters.
+	QuorumAtRisk    bool           // Whether the cluster would lose quorum or couldn't tolerate any further failure.
+}
+
+// String returns a human-readable summary of the impact.
+func (i RemovalImpact) String() string {
+	domains := make([]uint64, 0, len(i.FailureDomains))
+	for domain := range i.FailureDomains {
+		domains = append(domains, domain)
+	}
+	sort.Slice(domains, func(a, b int) bool { return domains[a] < domains[b] })
+
+	s := fmt.Sprintf(
+		"removing node %x (%s, %s) leaves %d voters (%d reachable), quorum %d, fault tolerance %d, voters per failure domain:",
+		i.Node.ID, i.Node.Address, i.Node.Role, i.Voters, i.ReachableVoters, i.Quorum, i.FaultTolerance)
+	for _, domain := range domains {
+		s += fmt.Sprintf(" %d=%d", domain, i.FailureDomains[domain])
+	}
+	if i.LostDomain {
+		s += ", failure domain of the node left without voters"
+	}
+	if i.QuorumAtRisk {
+		s += ", quorum at risk"
+	}
+	return s
+}
+
+// RemovalUnsafeError is returned by Client.RemoveGraceful when removing a
+// node would make the cluster lose quorum.
+type RemovalUnsafeError struct {
+	Impact RemovalImpact
+}
+
+func (e *RemovalUnsafeError) Error() string {
+	return fmt.Sprintf("removal would lose quorum: %s", e.Impact)
+}
+
+// How long to wait for each node when probing it for RemovalImpact.
+var removalProbeTimeout = 5 * time.Second
+
+// RemovalImpact reports what would happen to the cluster if the node with the
+// given ID was removed, without removing it.
+//
+// The voters that would be left and the node itself are probed, using the
+// same dial function as this client, to find out whether they are reachable
+// and what their failure domain is.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) RemovalImpact(ctx context.Context, id uint64) (*RemovalImpact, error) {
+	nodes, err := c.Cluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	metadata := map[uint64]*NodeMetadata{}
+	for _, node := range nodes {
+		if node.ID == id {
+			found = true
+		} else if node.Role != Voter {
+			continue
+		}
+		metadata[node.ID] = c.probeMetadata(ctx, node)
+	}
+	if !found {
+		return nil, errors.Errorf("node %d not found", id)
+	}
+
+	impact := removalImpact(nodes, id, metadata)
+
+	return &impact, nil
+}
+
+// RemoveGraceful removes the node with the given ID from the cluster, after
+// checking with RemovalImpact that the cluster would keep its quorum.
+//
+// If it would not, a *RemovalUnsafeError is returned and the node is not
+// removed. A voter is demoted to spare before being removed, so the
+// configuration changes one step at a time.
+//
+// This must be invoked on a client connected to the current leader.
+func (c *Client) RemoveGraceful(ctx context.Context, id uint64) error {
+	impact, err := c.RemovalImpact(ctx, id)
+	if err != nil {
+		return err
+	}
+	if impact.FaultTolerance < 0 {
+		return &RemovalUnsafeError{Impact: *impact}
+	}
+
+	if impact.Node.Role == Voter {
+		if err := c.Assign(ctx, id, Spare); err != nil {
+			return errors.Wrap(err, "failed to demote node")
+		}
+	}
+
+	return c.Remove(ctx, id)
+}
+
+// Connect to the given node and fetch its metadata, returning nil if the node
+// can't be reached.
+func (c *Client) probeMetadata(ctx context.Context, node NodeInfo) *NodeMetadata {
+	ctx, cancel := context.WithTimeout(ctx, removalProbeTimeout)
+	defer cancel()
+
+	cli, err := New(ctx, node.Address, WithDialFunc(c.dial), WithLogFunc(c.log))
+	if err != nil {
+		c.log(LogDebug, "probe node %d at %s: %v", node.ID, node.Address, err)
+		return nil
+	}
+	defer cli.Close()
+
+	metadata, err := cli.Describe(ctx)
+	if err != nil {
+		c.log(LogDebug, "describe node %d at %s: %v", node.ID, node.Address, err)
+		return nil
+	}
+
+	return metadata
+}
+
+// Compute the impact of removing the node with the given ID, given the
+// metadata of the nodes that could be reached.
+func removalImpact(nodes []NodeInfo, id uint64, metadata map[uint64]*NodeMetadata) RemovalImpact {
+	impact := RemovalImpact{FailureDomains: map[uint64]int{}}
+
+	for _, node := range nodes {
+		if node.ID == id {
+			impact.Node = node
+			continue
+		}
+		if node.Role != Voter {
+			continue
+		}
+		impact.Voters++
+		if m := metadata[node.ID]; m != nil {
+			impact.ReachableVoters++
+			impact.FailureDomains[m.FailureDomain]++
+		}
+	}
+
+	impact.Quorum = impact.Voters/2 + 1
+	impact.FaultTolerance = impact.ReachableVoters - impact.Quorum
+	impact.QuorumAtRisk = impact.FaultTolerance < 1
+
+	if m := metadata[id]; m != nil && impact.Node.Role == Voter {
+		impact.LostDomain = impact.FailureDomains[m.FailureDomain] == 0
+	}
+
+	return impact
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemovalImpact(t *testing.T) {
+	nodes := []NodeInfo{
+		{ID: 1, Address: "1", Role: Voter},
+		{ID: 2, Address: "2", Role: Voter},
+		{ID: 3, Address: "3", Role: Voter},
+		{ID: 4, Address: "4", Role: StandBy},
+	}
+	metadata := map[uint64]*NodeMetadata{
+		1: {FailureDomain: 1},
+		2: {FailureDomain: 2},
+		3: {FailureDomain: 2},
+	}
+
+	// Removing the only voter in domain 1.
+	impact := removalImpact(nodes, 1, metadata)
+	assert.Equal(t, nodes[0], impact.Node)
+	assert.Equal(t, 2, impact.Voters)
+	assert.Equal(t, 2, impact.ReachableVoters)
+	assert.Equal(t, 2, impact.Quorum)
+	assert.Equal(t, 0, impact.FaultTolerance)
+	assert.Equal(t, map[uint64]int{2: 2}, impact.FailureDomains)
+	assert.True(t, impact.LostDomain)
+	assert.True(t, impact.QuorumAtRisk)
+	assert.Equal(t,
+		"removing node 1 (1, voter) leaves 2 voters (2 reachable), quorum 2, fault tolerance 0, "+
+			"voters per failure domain: 2=2, failure domain of the node left without voters, quorum at risk",
+		impact.String())
+
+	// Removing a stand-by while a voter is unreachable.
+	delete(metadata, 3)
+	impact = removalImpact(nodes, 4, metadata)
+	assert.Equal(t, 3, impact.Voters)
+	assert.Equal(t, 2, impact.ReachableVoters)
+	assert.Equal(t, 0, impact.FaultTolerance)
+	assert.False(t, impact.LostDomain)
+	assert.True(t, impact.QuorumAtRisk)
+
+	// Removing a voter while another one is unreachable loses quorum.
+	impact = removalImpact(nodes, 2, metadata)
+	assert.Equal(t, 2, impact.Voters)
+	assert.Equal(t, 1, impact.ReachableVoters)
+	assert.Equal(t, -1, impact.FaultTolerance)
+}
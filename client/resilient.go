@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// Error codes returned by a node that is not the leader, or that lost
+// leadership while processing the request.
+const (
+	errIoErrNotLeaderLegacy      = 10 | 32<<8
+	errIoErrLeadershipLostLegacy = 10 | 33<<8
+	errIoErrNotLeader            = 10 | 40<<8
+	errIoErrLeadershipLost       = 10 | 41<<8
+)
+
+// Bounds of the backoff between replays of an admin operation.
+const (
+	replayBackoffFactor = 100 * time.Millisecond
+	replayBackoffCap    = time.Second
+)
+
+// WithResilientAdmin makes the Add, Assign, Remove and Transfer methods of a
+// client returned by FindLeader survive leadership changes and restarts of
+// the leader node.
+//
+// When one of these operations fails because the node is not the leader
+// anymore or the connection was lost, the client backs off, connects to the
+// new leader found in its store, checks whether the operation took effect
+// before the failure, and replays it otherwise. It keeps doing so until the
+// operation succeeds, fails with another error, or the context is done.
+//
+// Since the client may be connected to another node afterwards, it must not
+// be used concurrently while such an operation is in progress. The option has
+// no effect on clients returned by New.
+func WithResilientAdmin() Option {
+	return func(options *options) {
+		options.ResilientAdmin = true
+	}
+}
+
+// Whether the given error is caused by the node not being the leader anymore
+// or by the connection to it being lost, so the request can be sent again to
+// the new leader.
+func isTransient(err error) bool {
+	switch err := errors.Cause(err).(type) {
+	case syscall.Errno, *net.OpError:
+		return true
+	case protocol.ErrRequest:
+		switch err.Code {
+		case errIoErrNotLeaderLegacy, errIoErrLeadershipLostLegacy, errIoErrNotLeader, errIoErrLeadershipLost:
+			return true
+		}
+		return false
+	}
+	err = errors.Cause(err)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// Run the given operation, replaying it against the new leader if it fails
+// with a transient error and the client is resilient. The done function
+// tells whether an operation that failed took effect anyway.
+func (c *Client) replay(ctx context.Context, name string, op func() error, done func() (bool, error)) error {
+	err := op()
+	if c.reconnect == nil {
+		return err
+	}
+
+	backoff := replayBackoffFactor
+	for attempt := 1; err != nil && isTransient(err); attempt++ {
+		c.log(LogWarn, "%s failed (attempt %d): %v, retrying in %s", name, attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-c.clock.After(backoff):
+		}
+		if backoff *= 2; backoff > replayBackoffCap {
+			backoff = replayBackoffCap
+		}
+
+		if err = c.reconnectLeader(ctx); err != nil {
+			return err
+		}
+		var ok bool
+		if ok, err = done(); err != nil {
+			continue
+		}
+		if ok {
+			c.log(LogDebug, "%s already applied", name)
+			return nil
+		}
+		err = op()
+	}
+
+	return err
+}
+
+// Replace the connection of the client with a new one to the current leader.
+func (c *Client) reconnectLeader(ctx context.Context) error {
+	p, err := c.reconnect(ctx)
+	if err != nil {
+		return err
+	}
+	if c.protocol != nil {
+		c.protocol.Close()
+	}
+	c.protocol = p
+
+	// The config database was opened on the old connection.
+	c.configMu.Lock()
+	c.configOpen = false
+	c.configMu.Unlock()
+
+	return nil
+}
+
+// Return a function that tells whether the node with the given ID is part of
+// the cluster and matches the given condition, if not nil.
+func (c *Client) hasNode(ctx context.Context, id uint64, match func(NodeInfo) bool) func() (bool, error) {
+	return func() (bool, error) {
+		nodes, err := c.Cluster(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, node := range nodes {
+			if node.ID == id {
+				return match == nil || match(node), nil
+			}
+		}
+		return false, nil
+	}
+}
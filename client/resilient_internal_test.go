@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+	"github.com/cowsql/go-cowsql/logging"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTransient(t *testing.T) {
+	assert.True(t, isTransient(syscall.ECONNRESET))
+	assert.True(t, isTransient(&net.OpError{Op: "read", Err: syscall.EPIPE}))
+	assert.True(t, isTransient(errors.Wrap(io.EOF, "read response")))
+	assert.True(t, isTransient(protocol.ErrRequest{Code: errIoErrNotLeader}))
+	assert.True(t, isTransient(protocol.ErrRequest{Code: errIoErrLeadershipLostLegacy}))
+
+	assert.False(t, isTransient(protocol.ErrRequest{Code: 1, Description: "no such node"}))
+	assert.False(t, isTransient(context.Canceled))
+}
+
+func newResilientClient(t *testing.T) (*Client, *int) {
+	reconnects := 0
+	c := &Client{
+		log:   logging.Test(t),
+		clock: immediateClock{},
+		reconnect: func(context.Context) (*protocol.Protocol, error) {
+			reconnects++
+			return nil, nil
+		},
+	}
+	return c, &reconnects
+}
+
+// Clock whose waits end immediately.
+type immediateClock struct{}
+
+func (immediateClock) Now() time.Time { return time.Now() }
+func (immediateClock) After(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+// The operation is replayed after reconnecting, until it succeeds.
+func TestClient_Replay(t *testing.T) {
+	c, reconnects := newResilientClient(t)
+
+	errs := []error{protocol.ErrRequest{Code: errIoErrNotLeader}, io.EOF, nil}
+	calls := 0
+	op := func() error {
+		calls++
+		return errs[calls-1]
+	}
+	done := func() (bool, error) { return false, nil }
+
+	assert.NoError(t, c.replay(context.Background(), "test", op, done))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 2, *reconnects)
+}
+
+// The operation is not replayed if it took effect before failing.
+func TestClient_ReplayAlreadyApplied(t *testing.T) {
+	c, reconnects := newResilientClient(t)
+
+	calls := 0
+	op := func() error {
+		calls++
+		return io.EOF
+	}
+	done := func() (bool, error) { return true, nil }
+
+	assert.NoError(t, c.replay(context.Background(), "test", op, done))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, *reconnects)
+}
+
+// Other errors are returned right away, as well as any error if the client
+// is not resilient.
+func TestClient_ReplayNotTransient(t *testing.T) {
+	c, reconnects := newResilientClient(t)
+	failure := protocol.ErrRequest{Code: 1, Description: "no such node"}
+	op := func() error { return failure }
+	done := func() (bool, error) { return false, nil }
+
+	assert.Equal(t, failure, c.replay(context.Background(), "test", op, done))
+	assert.Equal(t, 0, *reconnects)
+
+	c.reconnect = nil
+	op = func() error { return io.EOF }
+	assert.Equal(t, io.EOF, c.replay(context.Background(), "test", op, done))
+}
+
+// Replaying stops when the context is done.
+func TestClient_ReplayContextDone(t *testing.T) {
+	c, _ := newResilientClient(t)
+	c.clock = NewManualClock(time.Time{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	op := func() error { return io.EOF }
+	done := func() (bool, error) { return false, nil }
+
+	assert.Equal(t, io.EOF, c.replay(ctx, "test", op, done))
+}
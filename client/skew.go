@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// Query returning the current time of the node, in milliseconds since the
+// Unix epoch. SQLite reads the clock with millisecond precision.
+const nowMillisSQL = "SELECT CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER)"
+
+// ClockSkew estimates the offset of the clock of the node the client is
+// connected to, relative to the local clock. A positive value means that
+// the node's clock is ahead.
+//
+// The Describe response of the engine carries no timestamp, so the time is
+// read with a query against the configuration database, and the client must
+// be connected to the leader, see FindLeader. As with NTP, the node's time is
+// assumed to have been read halfway through the round trip, so the estimate
+// is accurate within half of the round trip time, plus the millisecond
+// precision of the node's clock.
+func (c *Client) ClockSkew(ctx context.Context) (time.Duration, error) {
+	db, err := c.configDB(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	request := protocol.Message{}
+	request.Init(128)
+	defer request.Release()
+	response := protocol.Message{}
+	response.Init(128)
+	defer response.Release()
+
+	protocol.EncodeQuerySQLV0(&request, uint64(db), nowMillisSQL, nil)
+
+	start := time.Now()
+	if err := c.call(ctx, &request, &response); err != nil {
+		return 0, errors.Wrap(err, "failed to query node time")
+	}
+	rtt := time.Since(start)
+
+	rows, err := protocol.DecodeRows(&response)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse rows response")
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		return 0, errors.Wrap(err, "failed to parse row")
+	}
+	millis, ok := dest[0].(int64)
+	if !ok {
+		return 0, errors.Errorf("unexpected value type %T for node time", dest[0])
+	}
+
+	remote := time.Unix(0, millis*int64(time.Millisecond))
+	return remote.Sub(start.Add(rtt / 2)), nil
+}
@@ -1,13 +1,20 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/renameio"
-	"gopkg.in/yaml.v2"
 
 	"github.com/cowsql/go-cowsql/internal/protocol"
 )
@@ -28,16 +35,55 @@ type InmemNodeStore = protocol.InmemNodeStore
 // NewInmemNodeStore creates NodeStore which stores its data in-memory.
 var NewInmemNodeStore = protocol.NewInmemNodeStore
 
-// Persists a list addresses of cowsql nodes in a YAML file.
-type YamlNodeStore struct {
-	path    string
-	servers []NodeInfo
-	mu      sync.RWMutex
+// FileNodeStore persists a list of cowsql nodes in a file, serialized with a
+// NodeStoreCodec. The file is replaced atomically on every update that
+// changes the list.
+//
+// With the YAML and TOML codecs, the comment lines at the top of the file are
+// preserved when it's rewritten, followed by comments recording provenance
+// metadata about the last update, see StoreMetadata. Other comments are lost.
+type FileNodeStore struct {
+	path      string
+	codec     NodeStoreCodec
+	servers   []NodeInfo
+	comments  []string      // Leading comment lines, preserved on update
+	metadata  StoreMetadata // Provenance of the last update
+	updatedBy string        // Value of UpdatedBy for the next updates
+	mu        sync.RWMutex
 }
 
+// StoreMetadata records when and by whom the file of a FileNodeStore was last
+// updated. It's kept in comments, so it doesn't affect other readers of the
+// file.
+type StoreMetadata struct {
+	Generation uint64    // Number of updates since the file was created.
+	UpdatedAt  time.Time // Time of the last update.
+	UpdatedBy  string    // Program that made the last update.
+}
+
+// YamlNodeStore persists a list addresses of cowsql nodes in a YAML file.
+type YamlNodeStore = FileNodeStore
+
 // NewYamlNodeStore creates a new YamlNodeStore backed by the given YAML file.
 func NewYamlNodeStore(path string) (*YamlNodeStore, error) {
+	return NewFileNodeStore(path, YamlCodec)
+}
+
+// NewFileNodeStore creates a new FileNodeStore backed by the given file, using
+// the given codec to serialize the nodes. If the codec is nil, it's picked
+// based on the extension of the file, see CodecForPath.
+func NewFileNodeStore(path string, codec NodeStoreCodec) (*FileNodeStore, error) {
+	if codec == nil {
+		var err error
+		codec, err = CodecForPath(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	servers := []NodeInfo{}
+	comments := []string{}
+	metadata := StoreMetadata{}
 
 	_, err := os.Stat(path)
 	if err != nil {
@@ -50,21 +96,27 @@ func NewYamlNodeStore(path string) (*YamlNodeStore, error) {
 			return nil, err
 		}
 
-		if err := yaml.Unmarshal(data, &servers); err != nil {
+		comments, metadata = parseStoreHeader(codec, data)
+		servers, err = codec.Unmarshal(data)
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	store := &YamlNodeStore{
-		path:    path,
-		servers: servers,
+	store := &FileNodeStore{
+		path:      path,
+		codec:     codec,
+		servers:   servers,
+		comments:  comments,
+		metadata:  metadata,
+		updatedBy: filepath.Base(os.Args[0]),
 	}
 
 	return store, nil
 }
 
 // Get the current servers.
-func (s *YamlNodeStore) Get(ctx context.Context) ([]NodeInfo, error) {
+func (s *FileNodeStore) Get(ctx context.Context) ([]NodeInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	ret := make([]NodeInfo, len(s.servers))
@@ -73,20 +125,112 @@ func (s *YamlNodeStore) Get(ctx context.Context) ([]NodeInfo, error) {
 }
 
 // Set the servers addresses.
-func (s *YamlNodeStore) Set(ctx context.Context, servers []NodeInfo) error {
+//
+// The file is not rewritten if the servers didn't change.
+func (s *FileNodeStore) Set(ctx context.Context, servers []NodeInfo) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := yaml.Marshal(servers)
+	if reflect.DeepEqual(servers, s.servers) {
+		if _, err := os.Stat(s.path); err == nil {
+			return nil
+		}
+	}
+
+	data, err := s.codec.Marshal(servers)
 	if err != nil {
 		return err
 	}
 
+	metadata := StoreMetadata{
+		Generation: s.metadata.Generation + 1,
+		UpdatedAt:  time.Now().UTC().Truncate(time.Second),
+		UpdatedBy:  s.updatedBy,
+	}
+	if hasComments(s.codec) {
+		data = append(formatStoreHeader(s.comments, metadata), data...)
+	}
+
 	if err := renameio.WriteFile(s.path, data, 0600); err != nil {
 		return err
 	}
 
 	s.servers = servers
+	s.metadata = metadata
 
 	return nil
 }
+
+// Metadata returns the provenance metadata of the last update of the file.
+//
+// It's always zero with codecs that don't support comments, such as JSON.
+func (s *FileNodeStore) Metadata() StoreMetadata {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.metadata
+}
+
+// SetUpdatedBy sets the name recorded as author of the next updates of the
+// file. The default is the name of the running program.
+func (s *FileNodeStore) SetUpdatedBy(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updatedBy = name
+}
+
+// Prefix of the comment lines holding store metadata.
+const storeMetadataPrefix = "# cowsql-"
+
+// Return true if the given codec supports comments starting with '#'.
+func hasComments(codec NodeStoreCodec) bool {
+	return codec == YamlCodec || codec == TOMLCodec
+}
+
+// Parse the leading comment lines of the given store file, separating the
+// metadata ones from the others.
+func parseStoreHeader(codec NodeStoreCodec, data []byte) ([]string, StoreMetadata) {
+	comments := []string{}
+	metadata := StoreMetadata{}
+	if !hasComments(codec) {
+		return comments, metadata
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if !strings.HasPrefix(line, storeMetadataPrefix) {
+			comments = append(comments, line)
+			continue
+		}
+		parts := strings.SplitN(line[len(storeMetadataPrefix):], ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch parts[0] {
+		case "generation":
+			metadata.Generation, _ = strconv.ParseUint(value, 10, 64)
+		case "updated-at":
+			metadata.UpdatedAt, _ = time.Parse(time.RFC3339, value)
+		case "updated-by":
+			metadata.UpdatedBy = value
+		}
+	}
+
+	return comments, metadata
+}
+
+// Format the leading comment lines of a store file.
+func formatStoreHeader(comments []string, metadata StoreMetadata) []byte {
+	buf := bytes.Buffer{}
+	for _, comment := range comments {
+		buf.WriteString(comment + "\n")
+	}
+	fmt.Fprintf(&buf, "%sgeneration: %d\n", storeMetadataPrefix, metadata.Generation)
+	fmt.Fprintf(&buf, "%supdated-at: %s\n", storeMetadataPrefix, metadata.UpdatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&buf, "%supdated-by: %s\n", storeMetadataPrefix, metadata.UpdatedBy)
+	return buf.Bytes()
+}
@@ -5,6 +5,9 @@ package client_test
 import (
 	"context"
 	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	cowsql "github.com/cowsql/go-cowsql"
@@ -23,26 +26,26 @@ func TestDefaultNodeStore(t *testing.T) {
 
 	// Set and get some targets.
 	err = store.Set(context.Background(), []client.NodeInfo{
-		{Address: "1.2.3.4:666"}, {Address: "5.6.7.8:666"}},
+		{ID: 1, Address: "1.2.3.4:666"}, {ID: 2, Address: "5.6.7.8:666", Role: client.StandBy}},
 	)
 	require.NoError(t, err)
 
 	servers, err := store.Get(context.Background())
 	assert.Equal(t, []client.NodeInfo{
 		{ID: uint64(1), Address: "1.2.3.4:666"},
-		{ID: uint64(1), Address: "5.6.7.8:666"}},
+		{ID: uint64(2), Address: "5.6.7.8:666", Role: client.StandBy}},
 		servers)
 
 	// Set and get some new targets.
 	err = store.Set(context.Background(), []client.NodeInfo{
-		{Address: "1.2.3.4:666"}, {Address: "9.9.9.9:666"},
+		{ID: 1, Address: "1.2.3.4:666"}, {ID: 1<<64 - 1, Address: "9.9.9.9:666", Role: client.Spare},
 	})
 	require.NoError(t, err)
 
 	servers, err = store.Get(context.Background())
 	assert.Equal(t, []client.NodeInfo{
 		{ID: uint64(1), Address: "1.2.3.4:666"},
-		{ID: uint64(1), Address: "9.9.9.9:666"}},
+		{ID: uint64(1<<64 - 1), Address: "9.9.9.9:666", Role: client.Spare}},
 		servers)
 
 	// Setting duplicate targets returns an error and the change is not
@@ -55,10 +58,62 @@ func TestDefaultNodeStore(t *testing.T) {
 	servers, err = store.Get(context.Background())
 	assert.Equal(t, []client.NodeInfo{
 		{ID: uint64(1), Address: "1.2.3.4:666"},
-		{ID: uint64(1), Address: "9.9.9.9:666"}},
+		{ID: uint64(1<<64 - 1), Address: "9.9.9.9:666", Role: client.Spare}},
 		servers)
 }
 
+// A servers table created by an older version is upgraded to hold IDs and
+// roles.
+func TestDefaultNodeStore_Upgrade(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cowsql-store-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "servers.db")
+
+	db, err := sql.Open("sqlite3", filename)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE servers (address TEXT, UNIQUE(address))")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO servers(address) VALUES('1.2.3.4:666')")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	store, err := client.DefaultNodeStore(filename)
+	require.NoError(t, err)
+
+	servers, err := store.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []client.NodeInfo{{ID: uint64(1), Address: "1.2.3.4:666"}}, servers)
+
+	err = store.Set(context.Background(), []client.NodeInfo{{ID: 2, Address: "5.6.7.8:666", Role: client.StandBy}})
+	require.NoError(t, err)
+
+	servers, err = store.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []client.NodeInfo{{ID: uint64(2), Address: "5.6.7.8:666", Role: client.StandBy}}, servers)
+}
+
+// A custom table without id and role columns only persists addresses.
+func TestNodeStore_AddressOnly(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE nodes (addr TEXT)")
+	require.NoError(t, err)
+
+	store := client.NewNodeStore(db, "main", "nodes", "addr")
+
+	err = store.Set(context.Background(), []client.NodeInfo{{ID: 2, Address: "5.6.7.8:666", Role: client.StandBy}})
+	require.NoError(t, err)
+
+	servers, err := store.Get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []client.NodeInfo{{ID: uint64(1), Address: "5.6.7.8:666"}}, servers)
+}
+
 func TestConfigMultiThread(t *testing.T) {
 	cleanup := dummyDBSetup(t)
 	defer cleanup()
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version is the version of this go-cowsql module.
+const Version = "1.14.0"
+
+// ErrVersionSkew is returned by CheckVersionSkew when the nodes of a cluster
+// run versions that are too far apart.
+var ErrVersionSkew = errors.New("version skew")
+
+// NodeVersion holds the versions of the software run by a node.
+type NodeVersion struct {
+	Client string `json:"client"` // Version of go-cowsql
+	Engine string `json:"engine"` // Version of libcowsql
+}
+
+// Prefix of the configuration registry keys holding node versions.
+const versionsKeyPrefix = "versions/"
+
+// SetVersion publishes the versions run by the node with the given ID in the
+// cluster-wide configuration registry, see SetLabels.
+func (c *Client) SetVersion(ctx context.Context, id uint64, version NodeVersion) error {
+	data, err := json.Marshal(version)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode version")
+	}
+
+	if err := c.setNodeConfig(ctx, versionsKeyPrefix, id, string(data)); err != nil {
+		return errors.Wrapf(err, "failed to set version of node %x", id)
+	}
+
+	return nil
+}
+
+// Versions returns the versions published by the nodes of the cluster with
+// SetVersion, keyed by node ID.
+//
+// As with GetConfig, the client must be connected to the leader.
+func (c *Client) Versions(ctx context.Context) (map[uint64]NodeVersion, error) {
+	values, err := c.nodeConfig(ctx, versionsKeyPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get versions")
+	}
+
+	versions := make(map[uint64]NodeVersion, len(values))
+	for id, value := range values {
+		version := NodeVersion{}
+		if err := json.Unmarshal([]byte(value), &version); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode version of node %x", id)
+		}
+		versions[id] = version
+	}
+
+	return versions, nil
+}
+
+// CheckVersionSkew checks that the go-cowsql and libcowsql versions run by the
+// given nodes are within the given window of minor versions of each other.
+//
+// Nodes running different major versions are always considered skewed.
+// Versions that can't be parsed are ignored. If the check fails, the
+// returned error wraps ErrVersionSkew.
+func CheckVersionSkew(versions map[uint64]NodeVersion, window int) error {
+	var clients, engines []string
+	for _, version := range versions {
+		clients = append(clients, version.Client)
+		engines = append(engines, version.Engine)
+	}
+	if err := checkSkew(clients, window); err != nil {
+		return errors.Wrapf(ErrVersionSkew, "go-cowsql %s", err)
+	}
+	if err := checkSkew(engines, window); err != nil {
+		return errors.Wrapf(ErrVersionSkew, "libcowsql %s", err)
+	}
+	return nil
+}
+
+// Check that the given versions are within the given window of minor
+// versions of each other.
+func checkSkew(versions []string, window int) error {
+	var min, max [2]int
+	found := false
+	for _, version := range versions {
+		v, ok := parseVersion(version)
+		if !ok {
+			continue
+		}
+		if !found || v[0] < min[0] || (v[0] == min[0] && v[1] < min[1]) {
+			min = v
+		}
+		if !found || v[0] > max[0] || (v[0] == max[0] && v[1] > max[1]) {
+			max = v
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	if min[0] != max[0] || max[1]-min[1] > window {
+		return fmt.Errorf("versions range from %d.%d to %d.%d, more than %d minor versions apart",
+			min[0], min[1], max[0], max[1], window)
+	}
+	return nil
+}
+
+// Parse the major and minor numbers of a major.minor[.patch] version.
+func parseVersion(version string) ([2]int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return [2]int{}, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return [2]int{}, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return [2]int{}, false
+	}
+	return [2]int{major, minor}, true
+}
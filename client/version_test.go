@@ -0,0 +1,63 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckVersionSkew(t *testing.T) {
+	cases := []struct {
+		title    string
+		versions []client.NodeVersion
+		window   int
+		err      string
+	}{{
+		"same versions",
+		[]client.NodeVersion{{"1.14.0", "1.14.2"}, {"1.14.0", "1.14.2"}},
+		0,
+		"",
+	}, {
+		"patch versions differ",
+		[]client.NodeVersion{{"1.14.0", "1.14.2"}, {"1.14.3", "1.14.0"}},
+		0,
+		"",
+	}, {
+		"within window",
+		[]client.NodeVersion{{"1.14.0", "1.14.0"}, {"1.15.0", "1.15.1"}},
+		1,
+		"",
+	}, {
+		"client skew",
+		[]client.NodeVersion{{"1.14.0", "1.14.0"}, {"1.16.0", "1.14.0"}, {"1.15.0", "1.14.0"}},
+		1,
+		"go-cowsql versions range from 1.14 to 1.16, more than 1 minor versions apart: version skew",
+	}, {
+		"engine major skew",
+		[]client.NodeVersion{{"1.14.0", "1.14.0"}, {"1.14.0", "2.0.0"}},
+		1,
+		"libcowsql versions range from 1.14 to 2.0, more than 1 minor versions apart: version skew",
+	}, {
+		"unparsable versions are ignored",
+		[]client.NodeVersion{{"1.14.0", "1.14.0"}, {"devel", ""}},
+		0,
+		"",
+	}}
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			versions := map[uint64]client.NodeVersion{}
+			for i, version := range c.versions {
+				versions[uint64(i+1)] = version
+			}
+			err := client.CheckVersionSkew(versions, c.window)
+			if c.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, client.ErrVersionSkew))
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cowsql/go-cowsql/app"
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	cmd := &cobra.Command{
+		Use:   "cowsql-app",
+		Short: "Maintenance tools for the data directory of go-cowsql app nodes",
+	}
+	cmd.AddCommand(newStore())
+	cmd.AddCommand(newReconfigure())
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newStore() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "store",
+		Short: "Show or update the nodes in the cluster.yaml file of a node",
+	}
+
+	show := &cobra.Command{
+		Use:   "show <dir>",
+		Short: "Print the nodes in the cluster.yaml file of the given data directory",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			data, err := ioutil.ReadFile(filepath.Join(args[0], "cluster.yaml"))
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(data))
+			return nil
+		},
+	}
+
+	set := &cobra.Command{
+		Use:   "set <dir> <file>",
+		Short: "Replace the nodes in the cluster.yaml file of the given data directory",
+		Long: `Replace the nodes in the cluster.yaml file of the given data directory with
+the ones in the given YAML file, or standard input if the file is "-".
+
+The file has the same format as cluster.yaml. The nodes are validated and the
+file is replaced atomically. The node should be stopped, a running node picks
+up the change only the next time it fails to find the leader.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			var data []byte
+			var err error
+			if args[1] == "-" {
+				data, err = ioutil.ReadAll(os.Stdin)
+			} else {
+				data, err = ioutil.ReadFile(args[1])
+			}
+			if err != nil {
+				return err
+			}
+
+			nodes, err := client.YamlCodec.Unmarshal(data)
+			if err != nil {
+				return fmt.Errorf("parse nodes: %w", err)
+			}
+
+			return app.UpdateStoreFile(args[0], nodes)
+		},
+	}
+
+	cmd.AddCommand(show, set)
+
+	return cmd
+}
+
+func newReconfigure() *cobra.Command {
+	var confirm string
+
+	cmd := &cobra.Command{
+		Use:   "reconfigure <dir> <file>",
+		Short: "Force a new cluster configuration onto the data directory of a node (UNSAFE)",
+		Long: `Force the cluster configuration in the given YAML file onto the raft log of the
+given data directory, and replace its cluster.yaml file accordingly.
+
+THIS IS AN UNSAFE, LAST-RESORT RECOVERY PROCEDURE, for clusters that lost the
+majority of their voters. Entries not replicated to this node are lost, and
+using it on a cluster that can still make progress leads to divergent logs.
+
+  0. Back up the data directories of all nodes.
+  1. Stop all nodes.
+  2. Pick the node with the most recent raft log (see cowsql-inspect).
+  3. Run this command without --confirm to print the confirmation token,
+     then run it again passing the token with --confirm.
+  4. Copy the raft files and cluster.yaml of this node to the other nodes
+     of the new configuration, then start them.
+
+The file has the same format as cluster.yaml, or is read from standard input
+if it's "-". All nodes must have an ID and at least one must be a voter.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			var data []byte
+			var err error
+			if args[1] == "-" {
+				data, err = ioutil.ReadAll(os.Stdin)
+			} else {
+				data, err = ioutil.ReadFile(args[1])
+			}
+			if err != nil {
+				return err
+			}
+
+			nodes, err := client.YamlCodec.Unmarshal(data)
+			if err != nil {
+				return fmt.Errorf("parse nodes: %w", err)
+			}
+
+			if confirm == "" {
+				token, err := app.ForceReconfigureToken(args[0], nodes)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "WARNING: forcing a new configuration bypasses raft consensus and can lose data.\n")
+				fmt.Fprintf(os.Stderr, "Back up all data directories and stop all nodes before proceeding.\n")
+				fmt.Fprintf(os.Stderr, "To proceed, run again with --confirm %s\n", token)
+				return fmt.Errorf("confirmation required")
+			}
+
+			if err := app.ForceReconfigure(args[0], nodes, confirm); err != nil {
+				return err
+			}
+
+			fmt.Println("OK")
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&confirm, "confirm", "", "confirmation token printed by a previous run")
+
+	return cmd
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/cowsql/go-cowsql/app"
 	"github.com/cowsql/go-cowsql/benchmark"
+	"github.com/cowsql/go-cowsql/client"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"golang.org/x/sys/unix"
@@ -22,6 +23,7 @@ const (
 	defaultDurationS      = 60
 	defaultKvKeySize      = 32
 	defaultKvValueSize    = 1024
+	defaultMemoryInterval = 1000
 	defaultWorkers        = 1
 	defaultWorkload       = "kvwrite"
 	docString             = "For benchmarking cowsql.\n\n" +
@@ -39,7 +41,12 @@ const (
 		"cowsql-benchmark --db 127.0.0.1:9003 --join 127.0.0.1:9001 --driver --cluster 127.0.0.1:9001,127.0.0.1:9002,127.0.0.1:9003 &\n\n" +
 		"The results can be found on the `driver` node in " + defaultDir + "/results or in the directory provided to the tool.\n" +
 		"Benchmark results are files named `n-q-timestamp` where `n` is the number of the worker,\n" +
-		"`q` is the type of query that was tracked. All results in the file are in milliseconds.\n"
+		"`q` is the type of query that was tracked. All results in the file are in milliseconds.\n" +
+		"Memory usage is sampled during the run and written to a file named `memory-timestamp`.\n" +
+		"Queries started during the `--warmup` period are not included in the results.\n\n" +
+		"Replay a captured SQL trace against a 1 node cluster, preserving its timing:\n" +
+		"cowsql-benchmark -d 127.0.0.1:9001 --driver --cluster 127.0.0.1:9001 --workload replay --trace trace.jsonl\n\n" +
+		"TLS can be enabled with the `--cert` and `--key` flags, which must be given to all nodes.\n"
 )
 
 func signalChannel() chan os.Signal {
@@ -52,15 +59,25 @@ func signalChannel() chan os.Signal {
 }
 
 func main() {
+	var ca string
 	var cluster *[]string
 	var clusterTimeout int
+	var crt string
 	var db string
 	var dir string
 	var driver bool
 	var duration int
+	var external bool
 	var join *[]string
+	var key string
+	var killLeaderAt int
 	var kvKeySize int
 	var kvValueSize int
+	var maxSpeed bool
+	var memoryInterval int
+	var progress int
+	var trace string
+	var warmup int
 	var workers int
 	var workload string
 
@@ -74,7 +91,26 @@ func main() {
 				return errors.Wrapf(err, "can't create %s", dir)
 			}
 
-			app, err := app.New(dir, app.WithAddress(db), app.WithCluster(*join))
+			listen, dial, err := loadTLS(crt, key, ca)
+			if err != nil {
+				return err
+			}
+
+			options := []app.Option{app.WithAddress(db), app.WithCluster(*join)}
+			dialFunc := client.DefaultDialFunc
+			if external {
+				acceptCh, err := listenExternal(db, listen)
+				if err != nil {
+					return errors.Wrapf(err, "can't listen on %s", db)
+				}
+				dialFunc = dialExternal(dial)
+				options = append(options, app.WithExternalConn(dialFunc, acceptCh))
+			} else if listen != nil {
+				dialFunc = client.DialFuncWithTLS(dialFunc, dial)
+				options = append(options, app.WithTLS(listen, dial))
+			}
+
+			app, err := app.New(dir, options...)
 			if err != nil {
 				return err
 			}
@@ -116,6 +152,13 @@ func main() {
 				benchmark.WithKvValueSize(kvValueSize),
 				benchmark.WithCluster(*cluster),
 				benchmark.WithClusterTimeout(clusterTimeout),
+				benchmark.WithMemoryInterval(memoryInterval),
+				benchmark.WithDialFunc(dialFunc),
+				benchmark.WithTrace(trace),
+				benchmark.WithMaxSpeed(maxSpeed),
+				benchmark.WithWarmup(warmup),
+				benchmark.WithProgressInterval(progress),
+				benchmark.WithFailoverAt(killLeaderAt),
 			)
 			if err != nil {
 				return err
@@ -138,12 +181,25 @@ func main() {
 		"The driver will wait for all nodes to be online before running the benchmark.")
 	flags.IntVar(&clusterTimeout, "cluster-timeout", defaultClusterTimeout, "How long the benchmark should wait in seconds for the whole cluster to be online.")
 	flags.StringVarP(&dir, "dir", "D", defaultDir, "Data directory.")
-	flags.StringVarP(&workload, "workload", "w", defaultWorkload, "The workload to run: \"kvwrite\" or \"kvreadwrite\".")
+	flags.StringVarP(&workload, "workload", "w", defaultWorkload, "The workload to run: \"kvwrite\", \"kvreadwrite\" or \"replay\".")
+	flags.StringVar(&trace, "trace", "", "Trace file to run with the \"replay\" workload, holding one JSON object per line\n"+
+		"with \"timestamp\" (in ns), \"statement\" and \"params\" keys.")
+	flags.BoolVar(&maxSpeed, "max-speed", false, "Replay the trace as fast as possible instead of preserving its timing.")
 	flags.BoolVar(&driver, "driver", defaultDriver, "Set this flag to run the benchmark from this instance. Must be set on 1 node.")
 	flags.IntVar(&duration, "duration", defaultDurationS, "Run duration in seconds.")
+	flags.IntVar(&warmup, "warmup", 0, "Warm-up duration in seconds, run before --duration and excluded from the results.")
+	flags.IntVar(&progress, "progress", 0, "How often progress is printed in seconds, 0 to disable.")
+	flags.IntVar(&killLeaderAt, "kill-leader-at", 0, "Transfer leadership away from the leader after the given number of seconds\n"+
+		"and record the availability gap in a file named `failover-timestamp`, 0 to disable.")
 	flags.IntVar(&workers, "workers", defaultWorkers, "Number of workers executing the workload.")
 	flags.IntVar(&kvKeySize, "key-size", defaultKvKeySize, "Size of the KV keys in bytes.")
 	flags.IntVar(&kvValueSize, "value-size", defaultKvValueSize, "Size of the KV values in bytes.")
+	flags.StringVar(&crt, "cert", "", "Public TLS certificate.")
+	flags.StringVar(&key, "key", "", "Private TLS key.")
+	flags.StringVar(&ca, "ca", "", "TLS certificate of the CA signing the nodes certificates, defaults to --cert.")
+	flags.BoolVar(&external, "external-conn", false, "Serve and dial cowsql connections through an HTTP upgrade instead of a\n"+
+		"dedicated listener, as applications using app.WithExternalConn() do. Must be set on all nodes.")
+	flags.IntVar(&memoryInterval, "memory-interval", defaultMemoryInterval, "How often memory usage is sampled in milliseconds, 0 to disable.")
 
 	cmd.MarkFlagRequired("db")
 	if err := cmd.Execute(); err != nil {
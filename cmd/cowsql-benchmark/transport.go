@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/cowsql/go-cowsql/app"
+	"github.com/cowsql/go-cowsql/client"
+)
+
+const upgradeProtocol = "cowsql"
+
+// Load the TLS configurations for accepting and dialing connections. If no CA
+// is given, the certificate itself is used as the trusted authority.
+func loadTLS(crt, key, ca string) (*tls.Config, *tls.Config, error) {
+	if (crt != "" && key == "") || (key != "" && crt == "") {
+		return nil, nil, fmt.Errorf("both TLS certificate and key must be given")
+	}
+	if crt == "" {
+		if ca != "" {
+			return nil, nil, fmt.Errorf("TLS CA given without certificate and key")
+		}
+		return nil, nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(crt, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ca == "" {
+		ca = crt
+	}
+	data, err := ioutil.ReadFile(ca)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, nil, fmt.Errorf("bad CA certificate")
+	}
+
+	listen, dial := app.SimpleTLSConfig(cert, pool)
+	return listen, dial, nil
+}
+
+// Serve HTTP on the given address, handing over to the returned channel the
+// connections that were upgraded to the cowsql protocol.
+//
+// This mimics applications which multiplex cowsql traffic over their own HTTP
+// endpoint, and is meant to be used with app.WithExternalConn().
+func listenExternal(address string, config *tls.Config) (chan net.Conn, error) {
+	var listener net.Listener
+	var err error
+	if config != nil {
+		listener, err = tls.Listen("tcp", address, config)
+	} else {
+		listener, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	acceptCh := make(chan net.Conn)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != upgradeProtocol {
+			http.Error(w, "missing or invalid upgrade header", http.StatusBadRequest)
+			return
+		}
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "webserver doesn't support hijacking", http.StatusInternalServerError)
+			return
+		}
+
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := "HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: " + upgradeProtocol + "\r\n" +
+			"Connection: Upgrade\r\n\r\n"
+		if _, err := conn.Write([]byte(response)); err != nil {
+			conn.Close()
+			return
+		}
+
+		acceptCh <- conn
+	}
+
+	go http.Serve(listener, http.HandlerFunc(handler))
+
+	return acceptCh, nil
+}
+
+// Return a dial function that connects to endpoints served by
+// listenExternal().
+func dialExternal(config *tls.Config) client.DialFunc {
+	return func(ctx context.Context, address string) (net.Conn, error) {
+		dialer := &net.Dialer{}
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		if config != nil {
+			conn = tls.Client(conn, config.Clone())
+		}
+
+		request, err := http.NewRequest("GET", "http://"+address, nil)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		request.Header.Set("Upgrade", upgradeProtocol)
+		request.Header.Set("Connection", "Upgrade")
+
+		if err := request.Write(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		response, err := http.ReadResponse(bufio.NewReader(conn), request)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if response.StatusCode != http.StatusSwitchingProtocols {
+			conn.Close()
+			return nil, fmt.Errorf("upgrade to cowsql protocol failed: %s", response.Status)
+		}
+
+		return conn, nil
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cowsql/go-cowsql/app"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "cowsql-inspect <dir>",
+		Short: "Inspect the data directory of a stopped go-cowsql app node",
+		Long: `Print what the data directory of a go-cowsql app node contains: the node ID
+and address, the cluster members as last known by the node, the raft snapshots
+and log segments, and the databases found in the raft data.
+
+The node is not started and no file is modified, so it's safe to use for
+post-mortem analysis. The node should be stopped, otherwise the output might
+be inconsistent.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			info, err := app.InspectDir(args[0])
+			if err != nil {
+				return err
+			}
+
+			switch format {
+			case "text":
+				printText(info)
+			case "json":
+				data, err := json.MarshalIndent(info, "", "\t")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			default:
+				return fmt.Errorf("unknown format %q", format)
+			}
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&format, "format", "f", "text", "output format (text or json)")
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func printText(info *app.DirInfo) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Node:\t%x (%s)\n", info.Node.ID, info.Node.Address)
+	if info.Joining {
+		fmt.Fprintf(w, "Joining:\tyes\n")
+	}
+	fmt.Fprintf(w, "Last entry:\tterm %d, index %d\n", info.LastEntry.Term, info.LastEntry.Index)
+	fmt.Fprintf(w, "Databases:\t%s\n", strings.Join(info.Databases, ", "))
+	if info.Store.Generation > 0 {
+		fmt.Fprintf(w, "Cluster updated:\tgeneration %d at %s by %s\n",
+			info.Store.Generation, info.Store.UpdatedAt.Format(time.RFC3339), info.Store.UpdatedBy)
+	}
+	w.Flush()
+
+	fmt.Println("\nCluster:")
+	for _, node := range info.Cluster {
+		fmt.Fprintf(w, "  %x\t%s\t%s\n", node.ID, node.Address, node.Role)
+	}
+	w.Flush()
+
+	fmt.Println("\nSnapshots:")
+	for _, snapshot := range info.Snapshots {
+		fmt.Fprintf(w, "  %s\tterm %d\tindex %d\t%s\t%d bytes\n",
+			snapshot.Name, snapshot.Term, snapshot.Index, snapshot.Timestamp.Format(time.RFC3339), snapshot.Size)
+	}
+	w.Flush()
+
+	fmt.Println("\nSegments:")
+	for _, segment := range info.Segments {
+		partial := ""
+		if segment.Partial >= 0 {
+			partial = fmt.Sprintf("\tpartial batch at %d", segment.Partial)
+		}
+		fmt.Fprintf(w, "  %s\t%d entries\tterm %d\t%d bytes%s\n",
+			segment.Name, segment.Entries, segment.Term, segment.Size, partial)
+	}
+	w.Flush()
+
+	if len(info.Problems) > 0 {
+		fmt.Println("\nProblems:")
+		for _, problem := range info.Problems {
+			fmt.Printf("  %s\n", problem)
+		}
+	}
+}
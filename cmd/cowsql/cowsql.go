@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -22,12 +23,23 @@ func main() {
 	var key string
 	var servers *[]string
 	var format string
+	var command string
 
 	cmd := &cobra.Command{
 		Use:   "cowsql -s <servers> <database> [command]",
 		Short: "Standard cowsql shell",
-		Args:  cobra.RangeArgs(1, 2),
+		Long: `Standard cowsql shell.
+
+Statements are read from the [command] argument or the --command flag if
+given, otherwise from standard input if it's not a terminal, otherwise from an
+interactive prompt. In the first two cases the shell stops at the first
+failing statement, printing the error on standard error and exiting with
+status 1.`,
+		Args: cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Errors past this point are not about the command line usage.
+			cmd.SilenceUsage = true
+
 			if len(*servers) == 0 {
 				return fmt.Errorf("no servers provided")
 			}
@@ -89,6 +101,13 @@ func main() {
 				return err
 			}
 
+			if command != "" {
+				if len(args) > 1 {
+					return fmt.Errorf("can't mix --command and command argument")
+				}
+				args = append(args, command)
+			}
+
 			if len(args) > 1 {
 				for _, input := range strings.Split(args[1], ";") {
 					result, err := sh.Process(context.Background(), input)
@@ -101,6 +120,10 @@ func main() {
 				return nil
 			}
 
+			if !isTerminal(os.Stdin) {
+				return processBatch(sh, os.Stdin)
+			}
+
 			line := liner.NewLiner()
 			defer line.Close()
 
@@ -133,10 +156,130 @@ func main() {
 	flags.StringVarP(&crt, "cert", "c", "", "public TLS cert")
 	flags.StringVarP(&key, "key", "k", "", "private TLS key")
 	flags.StringVarP(&format, "format", "f", "tabular", "output format (tabular, json)")
+	flags.StringVarP(&command, "command", "e", "", "semicolon-separated statements to execute, instead of the interactive prompt")
 
 	cmd.MarkFlagRequired("servers")
 
+	cmd.AddCommand(newValidate())
+
 	if err := cmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
+
+func newValidate() *cobra.Command {
+	var address string
+	var cluster []string
+
+	cmd := &cobra.Command{
+		Use:   "validate <dir>",
+		Short: "Check the data directory of a go-cowsql app node before starting it",
+		Long: `Check the info.yaml and cluster.yaml files and the raft data in the data
+directory of a go-cowsql app node, applying the same rules as app.New, and
+print each problem found along with how to fix it.
+
+The --address and --cluster flags should match the app.WithAddress and
+app.WithCluster options that the node is going to be started with.
+
+The exit status is 1 if any problem is found.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			problems, err := app.ValidateDir(args[0], app.WithAddress(address), app.WithCluster(cluster))
+			if err != nil {
+				return err
+			}
+			for _, problem := range problems {
+				fmt.Println(problem)
+				switch {
+				case problem.Repair != "":
+					fmt.Printf("  fix: %s (done automatically with app.WithAutoRepair)\n", problem.Repair)
+				case problem.Hint != "":
+					fmt.Printf("  fix: %s\n", problem.Hint)
+				}
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %d problems", len(problems))
+			}
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&address, "address", "a", "", "address the node is going to be started with")
+	flags.StringSliceVarP(&cluster, "cluster", "C", nil, "addresses of existing nodes the node is going to join")
+
+	return cmd
+}
+
+// Return true if the given file is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Execute the statements read from the given reader, stopping at the first
+// error.
+//
+// Lines starting with a dot are shell commands, everything else is SQL text
+// which can span multiple lines and is executed whenever a line ends with a
+// semicolon.
+func processBatch(sh *shell.Shell, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var statement strings.Builder
+	start := 0 // Line where the current statement starts
+
+	process := func(input string) error {
+		result, err := sh.Process(context.Background(), input)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", start, err)
+		}
+		if result != "" {
+			fmt.Println(result)
+		}
+		return nil
+	}
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if statement.Len() == 0 {
+			start = n
+			if strings.HasPrefix(line, ".") {
+				if err := process(line); err != nil {
+					return err
+				}
+				continue
+			}
+		} else {
+			statement.WriteByte('\n')
+		}
+
+		statement.WriteString(line)
+		if strings.HasSuffix(line, ";") {
+			if err := process(statement.String()); err != nil {
+				return err
+			}
+			statement.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if statement.Len() > 0 {
+		return process(statement.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package driver
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+type cacheKey struct{}
+
+// WithCache returns a copy of the given context that makes the queries run
+// with it use the result cache of the driver, if enabled with
+// WithQueryCache.
+//
+// Only use it for queries whose results can be stale until the cache is
+// invalidated with Driver.InvalidateCache or the entry expires, for example
+// for read-mostly configuration tables. Cached results don't reflect the
+// changes made by the transaction the query runs in, if any.
+func WithCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheKey{}, true)
+}
+
+// Return whether the given context enables the result cache.
+func cacheEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(cacheKey{}).(bool)
+	return enabled
+}
+
+// WithQueryCache enables a cache of the results of the queries run with a
+// context returned by WithCache, keyed on their SQL text and arguments and
+// scoped per database, so repeated queries don't hit the leader.
+//
+// Entries expire after the given TTL, and the least recently used ones are
+// evicted when the total size of the cached rows exceeds the given number of
+// bytes. Results larger than that are not cached. Only queries executed
+// directly are cached, not the ones executed through explicitly prepared
+// statements.
+func WithQueryCache(size int64, ttl time.Duration) Option {
+	return func(options *options) {
+		options.CacheSize = size
+		options.CacheTTL = ttl
+	}
+}
+
+// InvalidateCache removes the cached results of the queries against the
+// given database, or of all queries if the database is empty.
+//
+// Applications should call it after changing data whose queries are cached,
+// on every process with a cache.
+func (d *Driver) InvalidateCache(database string) {
+	if d.cache != nil {
+		d.cache.invalidate(database)
+	}
+}
+
+// Cached result of a query.
+type cacheEntry struct {
+	key      string
+	database string
+	columns  []string
+	types    []string
+	rows     [][]driver.Value
+	size     int64
+	expires  time.Time
+}
+
+// LRU cache of query results.
+type queryCache struct {
+	mu      sync.Mutex
+	size    int64         // Maximum total size of the entries
+	ttl     time.Duration // Lifetime of the entries
+	used    int64         // Current total size of the entries
+	lru     *list.List    // Entries, most recently used first
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+func newQueryCache(size int64, ttl time.Duration) *queryCache {
+	return &queryCache{
+		size:    size,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		now:     time.Now,
+	}
+}
+
+// Return the cache key of the given query.
+func cacheKeyOf(database string, query string, args []driver.NamedValue) string {
+	key := strings.Builder{}
+	key.WriteString(database)
+	key.WriteByte(0)
+	key.WriteString(query)
+	for _, arg := range args {
+		fmt.Fprintf(&key, "\x00%s:%d:%T:%v", arg.Name, arg.Ordinal, arg.Value, arg.Value)
+	}
+	return key.String()
+}
+
+// Return the cached result for the given key, if any and not expired.
+func (c *queryCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(element)
+		return nil
+	}
+	c.lru.MoveToFront(element)
+
+	return entry
+}
+
+// Add the given result to the cache, evicting older entries if needed.
+func (c *queryCache) put(key, database string, columns, types []string, rows [][]driver.Value) {
+	entry := &cacheEntry{
+		key:      key,
+		database: database,
+		columns:  columns,
+		types:    types,
+		rows:     rows,
+		expires:  c.now().Add(c.ttl),
+	}
+	for _, row := range rows {
+		entry.size += rowSize(row)
+	}
+	if entry.size > c.size {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	for c.used+entry.size > c.size {
+		c.remove(c.lru.Back())
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.used += entry.size
+}
+
+// Remove the entries of the given database, or all entries.
+func (c *queryCache) invalidate(database string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := c.lru.Front(); element != nil; {
+		next := element.Next()
+		if database == "" || element.Value.(*cacheEntry).database == database {
+			c.remove(element)
+		}
+		element = next
+	}
+}
+
+// Remove the given element. Must be called with the lock held.
+func (c *queryCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.used -= entry.size
+}
+
+// Return rows iterating over the given cached result.
+func (c *Conn) cachedRows(ctx context.Context, entry *cacheEntry) *Rows {
+	buffer := newRowBuffer(0, "")
+	buffer.rows = append(buffer.rows, entry.rows...)
+
+	r := &Rows{
+		ctx:      ctx,
+		conn:     c,
+		consumed: true,
+		types:    entry.types,
+		log:      c.logger(ctx),
+		mapper:   c.mapper,
+		buffer:   buffer,
+	}
+	r.rows.Columns = entry.columns
+
+	return r
+}
+
+// Read all the given rows, which must be in their first batch, and add them
+// to the cache.
+func (c *Conn) cacheRows(r *Rows, key string) error {
+	if err := r.fill(math.MaxInt64, ""); err != nil {
+		return err
+	}
+
+	rows := append([][]driver.Value{}, r.buffer.rows...)
+	c.cache.put(key, c.database, r.rows.Columns, r.types, rows)
+
+	return nil
+}
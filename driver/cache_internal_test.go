@@ -0,0 +1,90 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheKeyOf(t *testing.T) {
+	args := func(values ...interface{}) []driver.NamedValue {
+		named := make([]driver.NamedValue, len(values))
+		for i, value := range values {
+			named[i] = driver.NamedValue{Ordinal: i + 1, Value: value}
+		}
+		return named
+	}
+
+	key := cacheKeyOf("test", "SELECT ?", args(int64(1)))
+	assert.Equal(t, key, cacheKeyOf("test", "SELECT ?", args(int64(1))))
+	assert.NotEqual(t, key, cacheKeyOf("other", "SELECT ?", args(int64(1))))
+	assert.NotEqual(t, key, cacheKeyOf("test", "SELECT ?", args("1")))
+	assert.NotEqual(t, key, cacheKeyOf("test", "SELECT ?", args(int64(2))))
+}
+
+func TestQueryCache(t *testing.T) {
+	now := time.Now()
+	cache := newQueryCache(64, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	row := []driver.Value{int64(1)} // 16 bytes
+	cache.put("a", "test", []string{"n"}, []string{"INTEGER"}, [][]driver.Value{row})
+	cache.put("b", "test", []string{"n"}, []string{"INTEGER"}, [][]driver.Value{row, row})
+	cache.put("c", "other", []string{"n"}, []string{"INTEGER"}, [][]driver.Value{row})
+
+	entry := cache.get("a")
+	require.NotNil(t, entry)
+	assert.Equal(t, []string{"n"}, entry.columns)
+	assert.Len(t, entry.rows, 1)
+
+	// Too large to be cached.
+	cache.put("d", "test", nil, nil, [][]driver.Value{row, row, row, row, row})
+	assert.Nil(t, cache.get("d"))
+
+	// Evicts the least recently used entry, "b".
+	cache.put("e", "test", nil, nil, [][]driver.Value{row})
+	assert.Nil(t, cache.get("b"))
+	assert.NotNil(t, cache.get("a"))
+	assert.Equal(t, int64(48), cache.used)
+
+	cache.invalidate("test")
+	assert.Nil(t, cache.get("a"))
+	assert.Nil(t, cache.get("e"))
+	assert.NotNil(t, cache.get("c"))
+
+	// Expired.
+	now = now.Add(time.Minute)
+	assert.Nil(t, cache.get("c"))
+	assert.Equal(t, int64(0), cache.used)
+}
+
+func TestConn_CachedRows(t *testing.T) {
+	cache := newQueryCache(1024, time.Minute)
+	cache.put("a", "test", []string{"n"}, []string{"INTEGER"}, [][]driver.Value{{int64(1)}, {int64(2)}})
+	conn := &Conn{cache: cache, metrics: newMetrics()}
+
+	// Rows can be iterated more than once.
+	for i := 0; i < 2; i++ {
+		rows := conn.cachedRows(context.Background(), cache.get("a"))
+		assert.Equal(t, []string{"n"}, rows.Columns())
+		assert.Equal(t, "INTEGER", rows.ColumnTypeDatabaseTypeName(0))
+
+		dest := make([]driver.Value, 1)
+		require.NoError(t, rows.Next(dest))
+		assert.Equal(t, int64(1), dest[0])
+		require.NoError(t, rows.Next(dest))
+		assert.Equal(t, int64(2), dest[0])
+		assert.Equal(t, io.EOF, rows.Next(dest))
+		require.NoError(t, rows.Close())
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	assert.False(t, cacheEnabled(context.Background()))
+	assert.True(t, cacheEnabled(WithCache(context.Background())))
+}
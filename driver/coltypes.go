@@ -0,0 +1,63 @@
+package driver
+
+import (
+	"sync"
+)
+
+// WithColumnTypeCache makes the driver remember the column types of the
+// results of up to the given number of distinct queries, keyed by SQL text.
+//
+// The server only sends the type of each column along with the rows, so the
+// types of an empty result set are unknown. With this option,
+// Rows.ColumnTypeDatabaseTypeName and Rows.ColumnTypeScanType report the
+// types of the last non-empty result of the same query instead.
+//
+// Types are those of the values of the cached result, so a column whose
+// values have different types across rows or results might be reported with
+// the wrong type.
+func WithColumnTypeCache(size int) Option {
+	return func(options *options) {
+		options.ColumnTypeCacheSize = size
+	}
+}
+
+// Cache the column types of query results, evicting the oldest entry when
+// full. All methods are no-ops on a nil cache.
+type columnTypes struct {
+	mu    sync.Mutex
+	size  int
+	types map[string][]string
+	order []string // Queries in insertion order
+}
+
+func newColumnTypes(size int) *columnTypes {
+	return &columnTypes{size: size, types: map[string][]string{}}
+}
+
+// Return the cached column types of the given query, or nil.
+func (c *columnTypes) get(query string) []string {
+	if c == nil || query == "" {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.types[query]
+}
+
+// Save the column types of the given query.
+func (c *columnTypes) put(query string, types []string) {
+	if c == nil || query == "" || len(types) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.types[query]; !ok {
+		if len(c.order) == c.size {
+			delete(c.types, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, query)
+	}
+	c.types[query] = types
+}
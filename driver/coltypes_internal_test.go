@@ -0,0 +1,32 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColumnTypes(t *testing.T) {
+	c := newColumnTypes(2)
+
+	c.put("SELECT a", []string{"INTEGER"})
+	c.put("SELECT b", []string{"TEXT"})
+	c.put("SELECT a", []string{"FLOAT"})
+	assert.Equal(t, []string{"FLOAT"}, c.get("SELECT a"))
+	assert.Equal(t, []string{"TEXT"}, c.get("SELECT b"))
+
+	// The oldest query is evicted.
+	c.put("SELECT c", []string{"BLOB"})
+	assert.Nil(t, c.get("SELECT a"))
+	assert.Equal(t, []string{"BLOB"}, c.get("SELECT c"))
+
+	// Empty types are not cached.
+	c.put("SELECT d", nil)
+	assert.Nil(t, c.get("SELECT d"))
+}
+
+func TestColumnTypes_Nil(t *testing.T) {
+	var c *columnTypes
+	c.put("SELECT a", []string{"INTEGER"})
+	assert.Nil(t, c.get("SELECT a"))
+}
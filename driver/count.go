@@ -0,0 +1,59 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RowCounter is the interface used by CountRows to run the counting query. It
+// is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type RowCounter interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// CountRows returns the number of rows that the given query would return with
+// the given arguments, for example to render a progress bar or a paginator
+// before iterating over the results.
+//
+// The count is exact: the query is wrapped in a SELECT COUNT(*) subquery, see
+// CountQuery, and executed on its own, so it costs about as much as running
+// the query once. Rows inserted or deleted between the count and the query
+// itself are not accounted for, unless both are run in the same transaction.
+func CountRows(ctx context.Context, db RowCounter, query string, args ...interface{}) (int64, error) {
+	count, err := CountQuery(query)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if err := db.QueryRowContext(ctx, count, args...).Scan(&n); err != nil {
+		return 0, errors.Wrap(err, "count rows")
+	}
+
+	return n, nil
+}
+
+// CountQuery returns a query returning the number of rows returned by the
+// given one, which must be a single SELECT, VALUES or WITH ... SELECT
+// statement. Parameters of the given query are preserved.
+func CountQuery(query string) (string, error) {
+	statements := splitStatements(query)
+	if len(statements) != 1 {
+		return "", errors.Errorf("expected a single statement, got %d", len(statements))
+	}
+	statement := strings.TrimSuffix(statements[0], ";")
+
+	fields := strings.Fields(statement)
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "VALUES", "WITH":
+	default:
+		return "", errors.Errorf("can't count rows of %s statement", strings.ToUpper(fields[0]))
+	}
+
+	// Put the closing parenthesis on its own line, in case the statement
+	// ends with a comment.
+	return "SELECT COUNT(*) FROM (" + statement + "\n)", nil
+}
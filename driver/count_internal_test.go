@@ -0,0 +1,36 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountQuery(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM test":                             "SELECT COUNT(*) FROM (SELECT * FROM test\n)",
+		"select n from test where n > ? order by n;":     "SELECT COUNT(*) FROM (select n from test where n > ? order by n\n)",
+		"WITH t AS (SELECT 1) SELECT * FROM t -- all":    "SELECT COUNT(*) FROM (WITH t AS (SELECT 1) SELECT * FROM t -- all\n)",
+		"VALUES (1), (2)":                                "SELECT COUNT(*) FROM (VALUES (1), (2)\n)",
+		"SELECT ';' FROM test WHERE s = 'a;b' LIMIT 10 ": "SELECT COUNT(*) FROM (SELECT ';' FROM test WHERE s = 'a;b' LIMIT 10\n)",
+	}
+	for query, expected := range cases {
+		count, err := CountQuery(query)
+		require.NoError(t, err, query)
+		assert.Equal(t, expected, count, query)
+	}
+}
+
+func TestCountQuery_Error(t *testing.T) {
+	cases := map[string]string{
+		"":                           "expected a single statement, got 0",
+		"SELECT 1; SELECT 2":         "expected a single statement, got 2",
+		"DELETE FROM test":           "can't count rows of DELETE statement",
+		"insert into test values(1)": "can't count rows of INSERT statement",
+	}
+	for query, message := range cases {
+		_, err := CountQuery(query)
+		assert.EqualError(t, err, message, query)
+	}
+}
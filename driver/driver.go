@@ -39,7 +39,25 @@ type Driver struct {
 	connectionTimeout time.Duration    // Max time to wait for a new connection
 	contextTimeout    time.Duration    // Default client context timeout.
 	clientConfig      protocol.Config  // Configuration for cowsql client instances
-	tracing           client.LogLevel  // Whether to trace statements
+	tracing           *tracer          // Whether to trace statements
+	rewriter          QueryRewriter    // Optional hook to rewrite statements
+	mapper            *typeMapper      // Custom conversions of Go types
+	spill             *spillConfig     // Buffering of result sets, if enabled
+	prefetch          bool             // Receive batches of rows in the background
+	slowQuery         *slowQueryConfig // Capture of slow query plans, if enabled
+	cache             *queryCache      // Results of queries, if enabled
+	functions         []string         // SQL functions required on the server
+	stats             *stats           // Leader changes statistics
+	metrics           *metrics         // Per-database usage statistics
+	statements        *statementStats  // Per-statement statistics, if enabled
+	scheduler         *scheduler       // Priority scheduling, if enabled
+	labelComments     bool             // Whether to send labels as SQL comments
+	maxStatementSize  int              // Maximum size of the SQL text of a statement
+	rejectURIs        bool             // Whether to reject "file:" database names
+	columnTypes       *columnTypes     // Column types of previous results, if enabled
+
+	// Extracts the log function to use from the context of a call, if set.
+	contextLog func(context.Context) client.LogFunc
 }
 
 // Error is returned in case of database errors.
@@ -80,6 +98,17 @@ func WithLogFunc(log client.LogFunc) Option {
 	}
 }
 
+// WithContextLogger sets a function extracting a logging function from the
+// context of a call, for example a per-request logger including a request
+// ID. The extracted function is used for the trace and error messages of the
+// call, while the one set with WithLogFunc is used if the extractor returns
+// nil, as well as for messages not related to a call.
+func WithContextLogger(extract func(ctx context.Context) client.LogFunc) Option {
+	return func(options *options) {
+		options.ContextLogger = extract
+	}
+}
+
 // DialFunc is a function that can be used to establish a network connection
 // with a cowsql node.
 type DialFunc = protocol.DialFunc
@@ -138,6 +167,97 @@ func WithAttemptTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithWriteTimeout sets the timeout for sending each request to the server.
+//
+// It applies in addition to the deadline of the context passed to the
+// request, if any, whichever expires first. It can be used to fail fast when
+// the connection is stuck, without limiting how long the server can take to
+// respond.
+//
+// If not used, the default is 0 (no timeout).
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.WriteTimeout = timeout
+	}
+}
+
+// WithReadTimeout sets the timeout for receiving each response from the
+// server.
+//
+// It applies in addition to the deadline of the context passed to the
+// request, if any, whichever expires first. For queries returning large
+// result sets it applies to each batch of rows separately, rather than to the
+// query as a whole.
+//
+// If not used, the default is 0 (no timeout).
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.ReadTimeout = timeout
+	}
+}
+
+// WithRowsTimeout sets the timeout for receiving each batch of rows after the
+// first one, for queries returning large result sets, overriding the read
+// timeout set with WithReadTimeout.
+//
+// It applies in addition to the deadline of the context passed to the query,
+// if any, whichever expires first. If a batch is not received in time the
+// connection is discarded, since the server might still be sending rows.
+//
+// If not used, the default is 0 (use the read timeout).
+func WithRowsTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.RowsTimeout = timeout
+	}
+}
+
+// WithInterruptTimeout sets the timeout for interrupting a query whose rows
+// are closed before being fully consumed, which requires the server to
+// acknowledge the interruption.
+//
+// If the server doesn't do so in time, for example because it's hung, the
+// connection is closed and discarded from the pool, instead of being stuck
+// forever. It applies in addition to the deadline of the context passed to
+// the query, if any, whichever expires first.
+//
+// If not used, the default is 10 seconds. A timeout of 0 means no timeout.
+func WithInterruptTimeout(timeout time.Duration) Option {
+	return func(options *options) {
+		options.InterruptTimeout = timeout
+	}
+}
+
+// WithCircuitBreaker makes the driver use the given circuit breaker to skip
+// nodes that failed repeatedly when looking for the leader, which speeds up
+// connecting while a node is down.
+//
+// The circuit breaker should be shared with any other user of the same node
+// store, see client.NewCircuitBreaker.
+func WithCircuitBreaker(breaker *client.CircuitBreaker) Option {
+	return func(options *options) {
+		options.Breaker = breaker
+	}
+}
+
+// WithClock sets the clock used to wait between attempts to find the leader.
+// The default is client.SystemClock.
+func WithClock(clock client.Clock) Option {
+	return func(options *options) {
+		options.Clock = clock
+	}
+}
+
+// WithStrictProtocol makes connections check that the type of each response
+// received from the server matches the type of the request it answers, for
+// example rows for a query, and fail with a descriptive error otherwise. It
+// helps detecting mismatches between the wire protocol spoken by the driver
+// and by the server.
+func WithStrictProtocol() Option {
+	return func(options *options) {
+		options.StrictProtocol = true
+	}
+}
+
 // WithRetryLimit sets the maximum number of connection retries.
 //
 // If not used, the default is 0 (unlimited retries)
@@ -147,6 +267,22 @@ func WithRetryLimit(limit uint) Option {
 	}
 }
 
+// WithMaxTotalRetryTime caps the total time spent finding the leader when
+// opening a connection, including redirects, retries and the backoff between
+// them. Once the budget is exhausted the connection fails with
+// ErrNoAvailableLeader, regardless of the number of retries left and of the
+// deadline of the context.
+//
+// No retry is started if its backoff would end past the budget, so the time
+// spent is bounded by the budget even with large backoff values.
+//
+// If not used, the default is 0 (no cap).
+func WithMaxTotalRetryTime(max time.Duration) Option {
+	return func(options *options) {
+		options.MaxTotalRetryTime = max
+	}
+}
+
 // WithContext sets a global cancellation context.
 //
 // DEPRECATED: This API is no a no-op. Users should explicitly pass a context
@@ -169,13 +305,31 @@ func WithContextTimeout(timeout time.Duration) Option {
 }
 
 // WithTracing will emit a log message at the given level every time a
-// statement gets executed.
+// statement gets executed. The level can be changed later with
+// Driver.SetTracing.
 func WithTracing(level client.LogLevel) Option {
 	return func(options *options) {
 		options.Tracing = level
 	}
 }
 
+// QueryRewriter is a function that can be used to rewrite the SQL text of a
+// statement before it gets sent to the server.
+type QueryRewriter func(sql string) string
+
+// WithQueryRewriter sets a hook that will be applied to the SQL text of every
+// statement prepared, executed or queried by the driver, before it gets sent
+// to the server. It can be used for example to inject comments or to enforce
+// limits, without wrapping every call site.
+//
+// The transaction control statements issued internally by the driver (BEGIN,
+// COMMIT and ROLLBACK) are not passed through the hook.
+func WithQueryRewriter(rewriter QueryRewriter) Option {
+	return func(options *options) {
+		options.QueryRewriter = rewriter
+	}
+}
+
 // NewDriver creates a new cowsql driver, which also implements the
 // driver.Driver interface.
 func New(store client.NodeStore, options ...Option) (*Driver, error) {
@@ -187,19 +341,55 @@ func New(store client.NodeStore, options ...Option) (*Driver, error) {
 
 	driver := &Driver{
 		log:               o.Log,
+		contextLog:        o.ContextLogger,
 		store:             store,
 		context:           o.Context,
 		connectionTimeout: o.ConnectionTimeout,
 		contextTimeout:    o.ContextTimeout,
-		tracing:           o.Tracing,
+		tracing:           newTracer(o.Tracing),
+		rewriter:          o.QueryRewriter,
+		mapper:            newTypeMapper(o.Encoders, o.Decoders),
+		spill:             o.Spill,
+		prefetch:          o.Prefetch,
+		functions:         o.RequiredFunctions,
+		labelComments:     o.LabelComments,
+		maxStatementSize:  o.MaxStatementSize,
+		rejectURIs:        o.RejectDatabaseURIs,
+		stats:             &stats{},
+		metrics:           newMetrics(),
 		clientConfig: protocol.Config{
-			Dial:           o.Dial,
-			AttemptTimeout: o.AttemptTimeout,
-			BackoffFactor:  o.ConnectionBackoffFactor,
-			BackoffCap:     o.ConnectionBackoffCap,
-			RetryLimit:     o.RetryLimit,
+			Dial:             o.Dial,
+			AttemptTimeout:   o.AttemptTimeout,
+			BackoffFactor:    o.ConnectionBackoffFactor,
+			BackoffCap:       o.ConnectionBackoffCap,
+			RetryLimit:       o.RetryLimit,
+			MaxRetryTime:     o.MaxTotalRetryTime,
+			WriteTimeout:     o.WriteTimeout,
+			ReadTimeout:      o.ReadTimeout,
+			Strict:           o.StrictProtocol,
+			InterruptTimeout: o.InterruptTimeout,
+			RowsTimeout:      o.RowsTimeout,
+			Breaker:          o.Breaker,
+			Clock:            o.Clock,
 		},
 	}
+	if o.SlowQuery != nil {
+		slowQuery := *o.SlowQuery
+		slowQuery.driver = driver
+		driver.slowQuery = &slowQuery
+	}
+	if o.StatementStatsSize > 0 {
+		driver.statements = newStatementStats(o.StatementStatsSize)
+	}
+	if o.BatchConcurrency > 0 {
+		driver.scheduler = newScheduler(o.BatchConcurrency)
+	}
+	if o.CacheSize > 0 {
+		driver.cache = newQueryCache(o.CacheSize, o.CacheTTL)
+	}
+	if o.ColumnTypeCacheSize > 0 {
+		driver.columnTypes = newColumnTypes(o.ColumnTypeCacheSize)
+	}
 
 	return driver, nil
 }
@@ -214,16 +404,41 @@ type options struct {
 	ConnectionBackoffFactor time.Duration
 	ConnectionBackoffCap    time.Duration
 	RetryLimit              uint
+	MaxTotalRetryTime       time.Duration
+	WriteTimeout            time.Duration
+	ReadTimeout             time.Duration
+	InterruptTimeout        time.Duration
+	RowsTimeout             time.Duration
+	StrictProtocol          bool
+	Breaker                 *client.CircuitBreaker
+	Clock                   client.Clock
 	Context                 context.Context
 	Tracing                 client.LogLevel
+	QueryRewriter           QueryRewriter
+	Encoders                map[reflect.Type]ValueEncoder
+	Decoders                map[string]ValueDecoder
+	Spill                   *spillConfig
+	SlowQuery               *slowQueryConfig
+	Prefetch                bool
+	CacheSize               int64
+	CacheTTL                time.Duration
+	RequiredFunctions       []string
+	BatchConcurrency        int
+	LabelComments           bool
+	MaxStatementSize        int
+	StatementStatsSize      int
+	ContextLogger           func(context.Context) client.LogFunc
+	RejectDatabaseURIs      bool
+	ColumnTypeCacheSize     int
 }
 
 // Create a options object with sane defaults.
 func defaultOptions() *options {
 	return &options{
-		Log:     client.DefaultLogFunc,
-		Dial:    client.DefaultDialFunc,
-		Tracing: client.LogNone,
+		Log:              client.DefaultLogFunc,
+		Dial:             client.DefaultDialFunc,
+		Tracing:          client.LogNone,
+		InterruptTimeout: 10 * time.Second,
 	}
 }
 
@@ -250,9 +465,24 @@ func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
 	connector := protocol.NewConnector(0, c.driver.store, c.driver.clientConfig, c.driver.log)
 
 	conn := &Conn{
-		log:            c.driver.log,
-		contextTimeout: c.driver.contextTimeout,
-		tracing:        c.driver.tracing,
+		log:              c.driver.log,
+		contextLog:       c.driver.contextLog,
+		contextTimeout:   c.driver.contextTimeout,
+		tracing:          c.driver.tracing,
+		rewriter:         c.driver.rewriter,
+		mapper:           c.driver.mapper,
+		spill:            c.driver.spill,
+		prefetch:         c.driver.prefetch,
+		slowQuery:        c.driver.slowQuery,
+		cache:            c.driver.cache,
+		stats:            c.driver.stats,
+		metrics:          c.driver.metrics,
+		statements:       c.driver.statements,
+		scheduler:        c.driver.scheduler,
+		labelComments:    c.driver.labelComments,
+		maxStatementSize: c.driver.maxStatementSize,
+		columnTypes:      c.driver.columnTypes,
+		database:         databaseName(c.uri),
 	}
 
 	var err error
@@ -276,6 +506,18 @@ func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
 		conn.protocol.Close()
 		return nil, errors.Wrap(err, "failed to open database")
 	}
+	conn.stats.connected()
+
+	if len(c.driver.functions) > 0 {
+		missing, err := conn.missingFunctions(ctx, c.driver.functions)
+		if err == nil && len(missing) > 0 {
+			err = missingFunctionsError(missing)
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
 
 	return conn, nil
 }
@@ -288,6 +530,9 @@ func (c *Connector) Driver() driver.Driver {
 // OpenConnector must parse the name in the same format that Driver.Open
 // parses the name parameter.
 func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	if err := checkDatabaseName(name, d.rejectURIs); err != nil {
+		return nil, err
+	}
 	connector := &Connector{
 		uri:    name,
 		driver: d,
@@ -299,8 +544,10 @@ func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
 //
 // The given name must be a pure file name without any directory segment,
 // cowsql will connect to a database with that name in its data directory.
+// The "file:" URI format is accepted, unless WithRejectDatabaseURIs is used.
 //
-// Query parameters are always valid except for "mode=memory".
+// Query parameters are always valid except for "mode=memory". Invalid names
+// are rejected with an error whose root cause is ErrInvalidDatabaseName.
 //
 // If this node is not the leader, or the leader is unknown an ErrNotLeader
 // error is returned.
@@ -324,51 +571,83 @@ func (d *Driver) SetContextTimeout(timeout time.Duration) {}
 // leader available in the cluster.
 var ErrNoAvailableLeader = protocol.ErrNoAvailableLeader
 
+// ErrConnBusy is returned when a connection is used while the rows of a
+// previous query are still being received from the server. Either close the
+// rows first, or iterate through them in full.
+var ErrConnBusy = errors.New("connection busy receiving rows of a previous query")
+
 // Conn implements the sql.Conn interface.
 type Conn struct {
-	log            client.LogFunc
-	protocol       *protocol.Protocol
-	request        protocol.Message
-	response       protocol.Message
-	id             uint32 // Database ID.
-	contextTimeout time.Duration
-	tracing        client.LogLevel
+	log              client.LogFunc
+	contextLog       func(context.Context) client.LogFunc
+	protocol         *protocol.Protocol
+	request          protocol.Message
+	response         protocol.Message
+	id               uint32 // Database ID.
+	contextTimeout   time.Duration
+	tracing          *tracer
+	rewriter         QueryRewriter
+	mapper           *typeMapper
+	spill            *spillConfig
+	prefetch         bool
+	slowQuery        *slowQueryConfig
+	cache            *queryCache
+	rows             *Rows    // Open rows using the response buffer, if any
+	stats            *stats   // Leader changes statistics of the driver
+	metrics          *metrics // Per-database usage statistics of the driver
+	statements       *statementStats
+	database         string // Name of the database
+	scheduler        *scheduler
+	labelComments    bool
+	maxStatementSize int
+	columnTypes      *columnTypes
 }
 
 // PrepareContext returns a prepared statement, bound to this connection.
 // context is for the preparation of the statement, it must not store the
 // context within the statement itself.
 func (c *Conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+
 	stmt := &Stmt{
+		conn:     c,
 		protocol: c.protocol,
 		request:  &c.request,
 		response: &c.response,
 		log:      c.log,
-		tracing:  c.tracing,
+		mapper:   c.mapper,
+		spill:    c.spill,
+	}
+
+	query = c.annotate(ctx, c.rewrite(query))
+	if err := c.checkStatementSize(query); err != nil {
+		return nil, err
 	}
 
 	protocol.EncodePrepare(&c.request, uint64(c.id), query)
 
+	tracing := c.tracing.get()
 	var start time.Time
-	if c.tracing != client.LogNone {
+	if tracing != client.LogNone {
 		start = time.Now()
 	}
 	err := c.protocol.Call(ctx, &c.request, &c.response)
-	if c.tracing != client.LogNone {
-		c.log(c.tracing, "%.3fs request prepared: %q", time.Since(start).Seconds(), query)
+	if tracing != client.LogNone {
+		c.logger(ctx)(tracing, "%.3fs request prepared (id %d): %q%s", time.Since(start).Seconds(), c.protocol.LastCallID(), query, labelsSuffix(ctx))
 	}
 	if err != nil {
-		return nil, driverError(c.log, err)
+		return nil, c.error(ctx, err)
 	}
 
 	stmt.db, stmt.id, stmt.params, err = protocol.DecodeStmt(&c.response)
 	if err != nil {
-		return nil, driverError(c.log, err)
+		return nil, c.error(ctx, err)
 	}
 
-	if c.tracing != client.LogNone {
-		stmt.sql = query
-	}
+	stmt.sql = query
+	stmt.fingerprint = c.statements.normalize(query)
 
 	return stmt, nil
 }
@@ -380,32 +659,57 @@ func (c *Conn) Prepare(query string) (driver.Stmt, error) {
 
 // ExecContext is an optional interface that may be implemented by a Conn.
 func (c *Conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.exec(ctx, c.rewrite(query), args)
+}
+
+// Execute the given statement, without passing it through the rewriter.
+func (c *Conn) exec(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.acquire(); err != nil {
+		return nil, err
+	}
+
+	query = c.annotate(ctx, query)
+	if err := c.checkStatementSize(query); err != nil {
+		return nil, err
+	}
+
 	if int64(len(args)) > math.MaxUint32 {
-		return nil, driverError(c.log, fmt.Errorf("too many parameters (%d)", len(args)))
+		return nil, c.error(ctx, fmt.Errorf("too many parameters (%d)", len(args)))
 	} else if len(args) > math.MaxUint8 {
 		protocol.EncodeExecSQLV1(&c.request, uint64(c.id), query, args)
 	} else {
 		protocol.EncodeExecSQLV0(&c.request, uint64(c.id), query, args)
 	}
 
-	var start time.Time
-	if c.tracing != client.LogNone {
-		start = time.Now()
+	priority, err := c.scheduler.acquire(ctx)
+	if err != nil {
+		return nil, err
 	}
-	err := c.protocol.Call(ctx, &c.request, &c.response)
-	if c.tracing != client.LogNone {
-		c.log(c.tracing, "%.3fs request exec: %q", time.Since(start).Seconds(), query)
+	start := time.Now()
+	err = c.protocol.Call(ctx, &c.request, &c.response)
+	c.scheduler.release(priority)
+	c.metrics.statement(c.database, time.Since(start))
+	fingerprint := c.statements.normalize(query)
+	c.statements.record(c.database, fingerprint, time.Since(start), err)
+	if err == nil {
+		c.slowQuery.observe(c.database, query, args, time.Since(start))
+	}
+	if tracing := c.tracing.get(); tracing != client.LogNone {
+		c.logger(ctx)(tracing, "%.3fs request exec (id %d): %q%s", time.Since(start).Seconds(), c.protocol.LastCallID(), query, labelsSuffix(ctx))
 	}
 	if err != nil {
-		return nil, driverError(c.log, err)
+		return nil, c.error(ctx, err)
 	}
 
 	var result protocol.Result
 	result, err = protocol.DecodeResult(&c.response)
 	if err != nil {
-		return nil, driverError(c.log, err)
+		return nil, c.error(ctx, err)
 	}
 
+	c.stats.succeeded()
+	c.statements.rows(c.database, fingerprint, result.RowsAffected)
+
 	return &Result{result: result}, nil
 }
 
@@ -416,40 +720,97 @@ func (c *Conn) Query(query string, args []driver.Value) (driver.Rows, error) {
 
 // QueryContext is an optional interface that may be implemented by a Conn.
 func (c *Conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query = c.rewrite(query)
+
+	var key string
+	cached := c.cache != nil && cacheEnabled(ctx)
+	if cached {
+		key = cacheKeyOf(c.database, query, args)
+		if entry := c.cache.get(key); entry != nil {
+			return c.cachedRows(ctx, entry), nil
+		}
+	}
+
+	rows, err := c.query(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rows{
+		ctx:      ctx,
+		conn:     c,
+		request:  &c.request,
+		response: &c.response,
+		protocol: c.protocol,
+		rows:     rows,
+		query:    query,
+		log:      c.logger(ctx),
+		mapper:   c.mapper,
+		spill:    c.spill,
+	}
+
+	if cached {
+		if err := c.cacheRows(r, key); err != nil {
+			r.Close()
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if err := r.start(); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Send a QuerySQL request and decode the first batch of rows.
+func (c *Conn) query(ctx context.Context, query string, args []driver.NamedValue) (protocol.Rows, error) {
+	if err := c.acquire(); err != nil {
+		return protocol.Rows{}, err
+	}
+
+	query = c.annotate(ctx, query)
+	if err := c.checkStatementSize(query); err != nil {
+		return protocol.Rows{}, err
+	}
+
 	if int64(len(args)) > math.MaxUint32 {
-		return nil, driverError(c.log, fmt.Errorf("too many parameters (%d)", len(args)))
+		return protocol.Rows{}, c.error(ctx, fmt.Errorf("too many parameters (%d)", len(args)))
 	} else if len(args) > math.MaxUint8 {
 		protocol.EncodeQuerySQLV1(&c.request, uint64(c.id), query, args)
 	} else {
 		protocol.EncodeQuerySQLV0(&c.request, uint64(c.id), query, args)
 	}
 
-	var start time.Time
-	if c.tracing != client.LogNone {
-		start = time.Now()
+	priority, err := c.scheduler.acquire(ctx)
+	if err != nil {
+		return protocol.Rows{}, err
 	}
-	err := c.protocol.Call(ctx, &c.request, &c.response)
-	if c.tracing != client.LogNone {
-		c.log(c.tracing, "%.3fs request query: %q", time.Since(start).Seconds(), query)
+	start := time.Now()
+	err = c.protocol.Call(ctx, &c.request, &c.response)
+	c.scheduler.release(priority)
+	c.metrics.statement(c.database, time.Since(start))
+	c.statements.record(c.database, c.statements.normalize(query), time.Since(start), err)
+	if err == nil {
+		c.slowQuery.observe(c.database, query, args, time.Since(start))
+	}
+	if tracing := c.tracing.get(); tracing != client.LogNone {
+		c.logger(ctx)(tracing, "%.3fs request query (id %d): %q%s", time.Since(start).Seconds(), c.protocol.LastCallID(), query, labelsSuffix(ctx))
 	}
 	if err != nil {
-		return nil, driverError(c.log, err)
+		return protocol.Rows{}, c.error(ctx, err)
 	}
 
-	var rows protocol.Rows
-	rows, err = protocol.DecodeRows(&c.response)
+	rows, err := protocol.DecodeRows(&c.response)
 	if err != nil {
-		return nil, driverError(c.log, err)
+		return protocol.Rows{}, c.error(ctx, err)
 	}
 
-	return &Rows{
-		ctx:      ctx,
-		request:  &c.request,
-		response: &c.response,
-		protocol: c.protocol,
-		rows:     rows,
-		log:      c.log,
-	}, nil
+	c.stats.succeeded()
+
+	return rows, nil
 }
 
 // Exec is an optional interface that may be implemented by a Conn.
@@ -464,9 +825,92 @@ func (c *Conn) Exec(query string, args []driver.Value) (driver.Result, error) {
 // Close when there's a surplus of idle connections, it shouldn't be necessary
 // for drivers to do their own connection caching.
 func (c *Conn) Close() error {
+	c.request.Release()
+
+	// Open rows might still be decoding the response buffer.
+	if c.rows == nil {
+		c.response.Release()
+	}
+
 	return c.protocol.Close()
 }
 
+// Ping verifies that the connection is still alive, by asking the node we're
+// connected to for the current leader. It returns ErrBadConn if the node has
+// no known leader, since any statement sent to it would fail.
+//
+// Ping does not check that the node is still the leader itself: in that case
+// the next statement fails with ErrBadConn and database/sql reconnects.
+func (c *Conn) Ping(ctx context.Context) error {
+	if err := c.acquire(); err != nil {
+		return err
+	}
+
+	protocol.EncodeLeader(&c.request)
+
+	if err := c.protocol.Call(ctx, &c.request, &c.response); err != nil {
+		return c.error(ctx, err)
+	}
+
+	_, address, err := protocol.DecodeNode(&c.response)
+	if err != nil {
+		return c.error(ctx, err)
+	}
+
+	if address == "" {
+		c.logger(ctx)(client.LogDebug, "ping: no known leader")
+		return driver.ErrBadConn
+	}
+
+	return nil
+}
+
+// ResetSession is called prior to executing a query on the connection if the
+// connection has been used before. If the driver returns ErrBadConn the
+// connection is discarded.
+func (c *Conn) ResetSession(ctx context.Context) error {
+	if !c.IsValid() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// IsValid is called prior to placing the connection into the connection pool.
+// The connection will be discarded if false is returned.
+func (c *Conn) IsValid() bool {
+	return c.protocol.Err() == nil
+}
+
+// CheckNamedValue is called before passing arguments to the driver and is
+// called in place of any ColumnConverter.
+//
+// Values having a type registered with WithValueEncoder are converted using
+// the associated encoder. Values natively supported by the wire protocol are
+// passed through as they are, everything else is converted using the default
+// converter.
+func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if c.mapper != nil {
+		value, err := c.mapper.encode(nv.Value)
+		if err != nil {
+			return err
+		}
+		nv.Value = value
+	}
+
+	switch nv.Value.(type) {
+	case int64, float64, bool, []byte, string, time.Time, nil:
+		return nil
+	}
+
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	nv.Value = value
+
+	return nil
+}
+
 // BeginTx starts and returns a new transaction.  If the context is canceled by
 // the user the sql package will call Tx.Rollback before discarding and closing
 // the connection.
@@ -480,7 +924,7 @@ func (c *Conn) Close() error {
 // true to either set the read-only transaction property if supported or return
 // an error if it is not supported.
 func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
-	if _, err := c.ExecContext(ctx, "BEGIN", nil); err != nil {
+	if _, err := c.exec(ctx, "BEGIN", nil); err != nil {
 		return nil, err
 	}
 
@@ -507,6 +951,52 @@ func (c *Conn) Begin() (driver.Tx, error) {
 	return c.BeginTx(ctx, driver.TxOptions{})
 }
 
+// Make sure that the request and response buffers of the connection can be
+// used for a new request.
+//
+// If there are open rows whose last batch has already been received, the
+// remaining rows are read into memory. Otherwise, if the server still has
+// rows to send, ErrConnBusy is returned, since only one request at a time
+// can be in flight on a connection.
+func (c *Conn) acquire() error {
+	if c.rows == nil {
+		return nil
+	}
+	if err := c.rows.detach(); err != nil {
+		return err
+	}
+	c.rows = nil
+	return nil
+}
+
+// Convert the given error to a driver error, recording lost connections and
+// reporting interrupted statements as ErrQueryTimeout.
+func (c *Conn) error(ctx context.Context, err error) error {
+	err = driverError(c.logger(ctx), err)
+	if err == driver.ErrBadConn {
+		c.stats.lost()
+	}
+	return queryTimeout(err, c.protocol.LastCallStart())
+}
+
+// Return the log function to use for a call with the given context.
+func (c *Conn) logger(ctx context.Context) client.LogFunc {
+	if c.contextLog != nil {
+		if log := c.contextLog(ctx); log != nil {
+			return log
+		}
+	}
+	return c.log
+}
+
+// Apply the query rewriter, if any.
+func (c *Conn) rewrite(query string) string {
+	if c.rewriter == nil {
+		return query
+	}
+	return c.rewriter(query)
+}
+
 // Tx is a transaction.
 type Tx struct {
 	conn *Conn
@@ -517,8 +1007,9 @@ type Tx struct {
 func (tx *Tx) Commit() error {
 	ctx := context.Background()
 
-	if _, err := tx.conn.ExecContext(ctx, "COMMIT", nil); err != nil {
-		return driverError(tx.log, err)
+	// Errors are already converted and counted by exec.
+	if _, err := tx.conn.exec(ctx, "COMMIT", nil); err != nil {
+		return err
 	}
 
 	return nil
@@ -528,8 +1019,9 @@ func (tx *Tx) Commit() error {
 func (tx *Tx) Rollback() error {
 	ctx := context.Background()
 
-	if _, err := tx.conn.ExecContext(ctx, "ROLLBACK", nil); err != nil {
-		return driverError(tx.log, err)
+	// Errors are already converted and counted by exec.
+	if _, err := tx.conn.exec(ctx, "ROLLBACK", nil); err != nil {
+		return err
 	}
 
 	return nil
@@ -538,29 +1030,36 @@ func (tx *Tx) Rollback() error {
 // Stmt is a prepared statement. It is bound to a Conn and not
 // used by multiple goroutines concurrently.
 type Stmt struct {
-	protocol *protocol.Protocol
-	request  *protocol.Message
-	response *protocol.Message
-	db       uint32
-	id       uint32
-	params   uint64
-	log      client.LogFunc
-	sql      string // Prepared SQL, only set when tracing
-	tracing  client.LogLevel
+	conn        *Conn
+	protocol    *protocol.Protocol
+	request     *protocol.Message
+	response    *protocol.Message
+	db          uint32
+	id          uint32
+	params      uint64
+	log         client.LogFunc
+	sql         string // Prepared SQL, used to prepare the statement again
+	fingerprint string // Normalized SQL, only set with statement statistics
+	mapper      *typeMapper
+	spill       *spillConfig
 }
 
 // Close closes the statement.
 func (s *Stmt) Close() error {
+	if err := s.conn.acquire(); err != nil {
+		return err
+	}
+
 	protocol.EncodeFinalize(s.request, s.db, s.id)
 
 	ctx := context.Background()
 
 	if err := s.protocol.Call(ctx, s.request, s.response); err != nil {
-		return driverError(s.log, err)
+		return s.conn.error(ctx, err)
 	}
 
 	if err := protocol.DecodeEmpty(s.response); err != nil {
-		return driverError(s.log, err)
+		return s.conn.error(ctx, err)
 	}
 
 	return nil
@@ -576,32 +1075,49 @@ func (s *Stmt) NumInput() int {
 //
 // ExecContext must honor the context timeout and return when it is canceled.
 func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.conn.acquire(); err != nil {
+		return nil, err
+	}
+
 	if int64(len(args)) > math.MaxUint32 {
-		return nil, driverError(s.log, fmt.Errorf("too many parameters (%d)", len(args)))
-	} else if len(args) > math.MaxUint8 {
-		protocol.EncodeExecV1(s.request, s.db, s.id, args)
-	} else {
-		protocol.EncodeExecV0(s.request, s.db, s.id, args)
+		return nil, s.conn.error(ctx, fmt.Errorf("too many parameters (%d)", len(args)))
+	}
+	encode := func() {
+		if len(args) > math.MaxUint8 {
+			protocol.EncodeExecV1(s.request, s.db, s.id, args)
+		} else {
+			protocol.EncodeExecV0(s.request, s.db, s.id, args)
+		}
 	}
 
-	var start time.Time
-	if s.tracing != client.LogNone {
-		start = time.Now()
+	priority, err := s.conn.scheduler.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	err = s.call(ctx, encode)
+	s.conn.scheduler.release(priority)
+	s.conn.metrics.statement(s.conn.database, time.Since(start))
+	s.conn.statements.record(s.conn.database, s.fingerprint, time.Since(start), err)
+	if err == nil {
+		s.conn.slowQuery.observe(s.conn.database, s.sql, args, time.Since(start))
 	}
-	err := s.protocol.Call(ctx, s.request, s.response)
-	if s.tracing != client.LogNone {
-		s.log(s.tracing, "%.3fs request prepared: %q", time.Since(start).Seconds(), s.sql)
+	if tracing := s.conn.tracing.get(); tracing != client.LogNone {
+		s.conn.logger(ctx)(tracing, "%.3fs request prepared (id %d): %q%s", time.Since(start).Seconds(), s.protocol.LastCallID(), s.sql, labelsSuffix(ctx))
 	}
 	if err != nil {
-		return nil, driverError(s.log, err)
+		return nil, s.conn.error(ctx, err)
 	}
 
 	var result protocol.Result
 	result, err = protocol.DecodeResult(s.response)
 	if err != nil {
-		return nil, driverError(s.log, err)
+		return nil, s.conn.error(ctx, err)
 	}
 
+	s.conn.stats.succeeded()
+	s.conn.statements.rows(s.conn.database, s.fingerprint, result.RowsAffected)
+
 	return &Result{result: result}, nil
 }
 
@@ -615,33 +1131,68 @@ func (s *Stmt) Exec(args []driver.Value) (driver.Result, error) {
 //
 // QueryContext must honor the context timeout and return when it is canceled.
 func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.conn.acquire(); err != nil {
+		return nil, err
+	}
+
 	if int64(len(args)) > math.MaxUint32 {
-		return nil, driverError(s.log, fmt.Errorf("too many parameters (%d)", len(args)))
-	} else if len(args) > math.MaxUint8 {
-		protocol.EncodeQueryV1(s.request, s.db, s.id, args)
-	} else {
-		protocol.EncodeQueryV0(s.request, s.db, s.id, args)
+		return nil, s.conn.error(ctx, fmt.Errorf("too many parameters (%d)", len(args)))
+	}
+	encode := func() {
+		if len(args) > math.MaxUint8 {
+			protocol.EncodeQueryV1(s.request, s.db, s.id, args)
+		} else {
+			protocol.EncodeQueryV0(s.request, s.db, s.id, args)
+		}
 	}
 
-	var start time.Time
-	if s.tracing != client.LogNone {
-		start = time.Now()
+	priority, err := s.conn.scheduler.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	err = s.call(ctx, encode)
+	s.conn.scheduler.release(priority)
+	s.conn.metrics.statement(s.conn.database, time.Since(start))
+	s.conn.statements.record(s.conn.database, s.fingerprint, time.Since(start), err)
+	if err == nil {
+		s.conn.slowQuery.observe(s.conn.database, s.sql, args, time.Since(start))
 	}
-	err := s.protocol.Call(ctx, s.request, s.response)
-	if s.tracing != client.LogNone {
-		s.log(s.tracing, "%.3fs request prepared: %q", time.Since(start).Seconds(), s.sql)
+	if tracing := s.conn.tracing.get(); tracing != client.LogNone {
+		s.conn.logger(ctx)(tracing, "%.3fs request prepared (id %d): %q%s", time.Since(start).Seconds(), s.protocol.LastCallID(), s.sql, labelsSuffix(ctx))
 	}
 	if err != nil {
-		return nil, driverError(s.log, err)
+		return nil, s.conn.error(ctx, err)
 	}
 
 	var rows protocol.Rows
 	rows, err = protocol.DecodeRows(s.response)
 	if err != nil {
-		return nil, driverError(s.log, err)
+		return nil, s.conn.error(ctx, err)
 	}
 
-	return &Rows{ctx: ctx, request: s.request, response: s.response, protocol: s.protocol, rows: rows}, nil
+	s.conn.stats.succeeded()
+
+	r := &Rows{
+		ctx:         ctx,
+		conn:        s.conn,
+		request:     s.request,
+		response:    s.response,
+		protocol:    s.protocol,
+		rows:        rows,
+		query:       s.sql,
+		fingerprint: s.fingerprint,
+		log:         s.conn.logger(ctx),
+		mapper:      s.mapper,
+		spill:       s.spill,
+	}
+
+	if err := r.start(); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	return r, nil
 }
 
 // Query executes a query that may return rows, such as a
@@ -670,6 +1221,7 @@ func (r *Result) RowsAffected() (int64, error) {
 // Rows is an iterator over an executed query's results.
 type Rows struct {
 	ctx      context.Context
+	conn     *Conn
 	protocol *protocol.Protocol
 	request  *protocol.Message
 	response *protocol.Message
@@ -677,6 +1229,17 @@ type Rows struct {
 	consumed bool
 	types    []string
 	log      client.LogFunc
+	mapper   *typeMapper
+	decoders []ValueDecoder // Per-column decoders, if any
+	spill    *spillConfig
+	buffer   *rowBuffer  // Rows fetched in advance, if spilling is enabled
+	prefetch *prefetcher // Receives the next batch in advance, if enabled
+	returned uint64      // Rows returned so far, not yet recorded in the metrics
+
+	// SQL text of the current statement, or its normalized form if
+	// already known, for statement statistics.
+	query       string
+	fingerprint string
 }
 
 // Columns returns the names of the columns. The number of
@@ -689,14 +1252,51 @@ func (r *Rows) Columns() []string {
 
 // Close closes the rows iterator.
 func (r *Rows) Close() error {
-	err := r.rows.Close()
+	if r.conn.rows == r {
+		r.conn.rows = nil
+	}
+
+	r.conn.metrics.rows(r.conn.database, r.returned)
+	if r.returned > 0 && r.conn.statements != nil {
+		if r.fingerprint == "" {
+			r.fingerprint = r.conn.statements.normalize(r.query)
+		}
+		r.conn.statements.rows(r.conn.database, r.fingerprint, r.returned)
+	}
+	r.returned = 0
+
+	if r.buffer != nil {
+		r.buffer.close()
+		r.buffer = nil
+	}
+
+	// Wait for the batch being received in the background, if any, so
+	// that the pending rows and the connection are in a known state.
+	if r.prefetch != nil {
+		defer r.prefetch.close()
+		if r.prefetch.busy() {
+			r.rows.Close()
+			if err := r.prefetch.wait(r.ctx, r.response); err != nil {
+				r.conn.error(r.ctx, err)
+				return driver.ErrBadConn
+			}
+			rows, err := protocol.DecodeRows(r.response)
+			if err != nil {
+				return r.conn.error(r.ctx, err)
+			}
+			r.rows = rows
+		}
+	}
 
 	// If we consumed the whole result set, there's nothing to do as
-	// there's no pending response from the server.
+	// there's no pending response from the server. The response buffer
+	// might be in use by another request, so don't touch it.
 	if r.consumed {
 		return nil
 	}
 
+	err := r.rows.Close()
+
 	// If there is was a single-response result set, we're done.
 	if err == io.EOF {
 		return nil
@@ -705,7 +1305,13 @@ func (r *Rows) Close() error {
 	// Let's issue an interrupt request and wait until we get an empty
 	// response, signalling that the query was interrupted.
 	if err := r.protocol.Interrupt(r.ctx, r.request, r.response); err != nil {
-		return driverError(r.log, err)
+		// A failed interrupt leaves the connection unusable, so make
+		// sure it gets discarded whatever the error.
+		if err := r.conn.error(r.ctx, err); err != driver.ErrBadConn {
+			r.conn.logger(r.ctx)(client.LogDebug, "interrupt failed: %v", err)
+			r.conn.stats.lost()
+		}
+		return driver.ErrBadConn
 	}
 
 	return nil
@@ -717,18 +1323,130 @@ func (r *Rows) Close() error {
 //
 // Next should return io.EOF when there are no more rows.
 func (r *Rows) Next(dest []driver.Value) error {
+	if err := r.next(dest); err != nil {
+		return err
+	}
+	r.returned++
+
+	if r.mapper == nil {
+		return nil
+	}
+
+	if r.decoders == nil {
+		types := make([]string, len(r.Columns()))
+		for i := range types {
+			types[i] = r.ColumnTypeDatabaseTypeName(i)
+		}
+		r.decoders = r.mapper.columnDecoders(types)
+	}
+
+	return decodeValues(r.decoders, dest)
+}
+
+// Return the next row, either from the buffer or from the server.
+func (r *Rows) next(dest []driver.Value) error {
+	if r.buffer != nil {
+		return r.buffer.next(dest)
+	}
+	return r.fetch(dest)
+}
+
+// Prepare the rows for iteration, once the first batch has been received.
+func (r *Rows) start() error {
+	if r.conn.prefetch && !r.consumed {
+		if r.prefetch == nil {
+			r.prefetch = newPrefetcher(r.protocol)
+		}
+		if r.rows.Part() {
+			r.prefetch.start(r.ctx)
+		}
+	}
+
+	if r.spill != nil {
+		return r.fill(r.spill.budget, r.spill.dir)
+	}
+
+	// Track the rows, since they are decoded from the response buffer of
+	// the connection.
+	if !r.consumed {
+		r.conn.rows = r
+	}
+
+	return nil
+}
+
+// Read the remaining rows into memory if all of them have been received, so
+// the connection can be used for other requests while iterating them.
+func (r *Rows) detach() error {
+	if r.consumed {
+		return nil
+	}
+	if r.rows.Part() {
+		return ErrConnBusy
+	}
+	return r.fill(math.MaxInt64, "")
+}
+
+// Fetch all the remaining rows of the current result set into a buffer,
+// spilling them to a file in the given directory past the given budget.
+func (r *Rows) fill(budget int64, dir string) error {
+	// Column types are cached by the last batch of rows, so determine them
+	// now, while the batch is available.
+	if r.types == nil {
+		if types, err := r.rows.ColumnTypes(); err == nil {
+			r.types = types
+			r.conn.columnTypes.put(r.query, types)
+		}
+	}
+
+	buffer := newRowBuffer(budget, dir)
+	dest := make([]driver.Value, len(r.rows.Columns))
+	for {
+		err := r.fetch(dest)
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = buffer.add(dest)
+		}
+		if err != nil {
+			buffer.close()
+			return err
+		}
+	}
+
+	if err := buffer.rewind(); err != nil {
+		buffer.close()
+		return err
+	}
+	r.buffer = buffer
+
+	return nil
+}
+
+// Fetch the next row, possibly requesting the next batch of rows from the
+// server.
+func (r *Rows) fetch(dest []driver.Value) error {
 	err := r.rows.Next(dest)
 
 	if err == protocol.ErrRowsPart {
 		r.rows.Close()
-		if err := r.protocol.More(r.ctx, r.response); err != nil {
-			return driverError(r.log, err)
+		if r.prefetch != nil {
+			err = r.prefetch.wait(r.ctx, r.response)
+		} else {
+			err = r.protocol.More(r.ctx, r.response)
+		}
+		if err != nil {
+			return r.conn.error(r.ctx, err)
 		}
 		rows, err := protocol.DecodeRows(r.response)
 		if err != nil {
-			return driverError(r.log, err)
+			return r.conn.error(r.ctx, err)
 		}
 		r.rows = rows
+		if r.prefetch != nil && r.rows.Part() {
+			r.prefetch.start(r.ctx)
+		}
 		return r.rows.Next(dest)
 	}
 
@@ -740,16 +1458,24 @@ func (r *Rows) Next(dest []driver.Value) error {
 }
 
 // ColumnTypeScanType implements RowsColumnTypeScanType.
+// warning: not thread safe
 func (r *Rows) ColumnTypeScanType(i int) reflect.Type {
-	// column := sql.NewColumn(r.rows, i)
-
-	// typ, err := r.protocol.ColumnTypeScanType(context.Background(), column)
-	// if err != nil {
-	// 	return nil
-	// }
-
-	// return typ.DriverType()
-	return nil
+	switch r.ColumnTypeDatabaseTypeName(i) {
+	case "INTEGER":
+		return reflect.TypeOf(int64(0))
+	case "FLOAT":
+		return reflect.TypeOf(float64(0))
+	case "TEXT":
+		return reflect.TypeOf("")
+	case "BLOB":
+		return reflect.TypeOf([]byte{})
+	case "TIME":
+		return reflect.TypeOf(time.Time{})
+	case "BOOL":
+		return reflect.TypeOf(false)
+	default:
+		return reflect.TypeOf((*interface{})(nil)).Elem()
+	}
 }
 
 // ColumnTypeDatabaseTypeName implements RowsColumnTypeDatabaseTypeName.
@@ -758,6 +1484,13 @@ func (r *Rows) ColumnTypeDatabaseTypeName(i int) string {
 	if r.types == nil {
 		var err error
 		r.types, err = r.rows.ColumnTypes()
+		if err == nil {
+			r.conn.columnTypes.put(r.query, r.types)
+		} else if types := r.conn.columnTypes.get(r.query); types != nil {
+			// Empty result sets carry no type information, use
+			// the types of a previous result of the same query.
+			r.types, err = types, nil
+		}
 		// an error might not matter if we get our types
 		if err != nil && i >= len(r.types) {
 			// a panic here doesn't really help,
@@ -771,6 +1504,27 @@ func (r *Rows) ColumnTypeDatabaseTypeName(i int) string {
 	return r.types[i]
 }
 
+// Assert that the optional database/sql/driver interfaces are implemented, so
+// database/sql always takes the fast paths.
+var (
+	_ driver.DriverContext      = (*Driver)(nil)
+	_ driver.Connector          = (*Connector)(nil)
+	_ driver.Conn               = (*Conn)(nil)
+	_ driver.ConnPrepareContext = (*Conn)(nil)
+	_ driver.ConnBeginTx        = (*Conn)(nil)
+	_ driver.ExecerContext      = (*Conn)(nil)
+	_ driver.QueryerContext     = (*Conn)(nil)
+	_ driver.Pinger             = (*Conn)(nil)
+	_ driver.SessionResetter    = (*Conn)(nil)
+	_ driver.Validator          = (*Conn)(nil)
+	_ driver.NamedValueChecker  = (*Conn)(nil)
+	_ driver.StmtExecContext    = (*Stmt)(nil)
+	_ driver.StmtQueryContext   = (*Stmt)(nil)
+
+	_ driver.RowsColumnTypeScanType         = (*Rows)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*Rows)(nil)
+)
+
 // Convert a driver.Value slice into a driver.NamedValue slice.
 func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
 	namedValues := make([]driver.NamedValue, len(args))
@@ -794,12 +1548,15 @@ type unwrappable interface {
 // possibly returning ErrBadCon.
 // https://cs.opensource.google/go/go/+/refs/tags/go1.20.4:src/database/sql/driver/driver.go;drc=a32a592c8c14927c20ac42808e1fb2e55b2e9470;l=162
 func driverError(log client.LogFunc, err error) error {
+	// Log the original error, which for failed calls includes the call ID.
+	orig := err
+
 	switch err := errors.Cause(err).(type) {
 	case syscall.Errno:
-		log(client.LogDebug, "network connection lost: %v", err)
+		log(client.LogDebug, "network connection lost: %v", orig)
 		return driver.ErrBadConn
 	case *net.OpError:
-		log(client.LogDebug, "network connection lost: %v", err)
+		log(client.LogDebug, "network connection lost: %v", orig)
 		return driver.ErrBadConn
 	case protocol.ErrRequest:
 		switch err.Code {
@@ -839,12 +1596,12 @@ func driverError(log client.LogFunc, err error) error {
 		}
 		switch err.(type) {
 		case *net.OpError:
-			log(client.LogDebug, "network connection lost: %v", err)
+			log(client.LogDebug, "network connection lost: %v", orig)
 			return driver.ErrBadConn
 		}
 	}
 	if errors.Is(err, io.EOF) {
-		log(client.LogDebug, "EOF detected: %v", err)
+		log(client.LogDebug, "EOF detected: %v", orig)
 		return driver.ErrBadConn
 	}
 	return err
@@ -17,6 +17,7 @@ package driver_test
 import (
 	"context"
 	"database/sql/driver"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -258,6 +259,36 @@ func TestStmt_ExecManyParams(t *testing.T) {
 	assert.NoError(t, conn.Close())
 }
 
+// A statement that the server doesn't know about anymore is prepared again
+// transparently.
+func TestStmt_ExecStale(t *testing.T) {
+	drv, cleanup := newDriver(t)
+	defer cleanup()
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	execer := conn.(driver.ExecerContext)
+	_, err = execer.ExecContext(context.Background(), "CREATE TABLE test (n INT)", nil)
+	require.NoError(t, err)
+
+	stmt, err := conn.Prepare("INSERT INTO test(n) VALUES(?)")
+	require.NoError(t, err)
+
+	// Finalizing the statement makes the server forget about it.
+	require.NoError(t, stmt.Close())
+
+	result, err := stmt.Exec([]driver.Value{int64(1)})
+	require.NoError(t, err)
+
+	rowsAffected, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rowsAffected)
+
+	require.NoError(t, stmt.Close())
+	assert.NoError(t, conn.Close())
+}
+
 func TestStmt_Query(t *testing.T) {
 	drv, cleanup := newDriver(t)
 	defer cleanup()
@@ -472,6 +503,43 @@ func Test_ColumnTypesEmpty(t *testing.T) {
 	assert.NoError(t, conn.Close())
 }
 
+// With a column type cache, empty result sets report the types of a previous
+// result of the same query.
+func Test_ColumnTypesEmptyCached(t *testing.T) {
+	_, cleanup := newNode(t)
+	defer cleanup()
+
+	store := newStore(t, "@1")
+	drv, err := cowsqldriver.New(store, cowsqldriver.WithLogFunc(logging.Test(t)), cowsqldriver.WithColumnTypeCache(16))
+	require.NoError(t, err)
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	execer := conn.(driver.ExecerContext)
+	_, err = execer.ExecContext(context.Background(), "CREATE TABLE test (n INT)", nil)
+	require.NoError(t, err)
+	_, err = execer.ExecContext(context.Background(), "INSERT INTO test(n) VALUES(1)", nil)
+	require.NoError(t, err)
+
+	stmt, err := conn.Prepare("SELECT n FROM test WHERE n > ?")
+	require.NoError(t, err)
+
+	for _, n := range []int64{0, 1} {
+		rows, err := stmt.Query([]driver.Value{n})
+		require.NoError(t, err)
+
+		rowTypes, ok := rows.(driver.RowsColumnTypeDatabaseTypeName)
+		require.True(t, ok)
+		assert.Equal(t, "INTEGER", rowTypes.ColumnTypeDatabaseTypeName(0))
+
+		require.NoError(t, rows.Close())
+	}
+
+	require.NoError(t, stmt.Close())
+	assert.NoError(t, conn.Close())
+}
+
 func Test_ColumnTypesExists(t *testing.T) {
 	drv, cleanup := newDriver(t)
 	defer cleanup()
@@ -583,6 +651,266 @@ func Test_ZeroColumns(t *testing.T) {
 	require.NoError(t, conn.Close())
 }
 
+func TestConn_Ping(t *testing.T) {
+	drv, cleanup := newDriver(t)
+	defer cleanup()
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	pinger := conn.(driver.Pinger)
+	require.NoError(t, pinger.Ping(context.Background()))
+
+	validator := conn.(driver.Validator)
+	assert.True(t, validator.IsValid())
+
+	assert.NoError(t, conn.Close())
+}
+
+func TestConn_CheckNamedValue(t *testing.T) {
+	conn := &cowsqldriver.Conn{}
+
+	cases := []struct {
+		value    interface{}
+		expected driver.Value
+	}{
+		{int64(1), int64(1)},
+		{"hello", "hello"},
+		{nil, nil},
+		{int(2), int64(2)},
+		{uint8(3), int64(3)},
+		{float32(0.5), float64(0.5)},
+	}
+
+	for _, c := range cases {
+		nv := &driver.NamedValue{Ordinal: 1, Value: c.value}
+		require.NoError(t, conn.CheckNamedValue(nv))
+		assert.Equal(t, c.expected, nv.Value)
+	}
+
+	nv := &driver.NamedValue{Ordinal: 1, Value: struct{}{}}
+	assert.Error(t, conn.CheckNamedValue(nv))
+}
+
+func TestConn_TypeMapper(t *testing.T) {
+	_, cleanup := newNode(t)
+	defer cleanup()
+
+	type celsius float64
+
+	store := newStore(t, "@1")
+	encoder := func(v interface{}) (driver.Value, error) {
+		return float64(v.(celsius)), nil
+	}
+	decoder := func(v driver.Value) (driver.Value, error) {
+		return celsius(v.(float64)), nil
+	}
+
+	drv, err := cowsqldriver.New(
+		store,
+		cowsqldriver.WithLogFunc(logging.Test(t)),
+		cowsqldriver.WithValueEncoder(celsius(0), encoder),
+		cowsqldriver.WithTypeDecoder("float", decoder))
+	require.NoError(t, err)
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	_, err = conn.Begin()
+	require.NoError(t, err)
+
+	execer := conn.(driver.ExecerContext)
+	checker := conn.(driver.NamedValueChecker)
+
+	_, err = execer.ExecContext(context.Background(), "CREATE TABLE test (temp REAL)", nil)
+	require.NoError(t, err)
+
+	value := driver.NamedValue{Ordinal: 1, Value: celsius(21.5)}
+	require.NoError(t, checker.CheckNamedValue(&value))
+	assert.Equal(t, float64(21.5), value.Value)
+
+	_, err = execer.ExecContext(context.Background(), "INSERT INTO test(temp) VALUES(?)", []driver.NamedValue{value})
+	require.NoError(t, err)
+
+	queryer := conn.(driver.Queryer)
+
+	rows, err := queryer.Query("SELECT temp FROM test", nil)
+	require.NoError(t, err)
+
+	values := make([]driver.Value, 1)
+	require.NoError(t, rows.Next(values))
+	assert.Equal(t, celsius(21.5), values[0])
+
+	require.NoError(t, rows.Close())
+	assert.NoError(t, conn.Close())
+}
+
+func TestConn_QueryRewriter(t *testing.T) {
+	_, cleanup := newNode(t)
+	defer cleanup()
+
+	store := newStore(t, "@1")
+	rewriter := func(sql string) string {
+		return strings.Replace(sql, "foo", "test", -1)
+	}
+
+	drv, err := cowsqldriver.New(store, cowsqldriver.WithLogFunc(logging.Test(t)), cowsqldriver.WithQueryRewriter(rewriter))
+	require.NoError(t, err)
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	_, err = conn.Begin()
+	require.NoError(t, err)
+
+	execer := conn.(driver.Execer)
+
+	_, err = execer.Exec("CREATE TABLE foo (n INT)", nil)
+	require.NoError(t, err)
+
+	_, err = execer.Exec("INSERT INTO test(n) VALUES(1)", nil)
+	require.NoError(t, err)
+
+	queryer := conn.(driver.Queryer)
+
+	rows, err := queryer.Query("SELECT n FROM foo", nil)
+	require.NoError(t, err)
+
+	values := make([]driver.Value, 1)
+	require.NoError(t, rows.Next(values))
+	assert.Equal(t, int64(1), values[0])
+
+	require.NoError(t, rows.Close())
+	assert.NoError(t, conn.Close())
+}
+
+func TestConn_SlowQueryPlan(t *testing.T) {
+	_, cleanup := newNode(t)
+	defer cleanup()
+
+	store := newStore(t, "@1")
+	captured := make(chan cowsqldriver.SlowQuery, 1)
+	hook := func(slow cowsqldriver.SlowQuery) { captured <- slow }
+
+	drv, err := cowsqldriver.New(store, cowsqldriver.WithLogFunc(logging.Test(t)), cowsqldriver.WithSlowQueryPlan(0, hook))
+	require.NoError(t, err)
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	execer := conn.(driver.Execer)
+
+	_, err = execer.Exec("CREATE TABLE test (n INT)", nil)
+	require.NoError(t, err)
+	<-captured
+
+	queryer := conn.(driver.Queryer)
+
+	rows, err := queryer.Query("SELECT n FROM test WHERE n > ?", []driver.Value{int64(1)})
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	slow := <-captured
+	require.NoError(t, slow.Err)
+	assert.Equal(t, "SELECT n FROM test WHERE n > ?", slow.Query)
+	require.Len(t, slow.Plan, 1)
+	assert.Contains(t, slow.Plan[0], "SCAN")
+
+	assert.NoError(t, conn.Close())
+}
+
+type requestIDKey struct{}
+
+// Trace messages use the logger extracted from the context of the call.
+func TestConn_ContextLogger(t *testing.T) {
+	_, cleanup := newNode(t)
+	defer cleanup()
+
+	store := newStore(t, "@1")
+
+	messages := []string{}
+	extract := func(ctx context.Context) client.LogFunc {
+		id, ok := ctx.Value(requestIDKey{}).(string)
+		if !ok {
+			return nil
+		}
+		return func(l client.LogLevel, format string, a ...interface{}) {
+			messages = append(messages, id+": "+fmt.Sprintf(format, a...))
+		}
+	}
+
+	drv, err := cowsqldriver.New(
+		store,
+		cowsqldriver.WithLogFunc(logging.Test(t)),
+		cowsqldriver.WithTracing(client.LogDebug),
+		cowsqldriver.WithContextLogger(extract))
+	require.NoError(t, err)
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-1")
+	_, err = conn.(driver.ExecerContext).ExecContext(ctx, "CREATE TABLE test (n INT)", nil)
+	require.NoError(t, err)
+
+	_, err = conn.(driver.ExecerContext).ExecContext(context.Background(), "CREATE TABLE other (n INT)", nil)
+	require.NoError(t, err)
+
+	require.Len(t, messages, 1)
+	assert.True(t, strings.HasPrefix(messages[0], "req-1: "))
+	assert.Contains(t, messages[0], "CREATE TABLE test")
+
+	assert.NoError(t, conn.Close())
+}
+
+func TestDriver_StatementStats(t *testing.T) {
+	_, cleanup := newNode(t)
+	defer cleanup()
+
+	store := newStore(t, "@1")
+
+	drv, err := cowsqldriver.New(store, cowsqldriver.WithLogFunc(logging.Test(t)), cowsqldriver.WithStatementStats(0))
+	require.NoError(t, err)
+
+	conn, err := drv.Open("test.db")
+	require.NoError(t, err)
+
+	execer := conn.(driver.Execer)
+
+	_, err = execer.Exec("CREATE TABLE test (n INT)", nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = execer.Exec(fmt.Sprintf("INSERT INTO test(n) VALUES(%d)", i), nil)
+		require.NoError(t, err)
+	}
+
+	queryer := conn.(driver.Queryer)
+
+	rows, err := queryer.Query("SELECT n FROM test WHERE n >= 1", nil)
+	require.NoError(t, err)
+	values := make([]driver.Value, 1)
+	for rows.Next(values) == nil {
+	}
+	require.NoError(t, rows.Close())
+
+	statements := map[string]cowsqldriver.StatementStats{}
+	for _, statement := range drv.StatementStats() {
+		assert.Equal(t, "test.db", statement.Database)
+		statements[statement.Query] = statement
+	}
+
+	insert := statements["INSERT INTO test(n) VALUES(?)"]
+	assert.Equal(t, uint64(3), insert.Calls)
+	assert.Equal(t, uint64(3), insert.Rows)
+
+	query := statements["SELECT n FROM test WHERE n >= ?"]
+	assert.Equal(t, uint64(1), query.Calls)
+	assert.Equal(t, uint64(2), query.Rows)
+
+	assert.NoError(t, conn.Close())
+}
+
 func newDriver(t *testing.T) (*cowsqldriver.Driver, func()) {
 	t.Helper()
 
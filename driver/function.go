@@ -0,0 +1,114 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// ErrNoSuchFunction is matched, using errors.Is, by the errors returned when
+// a statement uses an SQL function that the server doesn't provide, for
+// example a custom function registered only on some nodes. The name of the
+// function can be retrieved with MissingFunction.
+var ErrNoSuchFunction = protocol.ErrNoSuchFunction
+
+// MissingFunction returns the name of the SQL function that the given error
+// reports as unknown to the server, or an empty string if the error is not
+// about an unknown function.
+func MissingFunction(err error) string {
+	var e Error
+	if !stderrors.As(err, &e) {
+		return ""
+	}
+	return e.Function()
+}
+
+// WithRequiredFunctions makes the driver check that the server provides the
+// SQL functions with the given names whenever a new connection is opened,
+// failing with an error matching ErrNoSuchFunction if it doesn't.
+//
+// This makes missing functions surface at Open or at the first statement,
+// rather than when a statement using them is executed. Each check costs a
+// round trip per function, for each new connection.
+func WithRequiredFunctions(names ...string) Option {
+	return func(options *options) {
+		options.RequiredFunctions = names
+	}
+}
+
+// MissingFunctions returns the names of the SQL functions among the given
+// ones that are not provided by the server the given database is connected
+// to.
+func MissingFunctions(ctx context.Context, db *sql.DB, names ...string) ([]string, error) {
+	return missingFunctions(names, func(query string) error {
+		stmt, err := db.PrepareContext(ctx, query)
+		if err == nil {
+			stmt.Close()
+		}
+		return err
+	})
+}
+
+// Return the names of the required functions the server doesn't provide.
+func (c *Conn) missingFunctions(ctx context.Context, names []string) ([]string, error) {
+	return missingFunctions(names, func(query string) error {
+		stmt, err := c.PrepareContext(ctx, query)
+		if err == nil {
+			stmt.Close()
+		}
+		return err
+	})
+}
+
+// Check which of the given functions are missing by preparing a statement
+// calling each of them with the given function.
+func missingFunctions(names []string, prepare func(query string) error) ([]string, error) {
+	missing := []string{}
+	for _, name := range names {
+		if !isIdentifier(name) {
+			return nil, errors.Errorf("invalid function name %q", name)
+		}
+		err := prepare(fmt.Sprintf("SELECT %s()", name))
+		if err == nil {
+			continue
+		}
+		if stderrors.Is(err, ErrNoSuchFunction) {
+			missing = append(missing, name)
+			continue
+		}
+		// Other SQL errors, for example about the wrong number of
+		// arguments, mean that the function exists.
+		var e Error
+		if !stderrors.As(err, &e) {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// Return an error matching ErrNoSuchFunction and naming the given missing
+// functions.
+func missingFunctionsError(missing []string) error {
+	return fmt.Errorf("%w: %s", ErrNoSuchFunction, strings.Join(missing, ", "))
+}
+
+// Return whether the given name is a valid SQL identifier.
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
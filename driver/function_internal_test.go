@@ -0,0 +1,58 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingFunction(t *testing.T) {
+	err := fmt.Errorf("prepare: %w", Error{Code: 1, Message: "no such function: levenshtein"})
+	assert.True(t, errors.Is(err, ErrNoSuchFunction))
+	assert.Equal(t, "levenshtein", MissingFunction(err))
+
+	err = Error{Code: 1, Message: "no such table: test"}
+	assert.False(t, errors.Is(err, ErrNoSuchFunction))
+	assert.Equal(t, "", MissingFunction(err))
+	assert.Equal(t, "", MissingFunction(io.EOF))
+}
+
+func TestMissingFunctions(t *testing.T) {
+	prepare := func(query string) error {
+		switch query {
+		case "SELECT upper()":
+			return Error{Code: 1, Message: "wrong number of arguments to function upper()"}
+		case "SELECT random()":
+			return nil
+		case "SELECT levenshtein()":
+			return Error{Code: 1, Message: "no such function: levenshtein"}
+		}
+		return io.EOF
+	}
+
+	missing, err := missingFunctions([]string{"upper", "random", "levenshtein"}, prepare)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"levenshtein"}, missing)
+
+	_, err = missingFunctions([]string{"other"}, prepare)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = missingFunctions([]string{"x); DROP TABLE test; --"}, prepare)
+	assert.EqualError(t, err, `invalid function name "x); DROP TABLE test; --"`)
+
+	err = missingFunctionsError([]string{"a", "b"})
+	assert.True(t, errors.Is(err, ErrNoSuchFunction))
+	assert.EqualError(t, err, "no such function: a, b")
+}
+
+func TestIsIdentifier(t *testing.T) {
+	assert.True(t, isIdentifier("json_extract"))
+	assert.True(t, isIdentifier("_f1"))
+	assert.False(t, isIdentifier(""))
+	assert.False(t, isIdentifier("1f"))
+	assert.False(t, isIdentifier("f()"))
+}
@@ -101,6 +101,21 @@ func TestIntegration_ConstraintError(t *testing.T) {
 	}
 }
 
+func TestIntegration_CountRows(t *testing.T) {
+	db, _, cleanup := newDB(t, 1)
+	defer cleanup()
+
+	_, err := db.Exec("CREATE TABLE test (n INT)")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO test (n) VALUES (1), (2), (3)")
+	require.NoError(t, err)
+
+	n, err := driver.CountRows(context.Background(), db, "SELECT n FROM test WHERE n > ?", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
 func TestIntegration_ExecBindError(t *testing.T) {
 	db, _, cleanup := newDB(t, 1)
 	defer cleanup()
@@ -179,6 +194,79 @@ func TestIntegration_LargeQuery(t *testing.T) {
 	require.NoError(t, tx.Rollback())
 }
 
+// The connection can be used while iterating rows that have been received in
+// full.
+func TestIntegration_InterleavedQueryAndExec(t *testing.T) {
+	db, _, cleanup := newDB(t, 1)
+	defer cleanup()
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	_, err = tx.Exec("CREATE TABLE test (n INT)")
+	require.NoError(t, err)
+
+	_, err = tx.Exec("INSERT INTO test(n) VALUES(1), (2), (3)")
+	require.NoError(t, err)
+
+	rows, err := tx.Query("SELECT n FROM test ORDER BY n")
+	require.NoError(t, err)
+
+	ns := []int64{}
+	for rows.Next() {
+		var n int64
+		require.NoError(t, rows.Scan(&n))
+		ns = append(ns, n)
+
+		_, err := tx.Exec("INSERT INTO test(n) VALUES(?)", n*10)
+		require.NoError(t, err)
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+
+	assert.Equal(t, []int64{1, 2, 3}, ns)
+
+	var count int64
+	require.NoError(t, tx.QueryRow("SELECT count(*) FROM test").Scan(&count))
+	assert.Equal(t, int64(6), count)
+
+	require.NoError(t, tx.Rollback())
+}
+
+// Using the connection while the server is still sending rows fails cleanly.
+func TestIntegration_ConnBusy(t *testing.T) {
+	db, _, cleanup := newDB(t, 1)
+	defer cleanup()
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	_, err = tx.Exec("CREATE TABLE test (n INT)")
+	require.NoError(t, err)
+
+	stmt, err := tx.Prepare("INSERT INTO test(n) VALUES(?)")
+	require.NoError(t, err)
+	for i := 0; i < 512; i++ {
+		_, err = stmt.Exec(int64(i))
+		require.NoError(t, err)
+	}
+	require.NoError(t, stmt.Close())
+
+	rows, err := tx.Query("SELECT n FROM test")
+	require.NoError(t, err)
+
+	require.True(t, rows.Next())
+	_, err = tx.Exec("INSERT INTO test(n) VALUES(1)")
+	assert.Equal(t, driver.ErrConnBusy, err)
+
+	require.NoError(t, rows.Close())
+
+	_, err = tx.Exec("INSERT INTO test(n) VALUES(1)")
+	require.NoError(t, err)
+
+	require.NoError(t, tx.Rollback())
+}
+
 // Build a 2-node cluster, kill one node and recover the other.
 func TestIntegration_Recover(t *testing.T) {
 	db, helpers, cleanup := newDB(t, 2)
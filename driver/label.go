@@ -0,0 +1,61 @@
+package driver
+
+import (
+	"context"
+	"strings"
+)
+
+type labelsKey struct{}
+
+// WithLabel returns a copy of the given context that attaches the given label
+// (for example "job=billing") to the statements executed with it. Labels
+// added to a context that already has some are appended to them.
+//
+// Labels are included in the messages logged when tracing is enabled, see
+// WithTracing, and are also sent to the server as SQL comments if the driver
+// was created with WithLabelComments.
+func WithLabel(ctx context.Context, label string) context.Context {
+	labels := labelsOf(ctx)
+	extended := make([]string, len(labels), len(labels)+1)
+	copy(extended, labels)
+	return context.WithValue(ctx, labelsKey{}, append(extended, label))
+}
+
+// WithLabelComments makes the driver prepend the labels attached to the
+// context of a statement with WithLabel to its SQL text, as a comment, so
+// they also show up in server-side logs.
+func WithLabelComments() Option {
+	return func(options *options) {
+		options.LabelComments = true
+	}
+}
+
+// Return the labels attached to the given context.
+func labelsOf(ctx context.Context) []string {
+	labels, _ := ctx.Value(labelsKey{}).([]string)
+	return labels
+}
+
+// Return the labels attached to the given context formatted for inclusion in
+// a log message, or an empty string if there are none.
+func labelsSuffix(ctx context.Context) string {
+	labels := labelsOf(ctx)
+	if len(labels) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(labels, " ") + "]"
+}
+
+// Prepend the labels attached to the given context to the given SQL text as a
+// comment, if enabled.
+func (c *Conn) annotate(ctx context.Context, query string) string {
+	if !c.labelComments {
+		return query
+	}
+	labels := labelsOf(ctx)
+	if len(labels) == 0 {
+		return query
+	}
+	comment := strings.Replace(strings.Join(labels, " "), "*/", "* /", -1)
+	return "/* " + comment + " */ " + query
+}
@@ -0,0 +1,32 @@
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLabel(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "", labelsSuffix(ctx))
+
+	parent := WithLabel(ctx, "job=billing")
+	child1 := WithLabel(parent, "step=1")
+	child2 := WithLabel(parent, "step=2")
+
+	assert.Equal(t, " [job=billing]", labelsSuffix(parent))
+	assert.Equal(t, " [job=billing step=1]", labelsSuffix(child1))
+	assert.Equal(t, " [job=billing step=2]", labelsSuffix(child2))
+}
+
+func TestConn_Annotate(t *testing.T) {
+	ctx := WithLabel(context.Background(), "job=*/billing")
+
+	c := &Conn{}
+	assert.Equal(t, "SELECT 1", c.annotate(ctx, "SELECT 1"))
+
+	c.labelComments = true
+	assert.Equal(t, "SELECT 1", c.annotate(context.Background(), "SELECT 1"))
+	assert.Equal(t, "/* job=* /billing */ SELECT 1", c.annotate(ctx, "SELECT 1"))
+}
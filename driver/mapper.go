@@ -0,0 +1,119 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+)
+
+// ValueEncoder converts a statement parameter of a custom Go type into a value
+// natively supported by the wire protocol, i.e. one of int64, float64, bool,
+// []byte, string, time.Time or nil.
+type ValueEncoder func(value interface{}) (driver.Value, error)
+
+// ValueDecoder converts a value read from a result set column into a custom
+// Go value. It's never invoked for NULL values.
+type ValueDecoder func(value driver.Value) (driver.Value, error)
+
+// WithValueEncoder registers an encoder for statement parameters having the
+// same Go type as the given sample value.
+//
+// For example, to store time.Time parameters as unix timestamps:
+//
+//	WithValueEncoder(time.Time{}, func(v interface{}) (driver.Value, error) {
+//	        return v.(time.Time).Unix(), nil
+//	})
+func WithValueEncoder(sample interface{}, encoder ValueEncoder) Option {
+	return func(options *options) {
+		if options.Encoders == nil {
+			options.Encoders = map[reflect.Type]ValueEncoder{}
+		}
+		options.Encoders[reflect.TypeOf(sample)] = encoder
+	}
+}
+
+// WithTypeDecoder registers a decoder for all result set columns of the given
+// type, as returned by Rows.ColumnTypeDatabaseTypeName. The match is case
+// insensitive.
+//
+// The engine doesn't send the declared type of columns, only the storage
+// class of their values: one of INTEGER, FLOAT, TEXT, BLOB or NULL, or TIME
+// and BOOL for columns declared as DATETIME, DATE, TIMESTAMP or BOOLEAN. The
+// type of a column is the one of its value in the first row, so decoders
+// should check the type of the values they get.
+//
+// For example, to decode BOOL columns as custom flags:
+//
+//	WithTypeDecoder("BOOL", func(v driver.Value) (driver.Value, error) {
+//	        return flag(v.(bool)), nil
+//	})
+func WithTypeDecoder(typeName string, decoder ValueDecoder) Option {
+	return func(options *options) {
+		if options.Decoders == nil {
+			options.Decoders = map[string]ValueDecoder{}
+		}
+		options.Decoders[strings.ToUpper(typeName)] = decoder
+	}
+}
+
+// Hold the custom conversions registered with WithValueEncoder and
+// WithTypeDecoder.
+type typeMapper struct {
+	encoders map[reflect.Type]ValueEncoder
+	decoders map[string]ValueDecoder
+}
+
+// Return a new type mapper, or nil if there are no custom conversions.
+func newTypeMapper(encoders map[reflect.Type]ValueEncoder, decoders map[string]ValueDecoder) *typeMapper {
+	if len(encoders) == 0 && len(decoders) == 0 {
+		return nil
+	}
+	return &typeMapper{
+		encoders: encoders,
+		decoders: decoders,
+	}
+}
+
+// Convert the given value using the encoder registered for its type, if any.
+func (m *typeMapper) encode(value interface{}) (driver.Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encoder, ok := m.encoders[reflect.TypeOf(value)]
+	if !ok {
+		return value, nil
+	}
+	return encoder(value)
+}
+
+// Return the decoders matching the given column types, or an empty slice if
+// no column has a decoder.
+func (m *typeMapper) columnDecoders(types []string) []ValueDecoder {
+	decoders := make([]ValueDecoder, len(types))
+	found := false
+	for i, typeName := range types {
+		if decoder, ok := m.decoders[strings.ToUpper(typeName)]; ok {
+			decoders[i] = decoder
+			found = true
+		}
+	}
+	if !found {
+		return []ValueDecoder{}
+	}
+	return decoders
+}
+
+// Convert the values of a row using the given per-column decoders.
+func decodeValues(decoders []ValueDecoder, dest []driver.Value) error {
+	for i, decoder := range decoders {
+		if decoder == nil || dest[i] == nil {
+			continue
+		}
+		value, err := decoder(dest[i])
+		if err != nil {
+			return err
+		}
+		dest[i] = value
+	}
+	return nil
+}
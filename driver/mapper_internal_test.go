@@ -0,0 +1,30 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Decoders are matched against the column types, regardless of case.
+func TestTypeMapper_ColumnDecoders(t *testing.T) {
+	o := defaultOptions()
+	negate := func(v driver.Value) (driver.Value, error) {
+		return !v.(bool), nil
+	}
+	WithTypeDecoder("bool", negate)(o)
+
+	m := newTypeMapper(o.Encoders, o.Decoders)
+
+	assert.Empty(t, m.columnDecoders([]string{"INTEGER", "TEXT"}))
+
+	decoders := m.columnDecoders([]string{"INTEGER", "BOOL"})
+	require.Len(t, decoders, 2)
+	assert.Nil(t, decoders[0])
+
+	dest := []driver.Value{int64(1), true}
+	require.NoError(t, decodeValues(decoders, dest))
+	assert.Equal(t, []driver.Value{int64(1), false}, dest)
+}
@@ -0,0 +1,87 @@
+package driver
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DatabaseMetrics holds usage statistics about a single database, as observed
+// by a Driver.
+type DatabaseMetrics struct {
+	// Number of statements executed, including failed ones.
+	Statements uint64
+
+	// Number of rows returned by queries.
+	Rows uint64
+
+	// Total time spent waiting for statements to be executed, including
+	// network round trips.
+	Time time.Duration
+}
+
+// Track usage statistics of each database accessed by a driver.
+type metrics struct {
+	mu        sync.Mutex
+	databases map[string]*DatabaseMetrics
+}
+
+func newMetrics() *metrics {
+	return &metrics{databases: map[string]*DatabaseMetrics{}}
+}
+
+// Return the entry of the given database, creating it if needed. Must be
+// called with the lock held.
+func (m *metrics) database(name string) *DatabaseMetrics {
+	database, ok := m.databases[name]
+	if !ok {
+		database = &DatabaseMetrics{}
+		m.databases[name] = database
+	}
+	return database
+}
+
+// Record a statement executed against the given database.
+func (m *metrics) statement(name string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	database := m.database(name)
+	database.Statements++
+	database.Time += duration
+}
+
+// Record the rows returned by a query against the given database.
+func (m *metrics) rows(name string, n uint64) {
+	if n == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.database(name).Rows += n
+}
+
+func (m *metrics) get() map[string]DatabaseMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	databases := make(map[string]DatabaseMetrics, len(m.databases))
+	for name, database := range m.databases {
+		databases[name] = *database
+	}
+	return databases
+}
+
+// Return the name of the database referenced by the given URI, without any
+// query parameters.
+func databaseName(uri string) string {
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		return uri[:i]
+	}
+	return uri
+}
+
+// Metrics returns usage statistics about each database accessed through the
+// driver, keyed by database name. It can be used to identify the databases
+// generating most of the load on a cluster hosting many of them.
+func (d *Driver) Metrics() map[string]DatabaseMetrics {
+	return d.metrics.get()
+}
@@ -0,0 +1,23 @@
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics()
+
+	m.statement(databaseName("foo?_txlock=immediate"), time.Second)
+	m.statement("foo", time.Second)
+	m.rows("foo", 3)
+	m.statement("bar", time.Millisecond)
+	m.rows("baz", 0)
+
+	assert.Equal(t, map[string]DatabaseMetrics{
+		"foo": {Statements: 2, Rows: 3, Time: 2 * time.Second},
+		"bar": {Statements: 1, Time: time.Millisecond},
+	}, m.get())
+}
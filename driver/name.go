@@ -0,0 +1,64 @@
+package driver
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidDatabaseName is returned as root cause by Driver.Open and
+// Driver.OpenConnector when the given database name can't be opened by
+// cowsql, for example because it contains a directory segment.
+var ErrInvalidDatabaseName = errors.New("invalid database name")
+
+// WithRejectDatabaseURIs makes the driver reject database names in the
+// "file:" URI format, like "file:test.db?cache=shared", with an error whose
+// root cause is ErrInvalidDatabaseName. Plain names with query parameters,
+// like "test.db?_foreign_keys=1", are still accepted.
+//
+// If not used, "file:" URIs are accepted as long as they name a file without
+// any directory segment.
+func WithRejectDatabaseURIs() Option {
+	return func(options *options) {
+		options.RejectDatabaseURIs = true
+	}
+}
+
+// Check that the given database name is a file name, possibly in the "file:"
+// URI format and possibly with query parameters, that cowsql can open.
+func checkDatabaseName(name string, rejectURIs bool) error {
+	path, query := name, ""
+	if i := strings.IndexByte(name, '?'); i >= 0 {
+		path, query = name[:i], name[i+1:]
+	}
+
+	if strings.HasPrefix(path, "file:") {
+		if rejectURIs {
+			return errors.Wrapf(ErrInvalidDatabaseName, "%q: URIs are not allowed", name)
+		}
+		path = strings.TrimPrefix(path, "file:")
+	}
+
+	switch {
+	case path == "":
+		return errors.Wrapf(ErrInvalidDatabaseName, "%q: empty file name", name)
+	case path == ":memory:":
+		return errors.Wrapf(ErrInvalidDatabaseName, "%q: in-memory databases are not supported", name)
+	case strings.Contains(path, "/"):
+		return errors.Wrapf(ErrInvalidDatabaseName, "%q: must be a file name without directory", name)
+	}
+
+	if query == "" {
+		return nil
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return errors.Wrapf(ErrInvalidDatabaseName, "%q: parse query parameters: %v", name, err)
+	}
+	if values.Get("mode") == "memory" {
+		return errors.Wrapf(ErrInvalidDatabaseName, "%q: in-memory databases are not supported", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDatabaseName(t *testing.T) {
+	for _, name := range []string{
+		"test.db",
+		"test.db?_foreign_keys=1",
+		"file:test.db",
+		"file:test.db?cache=shared",
+	} {
+		assert.NoError(t, checkDatabaseName(name, false), name)
+	}
+
+	cases := map[string]string{
+		"":                      `"": empty file name`,
+		"?cache=shared":         `"?cache=shared": empty file name`,
+		":memory:":              `":memory:": in-memory databases are not supported`,
+		"test.db?mode=memory":   `"test.db?mode=memory": in-memory databases are not supported`,
+		"data/test.db":          `"data/test.db": must be a file name without directory`,
+		"file:///data/test.db":  `"file:///data/test.db": must be a file name without directory`,
+		"test.db?cache=%zz":     `"test.db?cache=%zz": parse query parameters: invalid URL escape "%zz"`,
+		"file:test.db?mode=rwc": "",
+	}
+	for name, message := range cases {
+		err := checkDatabaseName(name, false)
+		if message == "" {
+			assert.NoError(t, err, name)
+			continue
+		}
+		assert.Equal(t, ErrInvalidDatabaseName, errors.Cause(err), name)
+		assert.EqualError(t, err, message+": invalid database name", name)
+	}
+}
+
+func TestCheckDatabaseName_RejectURIs(t *testing.T) {
+	assert.NoError(t, checkDatabaseName("test.db?_foreign_keys=1", true))
+
+	err := checkDatabaseName("file:test.db", true)
+	assert.Equal(t, ErrInvalidDatabaseName, errors.Cause(err))
+	assert.EqualError(t, err, `"file:test.db": URIs are not allowed: invalid database name`)
+}
+
+func TestDriver_OpenConnectorInvalidName(t *testing.T) {
+	d := &Driver{}
+
+	_, err := d.OpenConnector("data/test.db")
+	assert.Equal(t, ErrInvalidDatabaseName, errors.Cause(err))
+
+	_, err = d.Open("data/test.db")
+	assert.Equal(t, ErrInvalidDatabaseName, errors.Cause(err))
+}
@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+)
+
+// WithPrefetch makes queries returning large result sets request the next
+// batch of rows from the server in the background, while the current batch
+// is being iterated, so network and processing time overlap.
+//
+// Each open result set uses an additional buffer as large as a batch of
+// rows. The connection can't be used for other requests until the rows are
+// closed, as without prefetching.
+func WithPrefetch() Option {
+	return func(options *options) {
+		options.Prefetch = true
+	}
+}
+
+// Receive the next batch of rows in the background, using a spare response
+// buffer.
+type prefetcher struct {
+	protocol *protocol.Protocol
+	spare    protocol.Message
+	init     bool       // Whether the spare buffer was initialized
+	pending  chan error // Outcome of the fetch in progress, nil if none
+}
+
+func newPrefetcher(protocol *protocol.Protocol) *prefetcher {
+	return &prefetcher{protocol: protocol}
+}
+
+// Start receiving the next batch of rows.
+func (p *prefetcher) start(ctx context.Context) {
+	if !p.init {
+		p.spare.Init(4096)
+		p.init = true
+	}
+	pending := make(chan error, 1)
+	p.pending = pending
+	go func() {
+		pending <- p.protocol.More(ctx, &p.spare)
+	}()
+}
+
+// Wait for the batch being received, and swap it with the content of the
+// given response, which must not be in use anymore.
+//
+// If no batch is being received, the next one is received synchronously.
+func (p *prefetcher) wait(ctx context.Context, response *protocol.Message) error {
+	if p.pending == nil {
+		return p.protocol.More(ctx, response)
+	}
+	err := <-p.pending
+	p.pending = nil
+	if err != nil {
+		return err
+	}
+	*response, p.spare = p.spare, *response
+	return nil
+}
+
+// Return whether a batch is being received.
+func (p *prefetcher) busy() bool {
+	return p.pending != nil
+}
+
+// Release the spare buffer. No batch must be being received.
+func (p *prefetcher) close() {
+	if p.init {
+		p.spare.Release()
+		p.init = false
+	}
+}
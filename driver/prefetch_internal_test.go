@@ -0,0 +1,54 @@
+package driver
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefetcher(t *testing.T) {
+	conn, server := net.Pipe()
+	defer server.Close()
+
+	proceed := make(chan struct{})
+	go func() {
+		io.ReadFull(server, make([]byte, 8)) // Handshake
+
+		// First batch, received in the background.
+		server.Write([]byte{1, 0, 0, 0, protocol.ResponseEmpty, 0, 0, 0})
+		server.Write(make([]byte, 8))
+
+		// Second batch, received synchronously.
+		<-proceed
+		server.Write([]byte{2, 0, 0, 0, protocol.ResponseFailure, 0, 0, 0})
+		server.Write([]byte{1, 0, 0, 0, 0, 0, 0, 0})
+		server.Write([]byte("boom\x00\x00\x00\x00"))
+	}()
+
+	ctx := context.Background()
+	p, err := protocol.Handshake(ctx, conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	response := protocol.Message{}
+	response.Init(64)
+	defer response.Release()
+
+	prefetcher := newPrefetcher(p)
+	defer prefetcher.close()
+
+	prefetcher.start(ctx)
+	assert.True(t, prefetcher.busy())
+	require.NoError(t, prefetcher.wait(ctx, &response))
+	assert.False(t, prefetcher.busy())
+	assert.NoError(t, protocol.DecodeEmpty(&response))
+
+	close(proceed)
+	require.NoError(t, prefetcher.wait(ctx, &response))
+	assert.EqualError(t, protocol.DecodeEmpty(&response), "boom (1)")
+}
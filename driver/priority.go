@@ -0,0 +1,114 @@
+package driver
+
+import (
+	"context"
+	"sync"
+)
+
+// Priority classifies statements for the scheduling enabled by
+// WithBatchConcurrency.
+type Priority int
+
+// Available priorities.
+const (
+	PriorityInteractive Priority = iota // Default, never delayed.
+	PriorityBatch                       // Bulk work, yields to interactive statements.
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of the given context that tags the statements
+// executed with it with the given priority.
+//
+// Statements executed with a context that has no priority are interactive.
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// Return the priority of the statements executed with the given context.
+func priorityOf(ctx context.Context) Priority {
+	priority, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		return PriorityInteractive
+	}
+	return priority
+}
+
+// WithBatchConcurrency enables scheduling of statements based on the priority
+// set with WithPriority, so interactive traffic isn't starved behind bulk
+// jobs.
+//
+// At most n batch statements are executed at the same time across all
+// connections of the driver, and only while no interactive statement is in
+// flight. Interactive statements are never delayed. Since interactive
+// statements always take precedence, a steady interactive load can delay
+// batch statements until their context is done.
+//
+// If not used, or if n is 0, all statements are executed right away.
+func WithBatchConcurrency(n int) Option {
+	return func(options *options) {
+		options.BatchConcurrency = n
+	}
+}
+
+// Admit statements according to their priority.
+type scheduler struct {
+	mu          sync.Mutex
+	limit       int           // Maximum number of batch statements in flight
+	interactive int           // Interactive statements in flight
+	batch       int           // Batch statements in flight
+	released    chan struct{} // Closed when a statement completes
+}
+
+func newScheduler(limit int) *scheduler {
+	return &scheduler{limit: limit, released: make(chan struct{})}
+}
+
+// Wait until a statement executed with the given context can be sent to the
+// server, returning its priority.
+func (s *scheduler) acquire(ctx context.Context) (Priority, error) {
+	priority := priorityOf(ctx)
+	if s == nil {
+		return priority, nil
+	}
+
+	for {
+		s.mu.Lock()
+		if priority != PriorityBatch {
+			s.interactive++
+			s.mu.Unlock()
+			return priority, nil
+		}
+		if s.interactive == 0 && s.batch < s.limit {
+			s.batch++
+			s.mu.Unlock()
+			return priority, nil
+		}
+		released := s.released
+		s.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return priority, ctx.Err()
+		}
+	}
+}
+
+// Signal that a statement with the given priority completed.
+func (s *scheduler) release(priority Priority) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if priority == PriorityBatch {
+		s.batch--
+	} else {
+		s.interactive--
+	}
+	close(s.released)
+	s.released = make(chan struct{})
+}
@@ -0,0 +1,62 @@
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	s := newScheduler(1)
+	ctx := context.Background()
+	batch := WithPriority(ctx, PriorityBatch)
+
+	// Batch statements wait for interactive ones.
+	priority, err := s.acquire(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, PriorityInteractive, priority)
+
+	admitted := make(chan struct{})
+	go func() {
+		_, err := s.acquire(batch)
+		assert.NoError(t, err)
+		close(admitted)
+	}()
+
+	select {
+	case <-admitted:
+		t.Fatal("batch statement admitted while interactive one in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	s.release(priority)
+	<-admitted
+
+	// At most one batch statement at a time.
+	timeout, cancel := context.WithTimeout(batch, 10*time.Millisecond)
+	defer cancel()
+	_, err = s.acquire(timeout)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// Interactive statements are never delayed.
+	priority, err = s.acquire(ctx)
+	require.NoError(t, err)
+	s.release(priority)
+	s.release(PriorityBatch)
+
+	assert.Equal(t, 0, s.batch)
+	assert.Equal(t, 0, s.interactive)
+}
+
+func TestScheduler_Disabled(t *testing.T) {
+	var s *scheduler
+
+	priority, err := s.acquire(WithPriority(context.Background(), PriorityBatch))
+	require.NoError(t, err)
+	assert.Equal(t, PriorityBatch, priority)
+
+	s.release(priority)
+}
@@ -0,0 +1,29 @@
+package driver
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrStatementTooLarge is returned as root cause when the SQL text of a
+// statement exceeds the limit set with WithMaxStatementSize.
+var ErrStatementTooLarge = errors.New("statement too large")
+
+// WithMaxStatementSize sets the maximum size in bytes of the SQL text of a
+// statement. Statements larger than that are rejected with an error whose
+// root cause is ErrStatementTooLarge, before being encoded, so a runaway
+// script doesn't end up being buffered in memory in its entirety.
+//
+// If not used, or if size is 0, statements of any size are accepted.
+func WithMaxStatementSize(size int) Option {
+	return func(options *options) {
+		options.MaxStatementSize = size
+	}
+}
+
+// Check that the given SQL text doesn't exceed the maximum statement size.
+func (c *Conn) checkStatementSize(query string) error {
+	if c.maxStatementSize == 0 || len(query) <= c.maxStatementSize {
+		return nil
+	}
+	return errors.Wrapf(ErrStatementTooLarge, "%d bytes exceeds limit of %d", len(query), c.maxStatementSize)
+}
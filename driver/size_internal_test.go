@@ -0,0 +1,29 @@
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn_MaxStatementSize(t *testing.T) {
+	c := &Conn{maxStatementSize: 16}
+	assert.NoError(t, c.checkStatementSize("SELECT 1"))
+
+	query := "INSERT INTO test VALUES(" + strings.Repeat("1", 16) + ")"
+
+	_, err := c.ExecContext(context.Background(), query, nil)
+	require.Error(t, err)
+	assert.Equal(t, ErrStatementTooLarge, errors.Cause(err))
+	assert.EqualError(t, err, "41 bytes exceeds limit of 16: statement too large")
+
+	_, err = c.QueryContext(context.Background(), query, nil)
+	assert.Equal(t, ErrStatementTooLarge, errors.Cause(err))
+
+	_, err = c.PrepareContext(context.Background(), query)
+	assert.Equal(t, ErrStatementTooLarge, errors.Cause(err))
+}
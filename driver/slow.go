@@ -0,0 +1,125 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// SlowQuery describes a statement that took longer than the threshold set
+// with WithSlowQueryPlan.
+type SlowQuery struct {
+	Database string        // Name of the database
+	Query    string        // SQL text of the statement
+	Duration time.Duration // Time it took to execute the statement
+	Plan     []string      // Detail of each step of the query plan
+	Err      error         // Why the plan could not be captured, if so
+}
+
+// WithSlowQueryPlan makes the driver capture the query plan of statements that
+// take longer than the given threshold, and pass it to the given hook, as
+// actionable diagnostics for slow queries.
+//
+// The plan is captured out-of-band, by running EXPLAIN QUERY PLAN with the
+// same parameters on a separate connection to the leader, so the slow
+// statement itself is not delayed further. Only one plan is captured at a
+// time: statements that turn out to be slow while a capture is in progress
+// are skipped, as are multi-statement queries. The hook is called from a
+// separate goroutine.
+func WithSlowQueryPlan(threshold time.Duration, hook func(SlowQuery)) Option {
+	return func(options *options) {
+		options.SlowQuery = &slowQueryConfig{threshold: threshold, hook: hook}
+	}
+}
+
+// Configuration set with WithSlowQueryPlan.
+type slowQueryConfig struct {
+	threshold time.Duration
+	hook      func(SlowQuery)
+	driver    *Driver // Used to connect to the leader
+	busy      int32   // Set atomically while a plan is being captured
+}
+
+// Maximum time spent capturing a query plan.
+const slowQueryTimeout = 10 * time.Second
+
+// Capture the plan of the given statement against the given database if it
+// took longer than the threshold.
+func (s *slowQueryConfig) observe(database, query string, args []driver.NamedValue, duration time.Duration) {
+	if s == nil || duration < s.threshold || query == "" {
+		return
+	}
+	if len(splitStatements(query)) > 1 {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&s.busy, 0, 1) {
+		return
+	}
+
+	// The caller might reuse the memory of the arguments once the statement
+	// returns.
+	values := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		if b, ok := arg.Value.([]byte); ok {
+			arg.Value = append([]byte(nil), b...)
+		}
+		values[i] = arg
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&s.busy, 0)
+		slow := SlowQuery{Database: database, Query: query, Duration: duration}
+		slow.Plan, slow.Err = s.explain(database, query, values)
+		s.hook(slow)
+	}()
+}
+
+// Run EXPLAIN QUERY PLAN for the given statement on a new connection.
+func (s *slowQueryConfig) explain(database, query string, args []driver.NamedValue) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), slowQueryTimeout)
+	defer cancel()
+
+	connector := &Connector{uri: database, driver: s.driver}
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Don't capture the plan of the plan, and don't count this statement
+	// in the driver metrics and statistics.
+	c := conn.(*Conn)
+	c.slowQuery = nil
+	c.metrics = newMetrics()
+	c.statements = nil
+
+	rows, err := c.query(ctx, "EXPLAIN QUERY PLAN "+query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := rows.Columns
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no query plan returned")
+	}
+
+	var plan []string
+	dest := make([]driver.Value, len(columns))
+	for {
+		if err := rows.Next(dest); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return plan, err
+		}
+		// The detail is always the last column.
+		detail, _ := dest[len(dest)-1].(string)
+		plan = append(plan, detail)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,70 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Statements faster than the threshold, or made of several statements, are
+// not explained.
+func TestSlowQuery_Skip(t *testing.T) {
+	called := make(chan SlowQuery, 1)
+	d := newSlowQueryDriver(t, func(slow SlowQuery) { called <- slow })
+
+	d.slowQuery.observe("test", "SELECT 1", nil, time.Millisecond)
+	d.slowQuery.observe("test", "SELECT 1; SELECT 2", nil, time.Minute)
+	d.slowQuery.observe("test", "", nil, time.Minute)
+
+	select {
+	case slow := <-called:
+		t.Fatalf("unexpected capture of %q", slow.Query)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// If the plan can't be captured, the hook is still called with the error.
+func TestSlowQuery_ConnectError(t *testing.T) {
+	called := make(chan SlowQuery, 1)
+	d := newSlowQueryDriver(t, func(slow SlowQuery) { called <- slow })
+
+	args := []driver.NamedValue{{Ordinal: 1, Value: []byte("x")}}
+	d.slowQuery.observe("test", "SELECT * FROM foo WHERE x = ?", args, time.Minute)
+
+	// A capture is in progress, further slow statements are skipped.
+	d.slowQuery.observe("test", "SELECT 2", nil, time.Minute)
+
+	select {
+	case slow := <-called:
+		assert.Equal(t, "test", slow.Database)
+		assert.Equal(t, "SELECT * FROM foo WHERE x = ?", slow.Query)
+		assert.Equal(t, time.Minute, slow.Duration)
+		assert.Nil(t, slow.Plan)
+		assert.Error(t, slow.Err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no slow query captured")
+	}
+
+	select {
+	case slow := <-called:
+		t.Fatalf("unexpected capture of %q", slow.Query)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func newSlowQueryDriver(t *testing.T, hook func(SlowQuery)) *Driver {
+	t.Helper()
+
+	d, err := New(
+		client.NewInmemNodeStore(),
+		WithSlowQueryPlan(time.Second, hook),
+		WithConnectionTimeout(100*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	return d
+}
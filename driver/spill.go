@@ -0,0 +1,283 @@
+package driver
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WithSpillToDisk makes queries fetch their whole result set from the server
+// as soon as they are executed, instead of streaming it while the rows are
+// being iterated.
+//
+// Up to budget bytes of rows are kept in memory, and the remaining ones are
+// written to a temporary file in the given directory (or in os.TempDir() if
+// dir is empty), which is removed when the rows are closed.
+//
+// This releases the connection and the server-side resources held by the
+// query right away, so that slowly processing a big result set doesn't stall
+// other requests, while still bounding the memory used by the client.
+func WithSpillToDisk(budget int64, dir string) Option {
+	return func(options *options) {
+		options.Spill = &spillConfig{budget: budget, dir: dir}
+	}
+}
+
+// Configuration set with WithSpillToDisk.
+type spillConfig struct {
+	budget int64
+	dir    string
+}
+
+// Type tags of the values encoded in a spill file.
+const (
+	spillNull uint8 = iota
+	spillInt64
+	spillFloat64
+	spillBool
+	spillString
+	spillBlob
+	spillTime
+)
+
+// Hold the rows of a result set, in memory up to a budget and in a temporary
+// file past it.
+type rowBuffer struct {
+	budget int64            // Maximum size of the rows kept in memory
+	dir    string           // Directory of the spill file
+	size   int64            // Size of the rows kept in memory
+	rows   [][]driver.Value // Rows kept in memory
+	file   *os.File         // Spill file, if any
+	writer *bufio.Writer    // Writes rows to the spill file
+	reader *bufio.Reader    // Reads rows from the spill file
+	n      int              // Number of rows in the spill file
+	read   int              // Number of rows returned so far
+}
+
+func newRowBuffer(budget int64, dir string) *rowBuffer {
+	return &rowBuffer{
+		budget: budget,
+		dir:    dir,
+		rows:   [][]driver.Value{},
+	}
+}
+
+// Add a copy of the given row to the buffer.
+func (b *rowBuffer) add(row []driver.Value) error {
+	if b.file == nil {
+		size := rowSize(row)
+		if b.size+size <= b.budget {
+			b.rows = append(b.rows, append([]driver.Value{}, row...))
+			b.size += size
+			return nil
+		}
+
+		file, err := ioutil.TempFile(b.dir, "cowsql-rows-")
+		if err != nil {
+			return errors.Wrap(err, "create spill file")
+		}
+		b.file = file
+		b.writer = bufio.NewWriter(file)
+	}
+
+	if err := writeRow(b.writer, row); err != nil {
+		return errors.Wrap(err, "write spill file")
+	}
+	b.n++
+
+	return nil
+}
+
+// Prepare the buffer for reading, once all rows have been added.
+func (b *rowBuffer) rewind() error {
+	if b.file == nil {
+		return nil
+	}
+	if err := b.writer.Flush(); err != nil {
+		return errors.Wrap(err, "write spill file")
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "rewind spill file")
+	}
+	b.reader = bufio.NewReader(b.file)
+	return nil
+}
+
+// Fill dest with the next row, or return io.EOF.
+func (b *rowBuffer) next(dest []driver.Value) error {
+	if b.read < len(b.rows) {
+		copy(dest, b.rows[b.read])
+		b.rows[b.read] = nil // Release memory as soon as possible.
+		b.read++
+		return nil
+	}
+
+	if b.read-len(b.rows) >= b.n {
+		return io.EOF
+	}
+
+	if err := readRow(b.reader, dest); err != nil {
+		return errors.Wrap(err, "read spill file")
+	}
+	b.read++
+
+	return nil
+}
+
+// Release the memory and remove the spill file, if any.
+func (b *rowBuffer) close() error {
+	b.rows = nil
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	err := os.Remove(b.file.Name())
+	b.file = nil
+	return err
+}
+
+// Return an estimate of the memory used by the given row.
+func rowSize(row []driver.Value) int64 {
+	size := int64(len(row)) * 16 // Size of an interface value
+	for _, value := range row {
+		switch v := value.(type) {
+		case string:
+			size += int64(len(v))
+		case []byte:
+			size += int64(len(v))
+		case time.Time:
+			size += 24
+		}
+	}
+	return size
+}
+
+func writeRow(w *bufio.Writer, row []driver.Value) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	writeUint := func(n uint64) error {
+		k := binary.PutUvarint(buf, n)
+		_, err := w.Write(buf[:k])
+		return err
+	}
+
+	for _, value := range row {
+		var err error
+		switch v := value.(type) {
+		case nil:
+			err = w.WriteByte(spillNull)
+		case int64:
+			if err = w.WriteByte(spillInt64); err == nil {
+				err = writeUint(uint64(v))
+			}
+		case float64:
+			if err = w.WriteByte(spillFloat64); err == nil {
+				err = writeUint(math.Float64bits(v))
+			}
+		case bool:
+			var b uint64
+			if v {
+				b = 1
+			}
+			if err = w.WriteByte(spillBool); err == nil {
+				err = writeUint(b)
+			}
+		case string:
+			if err = w.WriteByte(spillString); err == nil {
+				if err = writeUint(uint64(len(v))); err == nil {
+					_, err = w.WriteString(v)
+				}
+			}
+		case []byte:
+			if err = w.WriteByte(spillBlob); err == nil {
+				if err = writeUint(uint64(len(v))); err == nil {
+					_, err = w.Write(v)
+				}
+			}
+		case time.Time:
+			var data []byte
+			if data, err = v.MarshalBinary(); err != nil {
+				return err
+			}
+			if err = w.WriteByte(spillTime); err == nil {
+				if err = writeUint(uint64(len(data))); err == nil {
+					_, err = w.Write(data)
+				}
+			}
+		default:
+			return fmt.Errorf("unsupported type %T", value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readRow(r *bufio.Reader, dest []driver.Value) error {
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	for i := range dest {
+		tag, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case spillNull:
+			dest[i] = nil
+		case spillInt64, spillFloat64, spillBool:
+			n, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			switch tag {
+			case spillInt64:
+				dest[i] = int64(n)
+			case spillFloat64:
+				dest[i] = math.Float64frombits(n)
+			case spillBool:
+				dest[i] = n == 1
+			}
+		case spillString, spillBlob, spillTime:
+			data, err := readBytes()
+			if err != nil {
+				return err
+			}
+			switch tag {
+			case spillString:
+				dest[i] = string(data)
+			case spillBlob:
+				dest[i] = data
+			case spillTime:
+				var t time.Time
+				if err := t.UnmarshalBinary(data); err != nil {
+					return err
+				}
+				dest[i] = t
+			}
+		default:
+			return fmt.Errorf("unknown value type %d", tag)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cowsql-spill-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now().UTC()
+	rows := [][]driver.Value{}
+	for i := 0; i < 100; i++ {
+		rows = append(rows, []driver.Value{
+			int64(i), float64(i) / 2, i%2 == 0, "hello", []byte{byte(i)}, now, nil,
+		})
+	}
+
+	// The budget fits a few rows, the rest goes to the spill file.
+	buffer := newRowBuffer(4*rowSize(rows[0]), dir)
+	for _, row := range rows {
+		require.NoError(t, buffer.add(row))
+	}
+	require.NoError(t, buffer.rewind())
+
+	assert.Len(t, buffer.rows, 4)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	dest := make([]driver.Value, len(rows[0]))
+	for _, row := range rows {
+		require.NoError(t, buffer.next(dest))
+		assert.Equal(t, row, dest)
+	}
+	assert.Equal(t, io.EOF, buffer.next(dest))
+
+	require.NoError(t, buffer.close())
+
+	files, err = ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 0)
+}
+
+func TestRowBuffer_InMemory(t *testing.T) {
+	buffer := newRowBuffer(1024, "")
+
+	row := []driver.Value{int64(1), "x"}
+	require.NoError(t, buffer.add(row))
+	row[0] = int64(2) // The buffer holds a copy.
+	require.NoError(t, buffer.rewind())
+
+	dest := make([]driver.Value, 2)
+	require.NoError(t, buffer.next(dest))
+	assert.Equal(t, []driver.Value{int64(1), "x"}, dest)
+	assert.Equal(t, io.EOF, buffer.next(dest))
+
+	assert.Nil(t, buffer.file)
+	require.NoError(t, buffer.close())
+}
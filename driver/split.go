@@ -0,0 +1,115 @@
+package driver
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Split the given SQL text into its individual statements.
+//
+// Quoted strings and identifiers, comments and the bodies of CREATE TRIGGER
+// statements are honored, so semicolons appearing in them don't act as
+// separators. Statements consisting only of whitespace or comments are
+// dropped.
+func splitStatements(sql string) []string {
+	statements := []string{}
+
+	start := 0          // Start of the current statement
+	words := []string{} // Leading keywords of the current statement
+	depth := 0          // Nesting of BEGIN/CASE ... END blocks in triggers
+	empty := true       // Whether the current statement has no tokens yet
+
+	flush := func(end int) {
+		if !empty {
+			statements = append(statements, strings.TrimSpace(sql[start:end]))
+		}
+		start = end
+		words = words[:0]
+		depth = 0
+		empty = true
+	}
+
+	isTrigger := func() bool {
+		// CREATE [TEMP|TEMPORARY] TRIGGER
+		for i, word := range words {
+			if i == 0 && word != "CREATE" {
+				return false
+			}
+			if word == "TRIGGER" {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := strings.IndexByte(sql[i+1:], closing)
+			if j == -1 {
+				i = len(sql)
+			} else {
+				i += j + 2
+			}
+			empty = false
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			j := strings.IndexByte(sql[i:], '\n')
+			if j == -1 {
+				i = len(sql)
+			} else {
+				i += j + 1
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			j := strings.Index(sql[i+2:], "*/")
+			if j == -1 {
+				i = len(sql)
+			} else {
+				i += j + 4
+			}
+		case c == ';':
+			i++
+			if depth == 0 {
+				flush(i)
+			}
+		case isWordByte(c):
+			j := i
+			for j < len(sql) && isWordByte(sql[j]) {
+				j++
+			}
+			word := strings.ToUpper(sql[i:j])
+			if len(words) < 4 {
+				words = append(words, word)
+			}
+			if isTrigger() {
+				switch word {
+				case "BEGIN", "CASE":
+					depth++
+				case "END":
+					if depth > 0 {
+						depth--
+					}
+				}
+			}
+			i = j
+			empty = false
+		default:
+			if !unicode.IsSpace(rune(c)) {
+				empty = false
+			}
+			i++
+		}
+	}
+
+	flush(len(sql))
+
+	return statements
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,52 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		sql        string
+		statements []string
+	}{
+		{
+			"SELECT 1",
+			[]string{"SELECT 1"},
+		},
+		{
+			"SELECT 1; SELECT 2;",
+			[]string{"SELECT 1;", "SELECT 2;"},
+		},
+		{
+			"SELECT ';'; SELECT \"a;b\" FROM [c;d]",
+			[]string{"SELECT ';';", "SELECT \"a;b\" FROM [c;d]"},
+		},
+		{
+			"SELECT 1; -- trailing; comment\n /* another; one */",
+			[]string{"SELECT 1;"},
+		},
+		{
+			"CREATE TRIGGER t AFTER INSERT ON foo BEGIN UPDATE bar SET n = CASE WHEN n > 0 THEN 1 ELSE 0 END; DELETE FROM baz; END; SELECT 1",
+			[]string{
+				"CREATE TRIGGER t AFTER INSERT ON foo BEGIN UPDATE bar SET n = CASE WHEN n > 0 THEN 1 ELSE 0 END; DELETE FROM baz; END;",
+				"SELECT 1",
+			},
+		},
+		{
+			"BEGIN; SELECT 1; END",
+			[]string{"BEGIN;", "SELECT 1;", "END"},
+		},
+		{
+			"  ;; ",
+			[]string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.sql, func(t *testing.T) {
+			assert.Equal(t, c.statements, splitStatements(c.sql))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package driver
+
+import (
+	"context"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/cowsql/go-cowsql/internal/protocol"
+	"github.com/pkg/errors"
+)
+
+// ErrStaleStatement is returned as root cause when a prepared statement is
+// not known to the server anymore and can't be transparently prepared again
+// with the same SQL text, because the resulting statement expects a different
+// number of parameters, for example after a schema change.
+var ErrStaleStatement = errors.New("stale prepared statement")
+
+// Send the request encoded by the given function. If the server doesn't know
+// about the statement anymore, for example because it was finalized on the
+// server side after a leadership change, prepare the statement again and send
+// the request one more time.
+func (s *Stmt) call(ctx context.Context, encode func()) error {
+	encode()
+	err := s.protocol.Call(ctx, s.request, s.response)
+	if !isNotFound(err) {
+		return err
+	}
+
+	s.log(client.LogDebug, "statement %d not found, preparing it again", s.id)
+	if err := s.prepare(ctx); err != nil {
+		return err
+	}
+
+	encode()
+	return s.protocol.Call(ctx, s.request, s.response)
+}
+
+// Prepare the SQL text of the statement again, updating its ID.
+func (s *Stmt) prepare(ctx context.Context) error {
+	protocol.EncodePrepare(s.request, uint64(s.conn.id), s.sql)
+	if err := s.protocol.Call(ctx, s.request, s.response); err != nil {
+		return err
+	}
+
+	db, id, params, err := protocol.DecodeStmt(s.response)
+	if err != nil {
+		return err
+	}
+	if params != s.params {
+		protocol.EncodeFinalize(s.request, db, id)
+		if err := s.protocol.Call(ctx, s.request, s.response); err != nil {
+			s.log(client.LogDebug, "finalize statement %d: %v", id, err)
+		}
+		return errors.Wrapf(ErrStaleStatement, "statement now has %d parameters instead of %d", params, s.params)
+	}
+	s.db, s.id = db, id
+
+	return nil
+}
+
+// Whether the given error means that the server doesn't know about the
+// statement or database referenced by the request.
+func isNotFound(err error) bool {
+	request, ok := errors.Cause(err).(protocol.ErrRequest)
+	return ok && request.Code == errNotFound
+}
@@ -0,0 +1,19 @@
+package driver
+
+import (
+	"io"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, isNotFound(protocol.ErrRequest{Code: errNotFound, Description: "no stmt with id 1"}))
+	assert.True(t, isNotFound(errors.Wrap(protocol.ErrRequest{Code: errNotFound}, "exec")))
+
+	assert.False(t, isNotFound(nil))
+	assert.False(t, isNotFound(io.EOF))
+	assert.False(t, isNotFound(protocol.ErrRequest{Code: ErrBusy}))
+}
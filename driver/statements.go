@@ -0,0 +1,298 @@
+package driver
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// StatementStats holds execution statistics about all the statements sharing
+// the same normalized SQL text, as observed by a Driver.
+type StatementStats struct {
+	// Name of the database the statements were executed against.
+	Database string
+
+	// Normalized SQL text of the statements, as returned by
+	// NormalizeQuery.
+	Query string
+
+	// Number of times the statements were executed, including failed
+	// ones.
+	Calls uint64
+
+	// Number of executions that failed.
+	Errors uint64
+
+	// Number of rows returned by queries or affected by other statements.
+	Rows uint64
+
+	// Total and maximum time spent waiting for a single execution,
+	// including network round trips.
+	Time    time.Duration
+	MaxTime time.Duration
+}
+
+// Default maximum number of distinct statements tracked.
+const defaultStatementStatsSize = 1000
+
+// WithStatementStats makes the driver aggregate execution statistics of the
+// statements it runs, grouped by database and normalized SQL text, which can
+// then be retrieved with Driver.StatementStats. It's the equivalent of
+// PostgreSQL's pg_stat_statements.
+//
+// At most size distinct statements are tracked: when the limit is reached,
+// the least executed one is evicted to make room for a new one. If size is
+// zero or negative, a default of 1000 is used.
+func WithStatementStats(size int) Option {
+	return func(options *options) {
+		if size <= 0 {
+			size = defaultStatementStatsSize
+		}
+		options.StatementStatsSize = size
+	}
+}
+
+// Track execution statistics of each distinct statement.
+type statementStats struct {
+	mu         sync.Mutex
+	size       int
+	statements map[statementKey]*StatementStats
+}
+
+type statementKey struct {
+	database string
+	query    string
+}
+
+func newStatementStats(size int) *statementStats {
+	return &statementStats{size: size, statements: map[statementKey]*StatementStats{}}
+}
+
+// Return the normalized form of the given statement, or an empty string if
+// statistics are disabled.
+func (s *statementStats) normalize(query string) string {
+	if s == nil {
+		return ""
+	}
+	return NormalizeQuery(query)
+}
+
+// Record an execution of the statement with the given normalized SQL text.
+func (s *statementStats) record(database, query string, duration time.Duration, err error) {
+	if s == nil || query == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := statementKey{database: database, query: query}
+	statement, ok := s.statements[key]
+	if !ok {
+		if len(s.statements) >= s.size {
+			s.evict()
+		}
+		statement = &StatementStats{Database: database, Query: query}
+		s.statements[key] = statement
+	}
+
+	statement.Calls++
+	if err != nil {
+		statement.Errors++
+	}
+	statement.Time += duration
+	if duration > statement.MaxTime {
+		statement.MaxTime = duration
+	}
+}
+
+// Record the rows returned or affected by the statement with the given
+// normalized SQL text.
+func (s *statementStats) rows(database, query string, n uint64) {
+	if s == nil || query == "" || n == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if statement, ok := s.statements[statementKey{database: database, query: query}]; ok {
+		statement.Rows += n
+	}
+}
+
+// Remove the least executed statement. Must be called with the lock held.
+func (s *statementStats) evict() {
+	var victim statementKey
+	var calls uint64
+	first := true
+	for key, statement := range s.statements {
+		if first || statement.Calls < calls {
+			victim = key
+			calls = statement.Calls
+			first = false
+		}
+	}
+	delete(s.statements, victim)
+}
+
+func (s *statementStats) get() []StatementStats {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statements := make([]StatementStats, 0, len(s.statements))
+	for _, statement := range s.statements {
+		statements = append(statements, *statement)
+	}
+	return statements
+}
+
+func (s *statementStats) reset() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements = map[statementKey]*StatementStats{}
+}
+
+// StatementStats returns a snapshot of the statistics collected for each
+// distinct statement, sorted by total time, largest first. It returns nil
+// unless WithStatementStats was used.
+func (d *Driver) StatementStats() []StatementStats {
+	statements := d.statements.get()
+	sort.Slice(statements, func(i, j int) bool {
+		if statements[i].Time != statements[j].Time {
+			return statements[i].Time > statements[j].Time
+		}
+		return statements[i].Query < statements[j].Query
+	})
+	return statements
+}
+
+// ResetStatementStats discards the statistics collected so far.
+func (d *Driver) ResetStatementStats() {
+	d.statements.reset()
+}
+
+// NormalizeQuery returns the normalized form of the given SQL text, used to
+// group statements that differ only in their literal values.
+//
+// String, blob and numeric literals are replaced with a ? placeholder,
+// comments are removed, runs of whitespace are collapsed into a single space
+// and trailing semicolons are dropped. Quoted identifiers and parameters are
+// left untouched.
+func NormalizeQuery(sql string) string {
+	var b strings.Builder
+	b.Grow(len(sql))
+
+	space := false // Whether whitespace was skipped since the last token
+	emit := func(token string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(token)
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case (c == 'x' || c == 'X') && i+1 < len(sql) && sql[i+1] == '\'':
+			i = skipQuoted(sql, i+1, '\'')
+			emit("?")
+		case c == '\'':
+			i = skipQuoted(sql, i, '\'')
+			emit("?")
+		case c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := skipQuoted(sql, i, closing)
+			emit(sql[i:j])
+			i = j
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			j := strings.IndexByte(sql[i:], '\n')
+			if j == -1 {
+				i = len(sql)
+			} else {
+				i += j + 1
+			}
+			space = true
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			j := strings.Index(sql[i+2:], "*/")
+			if j == -1 {
+				i = len(sql)
+			} else {
+				i += j + 4
+			}
+			space = true
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9':
+			i = skipNumber(sql, i)
+			emit("?")
+		case isWordByte(c) || c == '?' || c == ':' || c == '@' || c == '$':
+			// Keywords, identifiers and parameters, including
+			// numbered ones like ?1.
+			j := i + 1
+			for j < len(sql) && isWordByte(sql[j]) {
+				j++
+			}
+			emit(sql[i:j])
+			i = j
+		case unicode.IsSpace(rune(c)):
+			space = true
+			i++
+		default:
+			emit(sql[i : i+1])
+			i++
+		}
+	}
+
+	return strings.TrimRight(b.String(), "; ")
+}
+
+// Return the index following the quoted text starting at the given index,
+// honoring doubled closing characters as escapes.
+func skipQuoted(sql string, i int, closing byte) int {
+	for j := i + 1; j < len(sql); j++ {
+		if sql[j] != closing {
+			continue
+		}
+		if j+1 < len(sql) && sql[j+1] == closing {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(sql)
+}
+
+// Return the index following the numeric literal starting at the given
+// index, including hexadecimal ones and exponents.
+func skipNumber(sql string, i int) int {
+	if sql[i] == '0' && i+1 < len(sql) && (sql[i+1] == 'x' || sql[i+1] == 'X') {
+		j := i + 2
+		for j < len(sql) && isWordByte(sql[j]) {
+			j++
+		}
+		return j
+	}
+	j := i
+	for j < len(sql) {
+		c := sql[j]
+		switch {
+		case c >= '0' && c <= '9' || c == '.' || c == '_':
+			j++
+		case (c == 'e' || c == 'E') && j+1 < len(sql):
+			j++
+			if sql[j] == '+' || sql[j] == '-' {
+				j++
+			}
+		default:
+			return j
+		}
+	}
+	return j
+}
@@ -0,0 +1,92 @@
+package driver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	cases := []struct {
+		sql        string
+		normalized string
+	}{
+		{"SELECT * FROM test WHERE n = 1", "SELECT * FROM test WHERE n = ?"},
+		{"SELECT * FROM test WHERE n = 123.5e-3", "SELECT * FROM test WHERE n = ?"},
+		{"SELECT * FROM test WHERE n = 0x1F", "SELECT * FROM test WHERE n = ?"},
+		{"SELECT * FROM test WHERE s = 'it''s'", "SELECT * FROM test WHERE s = ?"},
+		{"SELECT * FROM test WHERE b = X'00ff'", "SELECT * FROM test WHERE b = ?"},
+		{"SELECT * FROM t1 WHERE n = ?1 AND m = :m", "SELECT * FROM t1 WHERE n = ?1 AND m = :m"},
+		{"SELECT \"n 1\", [n 2], `n 3` FROM test", "SELECT \"n 1\", [n 2], `n 3` FROM test"},
+		{"  SELECT n\n\tFROM test -- comment\n WHERE n > 1; ", "SELECT n FROM test WHERE n > ?"},
+		{"/* app=web */ SELECT n FROM test", "SELECT n FROM test"},
+		{"INSERT INTO test(n) VALUES(1),(2)", "INSERT INTO test(n) VALUES(?),(?)"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.sql, func(t *testing.T) {
+			assert.Equal(t, c.normalized, NormalizeQuery(c.sql))
+		})
+	}
+}
+
+func TestStatementStats(t *testing.T) {
+	s := newStatementStats(10)
+
+	query := s.normalize("SELECT * FROM test WHERE n = 1")
+	s.record("foo", query, time.Second, nil)
+	s.record("foo", s.normalize("SELECT * FROM test WHERE n = 2"), 2*time.Second, errors.New("boom"))
+	s.rows("foo", query, 3)
+	s.record("bar", query, time.Millisecond, nil)
+	s.rows("baz", query, 1)
+
+	assert.ElementsMatch(t, []StatementStats{
+		{
+			Database: "foo",
+			Query:    "SELECT * FROM test WHERE n = ?",
+			Calls:    2,
+			Errors:   1,
+			Rows:     3,
+			Time:     3 * time.Second,
+			MaxTime:  2 * time.Second,
+		},
+		{
+			Database: "bar",
+			Query:    "SELECT * FROM test WHERE n = ?",
+			Calls:    1,
+			Time:     time.Millisecond,
+			MaxTime:  time.Millisecond,
+		},
+	}, s.get())
+
+	s.reset()
+	assert.Empty(t, s.get())
+}
+
+// When the limit is reached, the least executed statement is evicted.
+func TestStatementStats_Evict(t *testing.T) {
+	s := newStatementStats(2)
+
+	s.record("foo", "SELECT ?", time.Second, nil)
+	s.record("foo", "SELECT ?", time.Second, nil)
+	s.record("foo", "SELECT ? + ?", time.Second, nil)
+	s.record("foo", "SELECT ? * ?", time.Second, nil)
+
+	statements := s.get()
+	assert.Len(t, statements, 2)
+	queries := []string{statements[0].Query, statements[1].Query}
+	assert.ElementsMatch(t, []string{"SELECT ?", "SELECT ? * ?"}, queries)
+}
+
+// Statistics are not collected unless enabled.
+func TestStatementStats_Disabled(t *testing.T) {
+	var s *statementStats
+
+	assert.Equal(t, "", s.normalize("SELECT 1"))
+	s.record("foo", "SELECT ?", time.Second, nil)
+	s.rows("foo", "SELECT ?", 1)
+	s.reset()
+	assert.Nil(t, s.get())
+}
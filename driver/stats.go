@@ -0,0 +1,70 @@
+package driver
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats holds statistics about leader changes, as observed by a Driver.
+type Stats struct {
+	// Number of connections opened, each of them requiring to find the
+	// current leader.
+	LeaderLookups uint64
+
+	// Number of times connections were lost because the leader changed
+	// or could not be reached.
+	Failovers uint64
+
+	// Time between the last lost connection and the first statement that
+	// succeeded after it. It's zero if no failover has completed yet.
+	LastFailover time.Duration
+}
+
+// Track leader changes across all connections of a driver.
+type stats struct {
+	mu     sync.Mutex
+	stats  Stats
+	lostAt time.Time // Start of the failover in progress, if any
+}
+
+// Record a new connection.
+func (s *stats) connected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.LeaderLookups++
+}
+
+// Record a lost connection. Connections lost while a failover is already in
+// progress are part of the same failover.
+func (s *stats) lost() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.lostAt.IsZero() {
+		return
+	}
+	s.stats.Failovers++
+	s.lostAt = time.Now()
+}
+
+// Record a successful statement, completing the failover in progress, if
+// any.
+func (s *stats) succeeded() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lostAt.IsZero() {
+		return
+	}
+	s.stats.LastFailover = time.Since(s.lostAt)
+	s.lostAt = time.Time{}
+}
+
+func (s *stats) get() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Stats returns statistics about the leader changes observed by the driver.
+func (d *Driver) Stats() Stats {
+	return d.stats.get()
+}
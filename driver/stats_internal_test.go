@@ -0,0 +1,32 @@
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	s := &stats{}
+
+	s.connected()
+	s.succeeded()
+	assert.Equal(t, Stats{LeaderLookups: 1}, s.get())
+
+	// Connections lost during the same failover are counted once.
+	s.lost()
+	s.lost()
+	s.connected()
+	time.Sleep(time.Millisecond)
+	s.succeeded()
+
+	stats := s.get()
+	assert.Equal(t, uint64(2), stats.LeaderLookups)
+	assert.Equal(t, uint64(1), stats.Failovers)
+	assert.True(t, stats.LastFailover >= time.Millisecond)
+
+	// A success without failover in progress doesn't change anything.
+	s.succeeded()
+	assert.Equal(t, stats, s.get())
+}
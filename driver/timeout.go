@@ -0,0 +1,51 @@
+package driver
+
+import (
+	"fmt"
+	"time"
+)
+
+// Primary result code of interrupted statements.
+const errInterrupt = 9
+
+// ErrQueryTimeout is returned when the server interrupts a statement, for
+// example because an interrupt request was sent after its context deadline
+// expired.
+//
+// It lets applications tell timeouts apart from other failures, for example
+// to retry them. The Error returned by the server can be retrieved with
+// errors.As as well.
+type ErrQueryTimeout struct {
+	Elapsed time.Duration // Time since the statement was sent
+	Err     Error         // Error returned by the server
+}
+
+func (e ErrQueryTimeout) Error() string {
+	return fmt.Sprintf("query interrupted after %s: %s", e.Elapsed.Round(time.Millisecond), e.Err.Message)
+}
+
+// Unwrap returns the error returned by the server.
+func (e ErrQueryTimeout) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns true, as for net.Error.
+func (e ErrQueryTimeout) Timeout() bool {
+	return true
+}
+
+// Convert the given error to an ErrQueryTimeout if the server reported that
+// the statement started at the given time was interrupted.
+func queryTimeout(err error, start time.Time) error {
+	e, ok := err.(Error)
+	if !ok || e.Code&0xff != errInterrupt {
+		return err
+	}
+
+	timeout := ErrQueryTimeout{Err: e}
+	if !start.IsZero() {
+		timeout.Elapsed = time.Since(start)
+	}
+
+	return timeout
+}
@@ -0,0 +1,41 @@
+package driver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+
+	err := queryTimeout(Error{Code: errInterrupt, Message: "interrupted"}, start)
+
+	var timeout ErrQueryTimeout
+	require.True(t, errors.As(err, &timeout))
+	assert.True(t, timeout.Timeout())
+	assert.True(t, timeout.Elapsed >= time.Second)
+	assert.Contains(t, err.Error(), "interrupted")
+
+	var e Error
+	require.True(t, errors.As(err, &e))
+	assert.Equal(t, errInterrupt, e.Code)
+}
+
+// Errors other than interrupts are left untouched.
+func TestQueryTimeout_OtherErrors(t *testing.T) {
+	err := Error{Code: 1, Message: "no such table: foo"}
+	assert.Equal(t, err, queryTimeout(err, time.Now()))
+
+	other := errors.New("boom")
+	assert.Equal(t, other, queryTimeout(other, time.Now()))
+}
+
+// Without a start time the elapsed time is unknown.
+func TestQueryTimeout_NoStart(t *testing.T) {
+	err := queryTimeout(Error{Code: errInterrupt, Message: "interrupted"}, time.Time{})
+	assert.Equal(t, ErrQueryTimeout{Err: Error{Code: errInterrupt, Message: "interrupted"}}, err)
+}
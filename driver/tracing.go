@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"sync/atomic"
+
+	"github.com/cowsql/go-cowsql/client"
+)
+
+// SetTracing changes the level at which a message is logged every time a
+// statement gets executed, see WithTracing. The change applies to the
+// connections already open as well, so tracing can be turned on temporarily
+// to debug an issue without restarting the application. Use client.LogNone
+// to turn tracing off.
+func (d *Driver) SetTracing(level client.LogLevel) {
+	d.tracing.set(level)
+}
+
+// Tracing returns the level at which statements are currently traced.
+func (d *Driver) Tracing() client.LogLevel {
+	return d.tracing.get()
+}
+
+// Hold the tracing level shared by a driver and its connections. A nil tracer
+// has tracing off.
+type tracer struct {
+	level int32 // Accessed atomically
+}
+
+func newTracer(level client.LogLevel) *tracer {
+	return &tracer{level: int32(level)}
+}
+
+func (t *tracer) get() client.LogLevel {
+	if t == nil {
+		return client.LogNone
+	}
+	return client.LogLevel(atomic.LoadInt32(&t.level))
+}
+
+func (t *tracer) set(level client.LogLevel) {
+	atomic.StoreInt32(&t.level, int32(level))
+}
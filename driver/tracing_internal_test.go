@@ -0,0 +1,21 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriver_SetTracing(t *testing.T) {
+	d := &Driver{tracing: newTracer(client.LogNone)}
+	c := &Conn{tracing: d.tracing}
+	assert.Equal(t, client.LogNone, c.tracing.get())
+
+	d.SetTracing(client.LogInfo)
+	assert.Equal(t, client.LogInfo, d.Tracing())
+	assert.Equal(t, client.LogInfo, c.tracing.get())
+
+	var off *tracer
+	assert.Equal(t, client.LogNone, off.get())
+}
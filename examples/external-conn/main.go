@@ -0,0 +1,190 @@
+// This example shows how to carry cowsql traffic over an HTTP server owned by
+// the application, so a single port serves both the application API and
+// database replication.
+//
+// Connections between nodes are established by sending an HTTP request with
+// an "Upgrade: cowsql" header to the /cowsql endpoint. The server hijacks the
+// underlying connection and hands it over to cowsql with app.WithExternalConn.
+//
+// Start three nodes with:
+//
+//	external-conn --address 127.0.0.1:8001 &
+//	external-conn --address 127.0.0.1:8002 --join 127.0.0.1:8001 &
+//	external-conn --address 127.0.0.1:8003 --join 127.0.0.1:8001 &
+//
+// and then use the key/value API served on the same addresses:
+//
+//	curl -X PUT -d my-value http://127.0.0.1:8001/my-key
+//	curl http://127.0.0.1:8002/my-key
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/cowsql/go-cowsql/app"
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/cowsql/go-cowsql/examples/internal/kv"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	upgradePath     = "/cowsql"
+	upgradeProtocol = "cowsql"
+)
+
+// Return an HTTP handler which upgrades incoming requests to the cowsql
+// protocol and sends the hijacked connections to the given channel.
+func upgradeHandler(acceptCh chan net.Conn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != upgradeProtocol {
+			http.Error(w, "missing or invalid upgrade header", http.StatusBadRequest)
+			return
+		}
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "webserver doesn't support hijacking", http.StatusInternalServerError)
+			return
+		}
+
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := "HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: " + upgradeProtocol + "\r\n" +
+			"Connection: Upgrade\r\n\r\n"
+		if _, err := conn.Write([]byte(response)); err != nil {
+			conn.Close()
+			return
+		}
+
+		acceptCh <- conn
+	}
+}
+
+// Dial the upgrade endpoint of the node at the given address.
+func dial(ctx context.Context, address string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest("POST", "http://"+address+upgradePath, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	request.Header.Set("Upgrade", upgradeProtocol)
+	request.Header.Set("Connection", "Upgrade")
+
+	if err := request.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("upgrade to cowsql protocol failed: %s", response.Status)
+	}
+
+	return conn, nil
+}
+
+func main() {
+	var address string
+	var join *[]string
+	var dir string
+
+	cmd := &cobra.Command{
+		Use:   "external-conn",
+		Short: "Example of a cowsql application using external connections",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := filepath.Join(dir, address)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return errors.Wrapf(err, "can't create %s", dir)
+			}
+
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return err
+			}
+
+			acceptCh := make(chan net.Conn)
+			mux := http.NewServeMux()
+			mux.Handle(upgradePath, upgradeHandler(acceptCh))
+
+			// Serve the upgrade endpoint right away, since the
+			// application node needs it to join the cluster.
+			go http.Serve(listener, mux)
+
+			var dialFunc client.DialFunc = dial
+			app, err := app.New(
+				dir,
+				app.WithAddress(address),
+				app.WithCluster(*join),
+				app.WithExternalConn(dialFunc, acceptCh))
+			if err != nil {
+				return err
+			}
+
+			if err := app.Ready(context.Background()); err != nil {
+				return err
+			}
+
+			db, err := app.Open(context.Background(), "demo")
+			if err != nil {
+				return err
+			}
+
+			if _, err := db.Exec(kv.Schema); err != nil {
+				return err
+			}
+
+			mux.Handle("/", kv.Handler(db))
+
+			ch := make(chan os.Signal, 32)
+			signal.Notify(ch, unix.SIGINT)
+			signal.Notify(ch, unix.SIGTERM)
+
+			<-ch
+
+			db.Close()
+
+			app.Handover(context.Background())
+			app.Close()
+
+			listener.Close()
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&address, "address", "a", "", "address serving both the key/value API and database replication")
+	join = flags.StringSliceP("join", "j", nil, "addresses of existing nodes")
+	flags.StringVarP(&dir, "dir", "D", "/tmp/cowsql-external-conn", "data directory")
+
+	cmd.MarkFlagRequired("address")
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,129 @@
+// This example shows how to shut down an application node gracefully, so that
+// the cluster keeps being available while nodes are restarted one at a time,
+// for example during a rolling upgrade.
+//
+// Upon receiving SIGTERM or SIGINT the node:
+//
+//  1. stops accepting new API requests and waits for in-flight ones,
+//  2. closes its database handle,
+//  3. hands over its role (and leadership, if it's the leader) to another
+//     node with App.Handover, so the cluster doesn't lose a voter,
+//  4. closes the app.
+//
+// Start three nodes with:
+//
+//	handover --api 127.0.0.1:8001 --db 127.0.0.1:9001 &
+//	handover --api 127.0.0.1:8002 --db 127.0.0.1:9002 --join 127.0.0.1:9001 &
+//	handover --api 127.0.0.1:8003 --db 127.0.0.1:9003 --join 127.0.0.1:9001 &
+//
+// and stop any of them with "kill -TERM": the remaining nodes keep serving the
+// key/value API.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/cowsql/go-cowsql/app"
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/cowsql/go-cowsql/examples/internal/kv"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+func main() {
+	var api string
+	var db string
+	var join *[]string
+	var dir string
+	var timeout time.Duration
+	var verbose bool
+
+	cmd := &cobra.Command{
+		Use:   "handover",
+		Short: "Example of a cowsql application shutting down gracefully",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := filepath.Join(dir, db)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return errors.Wrapf(err, "can't create %s", dir)
+			}
+			logFunc := func(l client.LogLevel, format string, a ...interface{}) {
+				if !verbose {
+					return
+				}
+				log.Printf(fmt.Sprintf("%s: %s: %s\n", api, l.String(), format), a...)
+			}
+
+			app, err := app.New(dir, app.WithAddress(db), app.WithCluster(*join), app.WithLogFunc(logFunc))
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := app.Ready(ctx); err != nil {
+				return errors.Wrap(err, "app not ready in time")
+			}
+
+			db, err := app.Open(context.Background(), "demo")
+			if err != nil {
+				return err
+			}
+
+			if _, err := db.Exec(kv.Schema); err != nil {
+				return err
+			}
+
+			server := &http.Server{Addr: api, Handler: kv.Handler(db)}
+			go server.ListenAndServe()
+
+			ch := make(chan os.Signal, 32)
+			signal.Notify(ch, unix.SIGINT)
+			signal.Notify(ch, unix.SIGTERM)
+
+			<-ch
+
+			log.Printf("%s: shutting down", api)
+
+			ctx, cancel = context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			if err := server.Shutdown(ctx); err != nil {
+				log.Printf("%s: shutdown API server: %v", api, err)
+			}
+
+			db.Close()
+
+			// Handover might fail if no other node can take over the
+			// role of this one, e.g. in a one-node cluster. The node
+			// is closed anyway, since there's nothing else to do.
+			if err := app.Handover(ctx); err != nil {
+				log.Printf("%s: handover: %v", api, err)
+			}
+
+			return app.Close()
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&api, "api", "a", "", "address used to expose the key/value API")
+	flags.StringVarP(&db, "db", "d", "", "address used for internal database replication")
+	join = flags.StringSliceP("join", "j", nil, "database addresses of existing nodes")
+	flags.StringVarP(&dir, "dir", "D", "/tmp/cowsql-handover", "data directory")
+	flags.DurationVarP(&timeout, "timeout", "t", 30*time.Second, "timeout for starting up and shutting down")
+	flags.BoolVarP(&verbose, "verbose", "v", false, "verbose logging")
+
+	cmd.MarkFlagRequired("api")
+	cmd.MarkFlagRequired("db")
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
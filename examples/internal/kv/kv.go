@@ -0,0 +1,46 @@
+// Package kv implements the simple key/value HTTP API shared by the examples.
+package kv
+
+import (
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Schema of the key/value table used by the examples.
+const Schema = "CREATE TABLE IF NOT EXISTS model (key TEXT, value TEXT, UNIQUE(key))"
+
+const (
+	query  = "SELECT value FROM model WHERE key = ?"
+	update = "INSERT OR REPLACE INTO model(key, value) VALUES(?, ?)"
+)
+
+// Handler returns an HTTP handler which reads keys with GET requests and
+// writes them with PUT requests, e.g.:
+//
+//	curl -X PUT -d my-value http://127.0.0.1:8001/my-key
+//	curl http://127.0.0.1:8001/my-key
+func Handler(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimLeft(r.URL.Path, "/")
+		result := ""
+		switch r.Method {
+		case "GET":
+			row := db.QueryRowContext(r.Context(), query, key)
+			if err := row.Scan(&result); err != nil {
+				result = fmt.Sprintf("Error: %s", err.Error())
+			}
+		case "PUT":
+			result = "done"
+			value, _ := ioutil.ReadAll(r.Body)
+			if _, err := db.ExecContext(r.Context(), update, key, string(value)); err != nil {
+				result = fmt.Sprintf("Error: %s", err.Error())
+			}
+		default:
+			result = fmt.Sprintf("Error: unsupported method %q", r.Method)
+		}
+		fmt.Fprintf(w, "%s\n", result)
+	})
+}
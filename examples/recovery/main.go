@@ -0,0 +1,131 @@
+// This example shows how to recover a cluster that lost the majority of its
+// voters, and is therefore unavailable.
+//
+// The recovery forces a new membership configuration, containing only the
+// surviving nodes, into the raft log of each of them. It must be run while
+// all nodes are stopped, on the data directory of every surviving node, with
+// the same set of surviving nodes. See docs/restore-db.md for how to pick the
+// node holding the most up-to-date data and copy it to the others first.
+//
+// For example, if nodes 127.0.0.1:9001 and 127.0.0.1:9002 of a three-node
+// cluster created with the handover example survived, run:
+//
+//	recovery --dir /tmp/cowsql-handover/127.0.0.1:9001 --keep 127.0.0.1:9001,127.0.0.1:9002
+//	recovery --dir /tmp/cowsql-handover/127.0.0.1:9002 --keep 127.0.0.1:9001,127.0.0.1:9002
+//
+// and start the two nodes again. Always back up the data directories before
+// running the recovery.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cowsql/go-cowsql"
+	"github.com/cowsql/go-cowsql/client"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Return the nodes of the given configuration having one of the given
+// addresses, making sure that at least one of them is a voter.
+func survivors(nodes []client.NodeInfo, keep []string) ([]client.NodeInfo, error) {
+	kept := []client.NodeInfo{}
+	hasVoter := false
+	for _, address := range keep {
+		found := false
+		for _, node := range nodes {
+			if node.Address != address {
+				continue
+			}
+			kept = append(kept, node)
+			if node.Role == client.Voter {
+				hasVoter = true
+			}
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("node %s not found in the current configuration", address)
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("no surviving nodes given")
+	}
+
+	// A cluster without voters can't elect a leader.
+	if !hasVoter {
+		kept[0].Role = client.Voter
+	}
+
+	return kept, nil
+}
+
+func main() {
+	var dir string
+	var keep *[]string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "recovery",
+		Short: "Example of recovering a cowsql cluster which lost quorum",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// This is the node store file maintained by the app
+			// package, which lists the nodes of the cluster.
+			path := filepath.Join(dir, "cluster.yaml")
+			if _, err := os.Stat(path); err != nil {
+				return errors.Wrapf(err, "can't access node store")
+			}
+			store, err := client.NewYamlNodeStore(path)
+			if err != nil {
+				return errors.Wrapf(err, "can't open node store")
+			}
+
+			nodes, err := store.Get(context.Background())
+			if err != nil {
+				return err
+			}
+
+			nodes, err = survivors(nodes, *keep)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("New configuration:")
+			for _, node := range nodes {
+				fmt.Printf("%d %s %s\n", node.ID, node.Address, node.Role)
+			}
+			if dryRun {
+				return nil
+			}
+
+			if err := cowsql.ReconfigureMembershipExt(dir, nodes); err != nil {
+				return errors.Wrap(err, "reconfigure membership")
+			}
+
+			// Update the node store as well, so the node doesn't try
+			// to reach dead nodes when it starts again.
+			if err := store.Set(context.Background(), nodes); err != nil {
+				return errors.Wrap(err, "update node store")
+			}
+
+			fmt.Println("Done. Restart the surviving nodes once all of them are reconfigured.")
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&dir, "dir", "D", "", "data directory of the node to recover")
+	keep = flags.StringSliceP("keep", "k", nil, "database addresses of the surviving nodes")
+	flags.BoolVarP(&dryRun, "dry-run", "n", false, "only print the new configuration")
+
+	cmd.MarkFlagRequired("dir")
+	cmd.MarkFlagRequired("keep")
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
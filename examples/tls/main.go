@@ -0,0 +1,156 @@
+// This example shows how to encrypt the traffic between cowsql nodes, and
+// between nodes and clients, using TLS.
+//
+// Generate a self-signed certificate valid for all nodes, for example with:
+//
+//	openssl req -x509 -newkey rsa:4096 -sha256 -days 3650 -nodes \
+//	    -keyout cluster.key -out cluster.crt -subj "/CN=cowsql" \
+//	    -addext "subjectAltName=DNS:cowsql"
+//
+// and then start three nodes:
+//
+//	tls --api 127.0.0.1:8001 --db 127.0.0.1:9001 --cert cluster.crt --key cluster.key &
+//	tls --api 127.0.0.1:8002 --db 127.0.0.1:9002 --cert cluster.crt --key cluster.key --join 127.0.0.1:9001 &
+//	tls --api 127.0.0.1:8003 --db 127.0.0.1:9003 --cert cluster.crt --key cluster.key --join 127.0.0.1:9001 &
+//
+// The cowsql shell can connect to the cluster with the same certificate:
+//
+//	cowsql -s 127.0.0.1:9001 --cert cluster.crt --key cluster.key demo
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/cowsql/go-cowsql/app"
+	"github.com/cowsql/go-cowsql/examples/internal/kv"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+)
+
+func main() {
+	var api string
+	var db string
+	var join *[]string
+	var dir string
+	var crt string
+	var key string
+	var ca string
+
+	cmd := &cobra.Command{
+		Use:   "tls",
+		Short: "Example of a cowsql application using TLS",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := filepath.Join(dir, db)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return errors.Wrapf(err, "can't create %s", dir)
+			}
+
+			cert, err := tls.LoadX509KeyPair(crt, key)
+			if err != nil {
+				return errors.Wrap(err, "load TLS key pair")
+			}
+
+			// The CA is used to verify the certificates presented by
+			// other nodes. A self-signed certificate shared by all
+			// nodes can act as its own CA.
+			if ca == "" {
+				ca = crt
+			}
+			data, err := ioutil.ReadFile(ca)
+			if err != nil {
+				return err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(data) {
+				return fmt.Errorf("bad CA certificate")
+			}
+
+			// SimpleTLSConfig returns a configuration for accepting
+			// connections and one for dialing them, both requiring
+			// mutual authentication.
+			listen, dial := app.SimpleTLSConfig(cert, pool)
+
+			app, err := app.New(dir, app.WithAddress(db), app.WithCluster(*join), app.WithTLS(listen, dial))
+			if err != nil {
+				return err
+			}
+
+			if err := app.Ready(context.Background()); err != nil {
+				return err
+			}
+
+			// Clients obtained from the app dial other nodes using the
+			// TLS configuration as well.
+			cli, err := app.Leader(context.Background())
+			if err != nil {
+				return err
+			}
+			nodes, err := cli.Cluster(context.Background())
+			cli.Close()
+			if err != nil {
+				return err
+			}
+			for _, node := range nodes {
+				fmt.Printf("%s %s\n", node.Address, node.Role)
+			}
+
+			db, err := app.Open(context.Background(), "demo")
+			if err != nil {
+				return err
+			}
+
+			if _, err := db.Exec(kv.Schema); err != nil {
+				return err
+			}
+
+			listener, err := net.Listen("tcp", api)
+			if err != nil {
+				return err
+			}
+
+			go http.Serve(listener, kv.Handler(db))
+
+			ch := make(chan os.Signal, 32)
+			signal.Notify(ch, unix.SIGINT)
+			signal.Notify(ch, unix.SIGTERM)
+
+			<-ch
+
+			listener.Close()
+			db.Close()
+
+			app.Handover(context.Background())
+			app.Close()
+
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&api, "api", "a", "", "address used to expose the key/value API")
+	flags.StringVarP(&db, "db", "d", "", "address used for internal database replication")
+	join = flags.StringSliceP("join", "j", nil, "database addresses of existing nodes")
+	flags.StringVarP(&dir, "dir", "D", "/tmp/cowsql-tls", "data directory")
+	flags.StringVarP(&crt, "cert", "c", "", "public TLS cert")
+	flags.StringVarP(&key, "key", "k", "", "private TLS key")
+	flags.StringVar(&ca, "ca", "", "TLS cert of the CA signing the nodes certs, defaults to --cert")
+
+	cmd.MarkFlagRequired("api")
+	cmd.MarkFlagRequired("db")
+	cmd.MarkFlagRequired("cert")
+	cmd.MarkFlagRequired("key")
+
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,9 @@
+package bindings
+
+import (
+	"fmt"
+)
+
+// ErrNoEngine is returned by all operations that need the cowsql engine when
+// it's not available, because the program was built without cgo.
+var ErrNoEngine = fmt.Errorf("cowsql engine not available: built without cgo")
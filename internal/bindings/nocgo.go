@@ -0,0 +1,111 @@
+// +build !cgo
+
+package bindings
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/cowsql/go-cowsql/internal/protocol"
+)
+
+// ErrNodeStopped is returned by Node.Handle() is the server was stopped.
+var ErrNodeStopped = fmt.Errorf("server was stopped")
+
+type Node struct{}
+
+type SnapshotParams struct {
+	Threshold uint64
+	Trailing  uint64
+}
+
+// NewNode always fails, since the engine is not available.
+func NewNode(ctx context.Context, id uint64, address string, dir string) (*Node, error) {
+	return nil, ErrNoEngine
+}
+
+func (s *Node) SetDialFunc(dial protocol.DialFunc) error {
+	return ErrNoEngine
+}
+
+func (s *Node) SetBindAddress(address string) error {
+	return ErrNoEngine
+}
+
+func (s *Node) SetNetworkLatency(nanoseconds uint64) error {
+	return ErrNoEngine
+}
+
+func (s *Node) SetSnapshotParams(params SnapshotParams) error {
+	return ErrNoEngine
+}
+
+func (s *Node) SetFailureDomain(code uint64) error {
+	return ErrNoEngine
+}
+
+func (s *Node) SetAutoRecovery(on bool) error {
+	return ErrNoEngine
+}
+
+func (s *Node) GetBindAddress() string {
+	return ""
+}
+
+func (s *Node) Start() error {
+	return ErrNoEngine
+}
+
+func (s *Node) Stop() error {
+	return ErrNoEngine
+}
+
+func (s *Node) Close() {
+}
+
+func (s *Node) Recover(cluster []protocol.NodeInfo) error {
+	return ErrNoEngine
+}
+
+func (s *Node) RecoverExt(cluster []protocol.NodeInfo) error {
+	return ErrNoEngine
+}
+
+// GenerateID generates a unique ID for a server.
+//
+// Like the engine does, the ID is derived from the address and the current
+// time, mixed with random bytes.
+func GenerateID(address string) uint64 {
+	var salt [16]byte
+	binary.BigEndian.PutUint64(salt[:8], uint64(time.Now().UnixNano()))
+	rand.Read(salt[8:])
+
+	hash := sha256.New()
+	hash.Write([]byte(address))
+	hash.Write(salt[:])
+
+	id := binary.BigEndian.Uint64(hash.Sum(nil))
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+// EngineVersion returns an empty string, since no engine is loaded.
+func EngineVersion() string {
+	return ""
+}
+
+// ConfigSingleThread does nothing, since SQLite is not linked.
+func ConfigSingleThread() error {
+	return nil
+}
+
+// ConfigMultiThread does nothing, since SQLite is not linked.
+func ConfigMultiThread() error {
+	return nil
+}
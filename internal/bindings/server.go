@@ -247,6 +247,13 @@ func GenerateID(address string) uint64 {
 	return uint64(id)
 }
 
+// EngineVersion returns the version of the loaded libcowsql, formatted as
+// major.minor.patch.
+func EngineVersion() string {
+	version := int(C.cowsql_version_number())
+	return fmt.Sprintf("%d.%d.%d", version/10000, version/100%100, version%100)
+}
+
 // Extract the underlying socket from a connection.
 func connToSocket(conn net.Conn) (C.int, error) {
 	file, err := conn.(fileConn).File()
@@ -0,0 +1,105 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+)
+
+// Breaker is a circuit breaker tracking which nodes are failing, so the
+// connector can skip them instead of waiting for a dial timeout at every
+// attempt.
+//
+// After a given number of consecutive failures the circuit of a node opens
+// and the node is skipped. Once the cooldown has passed the circuit becomes
+// half-open: a single attempt is let through, closing the circuit if it
+// succeeds and opening it again otherwise.
+//
+// A Breaker is safe for concurrent use, and is meant to be shared by all the
+// connectors using the same node store.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	nodes map[string]*breakerState // Failing nodes by address
+}
+
+// State of the circuit of a failing node.
+type breakerState struct {
+	failures int       // Consecutive failures
+	openedAt time.Time // When the circuit last opened
+	probing  bool      // Whether a half-open attempt is in progress
+}
+
+// NewBreaker returns a circuit breaker opening after the given number of
+// consecutive failures, and letting a new attempt through after the given
+// cooldown.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		nodes:     map[string]*breakerState{},
+	}
+}
+
+// Allow returns whether an attempt to connect to the node with the given
+// address should be made.
+func (b *Breaker) Allow(address string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.nodes[address]
+	if !ok || state.failures < b.threshold {
+		return true
+	}
+	if state.probing || b.now().Sub(state.openedAt) < b.cooldown {
+		return false
+	}
+
+	// Half-open, let a single attempt through.
+	state.probing = true
+	return true
+}
+
+// Success records a successful attempt, closing the circuit of the node.
+func (b *Breaker) Success(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.nodes, address)
+}
+
+// Failure records a failed attempt, possibly opening the circuit of the node.
+func (b *Breaker) Failure(address string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.nodes[address]
+	if !ok {
+		state = &breakerState{}
+		b.nodes[address] = state
+	}
+	state.failures++
+	state.probing = false
+	if state.failures >= b.threshold {
+		state.openedAt = b.now()
+	}
+}
+
+// Open returns the addresses of the nodes whose circuit is currently open or
+// half-open.
+func (b *Breaker) Open() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	addresses := []string{}
+	for address, state := range b.nodes {
+		if state.failures >= b.threshold {
+			addresses = append(addresses, address)
+		}
+	}
+
+	return addresses
+}
@@ -0,0 +1,40 @@
+package protocol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := NewBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	// Closed until the threshold is reached.
+	assert.True(t, breaker.Allow("1"))
+	breaker.Failure("1")
+	assert.True(t, breaker.Allow("1"))
+	breaker.Failure("1")
+	assert.False(t, breaker.Allow("1"))
+	assert.True(t, breaker.Allow("2"))
+	assert.Equal(t, []string{"1"}, breaker.Open())
+
+	// Half-open after the cooldown, letting a single attempt through.
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow("1"))
+	assert.False(t, breaker.Allow("1"))
+
+	// The attempt fails, open again.
+	breaker.Failure("1")
+	assert.False(t, breaker.Allow("1"))
+
+	// The next attempt succeeds, closed.
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow("1"))
+	breaker.Success("1")
+	assert.True(t, breaker.Allow("1"))
+	assert.True(t, breaker.Allow("1"))
+	assert.Empty(t, breaker.Open())
+}
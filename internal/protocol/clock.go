@@ -0,0 +1,25 @@
+package protocol
+
+import (
+	"time"
+)
+
+// Clock tells the current time and waits for durations to elapse. It can be
+// replaced in tests and simulations, so they don't have to wait on real time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock based on real time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -6,10 +6,18 @@ import (
 
 // Config holds various configuration parameters for a cowsql client.
 type Config struct {
-	Dial           DialFunc      // Network dialer.
-	DialTimeout    time.Duration // Timeout for establishing a network connection .
-	AttemptTimeout time.Duration // Timeout for each individual attempt to probe a server's leadership.
-	BackoffFactor  time.Duration // Exponential backoff factor for retries.
-	BackoffCap     time.Duration // Maximum connection retry backoff value,
-	RetryLimit     uint          // Maximum number of retries, or 0 for unlimited.
+	Dial             DialFunc      // Network dialer.
+	DialTimeout      time.Duration // Timeout for establishing a network connection .
+	AttemptTimeout   time.Duration // Timeout for each individual attempt to probe a server's leadership.
+	BackoffFactor    time.Duration // Exponential backoff factor for retries.
+	BackoffCap       time.Duration // Maximum connection retry backoff value,
+	RetryLimit       uint          // Maximum number of retries, or 0 for unlimited.
+	MaxRetryTime     time.Duration // Maximum total time spent finding a leader, or 0 for unlimited.
+	WriteTimeout     time.Duration // Timeout for sending a request, or 0 for none.
+	ReadTimeout      time.Duration // Timeout for receiving each response, or 0 for none.
+	Strict           bool          // Validate the types of the responses against the requests.
+	InterruptTimeout time.Duration // Timeout for completing an interrupt, or 0 for none.
+	RowsTimeout      time.Duration // Timeout for receiving each batch of rows, or 0 to use ReadTimeout.
+	Breaker          *Breaker      // Skip failing nodes, if not nil.
+	Clock            Clock         // Used to wait between retries, SystemClock if nil.
 }
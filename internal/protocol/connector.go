@@ -51,6 +51,10 @@ func NewConnector(id uint64, store NodeStore, config Config, log logging.Func) *
 		config.BackoffCap = time.Second
 	}
 
+	if config.Clock == nil {
+		config.Clock = SystemClock
+	}
+
 	connector := &Connector{
 		id:     id,
 		store:  store,
@@ -67,7 +71,17 @@ func NewConnector(id uint64, store NodeStore, config Config, log logging.Func) *
 func (c *Connector) Connect(ctx context.Context) (*Protocol, error) {
 	var protocol *Protocol
 
-	strategies := makeRetryStrategies(c.config.BackoffFactor, c.config.BackoffCap, c.config.RetryLimit)
+	// Bound the attempts as well as the backoff between them, so the
+	// whole search for a leader fits in the configured budget.
+	deadline := time.Time{}
+	if c.config.MaxRetryTime > 0 {
+		deadline = c.config.Clock.Now().Add(c.config.MaxRetryTime)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.MaxRetryTime)
+		defer cancel()
+	}
+
+	strategies := makeRetryStrategies(c.config.BackoffFactor, c.config.BackoffCap, c.config.RetryLimit, c.config.Clock, deadline)
 
 	// The retry strategy should be configured to retry indefinitely, until
 	// the given context is done.
@@ -133,6 +147,11 @@ func (c *Connector) connectAttemptAll(ctx context.Context, log logging.Func) (*P
 			log(l, format, a...)
 		}
 
+		if !c.allow(server.Address) {
+			log(logging.Debug, "skipped, circuit open")
+			continue
+		}
+
 		ctx, cancel := context.WithTimeout(ctx, c.config.AttemptTimeout)
 		defer cancel()
 
@@ -143,6 +162,7 @@ func (c *Connector) connectAttemptAll(ctx context.Context, log logging.Func) (*P
 			version = VersionLegacy
 			protocol, leader, err = c.connectAttemptOne(ctx, server.Address, version)
 		}
+		c.record(server.Address, err)
 		if err != nil {
 			// This server is unavailable, try with the next target.
 			log(logging.Warn, err.Error())
@@ -164,11 +184,17 @@ func (c *Connector) connectAttemptAll(ctx context.Context, log logging.Func) (*P
 		// server is the leader, let's close the connection to this
 		// server and try with the suggested one.
 		log(logging.Debug, "connect to reported leader %s", leader)
+		if !c.allow(leader) {
+			log(logging.Debug, "reported leader skipped, circuit open")
+			continue
+		}
 
 		ctx, cancel = context.WithTimeout(ctx, c.config.AttemptTimeout)
 		defer cancel()
 
-		protocol, leader, err = c.connectAttemptOne(ctx, leader, version)
+		address := leader
+		protocol, leader, err = c.connectAttemptOne(ctx, address, version)
+		c.record(address, err)
 		if err != nil {
 			// The leader reported by the previous server is
 			// unavailable, try with the next target.
@@ -188,6 +214,28 @@ func (c *Connector) connectAttemptAll(ctx context.Context, log logging.Func) (*P
 	return nil, ErrNoAvailableLeader
 }
 
+// Return whether an attempt to connect to the given address should be made,
+// according to the circuit breaker, if any.
+func (c *Connector) allow(address string) bool {
+	if c.config.Breaker == nil {
+		return true
+	}
+	return c.config.Breaker.Allow(address)
+}
+
+// Record the outcome of an attempt to connect to the given address in the
+// circuit breaker, if any.
+func (c *Connector) record(address string, err error) {
+	if c.config.Breaker == nil {
+		return
+	}
+	if err != nil {
+		c.config.Breaker.Failure(address)
+	} else {
+		c.config.Breaker.Success(address)
+	}
+}
+
 // Perform the initial handshake using the given protocol version.
 func Handshake(ctx context.Context, conn net.Conn, version uint64) (*Protocol, error) {
 	// Latest protocol version.
@@ -239,8 +287,10 @@ func (c *Connector) connectAttemptOne(ctx context.Context, address string, versi
 	// Send the initial Leader request.
 	request := Message{}
 	request.Init(16)
+	defer request.Release()
 	response := Message{}
 	response.Init(512)
+	defer response.Release()
 
 	EncodeLeader(&request)
 
@@ -289,6 +339,12 @@ func (c *Connector) connectAttemptOne(ctx context.Context, address string, versi
 		// protocol.heartbeatTimeout = time.Duration(heartbeatTimeout) * time.Millisecond
 		// go protocol.heartbeat()
 
+		protocol.writeTimeout = c.config.WriteTimeout
+		protocol.readTimeout = c.config.ReadTimeout
+		protocol.interruptTimeout = c.config.InterruptTimeout
+		protocol.rowsTimeout = c.config.RowsTimeout
+		protocol.strict = c.config.Strict
+
 		return protocol, "", nil
 	default:
 		// This server claims to know who the current leader is.
@@ -298,8 +354,9 @@ func (c *Connector) connectAttemptOne(ctx context.Context, address string, versi
 }
 
 // Return a retry strategy with exponential backoff, capped at the given amount
-// of time and possibly with a maximum number of retries.
-func makeRetryStrategies(factor, cap time.Duration, limit uint) []strategy.Strategy {
+// of time and possibly with a maximum number of retries, waiting on the given
+// clock.
+func makeRetryStrategies(factor, cap time.Duration, limit uint, clock Clock, deadline time.Time) []strategy.Strategy {
 	limit += 1 // Fix for change in behavior: https://github.com/Rican7/retry/pull/12
 	backoff := backoff.BinaryExponential(factor)
 
@@ -317,7 +374,12 @@ func makeRetryStrategies(factor, cap time.Duration, limit uint) []strategy.Strat
 				if duration > cap || duration <= 0 {
 					duration = cap
 				}
-				time.Sleep(duration)
+				// Give up right away if the backoff would
+				// exceed the deadline.
+				if !deadline.IsZero() && clock.Now().Add(duration).After(deadline) {
+					return false
+				}
+				<-clock.After(duration)
 			}
 
 			return true
@@ -59,6 +59,90 @@ func TestConnector_LimitRetries(t *testing.T) {
 	})
 }
 
+// A clock that doesn't wait, recording the durations it was asked for.
+type instantClock struct {
+	waits []time.Duration
+}
+
+func (c *instantClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c *instantClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+// The backoff between attempts waits on the configured clock.
+func TestConnector_Clock(t *testing.T) {
+	store := newStore(t, []string{"@test-123"})
+	clock := &instantClock{}
+	config := protocol.Config{
+		RetryLimit:    2,
+		BackoffFactor: time.Hour,
+		BackoffCap:    time.Hour,
+		Clock:         clock,
+	}
+	log, _ := newLogFunc(t)
+	connector := protocol.NewConnector(0, store, config, log)
+
+	_, err := connector.Connect(context.Background())
+	assert.Equal(t, protocol.ErrNoAvailableLeader, err)
+
+	assert.Equal(t, []time.Duration{time.Hour, time.Hour}, clock.waits)
+}
+
+// No more attempts are made once the backoff would exceed the total retry
+// time.
+func TestConnector_MaxRetryTime(t *testing.T) {
+	store := newStore(t, []string{"@test-123"})
+	clock := &instantClock{}
+	config := protocol.Config{
+		BackoffFactor: 100 * time.Millisecond,
+		BackoffCap:    time.Hour,
+		MaxRetryTime:  time.Second,
+		Clock:         clock,
+	}
+	log, _ := newLogFunc(t)
+	connector := protocol.NewConnector(0, store, config, log)
+
+	_, err := connector.Connect(context.Background())
+	assert.Equal(t, protocol.ErrNoAvailableLeader, err)
+
+	// The clock doesn't advance while waiting, so the backoff stops as soon
+	// as a single wait exceeds the budget.
+	assert.Equal(t, []time.Duration{
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+	}, clock.waits)
+}
+
+// Nodes that failed repeatedly are skipped while their circuit is open.
+func TestConnector_CircuitBreaker(t *testing.T) {
+	store := newStore(t, []string{"@test-123"})
+	breaker := protocol.NewBreaker(2, time.Hour)
+	config := protocol.Config{
+		RetryLimit: 3,
+		Breaker:    breaker,
+	}
+	log, check := newLogFunc(t)
+	connector := protocol.NewConnector(0, store, config, log)
+
+	_, err := connector.Connect(context.Background())
+	assert.Equal(t, protocol.ErrNoAvailableLeader, err)
+
+	check([]string{
+		"WARN: attempt 1: server @test-123: dial: dial unix @test-123: connect: connection refused",
+		"WARN: attempt 2: server @test-123: dial: dial unix @test-123: connect: connection refused",
+		"DEBUG: attempt 3: server @test-123: skipped, circuit open",
+		"DEBUG: attempt 4: server @test-123: skipped, circuit open",
+	})
+	assert.Equal(t, []string{"@test-123"}, breaker.Open())
+}
+
 // The network connection can't be established because of a connection timeout.
 func TestConnector_DialTimeout(t *testing.T) {
 	store := newStore(t, []string{"8.8.8.8:9000"})
@@ -0,0 +1,174 @@
+package protocol
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Truncated or inconsistent responses are reported as ErrMalformed.
+func TestDecode_Malformed(t *testing.T) {
+	cases := []struct {
+		title  string
+		data   []byte
+		decode func(*Message) error
+	}{
+		{
+			"truncated node",
+			newResponse(ResponseNode, func(m *Message) {
+				m.putUint64(1)
+			}),
+			func(m *Message) error {
+				_, _, err := DecodeNode(m)
+				return err
+			},
+		},
+		{
+			"unterminated string",
+			newResponse(ResponseNode, func(m *Message) {
+				m.putUint64(1)
+				m.putUint64(0x4141414141414141)
+			}),
+			func(m *Message) error {
+				_, _, err := DecodeNode(m)
+				return err
+			},
+		},
+		{
+			"too many nodes",
+			newResponse(ResponseNodes, func(m *Message) {
+				m.putUint64(1 << 60)
+			}),
+			func(m *Message) error {
+				_, err := DecodeNodes(m)
+				return err
+			},
+		},
+		{
+			"too many columns",
+			newResponse(ResponseRows, func(m *Message) {
+				m.putUint64(1 << 60)
+			}),
+			func(m *Message) error {
+				_, err := DecodeRows(m)
+				return err
+			},
+		},
+		{
+			"blob too big",
+			newResponse(ResponseRows, func(m *Message) {
+				m.putUint64(1)
+				m.putString("b")
+				m.putUint64(Blob)
+				m.putUint64(1 << 40)
+			}),
+			func(m *Message) error {
+				rows, err := DecodeRows(m)
+				if err != nil {
+					return err
+				}
+				return rows.Next(make([]driver.Value, 1))
+			},
+		},
+		{
+			"unknown column type",
+			newResponse(ResponseRows, func(m *Message) {
+				m.putUint64(1)
+				m.putString("x")
+				m.putUint64(0x0d)
+				m.putUint64(0)
+			}),
+			func(m *Message) error {
+				rows, err := DecodeRows(m)
+				if err != nil {
+					return err
+				}
+				return rows.Next(make([]driver.Value, 1))
+			},
+		},
+		{
+			"file too big",
+			newResponse(ResponseFiles, func(m *Message) {
+				m.putUint64(1)
+				m.putString("test.db")
+				m.putUint64(1 << 40)
+			}),
+			func(m *Message) error {
+				_, err := DecodeFiles(m)
+				return err
+			},
+		},
+		{
+			"body bigger than buffer",
+			newResponse(ResponseEmpty, func(m *Message) {
+				m.putUint64(0)
+			})[:messageHeaderSize],
+			func(m *Message) error {
+				return DecodeEmpty(m)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			err := c.decode(decodeResponse(c.data))
+			require.Error(t, err)
+			_, ok := err.(ErrMalformed)
+			assert.True(t, ok, "unexpected error type %T: %v", err, err)
+		})
+	}
+}
+
+// Decoding a well-formed response still works.
+func TestDecode_Rows(t *testing.T) {
+	data := newResponse(ResponseRows, func(m *Message) {
+		m.putUint64(2)
+		m.putString("n")
+		m.putString("s")
+		m.putUint64(Integer | Text<<4)
+		m.putInt64(42)
+		m.putString("hello")
+		m.putUint64(0xffffffffffffffff)
+	})
+
+	rows, err := DecodeRows(decodeResponse(data))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"n", "s"}, rows.Columns)
+
+	types, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"INTEGER", "TEXT"}, types)
+
+	dest := make([]driver.Value, 2)
+	require.NoError(t, rows.Next(dest))
+	assert.Equal(t, []driver.Value{int64(42), "hello"}, dest)
+	assert.False(t, rows.Part())
+}
+
+// Return the header and body of a response encoded by the given function.
+func newResponse(mtype uint8, encode func(*Message)) []byte {
+	m := Message{}
+	m.Init(64)
+	encode(&m)
+	m.putHeader(mtype, 0)
+	return append(append([]byte{}, m.header...), m.body.Bytes[:m.body.Offset]...)
+}
+
+// Fill a message with the given header and body, as received by recv.
+func decodeResponse(data []byte) *Message {
+	m := &Message{}
+	m.Init(8)
+	if len(data) < messageHeaderSize {
+		return m
+	}
+	copy(m.header, data)
+	m.words = binary.LittleEndian.Uint32(m.header[0:])
+	m.mtype = m.header[4]
+	m.schema = m.header[5]
+	m.extra = binary.LittleEndian.Uint16(m.header[6:])
+	m.body.Bytes = append([]byte{}, data[messageHeaderSize:]...)
+	return m
+}
@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"strings"
 )
@@ -15,3 +16,30 @@ func Dial(ctx context.Context, address string) (net.Conn, error) {
 	dialer := net.Dialer{}
 	return dialer.DialContext(ctx, family, address)
 }
+
+// SessionCacheForAddress returns a TLS client session cache that stores
+// sessions in the given cache, using keys that include the given address.
+//
+// Go caches sessions by server name when one is set, but all nodes of a
+// cluster typically share the same certificate and hence server name. Since a
+// session can only be resumed with the node that created it, sessions of
+// different nodes must be stored under different keys.
+func SessionCacheForAddress(cache tls.ClientSessionCache, address string) tls.ClientSessionCache {
+	if cache == nil {
+		return nil
+	}
+	return &addressSessionCache{cache: cache, address: address}
+}
+
+type addressSessionCache struct {
+	cache   tls.ClientSessionCache
+	address string
+}
+
+func (c *addressSessionCache) Get(key string) (*tls.ClientSessionState, bool) {
+	return c.cache.Get(c.address + "/" + key)
+}
+
+func (c *addressSessionCache) Put(key string, session *tls.ClientSessionState) {
+	c.cache.Put(c.address+"/"+key, session)
+}
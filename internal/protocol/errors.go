@@ -2,6 +2,9 @@ package protocol
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // Client errors.
@@ -24,6 +27,49 @@ func (e ErrRequest) Error() string {
 	return fmt.Sprintf("%s (%d)", e.Description, e.Code)
 }
 
+// ErrMalformed is returned when a response doesn't conform to the wire
+// protocol, for example because it's truncated or holds out-of-bounds sizes.
+type ErrMalformed struct {
+	Reason string
+}
+
+func (e ErrMalformed) Error() string {
+	return fmt.Sprintf("malformed response: %s", e.Reason)
+}
+
+// ErrCall is returned when sending a request or receiving a response fails.
+// It holds the ID that was assigned to the call, which also appears in the
+// error message and in trace logs, so failures can be correlated across
+// components.
+type ErrCall struct {
+	ID  uint64
+	err error
+}
+
+func (e ErrCall) Error() string {
+	return e.err.Error()
+}
+
+// Cause returns the underlying error.
+func (e ErrCall) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the underlying error.
+func (e ErrCall) Unwrap() error {
+	return e.err
+}
+
+// CallID returns the ID of the call that caused the given error, or 0 if the
+// error was not caused by a failed call.
+func CallID(err error) uint64 {
+	var e ErrCall
+	if errors.As(err, &e) {
+		return e.ID
+	}
+	return 0
+}
+
 // ErrRowsPart is returned when the first batch of a multi-response result
 // batch is done.
 var ErrRowsPart = fmt.Errorf("not all rows were returned in this response")
@@ -37,3 +83,24 @@ type Error struct {
 func (e Error) Error() string {
 	return e.Message
 }
+
+// ErrNoSuchFunction is matched by errors reporting that a statement uses an
+// SQL function unknown to the server.
+var ErrNoSuchFunction = fmt.Errorf("no such function")
+
+// Prefix of the message of errors about unknown SQL functions.
+const noSuchFunctionPrefix = "no such function: "
+
+// Function returns the name of the SQL function unknown to the server, if
+// that's what the error is about, or an empty string otherwise.
+func (e Error) Function() string {
+	if !strings.HasPrefix(e.Message, noSuchFunctionPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(e.Message, noSuchFunctionPrefix)
+}
+
+// Is makes errors about unknown SQL functions match ErrNoSuchFunction.
+func (e Error) Is(target error) bool {
+	return target == ErrNoSuchFunction && e.Function() != ""
+}
@@ -0,0 +1,77 @@
+//go:build go1.18
+// +build go1.18
+
+package protocol
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+// Decoding arbitrary responses must never panic. Run with:
+//
+//	go test -fuzz FuzzDecode ./internal/protocol
+func FuzzDecode(f *testing.F) {
+	f.Add(newResponse(ResponseFailure, func(m *Message) {
+		m.putUint64(1)
+		m.putString("error")
+	}))
+	f.Add(newResponse(ResponseNode, func(m *Message) {
+		m.putUint64(1)
+		m.putString("127.0.0.1:9001")
+	}))
+	f.Add(newResponse(ResponseNodes, func(m *Message) {
+		m.putUint64(1)
+		m.putUint64(1)
+		m.putString("127.0.0.1:9001")
+		m.putUint64(0)
+	}))
+	f.Add(newResponse(ResponseRows, func(m *Message) {
+		m.putUint64(2)
+		m.putString("n")
+		m.putString("b")
+		m.putUint64(Integer | Blob<<4)
+		m.putInt64(1)
+		m.putBlob([]byte("blob"))
+		m.putUint64(0xeeeeeeeeeeeeeeee)
+	}))
+	f.Add(newResponse(ResponseFiles, func(m *Message) {
+		m.putUint64(1)
+		m.putString("test.db")
+		m.putUint64(8)
+		m.putUint64(0)
+	}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decodeAll(data)
+	})
+}
+
+// Run all decoders against the given response.
+func decodeAll(data []byte) {
+	DecodeFailure(decodeResponse(data))
+	DecodeWelcome(decodeResponse(data))
+	DecodeNodeLegacy(decodeResponse(data))
+	DecodeNode(decodeResponse(data))
+	DecodeNodes(decodeResponse(data))
+	DecodeDb(decodeResponse(data))
+	DecodeStmt(decodeResponse(data))
+	DecodeEmpty(decodeResponse(data))
+	DecodeResult(decodeResponse(data))
+	DecodeMetadata(decodeResponse(data))
+
+	if rows, err := DecodeRows(decodeResponse(data)); err == nil {
+		rows.ColumnTypes()
+		dest := make([]driver.Value, len(rows.Columns))
+		for rows.Next(dest) == nil {
+		}
+		rows.Part()
+		rows.Close()
+	}
+
+	if files, err := DecodeFiles(decodeResponse(data)); err == nil {
+		for name, _ := files.Next(); name != ""; name, _ = files.Next() {
+		}
+		files.Close()
+	}
+}
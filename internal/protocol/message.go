@@ -34,15 +34,31 @@ type Message struct {
 // Init initializes the message using the given initial size for the data
 // buffer, which is re-used across requests or responses encoded or decoded
 // using this message object.
+//
+// The buffer is taken from a process-wide pool, see Release.
 func (m *Message) Init(initialBufferSize int) {
 	if (initialBufferSize % messageWordSize) != 0 {
 		panic("initial buffer size is not aligned to word boundary")
 	}
 	m.header = make([]byte, messageHeaderSize)
-	m.body.Bytes = make([]byte, initialBufferSize)
+	m.body.Bytes = getBuffer(initialBufferSize)
 	m.reset()
 }
 
+// Release returns the data buffer of the message to a process-wide pool, so
+// it can be re-used by other messages. The message must not be used after
+// this method is called, unless it's initialized again.
+//
+// Releasing a message is optional, buffers of messages that are not released
+// are simply garbage collected.
+func (m *Message) Release() {
+	if m.body.Bytes == nil {
+		return
+	}
+	putBuffer(m.body.Bytes)
+	m.body.Bytes = nil
+}
+
 // Reset the state of the message so it can be used to encode or decode again.
 func (m *Message) reset() {
 	m.words = 0
@@ -160,38 +176,74 @@ func (m *Message) putFloat64(v float64) {
 }
 
 func (m *Message) putNamedValuesInner(values NamedValues) {
+	n := len(values)
+
+	// Write the type tags, computing the encoded size of the values along
+	// the way, so the buffer gets grown at most once for them.
+	b := m.bufferForPut(n + messageWordSize)
+	tags := b.Bytes[b.Offset : b.Offset+n]
+	size := 0
+	homogeneous := true
 	for i := range values {
 		if values[i].Ordinal != i+1 {
 			panic("unexpected ordinal")
 		}
 
-		switch values[i].Value.(type) {
+		switch v := values[i].Value.(type) {
 		case int64:
-			m.putUint8(Integer)
+			tags[i] = Integer
+			size += messageWordSize
 		case float64:
-			m.putUint8(Float)
+			tags[i] = Float
+			size += messageWordSize
 		case bool:
-			m.putUint8(Boolean)
+			tags[i] = Boolean
+			size += messageWordSize
 		case []byte:
-			m.putUint8(Blob)
+			tags[i] = Blob
+			size += messageWordSize + padded(len(v))
 		case string:
-			m.putUint8(Text)
+			tags[i] = Text
+			size += padded(len(v) + 1)
 		case nil:
-			m.putUint8(Null)
+			tags[i] = Null
+			size += messageWordSize
 		case time.Time:
-			m.putUint8(ISO8601)
+			tags[i] = ISO8601
+			size += padded(len(iso8601Formats[0]) + 1)
 		default:
 			panic("unsupported value type")
 		}
+		if tags[i] != tags[0] {
+			homogeneous = false
+		}
 	}
-
-	b := m.bufferForPut(1)
+	tag := tags[0]
+	b.Advance(n)
 
 	if trailing := b.Offset % messageWordSize; trailing != 0 {
-		// Skip padding bytes
-		b.Advance(messageWordSize - trailing)
+		// Zero the padding bytes
+		pad := messageWordSize - trailing
+		for i := 0; i < pad; i++ {
+			b.Bytes[b.Offset+i] = 0
+		}
+		b.Advance(pad)
 	}
 
+	m.bufferForPut(size)
+
+	switch {
+	case homogeneous && tag == Integer:
+		m.putInt64Values(values)
+	case homogeneous && tag == Text:
+		m.putStringValues(values)
+	default:
+		m.putValues(values)
+	}
+}
+
+// Encode the given values, which can be of any supported type.
+func (m *Message) putValues(values NamedValues) {
 	for i := range values {
 		switch v := values[i].Value.(type) {
 		case int64:
@@ -219,6 +271,42 @@ func (m *Message) putNamedValuesInner(values NamedValues) {
 	}
 }
 
+// Encode the given values, which must all be int64. The buffer must have
+// enough room for them.
+func (m *Message) putInt64Values(values NamedValues) {
+	b := &m.body
+	buf := b.Bytes[b.Offset : b.Offset+len(values)*messageWordSize]
+	for i := range values {
+		binary.LittleEndian.PutUint64(buf[i*messageWordSize:], uint64(values[i].Value.(int64)))
+	}
+	b.Advance(len(buf))
+}
+
+// Encode the given values, which must all be strings. The buffer must have
+// enough room for them.
+func (m *Message) putStringValues(values NamedValues) {
+	b := &m.body
+	offset := b.Offset
+	for i := range values {
+		v := values[i].Value.(string)
+		end := offset + padded(len(v)+1)
+		offset += copy(b.Bytes[offset:], v)
+		// Add the nul byte and the padding
+		for ; offset < end; offset++ {
+			b.Bytes[offset] = 0
+		}
+	}
+	b.Advance(offset - b.Offset)
+}
+
+// Return the given size rounded up to a multiple of the message word size.
+func padded(size int) int {
+	if trailing := size % messageWordSize; trailing != 0 {
+		size += messageWordSize - trailing
+	}
+	return size
+}
+
 // Encode the given driver values as binding parameters.
 func (m *Message) putNamedValues(values NamedValues) {
 	l := len(values)
@@ -282,9 +370,14 @@ func (m *Message) finalize() {
 }
 
 func (m *Message) bufferForPut(size int) *buffer {
-	for (m.body.Offset + size) > len(m.body.Bytes) {
-		// Grow message buffer.
-		bytes := make([]byte, len(m.body.Bytes)*2)
+	if (m.body.Offset + size) > len(m.body.Bytes) {
+		// Grow message buffer, doubling its size as many times as
+		// needed but allocating only once.
+		n := len(m.body.Bytes) * 2
+		for (m.body.Offset + size) > n {
+			n *= 2
+		}
+		bytes := make([]byte, n)
 		copy(bytes, m.body.Bytes)
 		m.body.Bytes = bytes
 	}
@@ -299,11 +392,11 @@ func (m *Message) getHeader() (uint8, uint8) {
 
 // Read a string from the message body.
 func (m *Message) getString() string {
-	b := m.bufferForGet()
+	b := m.bufferForGet(1)
 
-	index := bytes.IndexByte(b.Bytes[b.Offset:], 0)
+	index := bytes.IndexByte(b.Bytes[b.Offset:m.size()], 0)
 	if index == -1 {
-		panic("no string found")
+		m.malformed("unterminated string")
 	}
 	s := string(b.Bytes[b.Offset : b.Offset+index])
 
@@ -314,32 +407,31 @@ func (m *Message) getString() string {
 		index += messageWordSize - trailing
 	}
 
-	b.Advance(index)
+	m.bufferForGet(index).Advance(index)
 
 	return s
 }
 
 func (m *Message) getBlob() []byte {
 	size := m.getUint64()
-	data := make([]byte, size)
-	for i := range data {
-		data[i] = m.getUint8()
+	if size > uint64(m.remaining()) {
+		m.malformed("blob of %d bytes exceeds message body", size)
 	}
 	pad := 0
 	if (size % messageWordSize) != 0 {
 		// Account for padding
 		pad = int(messageWordSize - (size % messageWordSize))
 	}
-	// Consume padding
-	for i := 0; i < pad; i++ {
-		m.getUint8()
-	}
+	b := m.bufferForGet(int(size) + pad)
+	data := make([]byte, size)
+	copy(data, b.Bytes[b.Offset:])
+	b.Advance(int(size) + pad)
 	return data
 }
 
 // Read a byte from the message body.
 func (m *Message) getUint8() uint8 {
-	b := m.bufferForGet()
+	b := m.bufferForGet(1)
 	defer b.Advance(1)
 
 	return b.Bytes[b.Offset]
@@ -347,7 +439,7 @@ func (m *Message) getUint8() uint8 {
 
 // Read a 2-byte word from the message body.
 func (m *Message) getUint16() uint16 {
-	b := m.bufferForGet()
+	b := m.bufferForGet(2)
 	defer b.Advance(2)
 
 	return binary.LittleEndian.Uint16(b.Bytes[b.Offset:])
@@ -355,7 +447,7 @@ func (m *Message) getUint16() uint16 {
 
 // Read a 4-byte word from the message body.
 func (m *Message) getUint32() uint32 {
-	b := m.bufferForGet()
+	b := m.bufferForGet(4)
 	defer b.Advance(4)
 
 	return binary.LittleEndian.Uint32(b.Bytes[b.Offset:])
@@ -363,7 +455,7 @@ func (m *Message) getUint32() uint32 {
 
 // Read reads an 8-byte word from the message body.
 func (m *Message) getUint64() uint64 {
-	b := m.bufferForGet()
+	b := m.bufferForGet(8)
 	defer b.Advance(8)
 
 	return binary.LittleEndian.Uint64(b.Bytes[b.Offset:])
@@ -371,7 +463,7 @@ func (m *Message) getUint64() uint64 {
 
 // Read a signed 8-byte word from the message body.
 func (m *Message) getInt64() int64 {
-	b := m.bufferForGet()
+	b := m.bufferForGet(8)
 	defer b.Advance(8)
 
 	return int64(binary.LittleEndian.Uint64(b.Bytes[b.Offset:]))
@@ -379,7 +471,7 @@ func (m *Message) getInt64() int64 {
 
 // Read a floating point number from the message body.
 func (m *Message) getFloat64() float64 {
-	b := m.bufferForGet()
+	b := m.bufferForGet(8)
 	defer b.Advance(8)
 
 	return math.Float64frombits(binary.LittleEndian.Uint64(b.Bytes[b.Offset:]))
@@ -388,6 +480,10 @@ func (m *Message) getFloat64() float64 {
 // Decode a list of server objects from the message body.
 func (m *Message) getNodes() Nodes {
 	n := m.getUint64()
+	// Each node takes at least three words: ID, address and role.
+	if n > uint64(m.remaining()/(3*messageWordSize)) {
+		m.malformed("%d nodes exceed message body", n)
+	}
 	servers := make(Nodes, n)
 
 	for i := 0; i < int(n); i++ {
@@ -409,8 +505,13 @@ func (m *Message) getResult() Result {
 
 // Decode a query result set object from the message body.
 func (m *Message) getRows() Rows {
-	// Read the column count and column names.
-	columns := make([]string, m.getUint64())
+	// Read the column count and column names, each name taking at least
+	// one word.
+	n := m.getUint64()
+	if n > uint64(m.remaining()/messageWordSize) {
+		m.malformed("%d columns exceed message body", n)
+	}
+	columns := make([]string, n)
 
 	for i := range columns {
 		columns[i] = m.getString()
@@ -428,30 +529,73 @@ func (m *Message) getFiles() Files {
 		n:       m.getUint64(),
 		message: m,
 	}
+
+	// Check that all files fit in the message body, so Files.Next doesn't
+	// need to fail.
+	offset := m.body.Offset
+	for i := uint64(0); i < files.n; i++ {
+		m.getString()
+		length := m.getUint64()
+		m.bufferForGet(int(length)).Advance(int(length))
+	}
+	m.body.Offset = offset
+
 	return files
 }
 
 func (m *Message) hasBeenConsumed() bool {
-	size := int(m.words * messageWordSize)
-	return m.body.Offset == size
+	return m.body.Offset == m.size()
 }
 
 func (m *Message) lastByte() byte {
-	size := int(m.words * messageWordSize)
+	size := int(m.words) * messageWordSize
+	if size == 0 || size > len(m.body.Bytes) {
+		return 0
+	}
 	return m.body.Bytes[size-1]
 }
 
-func (m *Message) bufferForGet() *buffer {
-	size := int(m.words * messageWordSize)
-	// The static body has been exahusted, use the dynamic one.
-	if m.body.Offset == size {
-		err := fmt.Errorf("short message: type=%d words=%d off=%d", m.mtype, m.words, m.body.Offset)
-		panic(err)
+// Return the size of the message body.
+func (m *Message) size() int {
+	size := int(m.words) * messageWordSize
+	if size > len(m.body.Bytes) {
+		m.malformed("body of %d bytes exceeds buffer of %d bytes", size, len(m.body.Bytes))
+	}
+	return size
+}
+
+// Return the number of bytes of the message body not read yet.
+func (m *Message) remaining() int {
+	return m.size() - m.body.Offset
+}
+
+// Return the body buffer, checking that at least the given number of bytes
+// can be read from it.
+func (m *Message) bufferForGet(size int) *buffer {
+	if size < 0 || size > m.remaining() {
+		m.malformed("short message: type=%d words=%d off=%d", m.mtype, m.words, m.body.Offset)
 	}
 
 	return &m.body
 }
 
+// Abort decoding the message, see recoverMalformed.
+func (m *Message) malformed(format string, a ...interface{}) {
+	panic(ErrMalformed{Reason: fmt.Sprintf(format, a...)})
+}
+
+// Turn a panic raised because of a malformed message into an error. It must be
+// deferred by all the exported functions and methods decoding messages.
+func recoverMalformed(err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(ErrMalformed)
+		if !ok {
+			panic(r)
+		}
+		*err = e
+	}
+}
+
 // Result holds the result of a statement.
 type Result struct {
 	LastInsertID uint64
@@ -500,7 +644,7 @@ func (r *Rows) columnTypes(save bool) ([]uint8, error) {
 		if slot == 0xee {
 			// More rows are available.
 			if save {
-				r.message.bufferForGet().Advance(-(i + 1))
+				r.message.body.Advance(-(i + 1))
 			}
 			return r.types, ErrRowsPart
 		}
@@ -508,7 +652,7 @@ func (r *Rows) columnTypes(save bool) ([]uint8, error) {
 		if slot == 0xff {
 			// Rows EOF marker
 			if save {
-				r.message.bufferForGet().Advance(-(i + 1))
+				r.message.body.Advance(-(i + 1))
 			}
 			return r.types, io.EOF
 		}
@@ -530,13 +674,15 @@ func (r *Rows) columnTypes(save bool) ([]uint8, error) {
 		r.types[index] = slot >> 4
 	}
 	if save {
-		r.message.bufferForGet().Advance(-headerSize)
+		r.message.body.Advance(-headerSize)
 	}
 	return r.types, nil
 }
 
 // Next returns the next row in the result set.
-func (r *Rows) Next(dest []driver.Value) error {
+func (r *Rows) Next(dest []driver.Value) (err error) {
+	defer recoverMalformed(&err)
+
 	types, err := r.columnTypes(false)
 	if err != nil {
 		return err
@@ -582,7 +728,7 @@ func (r *Rows) Next(dest []driver.Value) error {
 		case Boolean:
 			dest[i] = r.message.getInt64() != 0
 		default:
-			panic("unknown data type")
+			r.message.malformed("unknown data type %d", types[i])
 		}
 	}
 
@@ -590,9 +736,10 @@ func (r *Rows) Next(dest []driver.Value) error {
 }
 
 // Close the result set and reset the underlying message.
-func (r *Rows) Close() error {
+func (r *Rows) Close() (err error) {
+	defer recoverMalformed(&err)
+
 	// If we didn't go through all rows, let's look at the last byte.
-	var err error
 	if !r.message.hasBeenConsumed() {
 		slot := r.message.lastByte()
 		if slot == 0xee {
@@ -609,6 +756,12 @@ func (r *Rows) Close() error {
 	return err
 }
 
+// Part returns true if the server has more rows to send after the ones in
+// this batch.
+func (r *Rows) Part() bool {
+	return r.message.lastByte() == 0xee
+}
+
 // Files holds a set of files encoded in a message body.
 type Files struct {
 	n       uint64
@@ -622,10 +775,10 @@ func (f *Files) Next() (string, []byte) {
 	f.n--
 	name := f.message.getString()
 	length := f.message.getUint64()
+	b := f.message.bufferForGet(int(length))
 	data := make([]byte, length)
-	for i := 0; i < int(length); i++ {
-		data[i] = f.message.getUint8()
-	}
+	copy(data, b.Bytes[b.Offset:])
+	b.Advance(int(length))
 	return name, data
 }
 
@@ -655,9 +808,11 @@ var iso8601Formats = []string{
 }
 
 // ColumnTypes returns the column types for the the result set.
-func (r *Rows) ColumnTypes() ([]string, error) {
+func (r *Rows) ColumnTypes() (kinds []string, err error) {
+	defer recoverMalformed(&err)
+
 	types, err := r.columnTypes(true)
-	kinds := make([]string, len(types))
+	kinds = make([]string, len(types))
 
 	for i, t := range types {
 		switch t {
@@ -1,5 +1,7 @@
 package protocol
 
+import "time"
+
 func (m *Message) Body() ([]byte, int) {
 	return m.body.Bytes, m.body.Offset
 }
@@ -7,3 +9,16 @@ func (m *Message) Body() ([]byte, int) {
 func (m *Message) Rewind() {
 	m.body.Offset = 0
 }
+
+func (p *Protocol) SetTimeouts(write, read time.Duration) {
+	p.writeTimeout = write
+	p.readTimeout = read
+}
+
+func (p *Protocol) SetInterruptTimeout(timeout time.Duration) {
+	p.interruptTimeout = timeout
+}
+
+func (p *Protocol) SetRowsTimeout(timeout time.Duration) {
+	p.rowsTimeout = timeout
+}
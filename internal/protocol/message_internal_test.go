@@ -1,7 +1,9 @@
 package protocol
 
 import (
+	"database/sql/driver"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 	"unsafe"
@@ -71,6 +73,24 @@ func TestMessage_putString(t *testing.T) {
 	}
 }
 
+// Growing the buffer to fit a large value takes a single allocation.
+func TestMessage_putString_Grow(t *testing.T) {
+	message := Message{}
+	message.Init(16)
+
+	s := strings.Repeat("x", 10000)
+	allocs := testing.AllocsPerRun(1, func() {
+		message.body.Bytes = make([]byte, 16)
+		message.reset()
+		message.putString(s)
+	})
+	assert.Equal(t, float64(2), allocs) // Initial buffer and grown buffer
+	assert.Equal(t, 16384, len(message.body.Bytes))
+
+	_, offset := message.Body()
+	assert.Equal(t, 10008, offset)
+}
+
 func TestMessage_putUint8(t *testing.T) {
 	message := Message{}
 	message.Init(8)
@@ -209,6 +229,43 @@ func TestMessage_putNamedValues32(t *testing.T) {
 	assert.Equal(t, bytes[10], byte(ISO8601))
 }
 
+// The fast paths for homogeneous parameters produce the same encoding as
+// the generic one.
+func TestMessage_putNamedValues_FastPaths(t *testing.T) {
+	ints := make(NamedValues, 33)
+	strings := make(NamedValues, 33)
+	for i := range ints {
+		ints[i] = driver.NamedValue{Ordinal: i + 1, Value: int64(-i * 1000)}
+		strings[i] = driver.NamedValue{Ordinal: i + 1, Value: fmt.Sprintf("%0*d", i, 0)}
+	}
+
+	for _, values := range []NamedValues{ints, strings} {
+		fast := Message{}
+		fast.Init(16)
+		fast.putNamedValues32(values)
+
+		generic := Message{}
+		generic.Init(16)
+		generic.putUint32(uint32(len(values)))
+		for _, value := range values {
+			switch value.Value.(type) {
+			case int64:
+				generic.putUint8(Integer)
+			case string:
+				generic.putUint8(Text)
+			}
+		}
+		generic.bufferForPut(messageWordSize)
+		generic.body.Advance(padded(generic.body.Offset) - generic.body.Offset)
+		generic.putValues(values)
+
+		fastBytes, fastOffset := fast.Body()
+		genericBytes, genericOffset := generic.Body()
+		require.Equal(t, genericOffset, fastOffset)
+		assert.Equal(t, genericBytes[:genericOffset], fastBytes[:fastOffset])
+	}
+}
+
 func TestMessage_putHeader(t *testing.T) {
 	message := Message{}
 	message.Init(64)
@@ -241,6 +298,43 @@ func BenchmarkMessage_putUint64(b *testing.B) {
 	}
 }
 
+func BenchmarkMessage_putNamedValues(b *testing.B) {
+	ints := make(NamedValues, 300)
+	strings := make(NamedValues, 300)
+	mixed := make(NamedValues, 300)
+	for i := range ints {
+		ints[i] = driver.NamedValue{Ordinal: i + 1, Value: int64(i)}
+		strings[i] = driver.NamedValue{Ordinal: i + 1, Value: fmt.Sprintf("value-%d", i)}
+		if i%2 == 0 {
+			mixed[i] = ints[i]
+		} else {
+			mixed[i] = strings[i]
+		}
+	}
+
+	cases := []struct {
+		name   string
+		values NamedValues
+	}{
+		{"int64", ints},
+		{"string", strings},
+		{"mixed", mixed},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			message := Message{}
+			message.Init(4096)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				message.reset()
+				message.putNamedValues32(c.values)
+			}
+		})
+	}
+}
+
 func TestMessage_getString(t *testing.T) {
 	cases := []struct {
 		String string
@@ -322,3 +416,52 @@ func TestMessage_getString_Overflow_WordBoundary(t *testing.T) {
 
 	assert.Equal(t, 32, message.body.Offset)
 }
+
+func TestMessage_Release(t *testing.T) {
+	message := Message{}
+	message.Init(64)
+
+	// Grow the buffer past its initial size.
+	message.putBlob(make([]byte, 100))
+	assert.Equal(t, 128, len(message.body.Bytes))
+
+	message.Release()
+	assert.Nil(t, message.body.Bytes)
+
+	// Releasing twice is harmless.
+	message.Release()
+
+	message.Init(4096)
+	assert.Equal(t, 4096, len(message.body.Bytes))
+	message.Release()
+}
+
+func TestSizeClass(t *testing.T) {
+	cases := []struct {
+		size  int
+		class uint
+	}{
+		{0, 3},
+		{8, 3},
+		{9, 4},
+		{512, 9},
+		{4096, 12},
+		{4097, 13},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%d", c.size), func(t *testing.T) {
+			assert.Equal(t, c.class, sizeClass(c.size))
+		})
+	}
+}
+
+func TestPutBuffer(t *testing.T) {
+	// Buffers are pooled under the largest size class they can hold.
+	putBuffer(make([]byte, 1000))
+	buf := getBuffer(512)
+	assert.Equal(t, 512, len(buf))
+
+	// Buffers too big for any pool are allocated on demand.
+	buf = getBuffer(1<<maxPoolClass + 1)
+	assert.Equal(t, 1<<maxPoolClass+1, len(buf))
+}
@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"sync"
+)
+
+// Range of the size classes of pooled message buffers, as powers of two.
+const (
+	minPoolClass = 3  // 8 bytes, the size of a word
+	maxPoolClass = 20 // 1 MiB
+)
+
+// Process-wide pools of message body buffers, one for each size class, shared
+// by the messages of all connections.
+var bufferPools [maxPoolClass - minPoolClass + 1]sync.Pool
+
+// Return a buffer of at least the given size, reusing a pooled one if
+// possible.
+func getBuffer(size int) []byte {
+	class := sizeClass(size)
+	if class > maxPoolClass {
+		return make([]byte, size)
+	}
+	if buf, ok := bufferPools[class-minPoolClass].Get().(*[]byte); ok {
+		return *buf
+	}
+	return make([]byte, 1<<class)
+}
+
+// Return the given buffer to the pool of the largest size class it can hold.
+func putBuffer(buf []byte) {
+	class := sizeClass(cap(buf))
+	if 1<<class > cap(buf) {
+		class--
+	}
+	if class < minPoolClass || class > maxPoolClass {
+		return
+	}
+	buf = buf[:1<<class]
+	bufferPools[class-minPoolClass].Put(&buf)
+}
+
+// Return the smallest size class holding the given size.
+func sizeClass(size int) uint {
+	class := uint(minPoolClass)
+	for 1<<class < size {
+		class++
+	}
+	return class
+}
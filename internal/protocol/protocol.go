@@ -6,18 +6,30 @@ import (
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
+// Source of the IDs assigned to calls, unique within the process.
+var callCounter uint64
+
 // Protocol sends and receive the cowsql message on the wire.
 type Protocol struct {
-	version uint64        // Protocol version
-	conn    net.Conn      // Underlying network connection.
-	closeCh chan struct{} // Stops the heartbeat when the connection gets closed
-	mu      sync.Mutex    // Serialize requests
-	netErr  error         // A network error occurred
+	lastID    uint64        // ID of the last call, first for atomic alignment
+	lastStart int64         // Start of the last call, in Unix nanoseconds
+	version   uint64        // Protocol version
+	conn      net.Conn      // Underlying network connection.
+	closeCh   chan struct{} // Stops the heartbeat when the connection gets closed
+	mu        sync.Mutex    // Serialize requests
+	netErr    error         // A network error occurred
+
+	writeTimeout     time.Duration // Timeout for sending a request, if any
+	readTimeout      time.Duration // Timeout for receiving a response, if any
+	interruptTimeout time.Duration // Timeout for completing an interrupt, if any
+	rowsTimeout      time.Duration // Timeout for receiving each batch of rows, if any
+	strict           bool          // Validate the types of the responses
 }
 
 func newProtocol(version uint64, conn net.Conn) *Protocol {
@@ -32,6 +44,9 @@ func newProtocol(version uint64, conn net.Conn) *Protocol {
 
 // Call invokes a cowsql RPC, sending a request message and receiving a
 // response message.
+//
+// Each call is assigned an ID, which is included in the returned error, if
+// any, and can be retrieved with LastCallID.
 func (p *Protocol) Call(ctx context.Context, request, response *Message) (err error) {
 	// We need to take a lock since the cowsql server currently does not
 	// support concurrent requests.
@@ -52,43 +67,169 @@ func (p *Protocol) Call(ctx context.Context, request, response *Message) (err er
 		}
 	}()
 
+	id := atomic.AddUint64(&callCounter, 1)
+	atomic.StoreUint64(&p.lastID, id)
+	atomic.StoreInt64(&p.lastStart, time.Now().UnixNano())
+
 	var budget time.Duration
 
-	// Honor the ctx deadline, if present.
-	if deadline, ok := ctx.Deadline(); ok {
-		p.conn.SetDeadline(deadline)
+	// Honor the ctx deadline, if present, as well as the send and receive
+	// timeouts.
+	deadline, ok := ctx.Deadline()
+	if ok {
 		budget = time.Until(deadline)
+	}
+	timed := ok || p.writeTimeout > 0 || p.readTimeout > 0
+	if timed {
 		defer p.conn.SetDeadline(time.Time{})
 	}
 
 	desc := requestDesc(request.mtype)
 
+	if timed {
+		p.conn.SetWriteDeadline(earliest(deadline, p.writeTimeout))
+	}
 	if err = p.send(request); err != nil {
-		return errors.Wrapf(err, "call %s (budget %s): send", desc, budget)
+		return ErrCall{ID: id, err: errors.Wrapf(err, "call %s (id %d, budget %s): send", desc, id, budget)}
 	}
 
+	if timed {
+		p.conn.SetReadDeadline(earliest(deadline, p.readTimeout))
+	}
 	if err = p.recv(response); err != nil {
-		return errors.Wrapf(err, "call %s (budget %s): receive", desc, budget)
+		return ErrCall{ID: id, err: errors.Wrapf(err, "call %s (id %d, budget %s): receive", desc, id, budget)}
+	}
+
+	if p.strict {
+		if err = checkResponse(request.mtype, response.mtype); err != nil {
+			return ErrCall{ID: id, err: errors.Wrapf(err, "call %s (id %d)", desc, id)}
+		}
 	}
 
 	return
 }
 
 // More is used when a request maps to multiple responses.
+//
+// The ctx deadline and cancellation are honored while waiting for each
+// response, as well as the rows timeout, or the read timeout if no rows
+// timeout is set. If a response can't be received, the connection is left
+// in an unknown state, so it's marked as failed and all further calls will
+// fail. Errors are tagged with the ID of the call that sent the request.
 func (p *Protocol) More(ctx context.Context, response *Message) error {
-	return p.recv(response)
+	id := p.LastCallID()
+
+	if err := ctx.Err(); err != nil {
+		return ErrCall{ID: id, err: errors.Wrapf(err, "more (id %d)", id)}
+	}
+
+	timeout := p.readTimeout
+	if p.rowsTimeout > 0 {
+		timeout = p.rowsTimeout
+	}
+	deadline, _ := ctx.Deadline()
+	deadline = earliest(deadline, timeout)
+
+	// Reset the read deadline on return. This is deferred first so it runs
+	// after the cancel goroutine below has exited, since that goroutine
+	// might set a deadline of its own.
+	if !deadline.IsZero() || ctx.Done() != nil {
+		defer p.conn.SetReadDeadline(time.Time{})
+	}
+	if !deadline.IsZero() {
+		p.conn.SetReadDeadline(deadline)
+	}
+
+	// Unblock the read if the context gets canceled.
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			select {
+			case <-ctx.Done():
+				p.conn.SetReadDeadline(time.Now())
+			case <-stop:
+			}
+		}()
+		defer func() {
+			close(stop)
+			<-stopped
+		}()
+	}
+
+	if err := p.recv(response); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		err = ErrCall{ID: id, err: errors.Wrapf(err, "more (id %d): receive", id)}
+		p.mu.Lock()
+		p.netErr = err
+		p.mu.Unlock()
+		return err
+	}
+
+	// Only queries map to multiple responses.
+	if p.strict {
+		if err := checkResponse(RequestQuery, response.mtype); err != nil {
+			id := p.LastCallID()
+			return ErrCall{ID: id, err: errors.Wrapf(err, "more (id %d)", id)}
+		}
+	}
+
+	return nil
+}
+
+// SetStrict enables or disables the strict mode, in which the type of each
+// response is checked against the type of the request it answers, and calls
+// fail with ErrUnexpectedResponse in case of mismatch.
+func (p *Protocol) SetStrict(strict bool) {
+	p.strict = strict
+}
+
+// LastCallID returns the ID assigned to the last call, or 0 if no call was
+// made yet.
+func (p *Protocol) LastCallID() uint64 {
+	return atomic.LoadUint64(&p.lastID)
+}
+
+// LastCallStart returns the time at which the last call was started, or the
+// zero time if no call was made yet. It's not affected by More, so while
+// fetching rows it's the time the query was sent.
+func (p *Protocol) LastCallStart() time.Time {
+	start := atomic.LoadInt64(&p.lastStart)
+	if start == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, start)
 }
 
 // Interrupt sends an interrupt request and awaits for the server's empty
 // response.
-func (p *Protocol) Interrupt(ctx context.Context, request *Message, response *Message) error {
+//
+// The wait is bounded by the ctx deadline and by the interrupt timeout, if
+// any. If the interrupt can't be completed the connection is left in an
+// unknown state, so it's marked as failed and all further calls will fail.
+func (p *Protocol) Interrupt(ctx context.Context, request *Message, response *Message) (err error) {
 	// We need to take a lock since the cowsql server currently does not
 	// support concurrent requests.
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Honor the ctx deadline, if present.
-	if deadline, ok := ctx.Deadline(); ok {
+	if p.netErr != nil {
+		return p.netErr
+	}
+
+	defer func() {
+		if err != nil {
+			p.netErr = err
+		}
+	}()
+
+	// Honor the ctx deadline, if present, as well as the interrupt timeout.
+	deadline, _ := ctx.Deadline()
+	deadline = earliest(deadline, p.interruptTimeout)
+	if !deadline.IsZero() {
 		p.conn.SetDeadline(deadline)
 		defer p.conn.SetDeadline(time.Time{})
 	}
@@ -114,6 +255,27 @@ func (p *Protocol) Interrupt(ctx context.Context, request *Message, response *Me
 	return nil
 }
 
+// Err returns the network error that occurred on the underlying connection,
+// if any. Once a network error has been hit, all further calls will fail.
+func (p *Protocol) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.netErr
+}
+
+// Return the earliest between the given deadline and the given timeout from
+// now. A zero deadline or timeout means none.
+func earliest(deadline time.Time, timeout time.Duration) time.Time {
+	if timeout <= 0 {
+		return deadline
+	}
+	t := time.Now().Add(timeout)
+	if deadline.IsZero() || t.Before(deadline) {
+		return t
+	}
+	return deadline
+}
+
 // Close the client connection.
 func (p *Protocol) Close() error {
 	close(p.closeCh)
@@ -225,7 +387,7 @@ func (p *Protocol) recvFill(buf []byte) (int, error) {
 	for i := messageMaxConsecutiveEmptyReads; i > 0; i-- {
 		n, err := p.conn.Read(buf)
 		if n < 0 {
-			panic(errNegativeRead)
+			return -1, errNegativeRead
 		}
 		if err != nil {
 			return -1, err
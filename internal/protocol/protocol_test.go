@@ -2,11 +2,16 @@ package protocol_test
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"testing"
 	"time"
 
 	"github.com/cowsql/go-cowsql/internal/protocol"
 	"github.com/cowsql/go-cowsql/logging"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -141,6 +146,249 @@ func TestProtocol_Query(t *testing.T) {
 }
 */
 
+// Failed calls are tagged with an ID.
+func TestProtocol_CallID(t *testing.T) {
+	conn, server := net.Pipe()
+	go func() {
+		// Consume the handshake and hang up.
+		io.ReadFull(server, make([]byte, 8))
+		server.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	p, err := protocol.Handshake(ctx, conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	assert.Equal(t, uint64(0), p.LastCallID())
+
+	request, response := newMessagePair(64, 64)
+	protocol.EncodeLeader(&request)
+
+	err = p.Call(ctx, &request, &response)
+	require.Error(t, err)
+
+	id := protocol.CallID(err)
+	assert.NotEqual(t, uint64(0), id)
+	assert.Equal(t, id, p.LastCallID())
+	assert.Contains(t, err.Error(), fmt.Sprintf("(id %d,", id))
+	assert.Equal(t, uint64(0), protocol.CallID(io.EOF))
+}
+
+// The read timeout applies even if the context has no deadline.
+func TestProtocol_ReadTimeout(t *testing.T) {
+	conn, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		// Consume the handshake and the request, but never reply.
+		io.Copy(ioutil.Discard, server)
+	}()
+
+	p, err := protocol.Handshake(context.Background(), conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	p.SetTimeouts(0, 50*time.Millisecond)
+
+	request, response := newMessagePair(64, 64)
+	protocol.EncodeLeader(&request)
+
+	err = p.Call(context.Background(), &request, &response)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "receive")
+
+	cause, ok := errors.Cause(err).(net.Error)
+	require.True(t, ok)
+	assert.True(t, cause.Timeout())
+}
+
+// An interrupt that the server never acknowledges times out and leaves the
+// connection failed.
+func TestProtocol_InterruptTimeout(t *testing.T) {
+	conn, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		// Consume the handshake and the request, but never reply.
+		io.Copy(ioutil.Discard, server)
+	}()
+
+	p, err := protocol.Handshake(context.Background(), conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	p.SetInterruptTimeout(50 * time.Millisecond)
+
+	request, response := newMessagePair(64, 64)
+
+	err = p.Interrupt(context.Background(), &request, &response)
+	require.Error(t, err)
+
+	cause, ok := errors.Cause(err).(net.Error)
+	require.True(t, ok)
+	assert.True(t, cause.Timeout())
+
+	assert.Equal(t, err, p.Err())
+
+	protocol.EncodeLeader(&request)
+	assert.Equal(t, err, p.Call(context.Background(), &request, &response))
+}
+
+// More honors the context cancellation and the rows timeout while waiting
+// for the next batch.
+func TestProtocol_More(t *testing.T) {
+	cases := map[string]func(*protocol.Protocol) (context.Context, context.CancelFunc){
+		"canceled": func(p *protocol.Protocol) (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			return ctx, cancel
+		},
+		"deadline": func(p *protocol.Protocol) (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 50*time.Millisecond)
+		},
+		"rows timeout": func(p *protocol.Protocol) (context.Context, context.CancelFunc) {
+			p.SetTimeouts(0, time.Hour)
+			p.SetRowsTimeout(50 * time.Millisecond)
+			return context.WithCancel(context.Background())
+		},
+	}
+	for name, setup := range cases {
+		t.Run(name, func(t *testing.T) {
+			conn, server := net.Pipe()
+			defer server.Close()
+			go func() {
+				// Consume the handshake, but never send the batch.
+				io.Copy(ioutil.Discard, server)
+			}()
+
+			p, err := protocol.Handshake(context.Background(), conn, protocol.VersionOne)
+			require.NoError(t, err)
+			defer p.Close()
+
+			ctx, cancel := setup(p)
+			defer cancel()
+			_, response := newMessagePair(64, 64)
+
+			done := make(chan error, 1)
+			go func() { done <- p.More(ctx, &response) }()
+
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("More did not return")
+			}
+			require.Error(t, err)
+			assert.Equal(t, err, p.Err())
+		})
+	}
+}
+
+// A context canceled while More is completing doesn't leave a read deadline
+// behind on the connection.
+func TestProtocol_MoreCanceledOnReceive(t *testing.T) {
+	pipe, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		// Consume the handshake, then send the batch.
+		io.ReadFull(server, make([]byte, 8))
+		server.Write([]byte{1, 0, 0, 0, protocol.ResponseRows, 0, 0, 0})
+		server.Write(make([]byte, 8))
+	}()
+
+	conn := &deadlineConn{Conn: pipe, set: make(chan struct{}, 1)}
+
+	p, err := protocol.Handshake(context.Background(), conn, protocol.VersionOne)
+	require.NoError(t, err)
+	defer p.Close()
+
+	// Cancel the context as soon as the batch starts being received, and
+	// let the cancel goroutine set its read deadline.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn.onRead = func() {
+		cancel()
+		<-conn.set
+	}
+
+	_, response := newMessagePair(64, 64)
+	require.NoError(t, p.More(ctx, &response))
+	assert.True(t, conn.deadline.IsZero())
+}
+
+// Record the read deadline set on a connection, without enforcing it.
+type deadlineConn struct {
+	net.Conn
+	onRead   func()        // Called after the first read, if set
+	deadline time.Time     // Last read deadline set
+	set      chan struct{} // Notified when a non-zero deadline is set
+}
+
+func (c *deadlineConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if c.onRead != nil {
+		onRead := c.onRead
+		c.onRead = nil
+		onRead()
+	}
+	return n, err
+}
+
+func (c *deadlineConn) SetReadDeadline(t time.Time) error {
+	c.deadline = t
+	if !t.IsZero() {
+		select {
+		case c.set <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// In strict mode, responses of the wrong type are detected.
+func TestProtocol_Strict(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		t.Run(fmt.Sprintf("%v", strict), func(t *testing.T) {
+			conn, server := net.Pipe()
+			defer server.Close()
+			go func() {
+				// Consume the handshake and the leader request,
+				// then reply with an empty response.
+				io.ReadFull(server, make([]byte, 8+16))
+				server.Write([]byte{1, 0, 0, 0, protocol.ResponseEmpty, 0, 0, 0})
+				server.Write(make([]byte, 8))
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+			defer cancel()
+
+			p, err := protocol.Handshake(ctx, conn, protocol.VersionOne)
+			require.NoError(t, err)
+			defer p.Close()
+
+			p.SetStrict(strict)
+
+			request, response := newMessagePair(64, 64)
+			protocol.EncodeLeader(&request)
+
+			err = p.Call(ctx, &request, &response)
+			if !strict {
+				require.NoError(t, err)
+				_, _, err = protocol.DecodeNode(&response)
+				assert.EqualError(t, err, "decode node: unexpected type 8")
+				return
+			}
+
+			require.Error(t, err)
+			cause, ok := errors.Cause(err).(protocol.ErrUnexpectedResponse)
+			require.True(t, ok)
+			assert.Equal(t, uint8(protocol.ResponseEmpty), cause.Response)
+			assert.Equal(t, uint8(protocol.ResponseNode), cause.Expected)
+			assert.Contains(t, err.Error(), "unexpected empty response (type 8) to leader request, expected node (type 1)")
+		})
+	}
+}
+
 func newProtocol(t *testing.T) (*protocol.Protocol, func()) {
 	t.Helper()
 
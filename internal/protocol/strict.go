@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"fmt"
+)
+
+// Type of the response expected for each type of request, when successful.
+var expectedResponses = map[uint8]uint8{
+	RequestLeader:    ResponseNode,
+	RequestClient:    ResponseWelcome,
+	RequestHeartbeat: ResponseNodes,
+	RequestOpen:      ResponseDb,
+	RequestPrepare:   ResponseStmt,
+	RequestExec:      ResponseResult,
+	RequestQuery:     ResponseRows,
+	RequestFinalize:  ResponseEmpty,
+	RequestExecSQL:   ResponseResult,
+	RequestQuerySQL:  ResponseRows,
+	RequestInterrupt: ResponseEmpty,
+	RequestAdd:       ResponseEmpty,
+	RequestAssign:    ResponseEmpty,
+	RequestRemove:    ResponseEmpty,
+	RequestDump:      ResponseFiles,
+	RequestCluster:   ResponseNodes,
+	RequestTransfer:  ResponseEmpty,
+	RequestDescribe:  ResponseMetadata,
+	RequestWeight:    ResponseEmpty,
+}
+
+// ErrUnexpectedResponse is returned in strict mode when the type of a
+// response doesn't match the type of the request it answers, which usually
+// means that the client and the server disagree about the wire protocol.
+type ErrUnexpectedResponse struct {
+	Request  uint8 // Type of the request
+	Response uint8 // Type of the response received
+	Expected uint8 // Type of the response expected
+}
+
+func (e ErrUnexpectedResponse) Error() string {
+	return fmt.Sprintf(
+		"unexpected %s response (type %d) to %s request, expected %s (type %d)",
+		responseDesc(e.Response), e.Response, requestDesc(e.Request), responseDesc(e.Expected), e.Expected)
+}
+
+// Check that the given response type is valid for the given request type.
+// Failures are valid responses to any request.
+func checkResponse(request, response uint8) error {
+	if response == ResponseFailure {
+		return nil
+	}
+	expected, ok := expectedResponses[request]
+	if !ok || response == expected {
+		return nil
+	}
+	return ErrUnexpectedResponse{Request: request, Response: response, Expected: expected}
+}
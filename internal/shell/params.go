@@ -0,0 +1,246 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (s *Shell) processParam(line string) (string, error) {
+	usage := fmt.Errorf("bad command format, should be: .param set <name> <value>, .param unset <name>, .param list or .param clear")
+
+	rest := strings.TrimSpace(line)
+	_, rest = nextField(rest) // .param
+	command, rest := nextField(rest)
+
+	switch strings.ToLower(command) {
+	case "set":
+		name, value := nextField(rest)
+		if name == "" || value == "" {
+			return "", usage
+		}
+		if err := checkParamName(name); err != nil {
+			return "", err
+		}
+		v, err := parseParamValue(value)
+		if err != nil {
+			return "", err
+		}
+		s.params[name] = v
+	case "unset":
+		name, extra := nextField(rest)
+		if name == "" || extra != "" {
+			return "", usage
+		}
+		delete(s.params, name)
+	case "clear":
+		if rest != "" {
+			return "", usage
+		}
+		s.params = map[string]interface{}{}
+	case "list":
+		if rest != "" {
+			return "", usage
+		}
+		return s.listParams()
+	default:
+		return "", usage
+	}
+
+	return "", nil
+}
+
+func (s *Shell) listParams() (string, error) {
+	names := make([]string, 0, len(s.params))
+	for name := range s.params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := ""
+	switch s.format {
+	case formatTabular:
+		for i, name := range names {
+			if i > 0 {
+				result += "\n"
+			}
+			result += fmt.Sprintf("%s|%s", name, formatParamValue(s.params[name]))
+		}
+	case formatJson:
+		data, err := json.Marshal(s.params)
+		if err != nil {
+			return "", err
+		}
+		var indented bytes.Buffer
+		json.Indent(&indented, data, "", "\t")
+		result = string(indented.Bytes())
+	}
+
+	return result, nil
+}
+
+// Return the values to bind to the parameters of the given SQL text.
+//
+// Since the wire protocol only supports binding by position, the parameters
+// are numbered the same way SQLite does: "?NNN" has index NNN, while "?" and
+// the first occurrence of a named parameter get the largest index assigned so
+// far plus one. Parameters that were never set are bound to NULL.
+func (s *Shell) bindParams(sql string) []interface{} {
+	names := paramNames(sql)
+	if len(names) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		if name == "" {
+			name = fmt.Sprintf("?%d", i+1)
+		}
+		args[i] = s.params[name]
+	}
+	return args
+}
+
+// Return the names of the parameters in the given SQL text, ordered by their
+// index. Anonymous parameters and index gaps have an empty name.
+func paramNames(sql string) []string {
+	names := []string{}
+	indexes := map[string]int{}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := strings.IndexByte(sql[i+1:], closing)
+			if j == -1 {
+				return names
+			}
+			i += j + 2
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			j := strings.IndexByte(sql[i:], '\n')
+			if j == -1 {
+				return names
+			}
+			i += j + 1
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			j := strings.Index(sql[i+2:], "*/")
+			if j == -1 {
+				return names
+			}
+			i += j + 4
+		case c == '?':
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				names = append(names, "")
+			} else if index, err := strconv.Atoi(sql[i+1 : j]); err == nil && index > 0 {
+				name := "?" + strconv.Itoa(index)
+				for len(names) < index {
+					names = append(names, "")
+				}
+				names[index-1] = name
+				indexes[name] = index
+			}
+			i = j
+		case c == ':' || c == '@' || c == '$':
+			j := i + 1
+			for j < len(sql) && isParamNameByte(sql[j]) {
+				j++
+			}
+			if j > i+1 {
+				name := sql[i:j]
+				if _, ok := indexes[name]; !ok {
+					names = append(names, name)
+					indexes[name] = len(names)
+				}
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+
+	return names
+}
+
+func isParamNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func checkParamName(name string) error {
+	switch name[0] {
+	case '?':
+		if index, err := strconv.Atoi(name[1:]); err != nil || index <= 0 {
+			return fmt.Errorf("bad parameter name %q", name)
+		}
+	case ':', '@', '$':
+		if len(name) == 1 {
+			return fmt.Errorf("bad parameter name %q", name)
+		}
+		for i := 1; i < len(name); i++ {
+			if !isParamNameByte(name[i]) {
+				return fmt.Errorf("bad parameter name %q", name)
+			}
+		}
+	default:
+		return fmt.Errorf("bad parameter name %q, should start with one of ?:@$", name)
+	}
+	return nil
+}
+
+// Parse a parameter value: NULL, an integer, a real number, a 'quoted' text,
+// a X'hex' blob, or otherwise a bare text.
+func parseParamValue(value string) (interface{}, error) {
+	if strings.EqualFold(value, "NULL") {
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f, nil
+	}
+	if len(value) >= 3 && (value[0] == 'x' || value[0] == 'X') && value[1] == '\'' && value[len(value)-1] == '\'' {
+		blob, err := hex.DecodeString(value[2 : len(value)-1])
+		if err != nil {
+			return nil, fmt.Errorf("bad blob value %s", value)
+		}
+		return blob, nil
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return strings.Replace(value[1:len(value)-1], "''", "'", -1), nil
+	}
+	return value, nil
+}
+
+func formatParamValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	case []byte:
+		return "X'" + strings.ToUpper(hex.EncodeToString(v)) + "'"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// Split the first whitespace-separated field from the given text.
+func nextField(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	i := strings.IndexAny(text, " \t")
+	if i == -1 {
+		return text, ""
+	}
+	return text[:i], strings.TrimSpace(text[i:])
+}
@@ -0,0 +1,110 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamNames(t *testing.T) {
+	cases := []struct {
+		title string
+		sql   string
+		names []string
+	}{
+		{"none", "SELECT 1", []string{}},
+		{"anonymous", "SELECT ?, ?", []string{"", ""}},
+		{"numbered", "SELECT ?2, ?1", []string{"?1", "?2"}},
+		{"numbered gap", "SELECT ?3", []string{"", "", "?3"}},
+		{"anonymous after numbered", "SELECT ?2, ?", []string{"", "?2", ""}},
+		{"named", "SELECT :a, @b, $c", []string{":a", "@b", "$c"}},
+		{"named repeated", "SELECT :a, :b, :a", []string{":a", ":b"}},
+		{"named and anonymous", "SELECT :a, ?", []string{":a", ""}},
+		{"single quoted", "SELECT '?', ':a', ?", []string{""}},
+		{"double quoted", `SELECT "?:a" FROM t WHERE n = :n`, []string{":n"}},
+		{"backquoted", "SELECT `:a` FROM t WHERE n = ?", []string{""}},
+		{"bracketed", "SELECT [?] FROM t WHERE n = ?", []string{""}},
+		{"unterminated quote", "SELECT ?, ':a", []string{""}},
+		{"line comment", "SELECT ? -- :a ?\n, :b", []string{"", ":b"}},
+		{"block comment", "SELECT /* ? :a */ :b", []string{":b"}},
+		{"colon without name", "SELECT ':' || :", []string{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			assert.Equal(t, c.names, paramNames(c.sql))
+		})
+	}
+}
+
+func TestBindParams(t *testing.T) {
+	s := &Shell{params: map[string]interface{}{
+		"?1": int64(1),
+		"?2": "two",
+		":a": []byte{0xab},
+	}}
+
+	cases := []struct {
+		title string
+		sql   string
+		args  []interface{}
+	}{
+		{"none", "SELECT 1", nil},
+		{"anonymous", "SELECT ?, ?", []interface{}{int64(1), "two"}},
+		{"numbered", "SELECT ?2", []interface{}{int64(1), "two"}},
+		{"named", "SELECT :a, :a", []interface{}{[]byte{0xab}}},
+		{"unset", "SELECT :a, :b, ?", []interface{}{[]byte{0xab}, nil, nil}},
+		{"quoted", "SELECT '?', :a", []interface{}{[]byte{0xab}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			assert.Equal(t, c.args, s.bindParams(c.sql))
+		})
+	}
+}
+
+func TestParseParamValue(t *testing.T) {
+	cases := []struct {
+		value  string
+		parsed interface{}
+	}{
+		{"NULL", nil},
+		{"null", nil},
+		{"123", int64(123)},
+		{"-7", int64(-7)},
+		{"1.5", 1.5},
+		{"'hello'", "hello"},
+		{"'it''s'", "it's"},
+		{"''", ""},
+		{"hello", "hello"},
+		{"X'0aFF'", []byte{0x0a, 0xff}},
+		{"x'00'", []byte{0x00}},
+		{"X''", []byte{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			parsed, err := parseParamValue(c.value)
+			assert.NoError(t, err)
+			assert.Equal(t, c.parsed, parsed)
+		})
+	}
+}
+
+func TestParseParamValue_Error(t *testing.T) {
+	cases := []struct {
+		value string
+		err   string
+	}{
+		{"X'0'", "bad blob value X'0'"},
+		{"X'zz'", "bad blob value X'zz'"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			_, err := parseParamValue(c.value)
+			assert.EqualError(t, err, c.err)
+		})
+	}
+}
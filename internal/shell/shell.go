@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -24,6 +25,7 @@ type Shell struct {
 	dial   client.DialFunc
 	db     *sql.DB
 	format string
+	params map[string]interface{} // Values set with .param
 }
 
 // New creates a new Shell connected to the given database.
@@ -57,6 +59,7 @@ func New(database string, store client.NodeStore, options ...Option) (*Shell, er
 		dial:   o.Dial,
 		db:     db,
 		format: o.Format,
+		params: map[string]interface{}{},
 	}
 
 	return shell, nil
@@ -75,6 +78,12 @@ func (s *Shell) Process(ctx context.Context, line string) (string, error) {
 	if strings.HasPrefix(strings.ToLower(strings.TrimLeft(line, " ")), ".remove") {
 		return s.processRemove(ctx, line)
 	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimLeft(line, " ")), ".impact") {
+		return s.processImpact(ctx, line)
+	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimLeft(line, " ")), ".assign") {
+		return s.processAssign(ctx, line)
+	}
 	if strings.HasPrefix(strings.ToLower(strings.TrimLeft(line, " ")), ".describe") {
 		return s.processDescribe(ctx, line)
 	}
@@ -87,6 +96,9 @@ func (s *Shell) Process(ctx context.Context, line string) (string, error) {
 	if strings.HasPrefix(strings.ToLower(strings.TrimLeft(line, " ")), ".reconfigure") {
 		return s.processReconfigure(ctx, line)
 	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimLeft(line, " ")), ".param") {
+		return s.processParam(line)
+	}
 	return s.processQuery(ctx, line)
 }
 
@@ -97,16 +109,62 @@ Enter a SQL statement to execute it, or one of the following built-in commands:
 
   .cluster                          Show the cluster membership
   .leader                           Show the current leader
-  .remove <address>                 Remove a node from the cluster
-  .describe <address>               Show the details of a node
+  .remove <id|address> [force]      Remove a node from the cluster, unless it would lose quorum
+  .impact <id|address>              Show the impact of removing a node from the cluster
+  .assign <id|address> <role>       Assign a role (voter, stand-by or spare) to a node
+  .describe <id|address>            Show the details of a node
   .weight <address> <weight>        Set the weight of a node
-  .dump <address> [<database>]      Dump the database
+  .dump <database> [<dir>]          Dump the database from the leader into a directory
+  .dump <address> [<database>]      Dump the database from the given node
   .reconfigure <dir> <clusteryaml>  Reconfigure the cluster
+  .param set <name> <value>         Set the value bound to a SQL parameter
+  .param unset <name>               Unset a SQL parameter
+  .param list                       Show the values of all SQL parameters
+  .param clear                      Unset all SQL parameters
+
+Node IDs are in hexadecimal, as shown by .cluster. Node addresses are host:port
+pairs, or abstract unix sockets starting with @.
+
+Parameter names are like in SQL statements, e.g. ?1, :name, @name or $name.
+Values can be NULL, integers, reals, 'quoted text', X'hex' blobs or bare text.
+Parameters that were not set are bound to NULL.
 `[1:]
 }
 
+// Find the node of the cluster with the given ID or address. IDs are parsed
+// as hexadecimal numbers, matching the output of .cluster.
+func findNode(cluster []client.NodeInfo, node string) (client.NodeInfo, error) {
+	id, err := strconv.ParseUint(node, 16, 64)
+	for _, info := range cluster {
+		if info.Address == node || (err == nil && info.ID == id) {
+			return info, nil
+		}
+	}
+	return client.NodeInfo{}, fmt.Errorf("no node has ID or address %q", node)
+}
+
+// Return true if the given command argument is a node address rather than a
+// node ID or a database name: either a host:port pair or an abstract unix
+// socket such as "@1".
+func isAddress(arg string) bool {
+	return strings.HasPrefix(arg, "@") || strings.Contains(arg, ":")
+}
+
+// Parse a node role name, as shown by .cluster.
+func parseRole(name string) (client.NodeRole, error) {
+	for _, role := range []client.NodeRole{client.Voter, client.StandBy, client.Spare} {
+		if strings.EqualFold(name, role.String()) {
+			return role, nil
+		}
+	}
+	if strings.EqualFold(name, "standby") {
+		return client.StandBy, nil
+	}
+	return 0, fmt.Errorf("bad role %q, should be one of: voter, stand-by, spare", name)
+}
+
 func (s *Shell) processCluster(ctx context.Context, line string) (string, error) {
-	cli, err := client.FindLeader(ctx, s.store, client.WithDialFunc(s.dial))
+	cli, err := client.FindLeader(ctx, s.store, client.WithDialFunc(s.dial), client.WithClusterOrder(client.OrderByID))
 	if err != nil {
 		return "", err
 	}
@@ -152,51 +210,149 @@ func (s *Shell) processLeader(ctx context.Context, line string) (string, error)
 }
 
 func (s *Shell) processRemove(ctx context.Context, line string) (string, error) {
-	parts := strings.Split(line, " ")
+	parts := strings.Fields(line)
+	if len(parts) != 2 && (len(parts) != 3 || parts[2] != "force") {
+		return "", fmt.Errorf("bad command format, should be: .remove <id|address> [force]")
+	}
+	cli, err := client.FindLeader(ctx, s.store, client.WithDialFunc(s.dial))
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+	cluster, err := cli.Cluster(ctx)
+	if err != nil {
+		return "", err
+	}
+	node, err := findNode(cluster, parts[1])
+	if err != nil {
+		return "", err
+	}
+	if len(parts) == 3 {
+		err = cli.Remove(ctx, node.ID)
+	} else {
+		err = cli.RemoveGraceful(ctx, node.ID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("remove node %q: %w", parts[1], err)
+	}
+
+	return "", nil
+}
+
+func (s *Shell) processImpact(ctx context.Context, line string) (string, error) {
+	parts := strings.Fields(line)
 	if len(parts) != 2 {
-		return "", fmt.Errorf("bad command format, should be: .remove <address>")
+		return "", fmt.Errorf("bad command format, should be: .impact <id|address>")
 	}
-	address := parts[1]
 	cli, err := client.FindLeader(ctx, s.store, client.WithDialFunc(s.dial))
 	if err != nil {
 		return "", err
 	}
+	defer cli.Close()
 	cluster, err := cli.Cluster(ctx)
 	if err != nil {
 		return "", err
 	}
-	for _, node := range cluster {
-		if node.Address != address {
-			continue
-		}
-		if err := cli.Remove(ctx, node.ID); err != nil {
-			return "", fmt.Errorf("remove node %q: %w", address, err)
+	node, err := findNode(cluster, parts[1])
+	if err != nil {
+		return "", err
+	}
+	impact, err := cli.RemovalImpact(ctx, node.ID)
+	if err != nil {
+		return "", err
+	}
+
+	result := ""
+	switch s.format {
+	case formatTabular:
+		result = impact.String()
+	case formatJson:
+		data, err := json.Marshal(impact)
+		if err != nil {
+			return "", err
 		}
-		return "", nil
+		var indented bytes.Buffer
+		json.Indent(&indented, data, "", "\t")
+		result = string(indented.Bytes())
+	}
+
+	return result, nil
+}
+
+func (s *Shell) processAssign(ctx context.Context, line string) (string, error) {
+	parts := strings.Fields(line)
+	if len(parts) != 3 {
+		return "", fmt.Errorf("bad command format, should be: .assign <id|address> <role>")
+	}
+	role, err := parseRole(parts[2])
+	if err != nil {
+		return "", err
+	}
+	cli, err := client.FindLeader(ctx, s.store, client.WithDialFunc(s.dial))
+	if err != nil {
+		return "", err
+	}
+	defer cli.Close()
+	cluster, err := cli.Cluster(ctx)
+	if err != nil {
+		return "", err
+	}
+	node, err := findNode(cluster, parts[1])
+	if err != nil {
+		return "", err
+	}
+	if err := cli.Assign(ctx, node.ID, role); err != nil {
+		return "", fmt.Errorf("assign role %s to node %q: %w", role, parts[1], err)
 	}
 
-	return "", fmt.Errorf("no node has address %q", address)
+	return "", nil
 }
 
 func (s *Shell) processDescribe(ctx context.Context, line string) (string, error) {
-	parts := strings.Split(line, " ")
+	parts := strings.Fields(line)
 	if len(parts) != 2 {
-		return "", fmt.Errorf("bad command format, should be: .describe <address>")
+		return "", fmt.Errorf("bad command format, should be: .describe <id|address>")
 	}
 	address := parts[1]
+	if !isAddress(address) {
+		leader, err := client.FindLeader(ctx, s.store, client.WithDialFunc(s.dial))
+		if err != nil {
+			return "", err
+		}
+		cluster, err := leader.Cluster(ctx)
+		leader.Close()
+		if err != nil {
+			return "", err
+		}
+		node, err := findNode(cluster, address)
+		if err != nil {
+			return "", err
+		}
+		address = node.Address
+	}
 	cli, err := client.New(ctx, address, client.WithDialFunc(s.dial))
 	if err != nil {
 		return "", err
 	}
+	defer cli.Close()
 	metadata, err := cli.Describe(ctx)
 	if err != nil {
 		return "", err
 	}
+	metadata.Labels = s.nodeLabels(ctx, address)
 
 	result := ""
 	switch s.format {
 	case formatTabular:
 		result += fmt.Sprintf("%s|%d|%d", address, metadata.FailureDomain, metadata.Weight)
+		if len(metadata.Labels) > 0 {
+			labels := make([]string, 0, len(metadata.Labels))
+			for key, value := range metadata.Labels {
+				labels = append(labels, key+"="+value)
+			}
+			sort.Strings(labels)
+			result += "|" + strings.Join(labels, ",")
+		}
 	case formatJson:
 		data, err := json.Marshal(metadata)
 		if err != nil {
@@ -210,29 +366,71 @@ func (s *Shell) processDescribe(ctx context.Context, line string) (string, error
 	return result, nil
 }
 
+// Return the labels of the node with the given address, as published in the
+// configuration registry of the leader, or nil if they can't be found.
+func (s *Shell) nodeLabels(ctx context.Context, address string) map[string]string {
+	leader, err := client.FindLeader(ctx, s.store, client.WithDialFunc(s.dial))
+	if err != nil {
+		return nil
+	}
+	defer leader.Close()
+	cluster, err := leader.Cluster(ctx)
+	if err != nil {
+		return nil
+	}
+	node, err := findNode(cluster, address)
+	if err != nil {
+		return nil
+	}
+	labels, err := leader.Labels(ctx)
+	if err != nil {
+		return nil
+	}
+	return labels[node.ID]
+}
+
 func (s *Shell) processDump(ctx context.Context, line string) (string, error) {
-	parts := strings.Split(line, " ")
+	parts := strings.Fields(line)
 	if len(parts) < 2 || len(parts) > 3 {
-		return "NOK", fmt.Errorf("bad command format, should be: .dump <address> [<database>]")
+		return "NOK", fmt.Errorf("bad command format, should be: .dump <database> [<dir>] or .dump <address> [<database>]")
+	}
+
+	var cli *client.Client
+	var err error
+	database := "db.bin"
+	dir := ""
+
+	// For backward compatibility, a first argument that looks like a
+	// node address selects the node to dump from.
+	if isAddress(parts[1]) {
+		cli, err = client.New(ctx, parts[1], client.WithDialFunc(s.dial))
+		if len(parts) == 3 {
+			database = parts[2]
+		}
+	} else {
+		cli, err = client.FindLeader(ctx, s.store, client.WithDialFunc(s.dial))
+		database = parts[1]
+		if len(parts) == 3 {
+			dir = parts[2]
+		}
 	}
-	address := parts[1]
-	cli, err := client.New(ctx, address, client.WithDialFunc(s.dial))
 	if err != nil {
 		return "NOK", fmt.Errorf("dial failed")
 	}
+	defer cli.Close()
 
-	database := "db.bin"
-	if len(parts) == 3 {
-		database = parts[2]
-	}
 	files, err := cli.Dump(ctx, database)
 	if err != nil {
 		return "NOK", fmt.Errorf("dump failed")
 	}
 
-	dir, err := os.Getwd()
-	if err != nil {
-		return "NOK", fmt.Errorf("os.Getwd() failed")
+	if dir == "" {
+		dir, err = os.Getwd()
+		if err != nil {
+			return "NOK", fmt.Errorf("os.Getwd() failed")
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return "NOK", fmt.Errorf("create directory %s failed", dir)
 	}
 
 	for _, file := range files {
@@ -319,7 +517,7 @@ func (s *Shell) processQuery(ctx context.Context, line string) (string, error) {
 		return "", fmt.Errorf("begin transaction: %w", err)
 	}
 
-	rows, err := tx.Query(line)
+	rows, err := tx.Query(line, s.bindParams(line)...)
 	if err != nil {
 		err = fmt.Errorf("query: %w", err)
 		if rbErr := tx.Rollback(); rbErr != nil {
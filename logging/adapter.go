@@ -0,0 +1,70 @@
+package logging
+
+// LeveledLogger is implemented by loggers with a printf-style method per
+// level, like *logrus.Logger, *logrus.Entry and *zap.SugaredLogger.
+type LeveledLogger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// FromLeveled returns a logging function that forwards each message to the
+// method of the given logger matching its level.
+//
+// Messages with an unknown level are logged as errors, so they are not lost.
+func FromLeveled(logger LeveledLogger) Func {
+	return FromFuncs(logger.Debugf, logger.Infof, logger.Warnf, logger.Errorf)
+}
+
+// FromFuncs returns a logging function that forwards each message to the
+// given function matching its level. It's meant for loggers that don't
+// implement LeveledLogger, like zerolog:
+//
+//	logging.FromFuncs(
+//		func(format string, a ...interface{}) { log.Debug().Msgf(format, a...) },
+//		func(format string, a ...interface{}) { log.Info().Msgf(format, a...) },
+//		func(format string, a ...interface{}) { log.Warn().Msgf(format, a...) },
+//		func(format string, a ...interface{}) { log.Error().Msgf(format, a...) },
+//	)
+//
+// Messages with an unknown level are logged as errors, so they are not lost.
+func FromFuncs(debug, info, warn, err func(string, ...interface{})) Func {
+	return func(l Level, format string, a ...interface{}) {
+		switch l {
+		case None:
+		case Debug:
+			debug(format, a...)
+		case Info:
+			info(format, a...)
+		case Warn:
+			warn(format, a...)
+		default:
+			err(format, a...)
+		}
+	}
+}
+
+// ToLeveled returns a LeveledLogger forwarding its messages to the given
+// logging function, for libraries that expect one.
+func ToLeveled(f Func) LeveledLogger {
+	return leveled(f)
+}
+
+type leveled Func
+
+func (f leveled) Debugf(format string, args ...interface{}) { f(Debug, format, args...) }
+func (f leveled) Infof(format string, args ...interface{})  { f(Info, format, args...) }
+func (f leveled) Warnf(format string, args ...interface{})  { f(Warn, format, args...) }
+func (f leveled) Errorf(format string, args ...interface{}) { f(Error, format, args...) }
+
+// MinLevel returns a logging function that forwards to the given one only
+// the messages at the given level or above.
+func MinLevel(f Func, level Level) Func {
+	return func(l Level, format string, a ...interface{}) {
+		if l < level {
+			return
+		}
+		f(l, format, a...)
+	}
+}
@@ -0,0 +1,64 @@
+package logging_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cowsql/go-cowsql/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	messages []string
+}
+
+func (r *recorder) record(prefix string) func(string, ...interface{}) {
+	return func(format string, args ...interface{}) {
+		r.messages = append(r.messages, prefix+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *recorder) Debugf(format string, args ...interface{}) { r.record("debug")(format, args...) }
+func (r *recorder) Infof(format string, args ...interface{})  { r.record("info")(format, args...) }
+func (r *recorder) Warnf(format string, args ...interface{})  { r.record("warn")(format, args...) }
+func (r *recorder) Errorf(format string, args ...interface{}) { r.record("error")(format, args...) }
+
+func (r *recorder) Func() logging.Func {
+	return func(l logging.Level, format string, a ...interface{}) {
+		r.messages = append(r.messages, l.String()+": "+fmt.Sprintf(format, a...))
+	}
+}
+
+func TestFromLeveled(t *testing.T) {
+	r := &recorder{}
+	f := logging.FromLeveled(r)
+
+	f(logging.None, "dropped")
+	f(logging.Debug, "n=%d", 1)
+	f(logging.Info, "n=%d", 2)
+	f(logging.Warn, "n=%d", 3)
+	f(logging.Error, "n=%d", 4)
+	f(logging.Level(666), "n=%d", 5)
+
+	assert.Equal(t, []string{"debug: n=1", "info: n=2", "warn: n=3", "error: n=4", "error: n=5"}, r.messages)
+}
+
+func TestToLeveled(t *testing.T) {
+	r := &recorder{}
+	logger := logging.ToLeveled(r.Func())
+
+	logger.Debugf("n=%d", 1)
+	logger.Errorf("n=%d", 2)
+
+	assert.Equal(t, []string{"DEBUG: n=1", "ERROR: n=2"}, r.messages)
+}
+
+func TestMinLevel(t *testing.T) {
+	r := &recorder{}
+	f := logging.MinLevel(r.Func(), logging.Warn)
+
+	f(logging.Info, "info")
+	f(logging.Warn, "warn")
+
+	assert.Equal(t, []string{"WARN: warn"}, r.messages)
+}
@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"sync"
+	"time"
+)
+
+// Sample returns a logging function that limits the number of debug messages
+// forwarded to the given one, for example to keep statement tracing from
+// flooding the logs.
+//
+// Messages are grouped by format string. In each interval of the given
+// duration, the first messages of a group are forwarded, then only one every
+// thereafter messages. If thereafter is zero, the rest of the group is dropped
+// until the next interval. Messages at other levels are always forwarded.
+func Sample(f Func, interval time.Duration, first, thereafter int) Func {
+	s := &sampler{
+		interval:   interval,
+		first:      first,
+		thereafter: thereafter,
+		counts:     map[string]int{},
+	}
+	return func(l Level, format string, a ...interface{}) {
+		if l == Debug && !s.allow(format, time.Now()) {
+			return
+		}
+		f(l, format, a...)
+	}
+}
+
+type sampler struct {
+	mu         sync.Mutex
+	interval   time.Duration
+	first      int
+	thereafter int
+	start      time.Time      // Start of the current interval
+	counts     map[string]int // Messages seen in the current interval
+}
+
+// Return true if a message with the given format seen at the given time
+// should be forwarded.
+func (s *sampler) allow(format string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.start) >= s.interval {
+		s.start = now
+		s.counts = map[string]int{}
+	}
+
+	s.counts[format]++
+	n := s.counts[format]
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
@@ -0,0 +1,29 @@
+package logging_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cowsql/go-cowsql/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	r := &recorder{}
+	f := logging.Sample(r.Func(), time.Hour, 2, 3)
+
+	for i := 1; i <= 8; i++ {
+		f(logging.Debug, "query %d", i)
+		f(logging.Info, "info %d", i)
+	}
+	f(logging.Debug, "other")
+
+	debug := []string{}
+	for _, message := range r.messages {
+		if message[0] == 'D' {
+			debug = append(debug, message)
+		}
+	}
+	assert.Equal(t, []string{"DEBUG: query 1", "DEBUG: query 2", "DEBUG: query 5", "DEBUG: query 8", "DEBUG: other"}, debug)
+	assert.Len(t, r.messages, 13)
+}
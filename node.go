@@ -2,6 +2,8 @@ package cowsql
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"time"
 
 	"github.com/cowsql/go-cowsql/client"
@@ -20,6 +22,15 @@ type Node struct {
 	cancel      context.CancelFunc
 }
 
+// ErrNoEngine is returned when creating or recovering a node in a program
+// built without cgo, where the cowsql engine is not available.
+//
+// The client, driver and protocol packages are pure Go, so programs that only
+// connect to existing nodes can still be built with CGO_ENABLED=0, for
+// example to cross-compile them. Such programs can import this package and
+// the app package, but can't run nodes.
+var ErrNoEngine = bindings.ErrNoEngine
+
 // NodeInfo is a convenience alias for client.NodeInfo.
 type NodeInfo = client.NodeInfo
 
@@ -197,6 +208,58 @@ func GenerateID(address string) uint64 {
 	return bindings.GenerateID(address)
 }
 
+// EngineVersion returns the version of the libcowsql engine this process is
+// linked against, formatted as major.minor.patch.
+func EngineVersion() string {
+	return bindings.EngineVersion()
+}
+
+// GenerateIDFromName derives a stable ID from the given name, for example a
+// hostname or an identifier chosen by the operator.
+//
+// The same name always yields the same ID, so it does not need to be stored
+// and can be derived again, unlike the one returned by GenerateID. Names must
+// be unique within a cluster: use client.CheckID to detect collisions before
+// joining. The returned ID is never 0, 1 or BootstrapID.
+func GenerateIDFromName(name string) uint64 {
+	sum := sha256.Sum256([]byte(name))
+	for {
+		id := binary.BigEndian.Uint64(sum[:8])
+		if id > 1 && id != BootstrapID {
+			return id
+		}
+		sum = sha256.Sum256(sum[:])
+	}
+}
+
+// Maximum number of IDs generated by AllocateID before giving up.
+const allocateIDAttempts = 10
+
+// AllocateID generates an ID for a new node with the given address, which is
+// not used by any member of the cluster the given client is connected to.
+//
+// The address alone does not make the ID returned by GenerateID unique, since
+// addresses can be reused over time, so IDs are generated until one that does
+// not collide with the current members is found.
+func AllocateID(ctx context.Context, cli *client.Client, address string) (uint64, error) {
+	nodes, err := cli.Cluster(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "get cluster members")
+	}
+
+	for i := 0; i < allocateIDAttempts; i++ {
+		id := GenerateID(address)
+		if id <= 1 || id == BootstrapID {
+			continue
+		}
+		if client.CheckID(nodes, id, "") == nil {
+			return id, nil
+		}
+	}
+
+	return 0, errors.Wrapf(client.ErrIDCollision, "no unused ID found after %d attempts", allocateIDAttempts)
+}
+
 // ReconfigureMembership can be used to recover a cluster whose majority of
 // nodes have died, and therefore has become unavailable.
 //
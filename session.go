@@ -0,0 +1,185 @@
+package cowsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Session provides read-your-writes consistency on top of a pool of cowsql
+// connections.
+//
+// The database/sql package may route a read to a different pooled connection
+// than the one used by the preceding write. While the leader is changing,
+// that connection might still be attached to a former leader that has not
+// yet seen the write, and return stale data. A Session avoids this by
+// pinning a single connection as soon as a write is performed through it,
+// and routing all further statements to that connection. Reads performed
+// before any write go through the pool.
+//
+// If the pinned connection is lost, for example because the leader changed,
+// the next statement pins a new one: since it's only opened once the new
+// leader is known, and the leader only serves reads once it has caught up
+// with all committed entries, the guarantee still holds.
+//
+// A Session is meant to be used by a single logical flow, for example the
+// handling of one request, and must be closed to release its connection.
+type Session struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	conn  *sql.Conn // Pinned connection, if any
+	wrote bool      // Whether a write was performed
+}
+
+// NewSession creates a new session issuing statements against the given
+// database, which must have been opened with the cowsql driver.
+func NewSession(db *sql.DB) *Session {
+	return &Session{db: db}
+}
+
+// ExecContext executes a statement on the pinned connection, pinning one if
+// needed.
+func (s *Session) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	conn, err := s.pin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := conn.ExecContext(ctx, query, args...)
+	s.check(conn, err)
+	return result, err
+}
+
+// QueryContext executes a query, on the pinned connection if a write was
+// performed through the session.
+func (s *Session) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	conn, err := s.pinned(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return s.db.QueryContext(ctx, query, args...)
+	}
+	rows, err := conn.QueryContext(ctx, query, args...)
+	s.check(conn, err)
+	return rows, err
+}
+
+// QueryRowContext executes a query that is expected to return at most one
+// row, on the pinned connection if a write was performed through the
+// session.
+//
+// The query goes through QueryContext, so a lost pinned connection is
+// dropped like for any other statement. Errors are deferred until the
+// returned row is scanned.
+func (s *Session) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	rows, err := s.QueryContext(ctx, query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+// BeginTx starts a transaction on the pinned connection, pinning one if
+// needed, since the transaction might perform writes.
+func (s *Session) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	conn, err := s.pin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	s.check(conn, err)
+	return tx, err
+}
+
+// Close releases the pinned connection, if any, returning it to the pool.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wrote = false
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// Row is the result of Session.QueryRowContext, and behaves like sql.Row.
+type Row struct {
+	rows *sql.Rows
+	err  error
+}
+
+// Scan copies the columns of the matched row into the values pointed at by
+// dest. If more than one row matches the query, Scan uses the first one and
+// discards the rest. If no row matches the query, Scan returns
+// sql.ErrNoRows.
+func (r *Row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+
+	return r.rows.Close()
+}
+
+// Mark the session as written and return the pinned connection, pinning one
+// if needed.
+func (s *Session) pin(ctx context.Context) (*sql.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wrote = true
+	return s.connect(ctx)
+}
+
+// Return the pinned connection if a write was performed, or nil otherwise.
+func (s *Session) pinned(ctx context.Context) (*sql.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.wrote {
+		return nil, nil
+	}
+	return s.connect(ctx)
+}
+
+// Return the pinned connection, opening a new one if needed. Must be called
+// with the lock held.
+func (s *Session) connect(ctx context.Context) (*sql.Conn, error) {
+	if s.conn != nil {
+		return s.conn, nil
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to pin connection")
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+// Drop the given connection if the given error means it can't be used
+// anymore, so the next statement pins a new one.
+func (s *Session) check(conn *sql.Conn, err error) {
+	if err == nil {
+		return
+	}
+	if errors.Cause(err) != driver.ErrBadConn && errors.Cause(err) != sql.ErrConnDone {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != conn {
+		return
+	}
+	conn.Close()
+	s.conn = nil
+}
@@ -0,0 +1,271 @@
+package cowsql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	cowsql "github.com/cowsql/go-cowsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Reads performed before any write go through the pool, without pinning a
+// connection.
+func TestSession_ReadBeforeWrite(t *testing.T) {
+	db, _, cleanup := newSessionDB(t)
+	defer cleanup()
+
+	session := cowsql.NewSession(db)
+	defer session.Close()
+
+	rows, err := session.QueryContext(context.Background(), "SELECT conn")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	var id int
+	require.NoError(t, session.QueryRowContext(context.Background(), "SELECT conn").Scan(&id))
+
+	assert.Equal(t, 0, db.Stats().InUse)
+}
+
+// A write pins a connection, and further reads go to it.
+func TestSession_ExecPins(t *testing.T) {
+	db, d, cleanup := newSessionDB(t)
+	defer cleanup()
+
+	session := cowsql.NewSession(db)
+	defer session.Close()
+
+	_, err := session.ExecContext(context.Background(), "INSERT")
+	require.NoError(t, err)
+	assert.Equal(t, 1, db.Stats().InUse)
+
+	var id int
+	require.NoError(t, session.QueryRowContext(context.Background(), "SELECT conn").Scan(&id))
+	assert.Equal(t, d.lastExec(), id)
+	assert.Equal(t, 1, db.Stats().InUse)
+}
+
+// Starting a transaction pins a connection, since it might perform writes.
+func TestSession_BeginTxPins(t *testing.T) {
+	db, d, cleanup := newSessionDB(t)
+	defer cleanup()
+
+	session := cowsql.NewSession(db)
+	defer session.Close()
+
+	tx, err := session.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	_, err = tx.Exec("INSERT")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, 1, db.Stats().InUse)
+
+	var id int
+	require.NoError(t, session.QueryRowContext(context.Background(), "SELECT conn").Scan(&id))
+	assert.Equal(t, d.lastExec(), id)
+}
+
+// If the pinned connection is lost, the next statement pins a new one.
+func TestSession_RepinAfterBadConn(t *testing.T) {
+	db, d, cleanup := newSessionDB(t)
+	defer cleanup()
+
+	session := cowsql.NewSession(db)
+	defer session.Close()
+
+	_, err := session.ExecContext(context.Background(), "INSERT")
+	require.NoError(t, err)
+	first := d.lastExec()
+
+	d.fail(first)
+	_, err = session.ExecContext(context.Background(), "INSERT")
+	assert.Equal(t, driver.ErrBadConn, err)
+
+	_, err = session.ExecContext(context.Background(), "INSERT")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, d.lastExec())
+	assert.Equal(t, 1, db.Stats().InUse)
+}
+
+// A lost pinned connection is also dropped when detected by QueryRowContext.
+func TestSession_RepinAfterBadConnQueryRow(t *testing.T) {
+	db, d, cleanup := newSessionDB(t)
+	defer cleanup()
+
+	session := cowsql.NewSession(db)
+	defer session.Close()
+
+	_, err := session.ExecContext(context.Background(), "INSERT")
+	require.NoError(t, err)
+	first := d.lastExec()
+
+	d.fail(first)
+	var id int
+	err = session.QueryRowContext(context.Background(), "SELECT conn").Scan(&id)
+	assert.Equal(t, driver.ErrBadConn, err)
+
+	require.NoError(t, session.QueryRowContext(context.Background(), "SELECT conn").Scan(&id))
+	assert.NotEqual(t, first, id)
+}
+
+// Closing the session returns the pinned connection to the pool.
+func TestSession_Close(t *testing.T) {
+	db, _, cleanup := newSessionDB(t)
+	defer cleanup()
+
+	session := cowsql.NewSession(db)
+
+	_, err := session.ExecContext(context.Background(), "INSERT")
+	require.NoError(t, err)
+	assert.Equal(t, 1, db.Stats().InUse)
+
+	require.NoError(t, session.Close())
+	assert.Equal(t, 0, db.Stats().InUse)
+
+	// Reads after closing go through the pool again.
+	var id int
+	require.NoError(t, session.QueryRowContext(context.Background(), "SELECT conn").Scan(&id))
+	assert.Equal(t, 0, db.Stats().InUse)
+}
+
+var sessionDrivers = 0
+
+// Open a database backed by a fake driver, whose queries return the ID of
+// the connection serving them.
+func newSessionDB(t *testing.T) (*sql.DB, *sessionDriver, func()) {
+	t.Helper()
+
+	d := &sessionDriver{bad: map[int]bool{}}
+	sessionDrivers++
+	name := fmt.Sprintf("cowsql-session-test-%d", sessionDrivers)
+	sql.Register(name, d)
+
+	db, err := sql.Open(name, "")
+	require.NoError(t, err)
+
+	cleanup := func() {
+		require.NoError(t, db.Close())
+	}
+
+	return db, d, cleanup
+}
+
+type sessionDriver struct {
+	mu    sync.Mutex
+	conns int          // Number of opened connections
+	execs []int        // IDs of the connections that executed a statement
+	bad   map[int]bool // IDs of the connections that are lost
+}
+
+func (d *sessionDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.conns++
+	return &sessionConn{driver: d, id: d.conns}, nil
+}
+
+// Mark the connection with the given ID as lost.
+func (d *sessionDriver) fail(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.bad[id] = true
+}
+
+// Return the ID of the connection that last executed a statement.
+func (d *sessionDriver) lastExec() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.execs) == 0 {
+		return 0
+	}
+	return d.execs[len(d.execs)-1]
+}
+
+// Record a statement executed by the given connection, failing if it's lost.
+func (d *sessionDriver) exec(id int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.bad[id] {
+		return driver.ErrBadConn
+	}
+	d.execs = append(d.execs, id)
+	return nil
+}
+
+// Fail if the connection with the given ID is lost.
+func (d *sessionDriver) check(id int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.bad[id] {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+type sessionConn struct {
+	driver *sessionDriver
+	id     int
+}
+
+func (c *sessionConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (c *sessionConn) Close() error {
+	return nil
+}
+
+func (c *sessionConn) Begin() (driver.Tx, error) {
+	if err := c.driver.check(c.id); err != nil {
+		return nil, err
+	}
+	return sessionTx{}, nil
+}
+
+func (c *sessionConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.driver.exec(c.id); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *sessionConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.driver.check(c.id); err != nil {
+		return nil, err
+	}
+	return &sessionRows{id: c.id}, nil
+}
+
+type sessionTx struct{}
+
+func (sessionTx) Commit() error   { return nil }
+func (sessionTx) Rollback() error { return nil }
+
+// A single row with the ID of the connection that served the query.
+type sessionRows struct {
+	id   int
+	done bool
+}
+
+func (r *sessionRows) Columns() []string {
+	return []string{"conn"}
+}
+
+func (r *sessionRows) Close() error {
+	return nil
+}
+
+func (r *sessionRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(r.id)
+	return nil
+}